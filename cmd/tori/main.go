@@ -6,13 +6,16 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -108,6 +111,11 @@ func runVersion() {
 }
 
 func runAgent(args []string) {
+	if len(args) > 0 && args[0] == "backtest" {
+		runBacktest(args[1:])
+		return
+	}
+
 	fs := flag.NewFlagSet("agent", flag.ExitOnError)
 	configPath := fs.String("config", "/etc/tori/config.toml", "path to config file")
 	fs.Parse(args)
@@ -149,6 +157,124 @@ func runAgent(args []string) {
 	}
 }
 
+// runBacktest replays one alert rule from the config against the agent's
+// stored history and prints the intervals it would have fired.
+func runBacktest(args []string) {
+	fs := flag.NewFlagSet("agent backtest", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage:\n  tori agent backtest --rule NAME [--since 7d] [flags]\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	configPath := fs.String("config", "/etc/tori/config.toml", "path to config file")
+	rule := fs.String("rule", "", "name of the alert rule to replay")
+	sinceStr := fs.String("since", "7d", "how far back to replay (e.g. 12h, 7d)")
+	fs.Parse(args)
+
+	if *rule == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+	since, err := parseSince(*sinceStr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	cfg, err := agent.LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: load config: %v\n", err)
+		os.Exit(1)
+	}
+	ac, ok := cfg.Alerts[*rule]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: no alert rule %q in %s\n", *rule, *configPath)
+		os.Exit(1)
+	}
+
+	store, err := agent.OpenStore(cfg.Storage.Path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: open store: %v\n", err)
+		os.Exit(1)
+	}
+	defer store.Close()
+
+	alerter, err := agent.NewAlerter(map[string]agent.AlertConfig{*rule: ac}, store, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	end := time.Now()
+	res, err := alerter.Backtest(ctx, *rule, end.Add(-since), end)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: backtest: %v\n", err)
+		os.Exit(1)
+	}
+	printBacktest(os.Stdout, res)
+}
+
+// printBacktest writes a backtest result as a table of firing intervals.
+func printBacktest(w io.Writer, res *agent.BacktestResult) {
+	const layout = "2006-01-02 15:04:05"
+	fmt.Fprintf(w, "rule %s: %s\n", res.Rule, res.Condition)
+	fmt.Fprintf(w, "replayed %d samples from %s to %s\n\n", res.Samples, res.Start.Format(layout), res.End.Format(layout))
+	if len(res.Intervals) == 0 {
+		fmt.Fprintln(w, "would not have fired")
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FIRED\tRESOLVED\tDURATION\tINSTANCE")
+	var total time.Duration
+	for _, iv := range res.Intervals {
+		resolved := "still firing"
+		until := res.End
+		if !iv.ResolvedAt.IsZero() {
+			resolved = iv.ResolvedAt.Format(layout)
+			until = iv.ResolvedAt
+		}
+		dur := until.Sub(iv.FiredAt).Round(time.Second)
+		total += dur
+		instance := iv.InstanceKey
+		if iv.Label != "" {
+			instance = iv.Label
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", iv.FiredAt.Format(layout), resolved, dur, instance)
+	}
+	tw.Flush()
+
+	noun := "firings"
+	if len(res.Intervals) == 1 {
+		noun = "firing"
+	}
+	fmt.Fprintf(w, "\n%d %s, %s firing in total\n", len(res.Intervals), noun, total)
+}
+
+// parseSince parses a lookback duration. It accepts Go durations ("90m",
+// "12h") and whole days ("7d").
+func parseSince(s string) (time.Duration, error) {
+	var d time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if d, err = time.ParseDuration(s); err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("duration must be positive, got %q", s)
+	}
+	return d, nil
+}
+
 // clientAction describes what runClient should do after parsing flags.
 type clientAction struct {
 	mode       string // "socket", "ssh", "config"
@@ -163,7 +289,7 @@ type clientAction struct {
 func parseClientArgs(args []string) (*clientAction, error) {
 	fs := flag.NewFlagSet("tori", flag.ContinueOnError)
 	fs.Usage = func() {
-		fmt.Fprintf(fs.Output(), "Usage:\n  tori [user@host] [flags]\n  tori agent [flags]\n\nFlags:\n")
+		fmt.Fprintf(fs.Output(), "Usage:\n  tori [user@host] [flags]\n  tori agent [flags]\n  tori agent backtest --rule NAME [--since 7d]\n\nFlags:\n")
 		fs.PrintDefaults()
 	}
 	socketPath := fs.String("socket", "", "path to agent socket (direct connection)")
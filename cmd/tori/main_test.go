@@ -2,6 +2,7 @@ package main
 
 import (
 	"testing"
+	"time"
 
 	"github.com/thobiasn/tori-cli/internal/tui"
 )
@@ -150,3 +151,31 @@ func TestParseClientArgs(t *testing.T) {
 		})
 	}
 }
+
+func TestParseSince(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"7d", 7 * 24 * time.Hour, false},
+		{"12h", 12 * time.Hour, false},
+		{"90m", 90 * time.Minute, false},
+		{"0d", 0, true},
+		{"-1h", 0, true},
+		{"1w", 0, true},
+		{"d", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseSince(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseSince(%q): expected error", tt.in)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseSince(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
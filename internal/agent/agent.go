@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/thobiasn/tori-cli/internal/protocol"
@@ -23,8 +26,17 @@ type Agent struct {
 	hub     *Hub
 	socket  *SocketServer
 
-	reload    chan *Config
-	lastPrune time.Time
+	reload     chan *Config
+	lastPrune  time.Time
+	nextDigest time.Time // zero = not scheduled yet
+
+	ruleChanges chan ruleChange // runtime rule edits from the socket
+
+	startedAt     time.Time
+	runID         int64     // agent_runs row, marked stopped on clean shutdown
+	notifier      *Notifier // delivers the startup notices while no alerter runs
+	nextHeartbeat time.Time
+	heartbeating  atomic.Bool // a heartbeat request is in flight
 }
 
 // New creates an Agent from the given config. cfgPath is stored for reload.
@@ -73,36 +85,110 @@ func New(cfg *Config, cfgPath string, version string) (*Agent, error) {
 		logs:    lt,
 		hub:     hub,
 		reload:  make(chan *Config, 1),
+
+		ruleChanges: make(chan ruleChange),
+
+		startedAt: time.Now(),
+	}
+
+	runID, prevRun, err := store.BeginRun(context.Background(), a.startedAt)
+	if err != nil {
+		slog.Warn("failed to record agent run", "error", err)
+	}
+	a.runID = runID
+
+	// Alerts are left firing on a clean stop too, to be adopted on start, so
+	// an unclean shutdown is told by the agent_runs row, not by them. Count
+	// them before the alerts of removed rules are resolved below.
+	unclean := prevRun != nil && prevRun.StoppedAt == nil
+	var firing int
+	if unclean {
+		if alerts, err := store.QueryFiringAlerts(context.Background()); err == nil {
+			firing = len(alerts)
+		}
 	}
 
-	if len(cfg.Alerts) > 0 {
-		notifier := NewNotifier(&cfg.Notify)
-		alerter, err := NewAlerter(cfg.Alerts, store, notifier)
+	// Notifications still queued from the previous run are replayed by the
+	// alerter's notifier.
+	if n, err := store.RequeueNotifications(context.Background()); err != nil {
+		slog.Warn("failed to requeue notifications", "error", err)
+	} else if n > 0 {
+		slog.Info("replaying queued notifications", "count", n)
+	}
+
+	alerts, catalog, err := a.loadAlertRules(context.Background(), cfg)
+	if err != nil {
+		store.Close()
+		docker.Close()
+		return nil, err
+	}
+	if len(alerts) > 0 {
+		alerter, err := a.newAlerter(cfg, alerts)
 		if err != nil {
 			store.Close()
 			docker.Close()
-			return nil, fmt.Errorf("alerter: %w", err)
+			return nil, err
 		}
-		alerter.onStateChange = a.makeOnStateChange()
 		a.alerter = alerter
 
 		// Adopt firing alerts from a previous run into the alerter's state.
 		if err := alerter.AdoptFiring(context.Background()); err != nil {
 			slog.Warn("failed to adopt firing alerts", "error", err)
 		}
-	} else {
-		// No alerter — bulk-resolve any leftover unresolved alerts.
-		if err := store.ResolveOrphanedAlerts(context.Background(), time.Now()); err != nil {
-			slog.Warn("failed to resolve orphaned alerts", "error", err)
+		if err := alerter.LoadSilences(context.Background()); err != nil {
+			slog.Warn("failed to load silences", "error", err)
 		}
+	} else {
+		// No alerter — resolve any leftover unresolved alerts through a
+		// notifier of the agent's own.
+		a.notifier = NewNotifier(&cfg.Notify, store)
+		a.resolveOrphans(context.Background())
+	}
+
+	if unclean {
+		a.notifyUncleanShutdown(context.Background(), prevRun, firing)
 	}
 
 	a.events = NewEventWatcher(docker, hub)
 	a.events.SetAlerter(a.alerter)
 	a.socket = NewSocketServer(hub, store, docker, a.alerter, cfg.Storage.RetentionDays, version)
+	a.socket.SetRuleEditor(a.editRules)
+	a.socket.SetRuleCatalog(catalog)
 	return a, nil
 }
 
+// newAlerter builds an alerter for the given rules with cfg's notifier,
+// maintenance windows, inhibit rules and actions.
+func (a *Agent) newAlerter(cfg *Config, alerts map[string]AlertConfig) (*Alerter, error) {
+	notifier := NewNotifier(&cfg.Notify, a.store)
+	alerter, err := NewAlerter(alerts, a.store, notifier)
+	if err != nil {
+		return nil, fmt.Errorf("alerter: %w", err)
+	}
+	if err := alerter.SetMaintenance(cfg.Maintenance); err != nil {
+		return nil, fmt.Errorf("alerter: %w", err)
+	}
+	alerter.SetInhibit(cfg.Inhibit)
+	alerter.SetExec(&cfg.Exec)
+	alerter.SetDocker(a.docker)
+	alerter.onStateChange = a.makeOnStateChange()
+	return alerter, nil
+}
+
+// setAlerter installs a new alerter (nil = none) and hands it to the socket
+// server and the event watcher. The alerter's notifier takes over from the
+// agent's own, and delivers what that one left queued.
+func (a *Agent) setAlerter(alerter *Alerter, catalog ruleCatalog) {
+	if alerter != nil && a.notifier != nil {
+		a.notifier.Stop()
+		a.notifier = nil
+	}
+	a.alerter = alerter
+	a.socket.SetAlerter(alerter)
+	a.socket.SetRuleCatalog(catalog)
+	a.events.SetAlerter(alerter)
+}
+
 // Reload re-reads the config file and sends it to the Run loop for application.
 // Safe to call from any goroutine (e.g. SIGHUP handler). If a reload is already
 // pending, the new one is dropped.
@@ -149,6 +235,8 @@ func (a *Agent) Run(ctx context.Context) error {
 		case newCfg := <-a.reload:
 			a.applyConfig(ctx, newCfg)
 			ticker.Reset(a.cfg.Collect.Interval.Duration)
+		case ch := <-a.ruleChanges:
+			ch.reply <- a.applyRuleChange(ctx, ch)
 		}
 	}
 }
@@ -188,37 +276,45 @@ func (a *Agent) applyConfig(ctx context.Context, newCfg *Config) {
 	a.socket.SetRetentionDays(newCfg.Storage.RetentionDays)
 
 	// Rebuild alerter + notifier if alert/notify config changed.
-	if len(newCfg.Alerts) > 0 {
-		notifier := NewNotifier(&newCfg.Notify)
-		alerter, err := NewAlerter(newCfg.Alerts, a.store, notifier)
+	alerts, catalog, err := a.loadAlertRules(ctx, newCfg)
+	if err != nil {
+		slog.Error("config reload: failed to load alert rules, keeping old", "error", err)
+		return
+	}
+	if len(alerts) > 0 {
+		alerter, err := a.newAlerter(newCfg, alerts)
 		if err != nil {
 			slog.Error("config reload: failed to create alerter, keeping old", "error", err)
 			return
 		}
-		alerter.onStateChange = a.makeOnStateChange()
+		if err := alerter.LoadSilences(ctx); err != nil {
+			slog.Warn("config reload: failed to load silences", "error", err)
+		}
 		if a.alerter != nil {
 			a.alerter.ResolveAll(ctx)
 			a.alerter.Stop()
 		}
-		a.alerter = alerter
-		a.socket.SetAlerter(alerter)
-		a.events.SetAlerter(alerter)
+		a.setAlerter(alerter, catalog)
 	} else {
 		if a.alerter != nil {
 			a.alerter.ResolveAll(ctx)
 			a.alerter.Stop()
 		}
-		a.alerter = nil
-		a.socket.SetAlerter(nil)
-		a.events.SetAlerter(nil)
+		a.setAlerter(nil, catalog)
 	}
 
 	a.cfg.Alerts = newCfg.Alerts
 	a.cfg.Notify = newCfg.Notify
+	a.cfg.Maintenance = newCfg.Maintenance
+	a.nextDigest = time.Time{} // reschedule with the new digest settings
+	a.cfg.Inhibit = newCfg.Inhibit
+	a.cfg.Heartbeat = newCfg.Heartbeat
+	a.cfg.Exec = newCfg.Exec
+	a.nextHeartbeat = time.Time{}
 
 	slog.Info("config reloaded",
 		"interval", a.cfg.Collect.Interval.Duration,
-		"alert_rules", len(a.cfg.Alerts),
+		"alert_rules", len(alerts),
 		"retention_days", a.cfg.Storage.RetentionDays,
 	)
 }
@@ -227,14 +323,20 @@ func (a *Agent) applyConfig(ctx context.Context, newCfg *Config) {
 func (a *Agent) makeOnStateChange() func(alert *Alert, state string) {
 	return func(alert *Alert, state string) {
 		event := &protocol.AlertEvent{
-			ID:          alert.ID,
-			RuleName:    alert.RuleName,
-			Severity:    alert.Severity,
-			Condition:   alert.Condition,
-			InstanceKey: alert.InstanceKey,
-			FiredAt:     alert.FiredAt.Unix(),
-			Message:     alert.Message,
-			State:       state,
+			ID:           alert.ID,
+			RuleName:     alert.RuleName,
+			Severity:     alert.Severity,
+			Condition:    alert.Condition,
+			InstanceKey:  alert.InstanceKey,
+			FiredAt:      alert.FiredAt.Unix(),
+			Message:      alert.Message,
+			State:        state,
+			Acknowledged: alert.Acknowledged,
+			InhibitedBy:  alert.InhibitedBy,
+			Remediations: convertRemediations(alert.Remediations),
+			Flapping:     alert.Flapping,
+			Description:  alert.Description,
+			RunbookURL:   alert.RunbookURL,
 		}
 		if alert.ResolvedAt != nil {
 			event.ResolvedAt = alert.ResolvedAt.Unix()
@@ -281,11 +383,16 @@ func (a *Agent) collect(ctx context.Context) {
 		a.logs.Sync(ctx, containers)
 	}
 
+	if hostMetrics != nil {
+		hostMetrics.AgentUptime = time.Since(a.startedAt).Seconds()
+	}
+
 	// Evaluate alert rules against collected data.
 	if a.alerter != nil {
 		a.alerter.Evaluate(ctx, &MetricSnapshot{
 			Host:       hostMetrics,
 			Disks:      diskMetrics,
+			Nets:       netMetrics,
 			Containers: containerMetrics,
 		})
 	}
@@ -337,6 +444,117 @@ func (a *Agent) collect(ctx context.Context) {
 			slog.Info("pruned old data", "retention_days", a.cfg.Storage.RetentionDays)
 		}
 	}
+
+	a.maybeSendDigest(ctx)
+	a.maybeSendHeartbeat(ctx)
+}
+
+// maybeSendHeartbeat pings the heartbeat URL once per interval. The request
+// runs in the background so a slow endpoint can't stall collection; a ping
+// still in flight when the next is due is not doubled up.
+func (a *Agent) maybeSendHeartbeat(ctx context.Context) {
+	hb := a.cfg.Heartbeat
+	now := time.Now()
+	if !hb.Enabled || now.Before(a.nextHeartbeat) {
+		return
+	}
+	if !a.heartbeating.CompareAndSwap(false, true) {
+		return
+	}
+	a.nextHeartbeat = now.Add(hb.Interval.Duration)
+
+	hostname, _ := os.Hostname()
+	p := heartbeatPayload{
+		Agent:         "tori",
+		Version:       a.version,
+		Hostname:      hostname,
+		StartedAt:     a.startedAt.UTC().Format(time.RFC3339),
+		UptimeSeconds: int64(now.Sub(a.startedAt).Seconds()),
+		Time:          now.UTC().Format(time.RFC3339),
+	}
+	if a.alerter != nil {
+		p.FiringAlerts = a.alerter.FiringCount()
+	}
+	go func() {
+		defer a.heartbeating.Store(false)
+		if err := sendHeartbeat(ctx, &hb, p); err != nil {
+			slog.Warn("heartbeat failed", "error", err)
+		}
+	}()
+}
+
+// resolveOrphans resolves the alerts left firing by the previous run when no
+// alert rules remain, and tells the incident channels so that upstream
+// incidents close.
+func (a *Agent) resolveOrphans(ctx context.Context) {
+	firing, err := a.store.QueryFiringAlerts(ctx)
+	if err != nil {
+		slog.Warn("failed to query orphaned alerts", "error", err)
+	}
+	now := time.Now()
+	if err := a.store.ResolveOrphanedAlerts(ctx, now); err != nil {
+		slog.Warn("failed to resolve orphaned alerts", "error", err)
+		return
+	}
+	for i := range firing {
+		a.notifier.Incident(orphanResolved(&firing[i], now))
+	}
+}
+
+// notifyUncleanShutdown tells every notification channel that the previous
+// agent run ended without a clean stop, e.g. a crash, OOM kill or power loss.
+// firing is the number of alerts the previous run left firing.
+func (a *Agent) notifyUncleanShutdown(ctx context.Context, prev *AgentRun, firing int) {
+	var body strings.Builder
+	hostname, _ := os.Hostname()
+	fmt.Fprintf(&body, "The tori agent on %s started after an unclean shutdown.\n", hostname)
+	fmt.Fprintf(&body, "The previous run started at %s and did not stop cleanly.\n", prev.StartedAt.Format(time.RFC1123))
+	if last, err := a.store.LastHostSample(ctx); err == nil && !last.IsZero() {
+		fmt.Fprintf(&body, "Its last metrics were collected at %s, %s before this start.\n",
+			last.Format(time.RFC1123), formatWindow(a.startedAt.Sub(last).Truncate(time.Second)))
+	}
+	if firing == 1 {
+		body.WriteString("1 alert was firing when it went down.\n")
+	} else if firing > 1 {
+		fmt.Fprintf(&body, "%d alerts were firing when it went down.\n", firing)
+	}
+	slog.Warn("previous agent run did not shut down cleanly", "started_at", prev.StartedAt)
+
+	subject := "tori agent restarted after unclean shutdown"
+	if a.alerter != nil {
+		a.alerter.notifier.Send(subject, body.String())
+	} else if a.notifier != nil {
+		a.notifier.Send(subject, body.String())
+	}
+}
+
+// maybeSendDigest sends the daily alert digest once its scheduled time has
+// passed. The first call only schedules it.
+func (a *Agent) maybeSendDigest(ctx context.Context) {
+	d := &a.cfg.Notify.Digest
+	if !d.Enabled || a.alerter == nil {
+		return
+	}
+	now := time.Now()
+	if !a.nextDigest.IsZero() && now.Before(a.nextDigest) {
+		return
+	}
+	due := !a.nextDigest.IsZero()
+
+	loc := time.Local
+	if d.Timezone != "" {
+		if l, err := time.LoadLocation(d.Timezone); err == nil {
+			loc = l
+		}
+	}
+	at, _ := parseClock(d.At) // validated at load
+	a.nextDigest = nextDigest(now, at, loc)
+	if !due {
+		return
+	}
+	if err := a.alerter.SendDigest(ctx, now.Add(-24*time.Hour).In(loc), now.In(loc)); err != nil {
+		slog.Error("send digest", "error", err)
+	}
 }
 
 // shutdown stops all components in the correct order:
@@ -354,7 +572,15 @@ func (a *Agent) shutdown() error {
 	if a.alerter != nil {
 		a.alerter.Stop()
 	}
+	if a.notifier != nil {
+		a.notifier.Stop()
+	}
 
+	if a.runID > 0 {
+		if err := a.store.EndRun(context.Background(), a.runID, time.Now()); err != nil {
+			slog.Error("record clean shutdown", "error", err)
+		}
+	}
 	if err := a.store.Close(); err != nil {
 		slog.Error("close store", "error", err)
 	}
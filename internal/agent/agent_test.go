@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -241,3 +242,67 @@ func TestApplyConfigRebuildsAlerter(t *testing.T) {
 		t.Error("alerter should be nil after reload without alert rules")
 	}
 }
+
+func TestNotifyUncleanShutdown(t *testing.T) {
+	a, s, rec := testAlerterWithRecorder(t, map[string]AlertConfig{
+		"cpu": {Condition: "host.cpu_percent > 90", Severity: "critical", Actions: []string{"notify"}},
+	})
+	ctx := context.Background()
+	started := time.Now().Add(-time.Hour)
+	if err := s.InsertHostMetrics(ctx, started.Add(30*time.Minute), &HostMetrics{}); err != nil {
+		t.Fatal(err)
+	}
+	ag := &Agent{store: s, alerter: a, startedAt: time.Now(), cfg: &Config{}}
+	ag.notifyUncleanShutdown(ctx, &AgentRun{ID: 1, StartedAt: started}, 1)
+	a.notifier.Flush()
+
+	notes := rec.Notifications()
+	if len(notes) != 1 || notes[0].subject != "tori agent restarted after unclean shutdown" {
+		t.Fatalf("notifications = %+v", notes)
+	}
+	for _, want := range []string{"unclean shutdown", "collected at", "30m before this start", "1 alert was firing"} {
+		if !strings.Contains(notes[0].body, want) {
+			t.Errorf("body missing %q:\n%s", want, notes[0].body)
+		}
+	}
+}
+
+func TestResolveOrphansWithoutRules(t *testing.T) {
+	s := testStore(t)
+	pager := &recordingIncidentChannel{}
+	n := testQueueNotifier(t, s, pager, &testClock{now: time.Now()})
+	n.incident = true
+	ctx := context.Background()
+	started := time.Now().Add(-time.Hour)
+	if _, err := s.InsertAlert(ctx, &Alert{RuleName: "cpu", Severity: "critical", InstanceKey: "cpu", FiredAt: started}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Start-up without rules after an unclean shutdown: the count is taken
+	// before the leftover alert is resolved.
+	ag := &Agent{store: s, notifier: n, startedAt: time.Now(), cfg: &Config{}}
+	firing, err := s.QueryFiringAlerts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ag.resolveOrphans(ctx)
+	ag.notifyUncleanShutdown(ctx, &AgentRun{ID: 1, StartedAt: started}, len(firing))
+	n.Flush()
+
+	notices := pager.Notifications()
+	if len(notices) != 2 {
+		t.Fatalf("sent %d notifications, want the resolve event and the notice", len(notices))
+	}
+	if !notices[0].incident || notices[0].alerts[0].key != "cpu" || notices[0].alerts[0].status != "resolved" {
+		t.Errorf("first = %+v, want the resolve event for cpu", notices[0])
+	}
+	if !strings.Contains(notices[1].body, "1 alert was firing") {
+		t.Errorf("notice body = %q", notices[1].body)
+	}
+	if got := notificationStatuses(t, s); len(got) != 2 || !strings.HasSuffix(got[0], ":sent") || !strings.HasSuffix(got[1], ":sent") {
+		t.Errorf("statuses = %v, want both delivered through the queue", got)
+	}
+	if left, _ := s.QueryFiringAlerts(ctx); len(left) != 0 {
+		t.Errorf("firing after resolveOrphans = %d, want 0", len(left))
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"text/template"
 	"time"
 )
 
@@ -14,6 +15,7 @@ import (
 type MetricSnapshot struct {
 	Host       *HostMetrics
 	Disks      []DiskMetrics
+	Nets       []NetMetrics // cumulative counters; the alerter derives rates
 	Containers []ContainerMetrics
 }
 
@@ -32,18 +34,40 @@ type alertInstance struct {
 	resolvedAt   time.Time // zero = never resolved; used for cooldown
 	falseAt      time.Time // when condition first went false while firing (resolve grace)
 	dbID         int64
+	containerID  string // container the instance belongs to, for inhibition matching
+	project      string
+	message      string // notification body, repeated by escalation steps
+	escalated    int    // escalation steps taken, persisted in alerts.escalation_step
+	inhibitedBy  string // source rule that suppressed the firing notification
+	flapping     bool
+	transitions  []time.Time // recent fire/resolve times, for flap detection
 }
 
 type alertRule struct {
 	name           string
-	condition      Condition
+	cond           *Expr
 	forDur         time.Duration
 	resolveForDur  time.Duration
 	cooldown       time.Duration
 	notifyCooldown time.Duration
 	severity       string
 	actions        []string
-	// Log-rule fields (only set when condition.Scope == "log").
+	channels       []string          // notify:NAME targets
+	routed         bool              // plain "notify" action: follows notify.routes
+	escalate       []EscalationStep  // ordered by after
+	commands       []string          // exec:PATH targets
+	remediate      string            // docker action: "restart", "stop" or ""
+	maxAttempts    int               // remediation attempts per instance
+	backoff        time.Duration     // wait after the first attempt, doubling
+	flapLimit      int               // flapping after this many state changes in flapWindow; 0 = off
+	flapWindow     time.Duration     // also how long a flapping instance must stay stable
+	selector       containerSelector // container and log rules only
+	ifaces         ifaceFilter       // net rules only
+	description    string
+	runbookURL     string
+	msgTemplate    string
+	tmpl           *template.Template // parsed msgTemplate, nil = default message
+	// Log-rule fields (only set when cond.Scope() == "log").
 	match      string
 	matchRegex bool
 	window     time.Duration
@@ -54,24 +78,41 @@ type evalContext struct {
 	rule        *alertRule
 	key         string
 	containerID string
+	project     string // compose project of the container, "" for other scopes
 	label       string
+	env         *condEnv // values the rule was evaluated against, for message templates
 }
 
 // Alerter evaluates alert rules against metric snapshots.
 type Alerter struct {
 	mu           sync.Mutex // protects instances, deferred, lastNotified; held during Evaluate and EvaluateContainerEvent
 	rules        []alertRule
+	evalOrder    []*alertRule // rules in evaluation order: inhibition sources first
+	inhibits     []inhibitRule
 	instances    map[string]*alertInstance
 	deferred     []func()             // slow side effects collected under mu, executed after release
+	noExec       bool                 // set while ResolveAll and ReplaceRule resolve; guarded by mu
 	lastNotified map[string]time.Time // rule name -> last notification time (for notify_cooldown)
 	store        *Store
 	notifier     *Notifier
+	exec         *executor // nil = exec actions disabled
 	now          func() time.Time
+	history      *metricHistory // samples for aggregate conditions; guarded by mu
+	netRates     *netRateCalc   // previous interface counters; guarded by mu
+	replay       *backtestLog   // set in backtests: record transitions instead of persisting and notifying
 
 	onStateChange func(a *Alert, state string) // called on "firing" / "resolved"
 
-	silences   map[string]time.Time // rule name -> silenced until
+	silences   []Silence // active silences, mirrored from the store
 	silencesMu sync.Mutex
+
+	maintenance []*maintenanceWindow // set once by SetMaintenance before evaluation starts
+
+	docker        containerController          // nil = docker actions disabled
+	remediations  map[string]*remediationState // instance key -> attempts; guarded by mu
+	remediating   sync.WaitGroup               // running docker actions
+	remediateCtx  context.Context              // cancelled by Stop
+	remediateStop context.CancelFunc
 }
 
 // NewAlerter creates an Alerter from the config's alert rules.
@@ -80,10 +121,12 @@ func NewAlerter(alerts map[string]AlertConfig, store *Store, notifier *Notifier)
 		instances:    make(map[string]*alertInstance),
 		deferred:     make([]func(), 0, 8),
 		lastNotified: make(map[string]time.Time),
+		remediations: make(map[string]*remediationState),
 		store:        store,
 		notifier:     notifier,
 		now:          time.Now,
-		silences:     make(map[string]time.Time),
+		history:      newMetricHistory(),
+		netRates:     newNetRateCalc(),
 	}
 
 	// Sort rule names for deterministic evaluation order.
@@ -94,28 +137,77 @@ func NewAlerter(alerts map[string]AlertConfig, store *Store, notifier *Notifier)
 	sort.Strings(names)
 
 	for _, name := range names {
-		ac := alerts[name]
-		cond, err := parseCondition(ac.Condition)
+		r, err := a.newRule(name, alerts[name])
 		if err != nil {
-			return nil, fmt.Errorf("alert %q: %w", name, err)
-		}
-		a.rules = append(a.rules, alertRule{
-			name:           name,
-			condition:      cond,
-			forDur:         ac.For.Duration,
-			resolveForDur:  ac.ResolveFor.Duration,
-			cooldown:       ac.Cooldown.Duration,
-			notifyCooldown: ac.NotifyCooldown.Duration,
-			severity:       ac.Severity,
-			actions:        ac.Actions,
-			match:          ac.Match,
-			matchRegex:     ac.MatchRegex,
-			window:         ac.Window.Duration,
-		})
+			return nil, err
+		}
+		a.rules = append(a.rules, r)
 	}
+	a.orderRules()
 	return a, nil
 }
 
+// newRule builds the named rule from its config and registers the windows of
+// its aggregate conditions with the metric history.
+func (a *Alerter) newRule(name string, ac AlertConfig) (alertRule, error) {
+	cond, err := parseCondition(ac.Condition)
+	if err != nil {
+		return alertRule{}, fmt.Errorf("alert %q: %w", name, err)
+	}
+	cond.windows(a.history.track)
+	var tmpl *template.Template
+	if ac.MessageTemplate != "" {
+		if tmpl, err = parseMessageTemplate(ac.MessageTemplate); err != nil {
+			return alertRule{}, fmt.Errorf("alert %q: message_template: %w", name, err)
+		}
+	}
+	channels, routed := parseNotifyActions(ac.Actions)
+	return alertRule{
+		name:           name,
+		cond:           cond,
+		forDur:         ac.For.Duration,
+		resolveForDur:  ac.ResolveFor.Duration,
+		cooldown:       ac.Cooldown.Duration,
+		notifyCooldown: ac.NotifyCooldown.Duration,
+		severity:       ac.Severity,
+		actions:        ac.Actions,
+		channels:       channels,
+		routed:         routed,
+		escalate:       ac.Escalate,
+		commands:       execCommands(ac.Actions),
+		remediate:      remediationAction(ac.Actions),
+		maxAttempts:    ac.Remediate.MaxAttempts,
+		backoff:        ac.Remediate.Backoff.Duration,
+		flapLimit:      ac.Flapping.Transitions,
+		flapWindow:     ac.Flapping.Window.Duration,
+		description:    ac.Description,
+		runbookURL:     ac.RunbookURL,
+		msgTemplate:    ac.MessageTemplate,
+		tmpl:           tmpl,
+		selector:       newContainerSelector(&ac),
+		ifaces:         newIfaceFilter(&ac),
+		match:          ac.Match,
+		matchRegex:     ac.MatchRegex,
+		window:         ac.Window.Duration,
+	}, nil
+}
+
+// orderRules rebuilds the evaluation order: inhibition sources first, then
+// the other rules, each by name.
+func (a *Alerter) orderRules() {
+	sources := make(map[string]bool, len(a.inhibits))
+	for _, ih := range a.inhibits {
+		sources[ih.source] = true
+	}
+	a.evalOrder = make([]*alertRule, len(a.rules))
+	for i := range a.rules {
+		a.evalOrder[i] = &a.rules[i]
+	}
+	sort.SliceStable(a.evalOrder, func(i, j int) bool {
+		return sources[a.evalOrder[i].name] && !sources[a.evalOrder[j].name]
+	})
+}
+
 // Evaluate checks all rules against the current snapshot and transitions state.
 func (a *Alerter) Evaluate(ctx context.Context, snap *MetricSnapshot) {
 	a.mu.Lock()
@@ -123,18 +215,24 @@ func (a *Alerter) Evaluate(ctx context.Context, snap *MetricSnapshot) {
 
 	now := a.now()
 	seen := make(map[string]bool)
+	var nets map[string]*netRates
+	if snap.Nets != nil {
+		nets = a.netRates.update(snap.Nets, now)
+	}
+	a.history.record(snap, nets, now)
 
-	for i := range a.rules {
-		r := &a.rules[i]
-		switch {
-		case r.condition.Scope == "host" && r.condition.Field == "disk_percent":
+	for _, r := range a.evalOrder {
+		switch scope := r.cond.Scope(); {
+		case scope == "host" && r.cond.hasField("disk_percent"):
 			a.evalDiskRule(ctx, r, snap, now, seen)
-		case r.condition.Scope == "host":
+		case scope == "host":
 			a.evalHostRule(ctx, r, snap, now, seen)
-		case r.condition.Scope == "container":
+		case scope == "container":
 			a.evalContainerRule(ctx, r, snap, now, seen)
-		case r.condition.Scope == "log":
+		case scope == "log":
 			a.evalLogRule(ctx, r, snap, now, seen)
+		case scope == "net":
+			a.evalNetRule(ctx, r, snap, nets, now, seen)
 		}
 	}
 
@@ -155,6 +253,9 @@ func (a *Alerter) Evaluate(ctx context.Context, snap *MetricSnapshot) {
 		}
 	}
 
+	a.checkFlapping(ctx, now)
+	a.escalate(ctx, now)
+	a.remediate(now)
 	a.runDeferred()
 }
 
@@ -166,26 +267,30 @@ func (a *Alerter) EvaluateContainerEvent(ctx context.Context, cm ContainerMetric
 	a.deferred = a.deferred[:0]
 
 	now := a.now()
-	for i := range a.rules {
-		r := &a.rules[i]
-		if r.condition.Scope != "container" {
+	for _, r := range a.evalOrder {
+		if r.cond.Scope() != "container" {
+			continue
+		}
+		// Skip rules with any numeric comparison — events don't carry metric
+		// data (CPU/mem = 0), which would cause false resolution of numeric alerts.
+		if !r.cond.stringOnly() {
 			continue
 		}
-		// Skip numeric rules — events don't carry metric data (CPU/mem = 0),
-		// which would cause false resolution of numeric alerts.
-		if !r.condition.IsStr {
+		if !r.selector.matches(&cm) {
 			continue
 		}
 		ec := &evalContext{
 			rule:        r,
 			key:         r.name + ":" + cm.ID,
 			containerID: cm.ID,
+			project:     cm.Project,
 			label:       cm.Name,
 		}
-		matched := compareStr(containerFieldStr(&cm, r.condition.Field), r.condition.Op, r.condition.StrVal)
-		a.transition(ctx, ec, matched, now)
+		ec.env = &condEnv{container: &cm}
+		a.transition(ctx, ec, r.cond.eval(ec.env), now)
 	}
 
+	a.remediate(now)
 	a.runDeferred()
 }
 
@@ -209,15 +314,15 @@ func (a *Alerter) evalHostRule(ctx context.Context, r *alertRule, snap *MetricSn
 		return
 	}
 
-	val := hostFieldValue(snap.Host, r.condition.Field)
 	key := r.name
 	seen[key] = true
-	matched := compareNum(val, r.condition.Op, r.condition.NumVal)
-	a.transition(ctx, &evalContext{rule: r, key: key}, matched, now)
+	env := &condEnv{host: snap.Host, history: a.history}
+	a.transition(ctx, &evalContext{rule: r, key: key, env: env}, r.cond.eval(env), now)
 }
 
 func (a *Alerter) evalDiskRule(ctx context.Context, r *alertRule, snap *MetricSnapshot, now time.Time, seen map[string]bool) {
-	if snap.Disks == nil {
+	// Rules combining disk_percent with other host fields also need host data.
+	if snap.Disks == nil || (snap.Host == nil && !r.cond.onlyField("disk_percent")) {
 		// Mark all existing instances for this rule as seen to avoid
 		// false resolution on transient collection failure.
 		for key := range a.instances {
@@ -228,11 +333,12 @@ func (a *Alerter) evalDiskRule(ctx context.Context, r *alertRule, snap *MetricSn
 		return
 	}
 
-	for _, d := range snap.Disks {
+	for i := range snap.Disks {
+		d := &snap.Disks[i]
 		key := r.name + ":" + d.Mountpoint
 		seen[key] = true
-		matched := compareNum(d.Percent, r.condition.Op, r.condition.NumVal)
-		a.transition(ctx, &evalContext{rule: r, key: key, label: d.Mountpoint}, matched, now)
+		env := &condEnv{host: snap.Host, disk: d, history: a.history}
+		a.transition(ctx, &evalContext{rule: r, key: key, label: d.Mountpoint, env: env}, r.cond.eval(env), now)
 	}
 }
 
@@ -249,16 +355,41 @@ func (a *Alerter) evalContainerRule(ctx context.Context, r *alertRule, snap *Met
 	}
 
 	for _, c := range snap.Containers {
+		if !r.selector.matches(&c) {
+			continue
+		}
 		key := r.name + ":" + c.ID
 		seen[key] = true
 
-		var matched bool
-		if r.condition.IsStr {
-			matched = compareStr(containerFieldStr(&c, r.condition.Field), r.condition.Op, r.condition.StrVal)
-		} else {
-			matched = compareNum(containerFieldNum(&c, r.condition.Field), r.condition.Op, r.condition.NumVal)
+		env := &condEnv{container: &c, history: a.history}
+		a.transition(ctx, &evalContext{rule: r, key: key, containerID: c.ID, project: c.Project, label: c.Name, env: env}, r.cond.eval(env), now)
+	}
+}
+
+func (a *Alerter) evalNetRule(ctx context.Context, r *alertRule, snap *MetricSnapshot, nets map[string]*netRates, now time.Time, seen map[string]bool) {
+	if snap.Nets == nil {
+		for key := range a.instances {
+			if strings.HasPrefix(key, r.name+":") {
+				seen[key] = true
+			}
+		}
+		return
+	}
+
+	for _, m := range snap.Nets {
+		if !r.ifaces.matches(m.Iface) {
+			continue
+		}
+		key := r.name + ":" + m.Iface
+		seen[key] = true
+		rates, ok := nets[m.Iface]
+		if !ok {
+			// No rate yet (first reading or counter reset); keep the
+			// current state until the next cycle.
+			continue
 		}
-		a.transition(ctx, &evalContext{rule: r, key: key, containerID: c.ID, label: c.Name}, matched, now)
+		env := &condEnv{net: rates, history: a.history}
+		a.transition(ctx, &evalContext{rule: r, key: key, label: m.Iface, env: env}, r.cond.eval(env), now)
 	}
 }
 
@@ -285,11 +416,13 @@ func (a *Alerter) evalLogRule(ctx context.Context, r *alertRule, snap *MetricSna
 	}
 
 	for _, c := range snap.Containers {
+		if !r.selector.matches(&c) {
+			continue
+		}
 		key := r.name + ":" + c.ID
 		seen[key] = true
-		count := float64(counts[c.ID])
-		matched := compareNum(count, r.condition.Op, r.condition.NumVal)
-		a.transition(ctx, &evalContext{rule: r, key: key, containerID: c.ID, label: c.Name}, matched, now)
+		env := &condEnv{logCount: float64(counts[c.ID])}
+		a.transition(ctx, &evalContext{rule: r, key: key, containerID: c.ID, project: c.Project, label: c.Name, env: env}, r.cond.eval(env), now)
 	}
 }
 
@@ -299,6 +432,8 @@ func (a *Alerter) transition(ctx context.Context, ec *evalContext, matched bool,
 		inst = &alertInstance{}
 		a.instances[ec.key] = inst
 	}
+	inst.containerID = ec.containerID
+	inst.project = ec.project
 
 	switch inst.state {
 	case stateInactive:
@@ -345,20 +480,19 @@ func (a *Alerter) transition(ctx context.Context, ec *evalContext, matched bool,
 
 func (a *Alerter) fire(ctx context.Context, ec *evalContext, inst *alertInstance, now time.Time) {
 	inst.firedAt = now
+	inst.escalated = 0
 	r := ec.rule
-
-	condStr := r.condition.Scope + "." + r.condition.Field + " " + r.condition.Op + " " + conditionValue(&r.condition)
-	var msg string
-	if r.condition.Scope == "log" {
-		msg = fmt.Sprintf("[%s] %s: log matches for %q", r.severity, r.name, r.match)
-	} else {
-		msg = fmt.Sprintf("[%s] %s: %s", r.severity, r.name, r.condition.Scope+"."+r.condition.Field)
-	}
-	if ec.label != "" {
-		msg += " (" + ec.label + ")"
+	if a.replay != nil {
+		a.replay.fired(ec, now)
+		return
 	}
+	startedFlapping := a.recordTransition(r, inst, now)
+
+	condStr := r.cond.String()
+	msg := r.message(ec)
 	slog.Warn("alert firing", "rule", r.name, "key", ec.key)
 
+	inhibitedBy := a.inhibitedBy(r.name, ec)
 	alert := &Alert{
 		RuleName:    r.name,
 		Severity:    r.severity,
@@ -366,47 +500,137 @@ func (a *Alerter) fire(ctx context.Context, ec *evalContext, inst *alertInstance
 		InstanceKey: ec.key,
 		FiredAt:     now,
 		Message:     msg,
+		InhibitedBy: inhibitedBy,
+		Flapping:    inst.flapping,
+		Description: r.description,
+		RunbookURL:  r.runbookURL,
 	}
 	id, err := a.store.InsertAlert(ctx, alert)
 	if err != nil {
 		slog.Error("insert alert", "error", err)
 	}
 	inst.dbID = id
+	inst.message = msg
+	inst.inhibitedBy = inhibitedBy
 	alert.ID = id
 
 	if a.onStateChange != nil {
 		a.onStateChange(alert, "firing")
 	}
+	a.queueExec(r, execEvent{
+		rule: r.name, severity: r.severity, state: "firing", key: ec.key,
+		containerID: ec.containerID, project: ec.project, message: msg, alertID: id, firedAt: now,
+	})
 
 	// Defer slow side effects (notify) to execute after mutex release.
-	silenced := a.isSilenced(r.name)
-	for _, action := range r.actions {
-		if action == "notify" && !silenced {
-			if r.notifyCooldown > 0 {
-				if last, ok := a.lastNotified[r.name]; ok && now.Sub(last) < r.notifyCooldown {
-					slog.Info("notification suppressed (cooldown)", "rule", r.name, "key", ec.key)
-					continue
-				}
-			}
-			a.lastNotified[r.name] = now
-			ruleName := r.name
-			severity := r.severity
-			a.deferred = append(a.deferred, func() {
-				a.notifier.SendAlert("Alert: "+ruleName, msg, severity, "firing")
-			})
+	if !r.notifies() || a.isSilenced(r.name, ec.key) {
+		return
+	}
+	if w := a.inMaintenance(r.name, ec.project, now); w != nil {
+		slog.Info("notification suppressed (maintenance)", "rule", r.name, "key", ec.key, "window", w.name)
+		return
+	}
+	if inhibitedBy != "" {
+		slog.Info("notification suppressed (inhibited)", "rule", r.name, "key", ec.key, "source", inhibitedBy)
+		return
+	}
+	if inst.flapping {
+		if startedFlapping {
+			slog.Warn("alert flapping", "rule", r.name, "key", ec.key)
+			a.notifyFlapping(r, ec.key, inst, "firing", now, "Flapping: "+r.name, r.noteBody(flappingBody(r, msg)))
+		} else {
+			slog.Info("notification suppressed (flapping)", "rule", r.name, "key", ec.key)
+		}
+		return
+	}
+	if r.notifyCooldown > 0 {
+		if last, ok := a.lastNotified[r.name]; ok && now.Sub(last) < r.notifyCooldown {
+			slog.Info("notification suppressed (cooldown)", "rule", r.name, "key", ec.key)
+			return
+		}
+	}
+	a.lastNotified[r.name] = now
+	note := alertNote{
+		rule:     r.name,
+		severity: r.severity,
+		status:   "firing",
+		subject:  "Alert: " + r.name,
+		body:     r.noteBody(msg),
+		project:  ec.project,
+		at:       now,
+		targets:  a.notifier.route(r.channels, r.routed, r.severity),
+		key:      ec.key,
+
+		containerID: ec.containerID,
+		metrics:     noteMetrics(ec.env),
+	}
+	a.deferred = append(a.deferred, func() {
+		a.notifier.Notify(note)
+	})
+}
+
+// parseNotifyActions splits a rule's actions into notify:NAME channel
+// targets and whether a plain "notify" action is present.
+func parseNotifyActions(actions []string) (channels []string, routed bool) {
+	for _, action := range actions {
+		if action == "notify" {
+			routed = true
+		} else if name, ok := strings.CutPrefix(action, "notify:"); ok {
+			channels = append(channels, name)
 		}
 	}
+	return channels, routed
+}
+
+// execCommands returns the command paths of a rule's exec:PATH actions.
+func execCommands(actions []string) []string {
+	var cmds []string
+	for _, action := range actions {
+		if path, ok := strings.CutPrefix(action, "exec:"); ok {
+			cmds = append(cmds, path)
+		}
+	}
+	return cmds
+}
+
+// queueExec runs the rule's exec actions for a transition once the mutex is
+// released. Unlike notifications they are not held back by silences,
+// maintenance windows, inhibition or notify_cooldown.
+func (a *Alerter) queueExec(r *alertRule, ev execEvent) {
+	if len(r.commands) == 0 || a.exec == nil || a.noExec {
+		return
+	}
+	for _, path := range r.commands {
+		a.deferred = append(a.deferred, func() {
+			a.exec.run(path, ev)
+		})
+	}
+}
+
+// notifies reports whether the rule sends notifications at all.
+func (r *alertRule) notifies() bool {
+	return r.routed || len(r.channels) > 0
 }
 
 func (a *Alerter) resolve(ctx context.Context, r *alertRule, key string, inst *alertInstance, now time.Time) {
-	slog.Info("alert resolved", "key", key)
 	inst.state = stateInactive
 	inst.resolvedAt = now
+	if a.replay != nil {
+		a.replay.resolved(key, now)
+		return
+	}
+	slog.Info("alert resolved", "key", key)
+	startedFlapping := r != nil && a.recordTransition(r, inst, now)
 
 	if inst.dbID > 0 {
 		if err := a.store.ResolveAlert(ctx, inst.dbID, now); err != nil {
 			slog.Error("resolve alert", "error", err)
 		}
+		if startedFlapping {
+			if err := a.store.SetAlertFlapping(ctx, inst.dbID, true); err != nil {
+				slog.Error("set alert flapping", "id", inst.dbID, "error", err)
+			}
+		}
 		if a.onStateChange != nil {
 			condStr := ""
 			ruleName := ""
@@ -414,7 +638,7 @@ func (a *Alerter) resolve(ctx context.Context, r *alertRule, key string, inst *a
 			if r != nil {
 				ruleName = r.name
 				severity = r.severity
-				condStr = r.condition.Scope + "." + r.condition.Field + " " + r.condition.Op + " " + conditionValue(&r.condition)
+				condStr = r.cond.String()
 			}
 			a.onStateChange(&Alert{
 				ID:          inst.dbID,
@@ -424,13 +648,97 @@ func (a *Alerter) resolve(ctx context.Context, r *alertRule, key string, inst *a
 				InstanceKey: key,
 				FiredAt:     inst.firedAt,
 				ResolvedAt:  &now,
+				Flapping:    inst.flapping,
 			}, "resolved")
 		}
 	}
 
+	if r != nil {
+		a.queueExec(r, execEvent{
+			rule: r.name, severity: r.severity, state: "resolved", key: key,
+			containerID: inst.containerID, project: inst.project, message: inst.message,
+			alertID: inst.dbID, firedAt: inst.firedAt,
+		})
+		if startedFlapping {
+			slog.Warn("alert flapping", "rule", r.name, "key", key)
+			a.notifyFlapping(r, key, inst, "resolved", now, "Flapping: "+r.name, r.noteBody(flappingBody(r, inst.message)))
+		}
+		a.queueIncident(r, key, inst, "resolved", now)
+	}
+
 	inst.dbID = 0
 }
 
+// queueIncident sends a resolve or acknowledge event to the incident
+// channels the rule notifies once the mutex is released. Unlike
+// notifications these are not held back: closing an upstream incident that
+// was never opened is harmless, while leaving one open pages someone.
+func (a *Alerter) queueIncident(r *alertRule, key string, inst *alertInstance, status string, now time.Time) {
+	if a.notifier == nil || !r.notifies() {
+		return
+	}
+	note := alertNote{
+		rule:     r.name,
+		severity: r.severity,
+		status:   status,
+		subject:  fmt.Sprintf("%s: %s", strings.ToUpper(status[:1])+status[1:], r.name),
+		body:     r.noteBody(inst.message),
+		project:  inst.project,
+		at:       now,
+		targets:  a.notifier.route(r.channels, r.routed, r.severity),
+		key:      key,
+	}
+	a.deferred = append(a.deferred, func() {
+		a.notifier.Incident(note)
+	})
+}
+
+// Acknowledge mirrors the acknowledgement of a firing alert to the incident
+// channels its rule notifies. Alerts that are no longer firing are ignored.
+func (a *Alerter) Acknowledge(id int64) {
+	a.mu.Lock()
+	a.deferred = a.deferred[:0]
+	for key, inst := range a.instances {
+		if inst.dbID != id || inst.state != stateFiring {
+			continue
+		}
+		if r := a.ruleForKey(key); r != nil {
+			a.queueIncident(r, key, inst, "acknowledged", a.now())
+		}
+	}
+	a.runDeferred()
+}
+
+// publishFiring re-sends a firing instance to onStateChange, e.g. after a
+// remediation attempt or when it stops flapping. Called with mu held.
+func (a *Alerter) publishFiring(ctx context.Context, r *alertRule, key string, inst *alertInstance) {
+	if a.onStateChange == nil || inst.dbID == 0 {
+		return
+	}
+	acked, err := a.store.AlertAcknowledged(ctx, inst.dbID)
+	if err != nil {
+		slog.Error("check alert acknowledged", "id", inst.dbID, "error", err)
+	}
+	alerts := []Alert{{
+		ID:           inst.dbID,
+		RuleName:     r.name,
+		Severity:     r.severity,
+		Condition:    r.cond.String(),
+		InstanceKey:  key,
+		FiredAt:      inst.firedAt,
+		Message:      inst.message,
+		Acknowledged: acked,
+		InhibitedBy:  inst.inhibitedBy,
+		Flapping:     inst.flapping,
+		Description:  r.description,
+		RunbookURL:   r.runbookURL,
+	}}
+	if err := a.store.AttachRemediations(ctx, alerts); err != nil {
+		slog.Error("attach remediations", "error", err)
+	}
+	a.onStateChange(&alerts[0], "firing")
+}
+
 // ruleForKey finds the alertRule for a given instance key (used for stale resolution).
 func (a *Alerter) ruleForKey(key string) *alertRule {
 	for i := range a.rules {
@@ -441,10 +749,89 @@ func (a *Alerter) ruleForKey(key string) *alertRule {
 	return nil
 }
 
+// ruleNameForKey returns the name of the rule an instance key belongs to,
+// or "" if none does.
+func (a *Alerter) ruleNameForKey(key string) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if r := a.ruleForKey(key); r != nil {
+		return r.name
+	}
+	return ""
+}
+
+// ruleNamed returns the rule called name, or nil. Caller holds a.mu.
+func (a *Alerter) ruleNamed(name string) *alertRule {
+	for i := range a.rules {
+		if a.rules[i].name == name {
+			return &a.rules[i]
+		}
+	}
+	return nil
+}
+
+// ReplaceRule swaps in the rule name from alerts after it was created,
+// updated, disabled or deleted at runtime; a name missing from alerts
+// removes the rule. Firing alerts of the old rule are resolved without
+// running exec actions, and its pending, cooldown and remediation state is
+// dropped. The other rules keep their state.
+func (a *Alerter) ReplaceRule(ctx context.Context, name string, alerts map[string]AlertConfig) error {
+	a.mu.Lock()
+	a.deferred = a.deferred[:0]
+	var rule *alertRule
+	if ac, ok := alerts[name]; ok {
+		r, err := a.newRule(name, ac)
+		if err != nil {
+			a.mu.Unlock()
+			return err
+		}
+		rule = &r
+	}
+
+	ofRule := func(key string) bool {
+		r := a.ruleForKey(key)
+		return r != nil && r.name == name
+	}
+	a.noExec = true
+	now := a.now()
+	for key, inst := range a.instances {
+		if !ofRule(key) {
+			continue
+		}
+		if inst.state == stateFiring {
+			a.resolve(ctx, a.ruleForKey(key), key, inst, now)
+		}
+		delete(a.instances, key)
+	}
+	a.noExec = false
+	for key := range a.remediations {
+		if ofRule(key) {
+			delete(a.remediations, key)
+		}
+	}
+	delete(a.lastNotified, name)
+
+	// Build a new slice: QueryRules reads the old one after releasing mu.
+	rules := make([]alertRule, 0, len(a.rules)+1)
+	for _, r := range a.rules {
+		if r.name != name {
+			rules = append(rules, r)
+		}
+	}
+	if rule != nil {
+		rules = append(rules, *rule)
+		sort.Slice(rules, func(i, j int) bool { return rules[i].name < rules[j].name })
+	}
+	a.rules = rules
+	a.orderRules()
+	a.runDeferred()
+	return nil
+}
+
 // AdoptFiring loads unresolved alerts from the store and adopts those whose
 // instance_key matches a current rule into the instances map. Alerts that no
-// longer match any rule are resolved. This lets alerts survive agent restarts
-// without the resolve/re-fire noise.
+// longer match any rule are resolved, and incident channels are told. This
+// lets alerts survive agent restarts without the resolve/re-fire noise.
 func (a *Alerter) AdoptFiring(ctx context.Context) error {
 	firing, err := a.store.QueryFiringAlerts(ctx)
 	if err != nil {
@@ -453,7 +840,7 @@ func (a *Alerter) AdoptFiring(ctx context.Context) error {
 
 	now := a.now()
 	a.mu.Lock()
-	defer a.mu.Unlock()
+	a.deferred = a.deferred[:0]
 
 	for _, alert := range firing {
 		r := a.ruleForKey(alert.InstanceKey)
@@ -461,46 +848,153 @@ func (a *Alerter) AdoptFiring(ctx context.Context) error {
 			// Rule was removed — resolve the orphan.
 			if err := a.store.ResolveAlert(ctx, alert.ID, now); err != nil {
 				slog.Error("resolve orphaned alert", "id", alert.ID, "error", err)
+				continue
+			}
+			if a.notifier != nil {
+				note := orphanResolved(&alert, now)
+				a.deferred = append(a.deferred, func() {
+					a.notifier.Incident(note)
+				})
 			}
 			continue
 		}
-		a.instances[alert.InstanceKey] = &alertInstance{
-			state:   stateFiring,
-			firedAt: alert.FiredAt,
-			dbID:    alert.ID,
+		inst := &alertInstance{
+			state:       stateFiring,
+			firedAt:     alert.FiredAt,
+			dbID:        alert.ID,
+			message:     alert.Message,
+			escalated:   alert.EscalationStep,
+			inhibitedBy: alert.InhibitedBy,
+		}
+		if alert.Flapping {
+			// Transition history is not persisted; start the stability
+			// window over.
+			inst.flapping = true
+			inst.transitions = []time.Time{now}
+		}
+		if scope := r.cond.Scope(); scope == "container" || scope == "log" {
+			inst.containerID = strings.TrimPrefix(alert.InstanceKey, r.name+":")
 		}
+		a.instances[alert.InstanceKey] = inst
 		slog.Info("adopted firing alert", "rule", r.name, "key", alert.InstanceKey, "id", alert.ID)
 	}
+	a.runDeferred()
 	return nil
 }
 
+// orphanResolved returns the resolve event for a stored alert whose rule no
+// longer exists. The rule's channels are unknown, so it goes to every
+// incident channel; closing an incident that was never opened is harmless.
+func orphanResolved(al *Alert, now time.Time) alertNote {
+	return alertNote{
+		rule:     al.RuleName,
+		severity: al.Severity,
+		status:   "resolved",
+		subject:  "Resolved: " + al.RuleName,
+		body:     al.Message,
+		at:       now,
+		key:      al.InstanceKey,
+	}
+}
+
 // ResolveAll resolves all firing alerts. Called before replacing the alerter on config reload.
+// Exec actions don't run for these, but incident channels are told, so
+// upstream incidents don't stay open. Call it before Stop, which delivers them.
 func (a *Alerter) ResolveAll(ctx context.Context) {
 	a.mu.Lock()
-	defer a.mu.Unlock()
+	a.deferred = a.deferred[:0]
+	a.noExec = true
 	now := a.now()
 	for key, inst := range a.instances {
 		if inst.state == stateFiring {
 			a.resolve(ctx, a.ruleForKey(key), key, inst, now)
 		}
 	}
+	a.noExec = false
+	a.runDeferred()
+}
+
+// FiringCount returns the number of alert instances currently firing.
+func (a *Alerter) FiringCount() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var n int
+	for _, inst := range a.instances {
+		if inst.state == stateFiring {
+			n++
+		}
+	}
+	return n
 }
 
 // HasRule returns whether a rule with the given name exists.
 func (a *Alerter) HasRule(name string) bool {
-	for i := range a.rules {
-		if a.rules[i].name == name {
-			return true
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.ruleNamed(name) != nil
+}
+
+// LoadSilences loads the active silences from the store, replacing any
+// already held. Called at startup and after a config reload.
+func (a *Alerter) LoadSilences(ctx context.Context) error {
+	silences, err := a.store.QueryActiveSilences(ctx, a.now())
+	if err != nil {
+		return fmt.Errorf("query silences: %w", err)
+	}
+	a.silencesMu.Lock()
+	a.silences = silences
+	a.silencesMu.Unlock()
+	return nil
+}
+
+// AddSilence persists a silence that starts now and lasts for dur, and
+// returns it with its ID set. Notifications for matching instances are
+// suppressed until it expires or is removed with Unsilence.
+func (a *Alerter) AddSilence(ctx context.Context, sl Silence, dur time.Duration) (Silence, error) {
+	now := a.now()
+	sl.CreatedAt = now
+	sl.ExpiresAt = now.Add(dur)
+	id, err := a.store.InsertSilence(ctx, &sl)
+	if err != nil {
+		return Silence{}, fmt.Errorf("insert silence: %w", err)
+	}
+	sl.ID = id
+	slog.Info("silence added", "id", id, "rule", sl.RuleName, "key", sl.InstanceKey,
+		"until", sl.ExpiresAt, "by", sl.CreatedBy)
+
+	a.silencesMu.Lock()
+	a.silences = append(a.silences, sl)
+	a.silencesMu.Unlock()
+	return sl, nil
+}
+
+// Unsilence expires the silence with the given ID.
+func (a *Alerter) Unsilence(ctx context.Context, id int64) error {
+	if err := a.store.ExpireSilence(ctx, id, a.now()); err != nil {
+		return err
+	}
+	slog.Info("silence removed", "id", id)
+
+	a.silencesMu.Lock()
+	defer a.silencesMu.Unlock()
+	for i := range a.silences {
+		if a.silences[i].ID == id {
+			a.silences = append(a.silences[:i], a.silences[i+1:]...)
+			break
 		}
 	}
-	return false
+	return nil
 }
 
-// Silence suppresses notifications for a rule for the given duration.
-func (a *Alerter) Silence(ruleName string, dur time.Duration) {
+// Silences returns the active silences, soonest to expire first.
+func (a *Alerter) Silences() []Silence {
 	a.silencesMu.Lock()
 	defer a.silencesMu.Unlock()
-	a.silences[ruleName] = a.now().Add(dur)
+	a.dropExpiredSilences()
+	out := make([]Silence, len(a.silences))
+	copy(out, a.silences)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].ExpiresAt.Before(out[j].ExpiresAt) })
+	return out
 }
 
 // RuleStatus describes a configured alert rule and its current runtime status.
@@ -513,11 +1007,20 @@ type RuleStatus struct {
 	Cooldown       time.Duration
 	NotifyCooldown time.Duration
 	Actions        []string
+	Channels       []string // enabled channels the rule's notifications reach
+	Selector       string
+	Aggregates     []string
 	FiringCount    int
 	SilencedUntil  time.Time
+	Maintenance    string    // active maintenance window covering the rule, if any
+	MaintenanceEnd time.Time // end of that window
 	Match          string
 	MatchRegex     bool
 	Window         time.Duration
+
+	Description     string
+	RunbookURL      string
+	MessageTemplate string
 }
 
 // QueryRules returns the status of all configured alert rules.
@@ -534,78 +1037,226 @@ func (a *Alerter) QueryRules() []RuleStatus {
 			firingCounts[r.name]++
 		}
 	}
+	rules := a.rules
 	a.mu.Unlock()
 
-	// Read silence state.
+	// Rule-wide silences; instance silences are listed separately.
 	a.silencesMu.Lock()
-	now := a.now()
+	a.dropExpiredSilences()
 	silences := make(map[string]time.Time)
-	for name, until := range a.silences {
-		if now.Before(until) {
-			silences[name] = until
+	for _, sl := range a.silences {
+		if sl.RuleName != "" && sl.InstanceKey == "" && sl.ExpiresAt.After(silences[sl.RuleName]) {
+			silences[sl.RuleName] = sl.ExpiresAt
 		}
 	}
 	a.silencesMu.Unlock()
 
-	out := make([]RuleStatus, len(a.rules))
-	for i, r := range a.rules {
-		condStr := r.condition.Scope + "." + r.condition.Field + " " + r.condition.Op + " " + conditionValue(&r.condition)
+	now := a.now()
+	out := make([]RuleStatus, len(rules))
+	for i, r := range rules {
+		var mName string
+		var mEnd time.Time
+		for _, w := range a.maintenance {
+			if len(w.rules) > 0 && !w.rules[r.name] {
+				continue
+			}
+			if until, ok := w.activeAt(now); ok {
+				mName, mEnd = w.name, until
+				break
+			}
+		}
+		var channels []string
+		if r.notifies() && a.notifier != nil {
+			channels = a.notifier.Targets(r.channels, r.routed, r.severity)
+		}
 		out[i] = RuleStatus{
 			Name:           r.name,
-			Condition:      condStr,
+			Condition:      r.cond.String(),
 			Severity:       r.severity,
 			For:            r.forDur,
 			ResolveFor:     r.resolveForDur,
 			Cooldown:       r.cooldown,
 			NotifyCooldown: r.notifyCooldown,
 			Actions:        r.actions,
+			Channels:       channels,
+			Selector:       r.selector.String() + r.ifaces.String(), // at most one is set
+			Aggregates:     r.cond.aggregates(),
 			FiringCount:    firingCounts[r.name],
 			SilencedUntil:  silences[r.name],
+			Maintenance:    mName,
+			MaintenanceEnd: mEnd,
 			Match:          r.match,
 			MatchRegex:     r.matchRegex,
 			Window:         r.window,
+
+			Description:     r.description,
+			RunbookURL:      r.runbookURL,
+			MessageTemplate: r.msgTemplate,
 		}
 	}
 	return out
 }
 
+// SetMaintenance installs the configured maintenance windows. The configs
+// must already have passed validation. Call before evaluation starts.
+func (a *Alerter) SetMaintenance(cfgs []MaintenanceConfig) error {
+	windows := make([]*maintenanceWindow, 0, len(cfgs))
+	for i := range cfgs {
+		w, err := newMaintenanceWindow(&cfgs[i])
+		if err != nil {
+			return fmt.Errorf("maintenance %q: %w", cfgs[i].Name, err)
+		}
+		windows = append(windows, w)
+	}
+	a.maintenance = windows
+	return nil
+}
+
+// inhibitRule suppresses notifications for target rules while an instance
+// of the source rule is firing.
+type inhibitRule struct {
+	source  string
+	targets map[string]bool
+	equal   string // "project", "container", or "" (any instance)
+}
+
+// SetInhibit installs the configured inhibition rules and moves their source
+// rules to the front of the evaluation order, so a source that starts firing
+// in the same cycle as its targets still inhibits them. The configs must
+// already have passed validation. Call before evaluation starts.
+func (a *Alerter) SetInhibit(cfgs []InhibitConfig) {
+	a.inhibits = make([]inhibitRule, len(cfgs))
+	for i, ic := range cfgs {
+		targets := make(map[string]bool, len(ic.Targets))
+		for _, t := range ic.Targets {
+			targets[t] = true
+		}
+		a.inhibits[i] = inhibitRule{source: ic.Source, targets: targets, equal: ic.Equal}
+	}
+	a.orderRules()
+}
+
+// inhibitedBy returns the name of a firing source rule that inhibits
+// notifications for this instance of ruleName, or "". Caller holds a.mu.
+func (a *Alerter) inhibitedBy(ruleName string, ec *evalContext) string {
+	for _, ih := range a.inhibits {
+		if !ih.targets[ruleName] {
+			continue
+		}
+		for key, inst := range a.instances {
+			if inst.state != stateFiring || (key != ih.source && !strings.HasPrefix(key, ih.source+":")) {
+				continue
+			}
+			switch ih.equal {
+			case "project":
+				if inst.project == "" || inst.project != ec.project {
+					continue
+				}
+			case "container":
+				if inst.containerID == "" || inst.containerID != ec.containerID {
+					continue
+				}
+			}
+			return ih.source
+		}
+	}
+	return ""
+}
+
+// MaintenanceStatus describes a configured maintenance window.
+type MaintenanceStatus struct {
+	Name     string
+	Schedule string
+	Active   bool
+	Until    time.Time // end of the current occurrence when Active
+}
+
+// Maintenance returns the status of all configured maintenance windows.
+func (a *Alerter) Maintenance() []MaintenanceStatus {
+	now := a.now()
+	out := make([]MaintenanceStatus, len(a.maintenance))
+	for i, w := range a.maintenance {
+		until, ok := w.activeAt(now)
+		out[i] = MaintenanceStatus{Name: w.name, Schedule: w.schedule, Active: ok, Until: until}
+	}
+	return out
+}
+
+// inMaintenance returns the first active window covering the rule and
+// project at now, or nil.
+func (a *Alerter) inMaintenance(ruleName, project string, now time.Time) *maintenanceWindow {
+	for _, w := range a.maintenance {
+		if !w.covers(ruleName, project) {
+			continue
+		}
+		if _, ok := w.activeAt(now); ok {
+			return w
+		}
+	}
+	return nil
+}
+
 // SendTestNotification sends a test notification for the given rule name.
 func (a *Alerter) SendTestNotification(ruleName string) error {
 	if a.notifier == nil || !a.notifier.HasChannels() {
 		return fmt.Errorf("no notification channels configured")
 	}
-	var rule *alertRule
-	for i := range a.rules {
-		if a.rules[i].name == ruleName {
-			rule = &a.rules[i]
-			break
-		}
-	}
+	a.mu.Lock()
+	rule := a.ruleNamed(ruleName)
+	a.mu.Unlock()
 	if rule == nil {
 		return fmt.Errorf("unknown rule: %s", ruleName)
 	}
+	if !rule.notifies() {
+		return fmt.Errorf("rule %s has no notify action", ruleName)
+	}
 	body := fmt.Sprintf("Test notification for rule '%s'.", ruleName)
-	a.notifier.SendAlert("Test: "+ruleName, body, rule.severity, "test")
+	a.notifier.SendAlertTo(a.notifier.route(rule.channels, rule.routed, rule.severity), "Test: "+ruleName, body, rule.severity, "test")
 	return nil
 }
 
-// Stop shuts down the alerter's notifier. Safe to call if notifier is nil.
+// SetExec enables exec actions with the given limits. Call before
+// evaluation starts.
+func (a *Alerter) SetExec(cfg *ExecConfig) {
+	a.exec = newExecutor(cfg)
+}
+
+// Stop cancels running docker actions, shuts down the alerter's notifier and
+// waits for running exec actions. Safe to call if notifier is nil.
 func (a *Alerter) Stop() {
+	if a.remediateStop != nil {
+		a.remediateStop()
+	}
+	a.remediating.Wait()
 	if a.notifier != nil {
 		a.notifier.Stop()
 	}
+	if a.exec != nil {
+		a.exec.wait()
+	}
 }
 
-func (a *Alerter) isSilenced(ruleName string) bool {
+// isSilenced reports whether an active silence matches the rule and instance.
+func (a *Alerter) isSilenced(ruleName, instanceKey string) bool {
 	a.silencesMu.Lock()
 	defer a.silencesMu.Unlock()
-	until, ok := a.silences[ruleName]
-	if !ok {
-		return false
+	a.dropExpiredSilences()
+	for i := range a.silences {
+		if a.silences[i].matches(ruleName, instanceKey) {
+			return true
+		}
 	}
-	if a.now().After(until) {
-		delete(a.silences, ruleName)
-		return false
+	return false
+}
+
+// dropExpiredSilences forgets silences that have run out. Caller holds silencesMu.
+func (a *Alerter) dropExpiredSilences() {
+	now := a.now()
+	active := a.silences[:0]
+	for _, sl := range a.silences {
+		if now.Before(sl.ExpiresAt) {
+			active = append(active, sl)
+		}
 	}
-	return true
+	a.silences = active
 }
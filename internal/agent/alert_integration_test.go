@@ -27,7 +27,7 @@ type alertPipeline struct {
 func newAlertPipeline(t *testing.T, alerts map[string]AlertConfig) *alertPipeline {
 	t.Helper()
 	s := testStore(t)
-	n := NewNotifier(&NotifyConfig{})
+	n := NewNotifier(&NotifyConfig{}, nil)
 	alerter, err := NewAlerter(alerts, s, n)
 	if err != nil {
 		t.Fatal(err)
@@ -351,7 +351,7 @@ func TestStartupAdoptsFiringAlerts(t *testing.T) {
 	}
 	defer s2.Close()
 
-	n := NewNotifier(&NotifyConfig{})
+	n := NewNotifier(&NotifyConfig{}, nil)
 	alerter, err := NewAlerter(map[string]AlertConfig{
 		"exited": {
 			Condition: "container.state == 'exited'",
@@ -427,7 +427,7 @@ func TestStartupAdoptThenEvaluateNoDuplicate(t *testing.T) {
 	}
 	defer s2.Close()
 
-	n := NewNotifier(&NotifyConfig{})
+	n := NewNotifier(&NotifyConfig{}, nil)
 	alerter, err := NewAlerter(map[string]AlertConfig{
 		"exited": {
 			Condition: "container.state == 'exited'",
@@ -629,12 +629,18 @@ func TestCollectPipelineAllHostConditions(t *testing.T) {
 			fire:      &HostMetrics{SwapTotal: 1000, SwapUsed: 900},
 			resolve:   &HostMetrics{SwapTotal: 1000, SwapUsed: 100},
 		},
+		{
+			name:      "agent_uptime",
+			condition: "host.agent_uptime < 300",
+			fire:      &HostMetrics{AgentUptime: 20},
+			resolve:   &HostMetrics{AgentUptime: 600},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := testStore(t)
-			n := NewNotifier(&NotifyConfig{})
+			n := NewNotifier(&NotifyConfig{}, nil)
 			alerter, err := NewAlerter(map[string]AlertConfig{
 				"rule": {Condition: tt.condition, Severity: "warning", Actions: []string{"notify"}},
 			}, s, n)
@@ -676,7 +682,7 @@ func TestCollectPipelineAllHostConditions(t *testing.T) {
 // Evaluate + Store pipeline (per-mountpoint keying).
 func TestCollectPipelineDiskPercent(t *testing.T) {
 	s := testStore(t)
-	n := NewNotifier(&NotifyConfig{})
+	n := NewNotifier(&NotifyConfig{}, nil)
 	alerter, err := NewAlerter(map[string]AlertConfig{
 		"disk_full": {Condition: "host.disk_percent > 90", Severity: "warning", Actions: []string{"notify"}},
 	}, s, n)
@@ -757,7 +763,7 @@ func TestCollectPipelineAllContainerConditions(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := testStore(t)
-			n := NewNotifier(&NotifyConfig{})
+			n := NewNotifier(&NotifyConfig{}, nil)
 			alerter, err := NewAlerter(map[string]AlertConfig{
 				"rule": {Condition: tt.condition, Severity: "warning", Actions: []string{"notify"}},
 			}, s, n)
@@ -859,7 +865,7 @@ func TestEventPipelineAllStringConditions(t *testing.T) {
 // firing alerts in the DB.
 func TestShutdownResolvesAlerts(t *testing.T) {
 	s := testStore(t)
-	n := NewNotifier(&NotifyConfig{})
+	n := NewNotifier(&NotifyConfig{}, nil)
 	alerter, err := NewAlerter(map[string]AlertConfig{
 		"exited": {
 			Condition: "container.state == 'exited'",
@@ -903,7 +909,7 @@ func TestShutdownResolvesAlerts(t *testing.T) {
 // reconnecting: the new connection should see all firing alerts via snapshot.
 func TestReconnectSeesExistingAlerts(t *testing.T) {
 	s := testStore(t)
-	n := NewNotifier(&NotifyConfig{})
+	n := NewNotifier(&NotifyConfig{}, nil)
 	alerter, err := NewAlerter(map[string]AlertConfig{
 		"exited": {
 			Condition: "container.state == 'exited'",
@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"text/template"
+)
+
+// messageData is what a rule's message_template is rendered with.
+type messageData struct {
+	Rule        string
+	Severity    string
+	Condition   string
+	Field       string  // operand of the first numeric comparison, e.g. "container.memory_percent"
+	Value       float64 // its value when the alert fired
+	Threshold   float64 // its threshold
+	Instance    string  // container name, mountpoint or interface; "" for host rules
+	ContainerID string
+	Project     string
+	Description string
+	RunbookURL  string
+}
+
+// parseMessageTemplate parses a message_template and renders it once with
+// empty data, so references to unknown fields fail at config load rather
+// than when the alert fires.
+func parseMessageTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("message").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if err := tmpl.Execute(&strings.Builder{}, messageData{}); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// message builds the alert message for a firing instance: the rule's
+// message_template if set, otherwise "[severity] rule: target (label)".
+func (r *alertRule) message(ec *evalContext) string {
+	if r.tmpl == nil {
+		return r.defaultMessage(ec.label)
+	}
+	var b strings.Builder
+	if err := r.tmpl.Execute(&b, r.messageData(ec)); err != nil {
+		slog.Warn("render message_template", "rule", r.name, "error", err)
+		return r.defaultMessage(ec.label)
+	}
+	return b.String()
+}
+
+func (r *alertRule) defaultMessage(label string) string {
+	var msg string
+	if r.cond.Scope() == "log" {
+		msg = fmt.Sprintf("[%s] %s: log matches for %q", r.severity, r.name, r.match)
+	} else {
+		msg = fmt.Sprintf("[%s] %s: %s", r.severity, r.name, r.cond.target())
+	}
+	if label != "" {
+		msg += " (" + label + ")"
+	}
+	return msg
+}
+
+func (r *alertRule) messageData(ec *evalContext) messageData {
+	d := messageData{
+		Rule:        r.name,
+		Severity:    r.severity,
+		Condition:   r.cond.String(),
+		Instance:    ec.label,
+		ContainerID: ec.containerID,
+		Project:     ec.project,
+		Description: r.description,
+		RunbookURL:  r.runbookURL,
+	}
+	if c := r.cond.firstNumeric(); c != nil {
+		d.Field = c.operand()
+		d.Threshold = c.NumVal
+		if ec.env != nil {
+			d.Value, _ = c.value(ec.env)
+		}
+	}
+	return d
+}
+
+// noteBody appends the rule's description and runbook link to a
+// notification body.
+func (r *alertRule) noteBody(msg string) string {
+	if r.description != "" {
+		msg += "\n\n" + r.description
+	}
+	if r.runbookURL != "" {
+		msg += "\nRunbook: " + r.runbookURL
+	}
+	return msg
+}
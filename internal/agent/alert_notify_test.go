@@ -2,6 +2,10 @@ package agent
 
 import (
 	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -118,6 +122,34 @@ func TestNotifyCooldownSuppresses(t *testing.T) {
 	}
 }
 
+func TestNotifyFiringContext(t *testing.T) {
+	alerts := map[string]AlertConfig{
+		"busy": {
+			Condition: "container.cpu_percent > 90",
+			Severity:  "warning",
+			Actions:   []string{"notify"},
+		},
+	}
+	a, _, rec := testAlerterWithRecorder(t, alerts)
+
+	a.Evaluate(context.Background(), &MetricSnapshot{
+		Containers: []ContainerMetrics{{ID: "aaa", Name: "web", State: "running", CPUPercent: 97}},
+	})
+	a.notifier.Flush()
+
+	notes := rec.Notifications()
+	if len(notes) != 1 || len(notes[0].alerts) != 1 {
+		t.Fatalf("notifications = %+v, want 1 with 1 alert", notes)
+	}
+	note := notes[0].alerts[0]
+	if note.containerID != "aaa" {
+		t.Errorf("containerID = %q, want aaa", note.containerID)
+	}
+	if !slices.Contains(note.metrics, noteMetric{"CPU", "97.0%"}) {
+		t.Errorf("metrics = %v, want CPU 97.0%%", note.metrics)
+	}
+}
+
 func TestNotifyCooldownZeroDisabled(t *testing.T) {
 	alerts := map[string]AlertConfig{
 		"exited": {
@@ -241,7 +273,7 @@ func TestNotifyCooldownSilenceInteraction(t *testing.T) {
 	a.now = func() time.Time { return now }
 
 	// Silence rule, fire "aaa" — silenced, no notification, lastNotified NOT set.
-	a.Silence("exited", 1*time.Minute)
+	a.AddSilence(ctx, Silence{RuleName: "exited"}, 1*time.Minute)
 	a.Evaluate(ctx, &MetricSnapshot{
 		Containers: []ContainerMetrics{{ID: "aaa", Name: "web", State: "exited"}},
 	})
@@ -274,6 +306,224 @@ func TestNotifyCooldownSilenceInteraction(t *testing.T) {
 	}
 }
 
+func TestNotifySilenceInstance(t *testing.T) {
+	alerts := map[string]AlertConfig{
+		"exited": {
+			Condition: "container.state == 'exited'",
+			Severity:  "critical",
+			Actions:   []string{"notify"},
+		},
+	}
+	a, s, rec := testAlerterWithRecorder(t, alerts)
+	ctx := context.Background()
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	a.now = func() time.Time { return now }
+
+	if _, err := a.AddSilence(ctx, Silence{InstanceKey: "exited:aaa", CreatedBy: "alice"}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the unsilenced container notifies.
+	a.Evaluate(ctx, &MetricSnapshot{
+		Containers: []ContainerMetrics{
+			{ID: "aaa", Name: "web", State: "exited"},
+			{ID: "bbb", Name: "api", State: "exited"},
+		},
+	})
+	a.notifier.Flush()
+	if notes := rec.Notifications(); len(notes) != 1 || notes[0].body != "[critical] exited: container.state (api)" {
+		t.Fatalf("notifications = %+v, want one for api", notes)
+	}
+
+	// Silences survive a restart: a new alerter on the same store loads them.
+	b, err := NewAlerter(alerts, s, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.now = a.now
+	if err := b.LoadSilences(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !b.isSilenced("exited", "exited:aaa") || b.isSilenced("exited", "exited:bbb") {
+		t.Error("reloaded silences do not match the instance")
+	}
+	if got := b.Silences(); len(got) != 1 || got[0].CreatedBy != "alice" {
+		t.Errorf("silences = %+v", got)
+	}
+}
+
+func TestNotifyMaintenanceSuppresses(t *testing.T) {
+	alerts := map[string]AlertConfig{
+		"exited": {
+			Condition: "container.state == 'exited'",
+			Severity:  "critical",
+			Actions:   []string{"notify"},
+		},
+	}
+	a, s, rec := testAlerterWithRecorder(t, alerts)
+	ctx := context.Background()
+	err := a.SetMaintenance([]MaintenanceConfig{{
+		Name: "deploys", Start: "02:00", End: "04:00", Timezone: "UTC", Projects: []string{"shop"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2025, 1, 1, 3, 0, 0, 0, time.UTC)
+	a.now = func() time.Time { return now }
+
+	// Inside the window only the container outside the project notifies,
+	// but both alerts are recorded.
+	a.Evaluate(ctx, &MetricSnapshot{
+		Containers: []ContainerMetrics{
+			{ID: "aaa", Name: "web", Project: "shop", State: "exited"},
+			{ID: "bbb", Name: "api", Project: "blog", State: "exited"},
+		},
+	})
+	a.notifier.Flush()
+	if notes := rec.Notifications(); len(notes) != 1 || notes[0].body != "[critical] exited: container.state (api)" {
+		t.Fatalf("notifications = %+v, want one for api", notes)
+	}
+	recorded, err := s.QueryAlerts(ctx, now.Add(-time.Minute).Unix(), now.Add(time.Minute).Unix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recorded) != 2 {
+		t.Errorf("recorded alerts = %d, want 2", len(recorded))
+	}
+
+	rs := a.QueryRules()
+	if rs[0].Maintenance != "deploys" || !rs[0].MaintenanceEnd.Equal(time.Date(2025, 1, 1, 4, 0, 0, 0, time.UTC)) {
+		t.Errorf("rule status = %q until %v", rs[0].Maintenance, rs[0].MaintenanceEnd)
+	}
+	if ms := a.Maintenance(); len(ms) != 1 || !ms[0].Active || ms[0].Schedule != "daily 02:00-04:00 UTC" {
+		t.Errorf("maintenance = %+v", ms)
+	}
+
+	// After the window a newly firing container notifies again.
+	now = time.Date(2025, 1, 1, 4, 0, 0, 0, time.UTC)
+	a.Evaluate(ctx, &MetricSnapshot{
+		Containers: []ContainerMetrics{
+			{ID: "aaa", Name: "web", Project: "shop", State: "exited"},
+			{ID: "bbb", Name: "api", Project: "blog", State: "exited"},
+			{ID: "ccc", Name: "cart", Project: "shop", State: "exited"},
+		},
+	})
+	a.notifier.Flush()
+	if n := len(rec.Notifications()); n != 2 {
+		t.Errorf("notifications = %d, want 2", n)
+	}
+}
+
+func TestNotifyInhibited(t *testing.T) {
+	alerts := map[string]AlertConfig{
+		"host_mem": {
+			Condition: "host.memory_percent > 90",
+			Severity:  "critical",
+			Actions:   []string{"notify"},
+		},
+		"exited": {
+			Condition: "container.state == 'exited'",
+			Severity:  "warning",
+			Actions:   []string{"notify"},
+		},
+	}
+	a, s, rec := testAlerterWithRecorder(t, alerts)
+	a.SetInhibit([]InhibitConfig{{Source: "host_mem", Targets: []string{"exited"}}})
+	ctx := context.Background()
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	a.now = func() time.Time { return now }
+
+	// Source and target fire in the same cycle; "exited" sorts first but the
+	// source is evaluated before it.
+	a.Evaluate(ctx, &MetricSnapshot{
+		Host:       &HostMetrics{MemPercent: 95},
+		Containers: []ContainerMetrics{{ID: "aaa", Name: "web", State: "exited"}},
+	})
+	a.notifier.Flush()
+	if calls := rec.Calls(); len(calls) != 1 || calls[0] != "Alert: host_mem" {
+		t.Fatalf("calls = %v, want only host_mem", calls)
+	}
+	firing, err := s.QueryFiringAlerts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inhibited := map[string]string{}
+	for _, al := range firing {
+		inhibited[al.RuleName] = al.InhibitedBy
+	}
+	if len(inhibited) != 2 || inhibited["exited"] != "host_mem" || inhibited["host_mem"] != "" {
+		t.Errorf("inhibited_by = %v", inhibited)
+	}
+
+	// Once the source resolves, new target instances notify again.
+	now = now.Add(time.Minute)
+	a.Evaluate(ctx, &MetricSnapshot{
+		Host: &HostMetrics{MemPercent: 50},
+		Containers: []ContainerMetrics{
+			{ID: "aaa", Name: "web", State: "exited"},
+			{ID: "bbb", Name: "api", State: "exited"},
+		},
+	})
+	a.notifier.Flush()
+	if calls := rec.Calls(); len(calls) != 2 || calls[1] != "Alert: exited" {
+		t.Errorf("calls = %v, want exited notified", calls)
+	}
+}
+
+func TestNotifyInhibitedEqual(t *testing.T) {
+	tests := []struct {
+		equal     string
+		container ContainerMetrics
+		want      int // target notifications
+	}{
+		{"project", ContainerMetrics{ID: "bbb", Name: "api", Project: "shop", State: "exited"}, 0},
+		{"project", ContainerMetrics{ID: "bbb", Name: "api", Project: "blog", State: "exited"}, 1},
+		{"container", ContainerMetrics{ID: "aaa", Name: "db", Project: "shop", State: "exited"}, 0},
+		{"container", ContainerMetrics{ID: "bbb", Name: "api", Project: "shop", State: "exited"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.equal+"/"+tt.container.Project+"/"+tt.container.ID, func(t *testing.T) {
+			alerts := map[string]AlertConfig{
+				"db_mem": {
+					Condition: "container.memory_percent > 90",
+					Severity:  "critical",
+					Actions:   []string{"notify"},
+				},
+				"exited": {
+					Condition: "container.state == 'exited'",
+					Severity:  "warning",
+					Actions:   []string{"notify"},
+				},
+			}
+			a, _, rec := testAlerterWithRecorder(t, alerts)
+			a.SetInhibit([]InhibitConfig{{Source: "db_mem", Targets: []string{"exited"}, Equal: tt.equal}})
+			ctx := context.Background()
+
+			containers := []ContainerMetrics{{ID: "aaa", Name: "db", Project: "shop", State: "running", MemPercent: 95}}
+			if tt.container.ID == "aaa" {
+				containers[0].State = "exited"
+			} else {
+				containers = append(containers, tt.container)
+			}
+			a.Evaluate(ctx, &MetricSnapshot{Containers: containers})
+			a.notifier.Flush()
+
+			got := 0
+			for _, c := range rec.Calls() {
+				if c == "Alert: exited" {
+					got++
+				}
+			}
+			if got != tt.want {
+				t.Errorf("exited notifications = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestSendTestNotification(t *testing.T) {
 	alerts := map[string]AlertConfig{
 		"high_cpu": {
@@ -376,3 +626,412 @@ func TestAlertNotificationCarriesSeverityAndStatus(t *testing.T) {
 		t.Errorf("notices[1].status = %q, want \"firing\"", notices[1].status)
 	}
 }
+
+func TestNotifyRouting(t *testing.T) {
+	alerts := map[string]AlertConfig{
+		"cpu": {
+			Condition: "host.cpu_percent > 90",
+			Severity:  "critical",
+			Actions:   []string{"notify"},
+		},
+		"mem": {
+			Condition: "host.memory_percent > 90",
+			Severity:  "warning",
+			Actions:   []string{"notify"},
+		},
+		"load": {
+			Condition: "host.load1 > 10",
+			Severity:  "warning",
+			Actions:   []string{"notify:oncall"},
+		},
+	}
+	s := testStore(t)
+	oncall, team := &recordingChannel{}, &recordingChannel{}
+	n := &Notifier{
+		channels: []Channel{oncall, team},
+		names:    []string{"oncall", "team"},
+		routes:   map[string][]string{"warning": {"team"}},
+		queue:    make(chan notification, 64),
+	}
+	n.wg.Add(1)
+	go n.run()
+	t.Cleanup(func() { n.Stop() })
+	a, err := NewAlerter(alerts, s, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a.Evaluate(context.Background(), &MetricSnapshot{Host: &HostMetrics{CPUPercent: 95, MemPercent: 95, Load1: 20}})
+	n.Flush()
+
+	// cpu has no route and reaches both; mem follows the warning route;
+	// load names its channel explicitly, bypassing routes.
+	if got := oncall.Calls(); !sameStrings(got, []string{"Alert: cpu", "Alert: load"}) {
+		t.Errorf("oncall = %v", got)
+	}
+	if got := team.Calls(); !sameStrings(got, []string{"Alert: cpu", "Alert: mem"}) {
+		t.Errorf("team = %v", got)
+	}
+
+	for _, rs := range a.QueryRules() {
+		want := map[string][]string{"cpu": {"oncall", "team"}, "mem": {"team"}, "load": {"oncall"}}[rs.Name]
+		if !sameStrings(rs.Channels, want) {
+			t.Errorf("%s channels = %v, want %v", rs.Name, rs.Channels, want)
+		}
+	}
+}
+
+// sameStrings reports whether a and b hold the same strings in any order.
+func sameStrings(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+func TestNotifyEscalation(t *testing.T) {
+	alerts := map[string]AlertConfig{
+		"cpu": {
+			Condition: "host.cpu_percent > 90",
+			Severity:  "critical",
+			Actions:   []string{"notify:oncall"},
+			Escalate: []EscalationStep{
+				{After: Duration{15 * time.Minute}, Channels: []string{"team"}},
+				{After: Duration{time.Hour}},
+			},
+		},
+		"mem": {
+			Condition: "host.memory_percent > 90",
+			Severity:  "warning",
+			Actions:   []string{"notify:oncall"},
+			Escalate:  []EscalationStep{{After: Duration{15 * time.Minute}, Channels: []string{"team"}}},
+		},
+	}
+	s := testStore(t)
+	oncall, team := &recordingChannel{}, &recordingChannel{}
+	n := &Notifier{
+		channels: []Channel{oncall, team},
+		names:    []string{"oncall", "team"},
+		queue:    make(chan notification, 64),
+	}
+	n.wg.Add(1)
+	go n.run()
+	t.Cleanup(func() { n.Stop() })
+	a, err := NewAlerter(alerts, s, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	a.now = func() time.Time { return now }
+	snap := &MetricSnapshot{Host: &HostMetrics{CPUPercent: 95, MemPercent: 95}}
+	eval := func(al *Alerter, at time.Duration) {
+		now = start.Add(at)
+		al.Evaluate(ctx, snap)
+		n.Flush()
+	}
+
+	eval(a, 0)
+	if got := oncall.Calls(); !sameStrings(got, []string{"Alert: cpu", "Alert: mem"}) {
+		t.Fatalf("oncall = %v", got)
+	}
+	// Acknowledging mem stops its escalation.
+	a.mu.Lock()
+	memID := a.instances["mem"].dbID
+	a.mu.Unlock()
+	if err := s.AckAlert(ctx, memID, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	eval(a, 10*time.Minute)
+	if got := team.Calls(); len(got) != 0 {
+		t.Fatalf("team before 15m = %v", got)
+	}
+	eval(a, 15*time.Minute)
+	if got := team.Calls(); !sameStrings(got, []string{"Escalation: cpu unacknowledged for 15m"}) {
+		t.Fatalf("team at 15m = %v", got)
+	}
+
+	// The step taken survives a restart.
+	b, err := NewAlerter(alerts, s, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.now = a.now
+	if err := b.AdoptFiring(ctx); err != nil {
+		t.Fatal(err)
+	}
+	eval(b, 20*time.Minute)
+	if got := team.Calls(); len(got) != 1 {
+		t.Fatalf("team after restart = %v, want no repeat", got)
+	}
+	eval(b, time.Hour)
+	want := "Escalation: cpu unacknowledged for 1h"
+	if got := oncall.Calls(); len(got) != 3 || got[2] != want {
+		t.Errorf("oncall = %v, want %q last", got, want)
+	}
+	if got := team.Calls(); len(got) != 2 || got[1] != want {
+		t.Errorf("team = %v, want %q last", got, want)
+	}
+
+	firing, err := s.QueryFiringAlerts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, al := range firing {
+		if wantStep := map[string]int{"cpu": 2, "mem": 1}[al.RuleName]; al.EscalationStep != wantStep {
+			t.Errorf("%s escalation_step = %d, want %d", al.RuleName, al.EscalationStep, wantStep)
+		}
+	}
+}
+
+func TestNotifyFlapping(t *testing.T) {
+	alerts := map[string]AlertConfig{
+		"down": {
+			Condition: "container.state == 'exited'",
+			Severity:  "critical",
+			Actions:   []string{"notify"},
+			Flapping:  FlappingConfig{Transitions: 4, Window: Duration{10 * time.Minute}},
+		},
+	}
+	a, s, rec := testAlerterWithRecorder(t, alerts)
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	a.now = func() time.Time { return now }
+	var published []*Alert
+	a.onStateChange = func(alert *Alert, state string) { published = append(published, alert) }
+
+	step := func(state string) {
+		now = now.Add(time.Minute)
+		a.Evaluate(ctx, &MetricSnapshot{Containers: []ContainerMetrics{{ID: "c1", Name: "web", State: state}}})
+	}
+	// Fire, resolve, fire, resolve: the fourth change starts flapping.
+	// The following fires are recorded but not notified.
+	for _, state := range []string{"exited", "running", "exited", "running", "exited", "running", "exited"} {
+		step(state)
+	}
+	if last := published[len(published)-1]; !last.Flapping {
+		t.Error("firing alert published while flapping should be marked flapping")
+	}
+	firing, err := s.QueryFiringAlerts(ctx)
+	if err != nil || len(firing) != 1 || !firing[0].Flapping {
+		t.Fatalf("firing = %+v, err = %v", firing, err)
+	}
+
+	// Stable for a full window: one notification with the settled state.
+	for range 10 {
+		step("exited")
+	}
+	if last := published[len(published)-1]; last.Flapping {
+		t.Error("alert should be republished without flapping once stable")
+	}
+
+	a.notifier.Flush()
+	var subjects []string
+	for _, n := range rec.Notifications() {
+		subjects = append(subjects, n.subject)
+	}
+	want := []string{"Alert: down", "Alert: down", "Flapping: down", "Stable: down"}
+	if !slices.Equal(subjects, want) {
+		t.Errorf("subjects = %v, want %v", subjects, want)
+	}
+	notes := rec.Notifications()
+	if body := notes[len(notes)-1].body; !strings.Contains(body, "now firing") {
+		t.Errorf("stable body = %q", body)
+	}
+}
+
+func TestNotifyAnnotationsAndTemplate(t *testing.T) {
+	alerts := map[string]AlertConfig{
+		"mem": {
+			Condition:       "container.memory_percent > 80",
+			Severity:        "warning",
+			Actions:         []string{"notify"},
+			Description:     "Memory is close to the container limit.",
+			RunbookURL:      "https://wiki.example.com/runbooks/mem",
+			MessageTemplate: `{{.Instance}} ({{.Project}}) {{.Field}} at {{printf "%.1f" .Value}}% (threshold {{.Threshold}})`,
+		},
+	}
+	a, s, rec := testAlerterWithRecorder(t, alerts)
+	ctx := context.Background()
+
+	a.Evaluate(ctx, &MetricSnapshot{Containers: []ContainerMetrics{
+		{ID: "c1", Name: "web", Project: "shop", State: "running", MemPercent: 91.25},
+	}})
+	a.notifier.Flush()
+
+	wantMsg := "web (shop) container.memory_percent at 91.2% (threshold 80)"
+	notes := rec.Notifications()
+	if len(notes) != 1 {
+		t.Fatalf("notifications = %+v", notes)
+	}
+	wantBody := wantMsg + "\n\nMemory is close to the container limit.\nRunbook: https://wiki.example.com/runbooks/mem"
+	if notes[0].body != wantBody {
+		t.Errorf("body = %q, want %q", notes[0].body, wantBody)
+	}
+
+	firing, err := s.QueryFiringAlerts(ctx)
+	if err != nil || len(firing) != 1 {
+		t.Fatalf("firing = %+v, err = %v", firing, err)
+	}
+	if f := firing[0]; f.Message != wantMsg || f.Description != alerts["mem"].Description || f.RunbookURL != alerts["mem"].RunbookURL {
+		t.Errorf("stored alert = %+v", f)
+	}
+	if rs := a.QueryRules(); rs[0].RunbookURL != alerts["mem"].RunbookURL || rs[0].MessageTemplate == "" {
+		t.Errorf("rule status = %+v", rs[0])
+	}
+}
+
+// recordingIncidentChannel is a recordingChannel that tracks incidents.
+type recordingIncidentChannel struct{ recordingChannel }
+
+func (r *recordingIncidentChannel) incidents() {}
+
+func TestNotifyIncidentEvents(t *testing.T) {
+	alerts := map[string]AlertConfig{
+		"cpu": {
+			Condition: "host.cpu_percent > 90",
+			Severity:  "critical",
+			Actions:   []string{"notify"},
+		},
+	}
+	s := testStore(t)
+	team, pager := &recordingChannel{}, &recordingIncidentChannel{}
+	n := &Notifier{
+		channels: []Channel{team, pager},
+		names:    []string{"team", "pager"},
+		queue:    make(chan notification, 64),
+		incident: true,
+	}
+	n.wg.Add(1)
+	go n.run()
+	t.Cleanup(func() { n.Stop() })
+	a, err := NewAlerter(alerts, s, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	a.Evaluate(ctx, &MetricSnapshot{Host: &HostMetrics{CPUPercent: 95}})
+	var id int64
+	if err := s.db.QueryRow("SELECT id FROM alerts").Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+	a.Acknowledge(id)
+	a.Evaluate(ctx, &MetricSnapshot{Host: &HostMetrics{CPUPercent: 10}})
+	// Acknowledging a resolved alert is not mirrored.
+	a.Acknowledge(id)
+	n.Flush()
+
+	if got := team.Calls(); !slices.Equal(got, []string{"Alert: cpu"}) {
+		t.Errorf("team = %v, want only the alert", got)
+	}
+	notices := pager.Notifications()
+	var got []string
+	for _, msg := range notices {
+		for _, note := range msg.alerts {
+			if note.key != "cpu" {
+				t.Errorf("%s key = %q, want cpu", note.status, note.key)
+			}
+			got = append(got, note.status)
+		}
+	}
+	if !slices.Equal(got, []string{"firing", "acknowledged", "resolved"}) {
+		t.Errorf("pager statuses = %v, want firing, acknowledged, resolved", got)
+	}
+}
+
+func TestResolveAllClosesIncidents(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	path := writeScript(t, "echo $TORI_STATE >> "+out+"\n")
+	alerts := map[string]AlertConfig{
+		"cpu": {
+			Condition: "host.cpu_percent > 90",
+			Severity:  "critical",
+			Actions:   []string{"notify", "exec:" + path},
+		},
+	}
+	s := testStore(t)
+	pager := &recordingIncidentChannel{}
+	n := &Notifier{
+		channels: []Channel{pager},
+		names:    []string{"pager"},
+		queue:    make(chan notification, 64),
+		incident: true,
+	}
+	n.wg.Add(1)
+	go n.run()
+	a, err := NewAlerter(alerts, s, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.SetExec(&ExecConfig{Timeout: Duration{5 * time.Second}, MaxConcurrent: 4})
+	ctx := context.Background()
+
+	a.Evaluate(ctx, &MetricSnapshot{Host: &HostMetrics{CPUPercent: 95}})
+	a.exec.wait()
+	// A config reload resolves the alert and then stops the alerter.
+	a.ResolveAll(ctx)
+	a.Stop()
+
+	var got []string
+	for _, msg := range pager.Notifications() {
+		for _, note := range msg.alerts {
+			got = append(got, note.status)
+		}
+	}
+	if !slices.Equal(got, []string{"firing", "resolved"}) {
+		t.Errorf("pager statuses = %v, want firing, resolved", got)
+	}
+	ran, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(ran) != "firing\n" {
+		t.Errorf("exec ran for %q, want only the firing", ran)
+	}
+}
+
+func TestAdoptFiringResolvesOrphanIncidents(t *testing.T) {
+	s := testStore(t)
+	pager := &recordingIncidentChannel{}
+	n := &Notifier{
+		channels: []Channel{pager},
+		names:    []string{"pager"},
+		queue:    make(chan notification, 64),
+		incident: true,
+	}
+	n.wg.Add(1)
+	go n.run()
+	t.Cleanup(func() { n.Stop() })
+	a, err := NewAlerter(map[string]AlertConfig{
+		"cpu": {Condition: "host.cpu_percent > 90", Severity: "critical", Actions: []string{"notify"}},
+	}, s, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	// The exited rule was removed while the agent was down.
+	if _, err := s.InsertAlert(ctx, &Alert{RuleName: "exited", Severity: "warning", InstanceKey: "exited:abc",
+		FiredAt: time.Now().Add(-time.Hour), Message: "web exited"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.AdoptFiring(ctx); err != nil {
+		t.Fatal(err)
+	}
+	n.Flush()
+
+	notices := pager.Notifications()
+	if len(notices) != 1 || !notices[0].incident || len(notices[0].alerts) != 1 {
+		t.Fatalf("pager = %+v, want one resolve event", notices)
+	}
+	if note := notices[0].alerts[0]; note.status != "resolved" || note.key != "exited:abc" ||
+		note.rule != "exited" || note.severity != "warning" {
+		t.Errorf("resolve event = %+v", note)
+	}
+	if firing, err := s.QueryFiringAlerts(ctx); err != nil || len(firing) != 0 {
+		t.Errorf("firing = %+v, err = %v; want the orphan resolved", firing, err)
+	}
+}
@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/thobiasn/tori-cli/internal/protocol"
+)
+
+// statsAcc accumulates the alerts of one group for alertStats.
+type statsAcc struct {
+	stats    protocol.AlertStats
+	ackSecs  int64
+	ackCount int
+	resSecs  int64
+}
+
+func (acc *statsAcc) add(inc *AlertIncident, end time.Time) {
+	acc.stats.Fired++
+	firingEnd := end
+	if inc.ResolvedAt != nil {
+		acc.stats.Resolved++
+		acc.resSecs += int64(inc.ResolvedAt.Sub(inc.FiredAt).Seconds())
+		if inc.ResolvedAt.Before(end) {
+			firingEnd = *inc.ResolvedAt
+		}
+	}
+	if d := firingEnd.Sub(inc.FiredAt); d > 0 {
+		acc.stats.FiringSecs += int64(d.Seconds())
+	}
+	if inc.Acknowledged {
+		acc.stats.Acknowledged++
+		if inc.AcknowledgedAt != nil {
+			acc.ackSecs += int64(inc.AcknowledgedAt.Sub(inc.FiredAt).Seconds())
+			acc.ackCount++
+		}
+	}
+}
+
+func (acc *statsAcc) result() protocol.AlertStats {
+	st := acc.stats
+	if acc.ackCount > 0 {
+		st.MTTASecs = acc.ackSecs / int64(acc.ackCount)
+	}
+	if st.Resolved > 0 {
+		st.MTTRSecs = acc.resSecs / int64(st.Resolved)
+	}
+	return st
+}
+
+// alertStats summarizes incidents per rule, per container and in total.
+// Firing time is counted up to end. Container alerts are grouped by the
+// container ID in their instance key; the scope is taken from the stored
+// condition, so rules that have since been removed are still grouped.
+func alertStats(incidents []AlertIncident, start, end time.Time) *protocol.QueryAlertStatsResp {
+	var total statsAcc
+	rules := make(map[string]*statsAcc)
+	containers := make(map[string]*statsAcc)
+	scopes := make(map[string]string) // condition -> scope
+
+	group := func(m map[string]*statsAcc, name string) *statsAcc {
+		acc := m[name]
+		if acc == nil {
+			acc = &statsAcc{stats: protocol.AlertStats{Name: name}}
+			m[name] = acc
+		}
+		return acc
+	}
+
+	for i := range incidents {
+		inc := &incidents[i]
+		total.add(inc, end)
+		group(rules, inc.RuleName).add(inc, end)
+
+		scope, ok := scopes[inc.Condition]
+		if !ok {
+			if expr, err := parseCondition(inc.Condition); err == nil {
+				scope = expr.Scope()
+			}
+			scopes[inc.Condition] = scope
+		}
+		if scope != "container" && scope != "log" {
+			continue
+		}
+		if id, ok := strings.CutPrefix(inc.InstanceKey, inc.RuleName+":"); ok && id != "" {
+			group(containers, id).add(inc, end)
+		}
+	}
+
+	return &protocol.QueryAlertStatsResp{
+		Start:      start.Unix(),
+		End:        end.Unix(),
+		Total:      total.result(),
+		Rules:      sortedStats(rules),
+		Containers: sortedStats(containers),
+	}
+}
+
+// sortedStats returns the groups noisiest first: by alerts fired, then by
+// firing time, then by name.
+func sortedStats(m map[string]*statsAcc) []protocol.AlertStats {
+	out := make([]protocol.AlertStats, 0, len(m))
+	for _, acc := range m {
+		out = append(out, acc.result())
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Fired != out[j].Fired {
+			return out[i].Fired > out[j].Fired
+		}
+		if out[i].FiringSecs != out[j].FiringSecs {
+			return out[i].FiringSecs > out[j].FiringSecs
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAlertStats(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+	at := func(d time.Duration) *time.Time {
+		t := start.Add(d)
+		return &t
+	}
+
+	incidents := []AlertIncident{
+		// Resolved after 10m, acknowledged after 2m.
+		{RuleName: "high_cpu", Condition: "host.cpu_percent > 90", InstanceKey: "high_cpu",
+			FiredAt: start, ResolvedAt: at(10 * time.Minute), Acknowledged: true, AcknowledgedAt: at(2 * time.Minute)},
+		// Resolved after 30m, never acknowledged.
+		{RuleName: "high_cpu", Condition: "host.cpu_percent > 90", InstanceKey: "high_cpu",
+			FiredAt: start.Add(time.Hour), ResolvedAt: at(90 * time.Minute)},
+		// Still firing: counts up to end.
+		{RuleName: "exited", Condition: "container.state == 'exited'", InstanceKey: "exited:abc",
+			FiredAt: start.Add(23 * time.Hour)},
+		// Acknowledged before the time was recorded: counted, but not in MTTA.
+		{RuleName: "errors", Condition: "log.count > 0", InstanceKey: "errors:abc",
+			FiredAt: start, ResolvedAt: at(time.Minute), Acknowledged: true},
+		// Disk alerts have an instance but are not per container.
+		{RuleName: "disk", Condition: "disk.percent > 90", InstanceKey: "disk:/",
+			FiredAt: start, ResolvedAt: at(time.Minute)},
+	}
+
+	got := alertStats(incidents, start, end)
+
+	tot := got.Total
+	if tot.Fired != 5 || tot.Acknowledged != 2 || tot.Resolved != 4 {
+		t.Errorf("total counts = %+v", tot)
+	}
+	if want := int64((10 + 30 + 60 + 1 + 1) * 60); tot.FiringSecs != want {
+		t.Errorf("total firing = %d, want %d", tot.FiringSecs, want)
+	}
+	if tot.MTTASecs != 120 {
+		t.Errorf("total MTTA = %d, want 120", tot.MTTASecs)
+	}
+	if want := int64((10 + 30 + 1 + 1) * 60 / 4); tot.MTTRSecs != want {
+		t.Errorf("total MTTR = %d, want %d", tot.MTTRSecs, want)
+	}
+
+	var order []string
+	for _, r := range got.Rules {
+		order = append(order, r.Name)
+	}
+	// high_cpu fired twice; exited fired once but has fired longest.
+	if want := []string{"high_cpu", "exited", "disk", "errors"}; len(order) != len(want) ||
+		order[0] != want[0] || order[1] != want[1] || order[2] != want[2] || order[3] != want[3] {
+		t.Errorf("rule order = %v, want %v", order, want)
+	}
+	if r := got.Rules[0]; r.MTTASecs != 120 || r.MTTRSecs != 20*60 {
+		t.Errorf("high_cpu = %+v, want MTTA 2m and MTTR 20m", r)
+	}
+	if r := got.Rules[1]; r.Resolved != 0 || r.MTTRSecs != 0 {
+		t.Errorf("exited = %+v, want no resolves", r)
+	}
+
+	if len(got.Containers) != 1 {
+		t.Fatalf("containers = %+v, want only abc", got.Containers)
+	}
+	if c := got.Containers[0]; c.Name != "abc" || c.Fired != 2 || c.FiringSecs != 61*60 {
+		t.Errorf("container abc = %+v, want 2 fired and 61m firing", c)
+	}
+}
@@ -11,7 +11,7 @@ import (
 func testAlerter(t *testing.T, alerts map[string]AlertConfig) (*Alerter, *Store) {
 	t.Helper()
 	s := testStore(t)
-	n := NewNotifier(&NotifyConfig{})
+	n := NewNotifier(&NotifyConfig{}, nil)
 	a, err := NewAlerter(alerts, s, n)
 	if err != nil {
 		t.Fatal(err)
@@ -496,20 +496,20 @@ func TestAlertSilence(t *testing.T) {
 	a.now = func() time.Time { return now }
 
 	// Silence for 1 minute.
-	a.Silence("high_cpu", 1*time.Minute)
+	a.AddSilence(context.Background(), Silence{RuleName: "high_cpu"}, 1*time.Minute)
 
-	if !a.isSilenced("high_cpu") {
+	if !a.isSilenced("high_cpu", "high_cpu") {
 		t.Fatal("expected silenced")
 	}
 
 	// Advance past silence duration.
 	now = now.Add(2 * time.Minute)
-	if a.isSilenced("high_cpu") {
+	if a.isSilenced("high_cpu", "high_cpu") {
 		t.Fatal("expected silence expired")
 	}
 
 	// Non-silenced rule.
-	if a.isSilenced("nonexistent") {
+	if a.isSilenced("nonexistent", "nonexistent") {
 		t.Fatal("nonexistent rule should not be silenced")
 	}
 }
@@ -562,7 +562,7 @@ func TestAlertSilenceSuppressesNotify(t *testing.T) {
 	a.now = func() time.Time { return now }
 
 	// Silence the rule.
-	a.Silence("exited", 5*time.Minute)
+	a.AddSilence(ctx, Silence{RuleName: "exited"}, 5*time.Minute)
 
 	// Fire — notify should be suppressed, but alert should still be created.
 	a.Evaluate(ctx, &MetricSnapshot{
@@ -756,6 +756,314 @@ func TestEvaluateContainerEventSkipsHostRules(t *testing.T) {
 	}
 }
 
+func TestCompoundContainerRule(t *testing.T) {
+	alerts := map[string]AlertConfig{
+		"running_unhealthy": {
+			Condition: "container.state == 'running' AND container.health == 'unhealthy'",
+			Severity:  "critical",
+			Actions:   []string{"notify"},
+		},
+	}
+	a, s := testAlerter(t, alerts)
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	a.now = func() time.Time { return now }
+
+	a.Evaluate(ctx, &MetricSnapshot{Containers: []ContainerMetrics{
+		{ID: "aaa", Name: "web", State: "running", Health: "unhealthy"},
+		{ID: "bbb", Name: "db", State: "exited", Health: "unhealthy"},
+		{ID: "ccc", Name: "api", State: "running", Health: "healthy"},
+	}})
+
+	if inst := a.instances["running_unhealthy:aaa"]; inst == nil || inst.state != stateFiring {
+		t.Error("expected running_unhealthy:aaa to fire")
+	}
+	for _, key := range []string{"running_unhealthy:bbb", "running_unhealthy:ccc"} {
+		if inst := a.instances[key]; inst != nil && inst.state == stateFiring {
+			t.Errorf("%s should not fire", key)
+		}
+	}
+
+	var cond, msg string
+	if err := s.db.QueryRow("SELECT condition, message FROM alerts WHERE instance_key = 'running_unhealthy:aaa'").Scan(&cond, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if cond != "container.state == 'running' AND container.health == 'unhealthy'" {
+		t.Errorf("condition = %q", cond)
+	}
+	if msg != "[critical] running_unhealthy: container.state == 'running' AND container.health == 'unhealthy' (web)" {
+		t.Errorf("message = %q", msg)
+	}
+
+	// The container event path evaluates string-only compound rules too.
+	now = now.Add(10 * time.Second)
+	a.EvaluateContainerEvent(ctx, ContainerMetrics{ID: "aaa", Name: "web", State: "running", Health: "healthy"})
+	if inst := a.instances["running_unhealthy:aaa"]; inst.state != stateInactive {
+		t.Errorf("expected resolve via container event, got state %d", inst.state)
+	}
+}
+
+func TestContainerRuleSelector(t *testing.T) {
+	alerts := map[string]AlertConfig{
+		"shop_exited": {
+			Condition: "container.state == 'exited'",
+			Severity:  "critical",
+			Actions:   []string{"notify"},
+			Project:   "shop",
+			Labels:    map[string]string{"tier": "back*"},
+		},
+	}
+	a, _ := testAlerter(t, alerts)
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	a.now = func() time.Time { return now }
+
+	a.Evaluate(ctx, &MetricSnapshot{Containers: []ContainerMetrics{
+		{ID: "aaa", Name: "shop-db-1", State: "exited", Project: "shop", Service: "db", Labels: map[string]string{"tier": "backend"}},
+		{ID: "bbb", Name: "shop-web-1", State: "exited", Project: "shop", Service: "web", Labels: map[string]string{"tier": "frontend"}},
+		{ID: "ccc", Name: "blog-db-1", State: "exited", Project: "blog", Service: "db", Labels: map[string]string{"tier": "backend"}},
+	}})
+
+	if inst := a.instances["shop_exited:aaa"]; inst == nil || inst.state != stateFiring {
+		t.Error("expected shop_exited:aaa to fire")
+	}
+	for _, key := range []string{"shop_exited:bbb", "shop_exited:ccc"} {
+		if _, ok := a.instances[key]; ok {
+			t.Errorf("%s should not be evaluated", key)
+		}
+	}
+
+	// Events respect the selector too.
+	a.EvaluateContainerEvent(ctx, ContainerMetrics{ID: "ddd", Name: "blog-web-1", State: "exited", Project: "blog", Service: "web"})
+	if _, ok := a.instances["shop_exited:ddd"]; ok {
+		t.Error("event for non-matching container should be ignored")
+	}
+	a.EvaluateContainerEvent(ctx, ContainerMetrics{ID: "eee", Name: "shop-worker-1", State: "exited", Project: "shop", Service: "worker", Labels: map[string]string{"tier": "backend"}})
+	if inst := a.instances["shop_exited:eee"]; inst == nil || inst.state != stateFiring {
+		t.Error("expected event for matching container to fire")
+	}
+
+	rules := a.QueryRules()
+	if len(rules) != 1 || rules[0].Selector != "project=shop label:tier=back*" {
+		t.Errorf("selector = %+v", rules)
+	}
+}
+
+func TestAggregateHostRule(t *testing.T) {
+	alerts := map[string]AlertConfig{
+		"cpu_sustained": {
+			Condition: "avg(host.cpu_percent, 30s) > 80",
+			Severity:  "warning",
+			Actions:   []string{"notify"},
+		},
+	}
+	a, s := testAlerter(t, alerts)
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	a.now = func() time.Time { return now }
+
+	// A spike alone does not move the 30s average over the threshold.
+	for _, cpu := range []float64{50, 50, 50, 100} {
+		a.Evaluate(ctx, &MetricSnapshot{Host: &HostMetrics{CPUPercent: cpu}})
+		now = now.Add(10 * time.Second)
+	}
+	if inst := a.instances["cpu_sustained"]; inst != nil && inst.state == stateFiring {
+		t.Fatal("spike should not fire")
+	}
+
+	// Sustained load does; the oldest samples have aged out of the window.
+	for _, cpu := range []float64{90, 95} {
+		a.Evaluate(ctx, &MetricSnapshot{Host: &HostMetrics{CPUPercent: cpu}})
+		now = now.Add(10 * time.Second)
+	}
+	if inst := a.instances["cpu_sustained"]; inst == nil || inst.state != stateFiring {
+		t.Fatal("expected cpu_sustained to fire")
+	}
+
+	var msg string
+	if err := s.db.QueryRow("SELECT message FROM alerts WHERE rule_name = 'cpu_sustained'").Scan(&msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg != "[warning] cpu_sustained: avg(host.cpu_percent, 30s)" {
+		t.Errorf("message = %q", msg)
+	}
+
+	rules := a.QueryRules()
+	if len(rules[0].Aggregates) != 1 || rules[0].Aggregates[0] != "avg of host.cpu_percent over 30s" {
+		t.Errorf("aggregates = %v", rules[0].Aggregates)
+	}
+}
+
+func TestAggregateContainerIncrease(t *testing.T) {
+	alerts := map[string]AlertConfig{
+		"crash_loop": {
+			Condition: "increase(container.restart_count, 1h) >= 3",
+			Severity:  "critical",
+			Actions:   []string{"notify"},
+		},
+	}
+	a, _ := testAlerter(t, alerts)
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	a.now = func() time.Time { return now }
+
+	for _, restarts := range []int{10, 11, 12, 12, 13} {
+		a.Evaluate(ctx, &MetricSnapshot{Containers: []ContainerMetrics{
+			{ID: "aaa", Name: "web", State: "running", RestartCount: restarts},
+			{ID: "bbb", Name: "db", State: "running", RestartCount: 1},
+		}})
+		now = now.Add(10 * time.Minute)
+	}
+	if inst := a.instances["crash_loop:aaa"]; inst == nil || inst.state != stateFiring {
+		t.Error("expected crash_loop:aaa to fire")
+	}
+	if inst := a.instances["crash_loop:bbb"]; inst != nil && inst.state == stateFiring {
+		t.Error("crash_loop:bbb should not fire")
+	}
+
+	// History for a removed container is dropped once its window passes.
+	now = now.Add(2 * time.Hour)
+	a.Evaluate(ctx, &MetricSnapshot{})
+	if len(a.history.series) != 0 {
+		t.Errorf("history not pruned: %d series", len(a.history.series))
+	}
+}
+
+func TestNetRulePerInterface(t *testing.T) {
+	alerts := map[string]AlertConfig{
+		"uplink_saturated": {
+			Condition:         "net.rx_bytes_per_sec > 1000 OR net.tx_bytes_per_sec > 1000",
+			Severity:          "warning",
+			Actions:           []string{"notify"},
+			ExcludeInterfaces: []string{"veth*"},
+		},
+	}
+	a, s := testAlerter(t, alerts)
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	a.now = func() time.Time { return now }
+
+	snap := func(eth0, eth1, veth uint64) *MetricSnapshot {
+		return &MetricSnapshot{Nets: []NetMetrics{
+			{Iface: "eth0", RxBytes: eth0},
+			{Iface: "eth1", TxBytes: eth1},
+			{Iface: "veth1", RxBytes: veth},
+		}}
+	}
+
+	// First reading establishes the baseline only.
+	a.Evaluate(ctx, snap(0, 0, 0))
+	if len(a.instances) != 0 {
+		t.Fatalf("instances after first reading = %d, want 0", len(a.instances))
+	}
+
+	now = now.Add(10 * time.Second)
+	a.Evaluate(ctx, snap(50000, 500, 1e9))
+	if inst := a.instances["uplink_saturated:eth0"]; inst == nil || inst.state != stateFiring {
+		t.Error("expected uplink_saturated:eth0 to fire")
+	}
+	if inst := a.instances["uplink_saturated:eth1"]; inst != nil && inst.state == stateFiring {
+		t.Error("eth1 at 50 B/s should not fire")
+	}
+	if _, ok := a.instances["uplink_saturated:veth1"]; ok {
+		t.Error("excluded interface should not be evaluated")
+	}
+
+	var msg string
+	if err := s.db.QueryRow("SELECT message FROM alerts WHERE instance_key = 'uplink_saturated:eth0'").Scan(&msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg != "[warning] uplink_saturated: net.rx_bytes_per_sec > 1000 OR net.tx_bytes_per_sec > 1000 (eth0)" {
+		t.Errorf("message = %q", msg)
+	}
+
+	// A failed network read keeps firing instances.
+	now = now.Add(10 * time.Second)
+	a.Evaluate(ctx, &MetricSnapshot{})
+	if inst := a.instances["uplink_saturated:eth0"]; inst == nil || inst.state != stateFiring {
+		t.Error("nil Nets should not resolve eth0")
+	}
+
+	// Traffic drops: resolves.
+	now = now.Add(10 * time.Second)
+	a.Evaluate(ctx, snap(50100, 600, 1e9))
+	if inst := a.instances["uplink_saturated:eth0"]; inst == nil || inst.state != stateInactive {
+		t.Error("expected uplink_saturated:eth0 to resolve")
+	}
+
+	if rules := a.QueryRules(); rules[0].Selector != "exclude=veth*" {
+		t.Errorf("selector = %q", rules[0].Selector)
+	}
+}
+
+func TestCompoundRuleWithNumericSkippedByEvents(t *testing.T) {
+	alerts := map[string]AlertConfig{
+		"hot_and_running": {
+			Condition: "container.state == 'running' AND container.cpu_percent > 80",
+			Severity:  "warning",
+			Actions:   []string{"notify"},
+		},
+	}
+	a, _ := testAlerter(t, alerts)
+	ctx := context.Background()
+	a.now = func() time.Time { return time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC) }
+
+	a.Evaluate(ctx, &MetricSnapshot{Containers: []ContainerMetrics{
+		{ID: "aaa", Name: "web", State: "running", CPUPercent: 95},
+	}})
+	if a.instances["hot_and_running:aaa"].state != stateFiring {
+		t.Fatal("expected hot_and_running:aaa firing")
+	}
+
+	// Events carry no CPU data, so rules mixing in numeric fields are skipped.
+	a.EvaluateContainerEvent(ctx, ContainerMetrics{ID: "aaa", Name: "web", State: "running"})
+	if a.instances["hot_and_running:aaa"].state != stateFiring {
+		t.Error("expected rule with numeric comparison to be skipped by event eval")
+	}
+}
+
+func TestCompoundHostRule(t *testing.T) {
+	alerts := map[string]AlertConfig{
+		"overloaded": {
+			Condition: "host.cpu_percent > 90 OR host.load5 > 8",
+			Severity:  "warning",
+			Actions:   []string{"notify"},
+		},
+		"disk_and_mem": {
+			Condition: "host.disk_percent > 90 AND host.memory_percent > 80",
+			Severity:  "critical",
+			Actions:   []string{"notify"},
+		},
+	}
+	a, _ := testAlerter(t, alerts)
+	ctx := context.Background()
+	a.now = func() time.Time { return time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC) }
+
+	a.Evaluate(ctx, &MetricSnapshot{
+		Host: &HostMetrics{CPUPercent: 10, Load5: 9, MemPercent: 85},
+		Disks: []DiskMetrics{
+			{Mountpoint: "/", Percent: 95},
+			{Mountpoint: "/data", Percent: 50},
+		},
+	})
+
+	if inst := a.instances["overloaded"]; inst == nil || inst.state != stateFiring {
+		t.Error("expected overloaded to fire on load5 alone")
+	}
+	if inst := a.instances["disk_and_mem:/"]; inst == nil || inst.state != stateFiring {
+		t.Error("expected disk_and_mem:/ to fire")
+	}
+	if inst := a.instances["disk_and_mem:/data"]; inst != nil && inst.state == stateFiring {
+		t.Error("disk_and_mem:/data should not fire")
+	}
+
+	// Host data missing: per-disk rules that need host fields must not resolve.
+	a.Evaluate(ctx, &MetricSnapshot{Disks: []DiskMetrics{{Mountpoint: "/", Percent: 95}}})
+	if inst := a.instances["disk_and_mem:/"]; inst == nil || inst.state != stateFiring {
+		t.Error("disk_and_mem:/ should stay firing when host metrics are missing")
+	}
+}
+
 func TestEvaluateContainerEventForDurationPending(t *testing.T) {
 	alerts := map[string]AlertConfig{
 		"exited": {
@@ -1456,7 +1764,7 @@ func TestQueryRules(t *testing.T) {
 	}
 
 	// Silence a rule and verify.
-	a.Silence("exited", 5*time.Minute)
+	a.AddSilence(ctx, Silence{RuleName: "exited"}, 5*time.Minute)
 	rules = a.QueryRules()
 	if rules[0].SilencedUntil.IsZero() {
 		t.Error("exited should be silenced")
@@ -1938,7 +2246,7 @@ func TestAdoptFiringOrphanResolution(t *testing.T) {
 	}
 
 	// Create alerter with only "new_rule" — old_rule is orphaned.
-	n := NewNotifier(&NotifyConfig{})
+	n := NewNotifier(&NotifyConfig{}, nil)
 	a, err := NewAlerter(map[string]AlertConfig{
 		"new_rule": {Condition: "host.cpu_percent > 90", Severity: "critical", Actions: []string{"notify"}},
 	}, s, n)
@@ -0,0 +1,223 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// backtestChunk is the span of history loaded from the store at a time, so a
+// long replay does not hold every row in memory.
+const backtestChunk = 6 * 3600 // seconds
+
+// BacktestResult describes what a rule would have done over a time range.
+type BacktestResult struct {
+	Rule      string
+	Condition string
+	Start     time.Time
+	End       time.Time
+	Samples   int // collect cycles replayed
+	Intervals []BacktestInterval
+}
+
+// BacktestInterval is one fire/resolve cycle of a rule instance.
+type BacktestInterval struct {
+	InstanceKey string
+	Label       string // container, mountpoint or interface; "" for host rules
+	FiredAt     time.Time
+	ResolvedAt  time.Time // zero if still firing at the end of the range
+}
+
+// backtestLog collects the transitions of a replayed Alerter.
+type backtestLog struct {
+	intervals []BacktestInterval
+	open      map[string]int // instance key -> index of its unresolved interval
+}
+
+func (l *backtestLog) fired(ec *evalContext, at time.Time) {
+	l.open[ec.key] = len(l.intervals)
+	l.intervals = append(l.intervals, BacktestInterval{InstanceKey: ec.key, Label: ec.label, FiredAt: at})
+}
+
+func (l *backtestLog) resolved(key string, at time.Time) {
+	if i, ok := l.open[key]; ok {
+		l.intervals[i].ResolvedAt = at
+		delete(l.open, key)
+	}
+}
+
+// Backtest replays the named rule against the metrics stored between start
+// and end and reports the intervals it would have fired. The rule runs
+// through the same state machine as live evaluation (for, resolve_for,
+// cooldown), but nothing is written to the store and no notifications are
+// sent.
+func (a *Alerter) Backtest(ctx context.Context, name string, start, end time.Time) (*BacktestResult, error) {
+	a.mu.Lock()
+	r := a.ruleNamed(name)
+	a.mu.Unlock()
+	if r == nil {
+		return nil, fmt.Errorf("unknown rule %q", name)
+	}
+	return backtest(ctx, a.store, *r, start, end)
+}
+
+func backtest(ctx context.Context, store *Store, rule alertRule, start, end time.Time) (*BacktestResult, error) {
+	if err := checkBacktestable(&rule); err != nil {
+		return nil, err
+	}
+	if start.After(end) {
+		return nil, fmt.Errorf("start must be before end")
+	}
+
+	log := &backtestLog{open: make(map[string]int)}
+	r := &Alerter{
+		rules:        []alertRule{rule},
+		instances:    make(map[string]*alertInstance),
+		lastNotified: make(map[string]time.Time),
+		store:        store,
+		history:      newMetricHistory(),
+		netRates:     newNetRateCalc(),
+		replay:       log,
+	}
+	r.evalOrder = []*alertRule{&r.rules[0]}
+	rule.cond.windows(r.history.track)
+
+	// Log rules are evaluated per container; history has no container list,
+	// so use every container that logged during the range.
+	var logContainers []ContainerMetrics
+	if rule.cond.Scope() == "log" {
+		var err error
+		logContainers, err = store.QueryLogContainers(ctx, start.Unix(), end.Unix())
+		if err != nil {
+			return nil, fmt.Errorf("query log containers: %w", err)
+		}
+		if logContainers == nil {
+			logContainers = []ContainerMetrics{}
+		}
+	}
+
+	res := &BacktestResult{
+		Rule:      rule.name,
+		Condition: rule.cond.String(),
+		Start:     start,
+		End:       end,
+	}
+	for lo := start.Unix(); lo <= end.Unix(); lo += backtestChunk {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		hi := min(lo+backtestChunk-1, end.Unix())
+		snaps, err := loadBacktestSnapshots(ctx, store, &rule, lo, hi, logContainers)
+		if err != nil {
+			return nil, err
+		}
+		for _, ts := range snaps {
+			now := ts.t
+			r.now = func() time.Time { return now }
+			r.Evaluate(ctx, ts.snap)
+			res.Samples++
+		}
+	}
+	res.Intervals = log.intervals
+	return res, nil
+}
+
+// checkBacktestable rejects rules that reference data the store does not keep.
+func checkBacktestable(r *alertRule) error {
+	var err error
+	if r.cond.Scope() == "host" && r.cond.hasField("agent_uptime") {
+		return fmt.Errorf("host.agent_uptime is not kept in history and cannot be backtested")
+	}
+	if r.cond.Scope() == "container" {
+		r.cond.walk(func(c *Condition) {
+			if err == nil && c.Field != "cpu_percent" && c.Field != "memory_percent" {
+				err = fmt.Errorf("container.%s is not kept in history; only cpu_percent and memory_percent can be backtested", c.Field)
+			}
+		})
+	}
+	if err != nil {
+		return err
+	}
+	if r.selector.name != "" || r.selector.image != "" || len(r.selector.labels) > 0 {
+		return fmt.Errorf("container, image and labels selectors cannot be backtested; history only records project and service")
+	}
+	return nil
+}
+
+type timedSnapshot struct {
+	t    time.Time
+	snap *MetricSnapshot
+}
+
+// loadBacktestSnapshots rebuilds the per-cycle snapshots a rule needs from
+// the rows stored in [start, end], ordered by time.
+func loadBacktestSnapshots(ctx context.Context, store *Store, r *alertRule, start, end int64, logContainers []ContainerMetrics) ([]timedSnapshot, error) {
+	byTS := make(map[int64]*timedSnapshot)
+	at := func(t time.Time) *MetricSnapshot {
+		ts := byTS[t.Unix()]
+		if ts == nil {
+			ts = &timedSnapshot{t: t, snap: &MetricSnapshot{}}
+			byTS[t.Unix()] = ts
+		}
+		return ts.snap
+	}
+
+	switch scope := r.cond.Scope(); scope {
+	case "host", "log":
+		hosts, err := store.QueryHostMetrics(ctx, start, end)
+		if err != nil {
+			return nil, fmt.Errorf("query host metrics: %w", err)
+		}
+		for i := range hosts {
+			at(hosts[i].Timestamp).Host = &hosts[i].HostMetrics
+		}
+		if scope == "log" {
+			for _, ts := range byTS {
+				ts.snap.Containers = logContainers
+			}
+		}
+		if r.cond.hasField("disk_percent") {
+			disks, err := store.QueryDiskMetrics(ctx, start, end)
+			if err != nil {
+				return nil, fmt.Errorf("query disk metrics: %w", err)
+			}
+			for _, d := range disks {
+				snap := at(d.Timestamp)
+				snap.Disks = append(snap.Disks, d.DiskMetrics)
+			}
+		}
+	case "net":
+		nets, err := store.QueryNetMetrics(ctx, start, end)
+		if err != nil {
+			return nil, fmt.Errorf("query net metrics: %w", err)
+		}
+		for _, n := range nets {
+			snap := at(n.Timestamp)
+			snap.Nets = append(snap.Nets, n.NetMetrics)
+		}
+	case "container":
+		containers, err := store.QueryContainerMetrics(ctx, start, end)
+		if err != nil {
+			return nil, fmt.Errorf("query container metrics: %w", err)
+		}
+		for _, c := range containers {
+			// History is keyed by service identity, not container ID.
+			m := c.ContainerMetrics
+			m.ID = m.Service
+			if m.Project != "" {
+				m.ID = m.Project + "/" + m.Service
+			}
+			m.Name = m.ID
+			snap := at(c.Timestamp)
+			snap.Containers = append(snap.Containers, m)
+		}
+	}
+
+	out := make([]timedSnapshot, 0, len(byTS))
+	for _, ts := range byTS {
+		out = append(out, *ts)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].t.Before(out[j].t) })
+	return out, nil
+}
@@ -0,0 +1,170 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBacktestHostRule(t *testing.T) {
+	a, s := testAlerter(t, map[string]AlertConfig{
+		"high_cpu": {
+			Condition: "host.cpu_percent > 90",
+			For:       Duration{20 * time.Second},
+			Cooldown:  Duration{time.Minute},
+			Severity:  "warning",
+			Actions:   []string{"notify"},
+		},
+	})
+	ctx := context.Background()
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// One sample every 10s. The first spike (t=10) is too short for "for",
+	// the second (t=30..70) fires at t=50 and resolves at t=80, the third
+	// (t=100..) would re-fire at t=120 but is inside cooldown, and the last
+	// stretch fires at t=170 and is still firing at the end.
+	cpu := []float64{10, 95, 10, 95, 95, 95, 95, 95, 10, 10, 95, 95, 95, 10, 10, 95, 95, 95, 95}
+	for i, v := range cpu {
+		if err := s.InsertHostMetrics(ctx, t0.Add(time.Duration(i)*10*time.Second), &HostMetrics{CPUPercent: v}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := a.Backtest(ctx, "high_cpu", t0, t0.Add(time.Duration(len(cpu)-1)*10*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Samples != len(cpu) {
+		t.Errorf("samples = %d, want %d", res.Samples, len(cpu))
+	}
+	if len(res.Intervals) != 2 {
+		t.Fatalf("intervals = %+v, want 2", res.Intervals)
+	}
+	first, second := res.Intervals[0], res.Intervals[1]
+	if !first.FiredAt.Equal(t0.Add(50*time.Second)) || !first.ResolvedAt.Equal(t0.Add(80*time.Second)) {
+		t.Errorf("first interval = %v – %v", first.FiredAt, first.ResolvedAt)
+	}
+	if !second.FiredAt.Equal(t0.Add(170*time.Second)) || !second.ResolvedAt.IsZero() {
+		t.Errorf("second interval = %v – %v, want still firing", second.FiredAt, second.ResolvedAt)
+	}
+
+	// Replays never touch the alerts table or the live alerter.
+	var n int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM alerts").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Errorf("alerts rows = %d, want 0", n)
+	}
+	if len(a.instances) != 0 {
+		t.Errorf("live instances = %d, want 0", len(a.instances))
+	}
+}
+
+func TestBacktestContainerRuleByService(t *testing.T) {
+	a, s := testAlerter(t, map[string]AlertConfig{
+		"db_memory": {
+			Condition: "max(container.memory_percent, 30s) > 90",
+			Project:   "shop",
+			Severity:  "critical",
+			Actions:   []string{"notify"},
+		},
+	})
+	ctx := context.Background()
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i, mem := range []float64{50, 95, 50, 50, 50, 50} {
+		ts := t0.Add(time.Duration(i) * 10 * time.Second)
+		err := s.InsertContainerMetrics(ctx, ts, []ContainerMetrics{
+			{Project: "shop", Service: "db", MemPercent: mem},
+			{Project: "blog", Service: "db", MemPercent: 99},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := a.Backtest(ctx, "db_memory", t0, t0.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Intervals) != 1 {
+		t.Fatalf("intervals = %+v, want 1", res.Intervals)
+	}
+	iv := res.Intervals[0]
+	if iv.InstanceKey != "db_memory:shop/db" || iv.Label != "shop/db" {
+		t.Errorf("instance = %q (%q)", iv.InstanceKey, iv.Label)
+	}
+	// max over 30s has a value once three samples cover the window at t=20
+	// and stays above 90 until the spike ages out at t=50.
+	if !iv.FiredAt.Equal(t0.Add(20*time.Second)) || !iv.ResolvedAt.Equal(t0.Add(50*time.Second)) {
+		t.Errorf("interval = %v – %v", iv.FiredAt, iv.ResolvedAt)
+	}
+}
+
+func TestBacktestLogRule(t *testing.T) {
+	a, s := testAlerter(t, map[string]AlertConfig{
+		"errors": {
+			Condition: "log.count > 1",
+			Match:     "error",
+			Window:    Duration{30 * time.Second},
+			Severity:  "warning",
+			Actions:   []string{"notify"},
+		},
+	})
+	ctx := context.Background()
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 8; i++ {
+		if err := s.InsertHostMetrics(ctx, t0.Add(time.Duration(i)*10*time.Second), &HostMetrics{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := s.InsertLogs(ctx, []LogEntry{
+		{Timestamp: t0.Add(15 * time.Second), ContainerID: "aaa", ContainerName: "web", Stream: "stderr", Message: "error: boom"},
+		{Timestamp: t0.Add(18 * time.Second), ContainerID: "aaa", ContainerName: "web", Stream: "stderr", Message: "ERROR again"},
+		{Timestamp: t0.Add(19 * time.Second), ContainerID: "bbb", ContainerName: "api", Stream: "stdout", Message: "all fine"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := a.Backtest(ctx, "errors", t0, t0.Add(70*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Intervals) != 1 {
+		t.Fatalf("intervals = %+v, want 1", res.Intervals)
+	}
+	iv := res.Intervals[0]
+	if iv.InstanceKey != "errors:aaa" || iv.Label != "web" {
+		t.Errorf("instance = %q (%q)", iv.InstanceKey, iv.Label)
+	}
+	if !iv.FiredAt.Equal(t0.Add(20*time.Second)) || !iv.ResolvedAt.Equal(t0.Add(50*time.Second)) {
+		t.Errorf("interval = %v – %v", iv.FiredAt, iv.ResolvedAt)
+	}
+}
+
+func TestBacktestUnsupported(t *testing.T) {
+	a, _ := testAlerter(t, map[string]AlertConfig{
+		"exited":  {Condition: "container.state == 'exited'", Severity: "warning"},
+		"by_name": {Condition: "container.cpu_percent > 90", Container: "web-*", Severity: "warning"},
+	})
+	ctx := context.Background()
+	now := time.Now()
+
+	tests := []struct {
+		rule, want string
+	}{
+		{"exited", "container.state is not kept in history"},
+		{"by_name", "selectors cannot be backtested"},
+		{"missing", "unknown rule"},
+	}
+	for _, tt := range tests {
+		_, err := a.Backtest(ctx, tt.rule, now.Add(-time.Hour), now)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Backtest(%q) error = %v, want %q", tt.rule, err, tt.want)
+		}
+	}
+}
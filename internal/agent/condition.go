@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Known fields per scope, used for validation.
@@ -16,6 +17,7 @@ var validFields = map[string]map[string]bool{
 		"load5":          true,
 		"load15":         true,
 		"swap_percent":   true,
+		"agent_uptime":   true,
 	},
 	"container": {
 		"cpu_percent":       true,
@@ -29,6 +31,14 @@ var validFields = map[string]map[string]bool{
 	"log": {
 		"count": true,
 	},
+	"net": {
+		"rx_bytes_per_sec":   true,
+		"tx_bytes_per_sec":   true,
+		"rx_packets_per_sec": true,
+		"tx_packets_per_sec": true,
+		"rx_errors_per_sec":  true,
+		"tx_errors_per_sec":  true,
+	},
 }
 
 // String-only fields that only support == and != operators.
@@ -37,65 +47,585 @@ var stringFields = map[string]bool{
 	"health": true,
 }
 
-// Condition represents a parsed alert condition like "host.cpu_percent > 90".
+// Aggregate functions usable as func(scope.field, window) in a comparison.
+var aggFuncs = map[string]bool{
+	"avg":      true,
+	"min":      true,
+	"max":      true,
+	"rate":     true, // per-second increase
+	"increase": true, // total increase, counter resets count from zero
+}
+
+// maxAggWindow bounds aggregate windows so the in-memory history stays small.
+const maxAggWindow = 24 * time.Hour
+
+// Condition is a single comparison like "host.cpu_percent > 90" or
+// "avg(host.cpu_percent, 5m) > 80". It is a leaf of an Expr.
 type Condition struct {
-	Scope  string  // "host", "container", or "log"
-	Field  string  // "cpu_percent", "memory_percent", "disk_percent", "state", "count"
-	Op     string  // ">", "<", ">=", "<=", "==", "!="
-	NumVal float64 // numeric threshold (when IsStr is false)
-	StrVal string  // string value (when IsStr is true)
+	Scope  string        // "host", "container", "log", or "net"
+	Field  string        // "cpu_percent", "memory_percent", "disk_percent", "state", "count"
+	Func   string        // aggregate function, "" for the current sample
+	Window time.Duration // aggregate window (when Func is set)
+	Op     string        // ">", "<", ">=", "<=", "==", "!="
+	NumVal float64       // numeric threshold (when IsStr is false)
+	StrVal string        // string value (when IsStr is true)
 	IsStr  bool
+	pos    int // 1-based offset of the comparison in the condition text
+}
+
+// Expr is a parsed alert condition: a boolean tree of comparisons joined by
+// AND, OR and NOT. All comparisons in one expression share a scope.
+type Expr struct {
+	Op    string     // "and", "or", "not", or "" for a comparison leaf
+	Left  *Expr      // operand of "not", left operand of "and"/"or"
+	Right *Expr      // right operand of "and"/"or"
+	Cond  *Condition // set when Op == ""
+}
+
+// parseCondition parses a condition expression. The grammar is:
+//
+//	expr       = and { OR and }
+//	and        = unary { AND unary }
+//	unary      = NOT unary | "(" expr ")" | comparison
+//	comparison = operand op value
+//	operand    = scope.field | func "(" scope.field "," duration ")"
+//
+// Keywords are case-insensitive. Errors carry the 1-based position of the
+// offending token.
+func parseCondition(s string) (*Expr, error) {
+	toks, err := lexCondition(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &condParser{toks: toks}
+	if p.peek().kind == tokEOF {
+		return nil, fmt.Errorf("condition is empty")
+	}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("position %d: unexpected %q after end of condition", t.pos, t.text)
+	}
+
+	// Every comparison must target the same scope so the expression can be
+	// evaluated per instance (one container, one mountpoint, ...).
+	var first *Condition
+	var mixErr error
+	e.walk(func(c *Condition) {
+		if first == nil {
+			first = c
+		} else if mixErr == nil && c.Scope != first.Scope {
+			mixErr = fmt.Errorf("position %d: cannot mix scope %q with %q in one condition", c.pos, c.Scope, first.Scope)
+		}
+	})
+	if mixErr != nil {
+		return nil, mixErr
+	}
+	return e, nil
 }
 
-func parseCondition(s string) (Condition, error) {
-	tokens := strings.Fields(s)
-	if len(tokens) != 3 {
-		return Condition{}, fmt.Errorf("condition must be 3 tokens (got %d): %q", len(tokens), s)
+// walk calls fn for every comparison in the expression, left to right.
+func (e *Expr) walk(fn func(c *Condition)) {
+	if e == nil {
+		return
+	}
+	if e.Cond != nil {
+		fn(e.Cond)
+		return
 	}
+	e.Left.walk(fn)
+	e.Right.walk(fn)
+}
 
-	parts := strings.SplitN(tokens[0], ".", 2)
-	if len(parts) != 2 {
-		return Condition{}, fmt.Errorf("condition target must be scope.field: %q", tokens[0])
+// Scope returns the scope shared by all comparisons in the expression.
+func (e *Expr) Scope() string {
+	for e.Cond == nil {
+		e = e.Left
+	}
+	return e.Cond.Scope
+}
+
+// hasField reports whether any comparison references the given field.
+func (e *Expr) hasField(field string) bool {
+	found := false
+	e.walk(func(c *Condition) {
+		if c.Field == field {
+			found = true
+		}
+	})
+	return found
+}
+
+// onlyField reports whether every comparison references the given field.
+func (e *Expr) onlyField(field string) bool {
+	only := true
+	e.walk(func(c *Condition) {
+		if c.Field != field {
+			only = false
+		}
+	})
+	return only
+}
+
+// stringOnly reports whether every comparison is against a string field.
+func (e *Expr) stringOnly() bool {
+	only := true
+	e.walk(func(c *Condition) {
+		if !c.IsStr {
+			only = false
+		}
+	})
+	return only
+}
+
+// String returns the canonical text form of the expression. A single
+// comparison renders as "scope.field op value"; OR operands of AND are
+// parenthesized to keep precedence.
+func (e *Expr) String() string {
+	switch e.Op {
+	case "and":
+		return e.Left.operand("or") + " AND " + e.Right.operand("or")
+	case "or":
+		return e.Left.String() + " OR " + e.Right.String()
+	case "not":
+		return "NOT " + e.Left.operand("and", "or")
+	}
+	c := e.Cond
+	return c.operand() + " " + c.Op + " " + conditionValue(c)
+}
+
+// operand renders e, wrapped in parentheses if its operator is one of wrap.
+func (e *Expr) operand(wrap ...string) string {
+	for _, op := range wrap {
+		if e.Op == op {
+			return "(" + e.String() + ")"
+		}
+	}
+	return e.String()
+}
+
+// target returns a short description of what the expression measures, used in
+// alert messages: "scope.field" (or "func(scope.field, window)") for a single
+// comparison, otherwise the full expression.
+func (e *Expr) target() string {
+	if e.Cond != nil {
+		return e.Cond.operand()
+	}
+	return e.String()
+}
+
+// aggregates returns a description of each aggregate comparison, e.g.
+// "avg of host.cpu_percent over 5m", in expression order.
+func (e *Expr) aggregates() []string {
+	var out []string
+	e.walk(func(c *Condition) {
+		if c.Func != "" {
+			out = append(out, c.Func+" of "+c.Scope+"."+c.Field+" over "+formatWindow(c.Window))
+		}
+	})
+	return out
+}
+
+// windows calls fn for every aggregate comparison with its "scope.field"
+// and window.
+func (e *Expr) windows(fn func(target string, window time.Duration)) {
+	e.walk(func(c *Condition) {
+		if c.Func != "" {
+			fn(c.Scope+"."+c.Field, c.Window)
+		}
+	})
+}
+
+// operand renders the left-hand side of the comparison.
+func (c *Condition) operand() string {
+	if c.Func != "" {
+		return c.Func + "(" + c.Scope + "." + c.Field + ", " + formatWindow(c.Window) + ")"
 	}
+	return c.Scope + "." + c.Field
+}
 
-	c := Condition{
-		Scope: parts[0],
-		Field: parts[1],
-		Op:    tokens[1],
+// formatWindow renders a duration without trailing zero units: 5m, 1h, 1h30m.
+func formatWindow(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = s[:len(s)-2]
 	}
+	if strings.HasSuffix(s, "h0m") {
+		s = s[:len(s)-2]
+	}
+	return s
+}
 
+// condEnv holds the values a condition is evaluated against for one instance.
+// Only the members relevant to the expression's scope need to be set.
+type condEnv struct {
+	host      *HostMetrics
+	disk      *DiskMetrics
+	container *ContainerMetrics
+	net       *netRates
+	logCount  float64
+	history   *metricHistory // samples for aggregate comparisons; nil = none
+}
+
+// subject returns the history subject a comparison reads from, or "" if the
+// environment has no matching instance.
+func (env *condEnv) subject(c *Condition) string {
+	switch {
+	case c.Scope == "host" && c.Field == "disk_percent":
+		if env.disk != nil {
+			return "disk:" + env.disk.Mountpoint
+		}
+	case c.Scope == "host":
+		if env.host != nil {
+			return "host"
+		}
+	case c.Scope == "container":
+		if env.container != nil {
+			return "container:" + env.container.ID
+		}
+	case c.Scope == "net":
+		if env.net != nil {
+			return "net:" + env.net.Iface
+		}
+	}
+	return ""
+}
+
+// eval evaluates the expression against env.
+func (e *Expr) eval(env *condEnv) bool {
+	switch e.Op {
+	case "and":
+		return e.Left.eval(env) && e.Right.eval(env)
+	case "or":
+		return e.Left.eval(env) || e.Right.eval(env)
+	case "not":
+		return !e.Left.eval(env)
+	}
+	return e.Cond.eval(env)
+}
+
+func (c *Condition) eval(env *condEnv) bool {
+	if c.IsStr {
+		var actual string
+		if c.Scope == "container" && env.container != nil {
+			actual = containerFieldStr(env.container, c.Field)
+		}
+		return compareStr(actual, c.Op, c.StrVal)
+	}
+	actual, ok := c.value(env)
+	if !ok {
+		return false
+	}
+	return compareNum(actual, c.Op, c.NumVal)
+}
+
+// value returns the current value of a numeric comparison's operand. It is
+// false for aggregates without enough history.
+func (c *Condition) value(env *condEnv) (float64, bool) {
+	if c.Func != "" {
+		subject := env.subject(c)
+		if subject == "" || env.history == nil {
+			return 0, false
+		}
+		return env.history.aggregate(subject, c.Scope+"."+c.Field, c.Func, c.Window)
+	}
+	var actual float64
 	switch c.Scope {
-	case "host", "container", "log":
+	case "host":
+		if c.Field == "disk_percent" {
+			if env.disk != nil {
+				actual = env.disk.Percent
+			}
+		} else if env.host != nil {
+			actual = hostFieldValue(env.host, c.Field)
+		}
+	case "container":
+		if env.container != nil {
+			actual = containerFieldNum(env.container, c.Field)
+		}
+	case "net":
+		if env.net != nil {
+			actual = netFieldValue(env.net, c.Field)
+		}
+	case "log":
+		actual = env.logCount
+	}
+	return actual, true
+}
+
+// firstNumeric returns the first numeric comparison in the expression, or nil
+// if it only compares strings.
+func (e *Expr) firstNumeric() *Condition {
+	var first *Condition
+	e.walk(func(c *Condition) {
+		if first == nil && !c.IsStr {
+			first = c
+		}
+	})
+	return first
+}
+
+// --- Lexer ---
+
+type condTokenKind int
+
+const (
+	tokEOF condTokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokOp
+	tokAnd
+	tokOr
+	tokNot
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type condToken struct {
+	kind condTokenKind
+	text string // raw text; unquoted contents for tokString
+	pos  int    // 1-based offset in the input
+}
+
+func lexCondition(s string) ([]condToken, error) {
+	var toks []condToken
+	i := 0
+	for i < len(s) {
+		ch := s[i]
+		pos := i + 1
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			i++
+		case ch == '(':
+			toks = append(toks, condToken{tokLParen, "(", pos})
+			i++
+		case ch == ')':
+			toks = append(toks, condToken{tokRParen, ")", pos})
+			i++
+		case ch == ',':
+			toks = append(toks, condToken{tokComma, ",", pos})
+			i++
+		case ch == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("position %d: unterminated string", pos)
+			}
+			toks = append(toks, condToken{tokString, s[i+1 : i+1+end], pos})
+			i += end + 2
+		case strings.IndexByte("<>=!", ch) >= 0:
+			j := i
+			for j < len(s) && strings.IndexByte("<>=!", s[j]) >= 0 {
+				j++
+			}
+			toks = append(toks, condToken{tokOp, s[i:j], pos})
+			i = j
+		case isWordByte(ch):
+			j := i
+			for j < len(s) && isWordByte(s[j]) {
+				j++
+			}
+			word := s[i:j]
+			kind := tokNumber
+			switch {
+			case strings.EqualFold(word, "and"):
+				kind = tokAnd
+			case strings.EqualFold(word, "or"):
+				kind = tokOr
+			case strings.EqualFold(word, "not"):
+				kind = tokNot
+			case ch == '_' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z':
+				kind = tokIdent
+			}
+			toks = append(toks, condToken{kind, word, pos})
+			i = j
+		default:
+			return nil, fmt.Errorf("position %d: unexpected character %q", pos, ch)
+		}
+	}
+	return append(toks, condToken{tokEOF, "", len(s) + 1}), nil
+}
+
+func isWordByte(ch byte) bool {
+	return ch == '_' || ch == '.' || ch == '-' || ch == '+' ||
+		ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9'
+}
+
+// --- Parser ---
+
+type condParser struct {
+	toks []condToken
+	i    int
+}
+
+func (p *condParser) peek() condToken { return p.toks[p.i] }
+
+func (p *condParser) next() condToken {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *condParser) parseOr() (*Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &Expr{Op: "or", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *condParser) parseAnd() (*Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokAnd {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &Expr{Op: "and", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *condParser) parseUnary() (*Expr, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokNot:
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &Expr{Op: "not", Left: operand}, nil
+	case tokLParen:
+		p.next()
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if r := p.next(); r.kind != tokRParen {
+			return nil, fmt.Errorf("position %d: expected \")\" to close \"(\" at position %d", r.pos, t.pos)
+		}
+		return e, nil
+	case tokIdent:
+		c, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		return &Expr{Cond: c}, nil
+	case tokEOF:
+		return nil, fmt.Errorf("position %d: unexpected end of condition", t.pos)
+	}
+	return nil, fmt.Errorf("position %d: expected scope.field, got %q", t.pos, t.text)
+}
+
+func (p *condParser) parseComparison() (*Condition, error) {
+	target := p.next()
+	c := &Condition{pos: target.pos}
+
+	// func(scope.field, window)
+	if fn := strings.ToLower(target.text); aggFuncs[fn] && p.peek().kind == tokLParen {
+		open := p.next()
+		c.Func = fn
+		target = p.next()
+		if target.kind != tokIdent {
+			return nil, fmt.Errorf("position %d: expected scope.field in %s()", target.pos, fn)
+		}
+		if t := p.next(); t.kind != tokComma {
+			return nil, fmt.Errorf("position %d: expected \",\" and a window after %q", t.pos, target.text)
+		}
+		w := p.next()
+		d, err := time.ParseDuration(w.text)
+		if w.kind != tokNumber || err != nil {
+			return nil, fmt.Errorf("position %d: invalid window %q (e.g. 5m, 1h)", w.pos, w.text)
+		}
+		if d <= 0 || d > maxAggWindow {
+			return nil, fmt.Errorf("position %d: window must be between 0 and %s, got %q", w.pos, formatWindow(maxAggWindow), w.text)
+		}
+		c.Window = d
+		if t := p.next(); t.kind != tokRParen {
+			return nil, fmt.Errorf("position %d: expected \")\" to close \"(\" at position %d", t.pos, open.pos)
+		}
+	}
+
+	parts := strings.SplitN(target.text, ".", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("position %d: condition target must be scope.field: %q", target.pos, target.text)
+	}
+	c.Scope = parts[0]
+	c.Field = parts[1]
+
+	switch c.Scope {
+	case "host", "container", "log", "net":
 	default:
-		return Condition{}, fmt.Errorf("unknown scope %q (must be host, container, or log)", c.Scope)
+		return nil, fmt.Errorf("position %d: unknown scope %q (must be host, container, log, or net)", target.pos, c.Scope)
 	}
 
 	fields, ok := validFields[c.Scope]
 	if !ok || !fields[c.Field] {
-		return Condition{}, fmt.Errorf("unknown field %q for scope %q", c.Field, c.Scope)
+		return nil, fmt.Errorf("position %d: unknown field %q for scope %q", target.pos, c.Field, c.Scope)
+	}
+	if c.Func != "" {
+		if c.Scope == "log" {
+			return nil, fmt.Errorf("position %d: %s() is not supported for log rules; use window instead", c.pos, c.Func)
+		}
+		if stringFields[c.Field] {
+			return nil, fmt.Errorf("position %d: %s() requires a numeric field, %q is a string", c.pos, c.Func, c.Field)
+		}
 	}
 
+	op := p.next()
+	if op.kind != tokOp {
+		return nil, fmt.Errorf("position %d: expected operator after %q", op.pos, c.operand())
+	}
+	c.Op = op.text
 	switch c.Op {
 	case ">", "<", ">=", "<=", "==", "!=":
 	default:
-		return Condition{}, fmt.Errorf("unknown operator %q", c.Op)
+		return nil, fmt.Errorf("position %d: unknown operator %q", op.pos, c.Op)
 	}
 
-	val := tokens[2]
-	if strings.HasPrefix(val, "'") && strings.HasSuffix(val, "'") && len(val) >= 2 {
+	val := p.next()
+	switch val.kind {
+	case tokString:
 		c.IsStr = true
-		c.StrVal = val[1 : len(val)-1]
-	} else {
-		v, err := strconv.ParseFloat(val, 64)
+		c.StrVal = val.text
+	case tokNumber, tokIdent:
+		v, err := strconv.ParseFloat(val.text, 64)
 		if err != nil {
-			return Condition{}, fmt.Errorf("invalid numeric value %q: %w", val, err)
+			return nil, fmt.Errorf("position %d: invalid numeric value %q", val.pos, val.text)
 		}
 		c.NumVal = v
+	default:
+		return nil, fmt.Errorf("position %d: expected value after %q", val.pos, op.text)
 	}
 
-	// String fields only support == and !=.
-	if stringFields[c.Field] && c.Op != "==" && c.Op != "!=" {
-		return Condition{}, fmt.Errorf("field %q only supports == and != operators, got %q", c.Field, c.Op)
+	// String fields only support == and !=, and only compare against strings.
+	if stringFields[c.Field] {
+		if c.Op != "==" && c.Op != "!=" {
+			return nil, fmt.Errorf("position %d: field %q only supports == and != operators, got %q", op.pos, c.Field, c.Op)
+		}
+		if !c.IsStr {
+			return nil, fmt.Errorf("position %d: field %q must be compared to a quoted string", val.pos, c.Field)
+		}
+	} else if c.IsStr {
+		return nil, fmt.Errorf("position %d: field %q is numeric, got string %q", val.pos, c.Field, val.text)
 	}
 
 	return c, nil
@@ -153,6 +683,8 @@ func hostFieldValue(m *HostMetrics, field string) float64 {
 			return 0
 		}
 		return float64(m.SwapUsed) / float64(m.SwapTotal) * 100
+	case "agent_uptime":
+		return m.AgentUptime
 	}
 	return 0
 }
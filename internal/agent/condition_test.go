@@ -1,6 +1,9 @@
 package agent
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestParseCondition(t *testing.T) {
 	tests := []struct {
@@ -29,11 +32,13 @@ func TestParseCondition(t *testing.T) {
 		{"log.count > 5", "log", "count", ">", 5, "", false, false},
 		{"log.count >= 1", "log", "count", ">=", 1, "", false, false},
 		{"log.count == 0", "log", "count", "==", 0, "", false, false},
+		{"net.rx_bytes_per_sec > 1e8", "net", "rx_bytes_per_sec", ">", 1e8, "", false, false},
+		{"net.rx_errors_per_sec > 0", "net", "rx_errors_per_sec", ">", 0, "", false, false},
 
 		// Invalid cases.
 		{"", "", "", "", 0, "", false, true},
 		{"host.cpu_percent", "", "", "", 0, "", false, true},            // too few tokens
-		{"host.cpu_percent > 90 extra", "", "", "", 0, "", false, true}, // too many tokens
+		{"host.cpu_percent > 90 extra", "", "", "", 0, "", false, true}, // trailing garbage
 		{"cpu_percent > 90", "", "", "", 0, "", false, true},            // no scope.field
 		{"host.cpu_percent ~ 90", "", "", "", 0, "", false, true},       // bad operator
 		{"host.cpu_percent > abc", "", "", "", 0, "", false, true},      // bad numeric
@@ -41,13 +46,14 @@ func TestParseCondition(t *testing.T) {
 		{"host.unknown_field > 2", "", "", "", 0, "", false, true},      // unknown field
 		{"container.image == 'nginx'", "", "", "", 0, "", false, true},  // unknown field
 		{"log.message == 'error'", "", "", "", 0, "", false, true},      // unknown log field
+		{"net.rx_bytes > 1", "", "", "", 0, "", false, true},            // unknown net field
 		{"container.state > 'exited'", "", "", "", 0, "", false, true},  // string field with > op
 		{"container.state >= 'a'", "", "", "", 0, "", false, true},      // string field with >= op
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.input, func(t *testing.T) {
-			c, err := parseCondition(tt.input)
+			e, err := parseCondition(tt.input)
 			if tt.wantErr {
 				if err == nil {
 					t.Fatal("expected error")
@@ -57,6 +63,10 @@ func TestParseCondition(t *testing.T) {
 			if err != nil {
 				t.Fatalf("unexpected error: %v", err)
 			}
+			c := e.Cond
+			if c == nil {
+				t.Fatalf("expected a single comparison, got %q", e.Op)
+			}
 			if c.Scope != tt.scope {
 				t.Errorf("scope = %q, want %q", c.Scope, tt.scope)
 			}
@@ -79,6 +89,120 @@ func TestParseCondition(t *testing.T) {
 	}
 }
 
+func TestParseConditionExpr(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string // canonical String() form
+	}{
+		{"container.state == 'running' AND container.health == 'unhealthy'", "container.state == 'running' AND container.health == 'unhealthy'"},
+		{"host.cpu_percent > 90 OR host.load5 > 8", "host.cpu_percent > 90 OR host.load5 > 8"},
+		{"host.cpu_percent > 90 or host.load5 > 8 and host.load1 > 4", "host.cpu_percent > 90 OR host.load5 > 8 AND host.load1 > 4"},
+		{"(host.cpu_percent > 90 OR host.load5 > 8) AND host.load1 > 4", "(host.cpu_percent > 90 OR host.load5 > 8) AND host.load1 > 4"},
+		{"NOT container.state == 'running'", "NOT container.state == 'running'"},
+		{"not (container.state == 'running' or container.state == 'paused')", "NOT (container.state == 'running' OR container.state == 'paused')"},
+		{"container.state=='exited'&&container.exit_code!=0", ""}, // && is not a keyword
+		{"container.state == 'created by compose'", "container.state == 'created by compose'"},
+		{"((host.cpu_percent>90))", "host.cpu_percent > 90"},
+		{"container.state != 'running' AND container.memory_percent > 95", "container.state != 'running' AND container.memory_percent > 95"},
+		{"avg(host.cpu_percent, 5m) > 80", "avg(host.cpu_percent, 5m) > 80"},
+		{"MAX(container.memory_percent,10m)>95", "max(container.memory_percent, 10m) > 95"},
+		{"increase(container.restart_count, 1h) >= 3", "increase(container.restart_count, 1h) >= 3"},
+		{"rate(container.restart_count, 90s) > 0.1 AND container.state == 'running'", "rate(container.restart_count, 1m30s) > 0.1 AND container.state == 'running'"},
+		{"min(host.disk_percent, 1h30m) > 90 OR host.disk_percent > 99", "min(host.disk_percent, 1h30m) > 90 OR host.disk_percent > 99"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			e, err := parseCondition(tt.input)
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("expected error, got %q", e)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := e.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+			// The canonical form must parse back to the same expression.
+			again, err := parseCondition(e.String())
+			if err != nil {
+				t.Fatalf("reparse: %v", err)
+			}
+			if again.String() != e.String() {
+				t.Errorf("reparse = %q, want %q", again.String(), e.String())
+			}
+		})
+	}
+}
+
+func TestParseConditionErrorPositions(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"host.cpu_percent > 90 AND container.state == 'exited'", "position 27: cannot mix scope"},
+		{"host.cpu_percent > 90 AND", "position 26: unexpected end of condition"},
+		{"(host.cpu_percent > 90", "position 23: expected \")\" to close \"(\" at position 1"},
+		{"host.cpu_percent > 90)", "position 22: unexpected \")\""},
+		{"host.load1 > 1 OR host.bogus > 2", "position 19: unknown field \"bogus\""},
+		{"container.state == 'exited", "position 20: unterminated string"},
+		{"host.cpu_percent => 90", "position 18: unknown operator \"=>\""},
+		{"container.health == 1", "position 21: field \"health\" must be compared to a quoted string"},
+		{"host.cpu_percent > 'high'", "position 20: field \"cpu_percent\" is numeric"},
+		{"host.cpu_percent ~ 90", "position 18: unexpected character"},
+		{"   ", "condition is empty"},
+		{"avg(host.cpu_percent) > 80", "position 21: expected \",\" and a window"},
+		{"avg(host.cpu_percent, soon) > 80", "position 23: invalid window \"soon\""},
+		{"avg(host.cpu_percent, 48h) > 80", "position 23: window must be between 0 and 24h"},
+		{"avg(host.cpu_percent, 5m > 80", "position 26: expected \")\" to close \"(\" at position 4"},
+		{"avg(container.state, 5m) == 'exited'", "position 1: avg() requires a numeric field"},
+		{"sum(log.count, 5m) > 1", "position 1: condition target must be scope.field"},
+		{"max(log.count, 5m) > 1", "position 1: max() is not supported for log rules"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := parseCondition(tt.input)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %q, want substring %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestExprEval(t *testing.T) {
+	running := &ContainerMetrics{State: "running", Health: "unhealthy", MemPercent: 50}
+	exited := &ContainerMetrics{State: "exited", Health: "", ExitCode: 137}
+	tests := []struct {
+		cond string
+		env  condEnv
+		want bool
+	}{
+		{"container.state == 'running' AND container.health == 'unhealthy'", condEnv{container: running}, true},
+		{"container.state == 'running' AND container.health == 'unhealthy'", condEnv{container: exited}, false},
+		{"container.state == 'exited' AND container.exit_code != 0", condEnv{container: exited}, true},
+		{"container.memory_percent > 90 OR container.health == 'unhealthy'", condEnv{container: running}, true},
+		{"NOT container.state == 'running'", condEnv{container: exited}, true},
+		{"host.cpu_percent > 90 OR host.load5 > 8", condEnv{host: &HostMetrics{CPUPercent: 10, Load5: 9}}, true},
+		{"host.cpu_percent > 90 OR host.load5 > 8", condEnv{host: &HostMetrics{CPUPercent: 10, Load5: 1}}, false},
+		{"host.disk_percent > 90 AND host.memory_percent > 50", condEnv{host: &HostMetrics{MemPercent: 60}, disk: &DiskMetrics{Percent: 95}}, true},
+		{"log.count > 5", condEnv{logCount: 6}, true},
+	}
+	for _, tt := range tests {
+		e, err := parseCondition(tt.cond)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.cond, err)
+		}
+		if got := e.eval(&tt.env); got != tt.want {
+			t.Errorf("eval(%q) = %v, want %v", tt.cond, got, tt.want)
+		}
+	}
+}
+
 func TestCompareNum(t *testing.T) {
 	tests := []struct {
 		actual    float64
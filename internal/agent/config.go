@@ -2,16 +2,21 @@ package agent
 
 import (
 	"fmt"
+	htmltemplate "html/template"
 	"io/fs"
+	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"text/template"
 	"time"
 
 	"github.com/BurntSushi/toml"
+	"github.com/thobiasn/tori-cli/webhook"
 )
 
 // Duration wraps time.Duration for TOML string parsing ("10s", "1m").
@@ -51,6 +56,55 @@ type Config struct {
 	Collect CollectConfig          `toml:"collect"`
 	Alerts  map[string]AlertConfig `toml:"alerts"`
 	Notify  NotifyConfig           `toml:"notify"`
+
+	Maintenance []MaintenanceConfig `toml:"maintenance"`
+	Inhibit     []InhibitConfig     `toml:"inhibit"`
+	Heartbeat   HeartbeatConfig     `toml:"heartbeat"`
+	Exec        ExecConfig          `toml:"exec"`
+}
+
+// ExecConfig limits the exec:PATH alert action. Only commands listed in
+// Allow can be run, so rules can't name arbitrary programs.
+type ExecConfig struct {
+	Allow         []string `toml:"allow"`          // absolute command paths
+	Timeout       Duration `toml:"timeout"`        // per command, default 30s
+	MaxConcurrent int      `toml:"max_concurrent"` // default 4
+
+	AllowManaged bool `toml:"allow_managed"` // rules created over the socket may use exec actions
+}
+
+// HeartbeatConfig is a periodic outbound ping that lets an external checker
+// alert when the agent stops reporting.
+type HeartbeatConfig struct {
+	Enabled  bool              `toml:"enabled"`
+	URL      string            `toml:"url"`
+	Interval Duration          `toml:"interval"`
+	Headers  map[string]string `toml:"headers"`
+}
+
+// InhibitConfig suppresses notifications for the Targets rules while the
+// Source rule is firing. Equal limits this to target instances on the same
+// compose project or container as a firing source instance.
+type InhibitConfig struct {
+	Source  string   `toml:"source"`  // rule name
+	Targets []string `toml:"targets"` // rule names
+	Equal   string   `toml:"equal"`   // "project", "container", or "" (any instance)
+}
+
+// MaintenanceConfig is a recurring window during which alerts are recorded
+// but not notified. Use either Schedule+Duration (5-field cron, start times)
+// or Start+End with optional Days. With Start+End, an End before Start wraps
+// past midnight and Days refer to the day the window starts.
+type MaintenanceConfig struct {
+	Name     string   `toml:"name"`
+	Schedule string   `toml:"schedule"` // cron: minute hour dom month dow
+	Duration Duration `toml:"duration"`
+	Days     []string `toml:"days"`     // mon..sun, empty = every day
+	Start    string   `toml:"start"`    // HH:MM
+	End      string   `toml:"end"`      // HH:MM
+	Timezone string   `toml:"timezone"` // IANA name, empty = local time
+	Rules    []string `toml:"rules"`    // alert rule names, empty = all
+	Projects []string `toml:"projects"` // compose projects, empty = all
 }
 
 type AlertConfig struct {
@@ -64,14 +118,126 @@ type AlertConfig struct {
 	Match          string   `toml:"match"`       // log pattern (text substring or regex)
 	MatchRegex     bool     `toml:"match_regex"` // true = regex, false = substring
 	Window         Duration `toml:"window"`      // time window for log.count
+
+	// Annotations added to notifications and shown in the TUI.
+	Description     string `toml:"description"`
+	RunbookURL      string `toml:"runbook_url"`
+	MessageTemplate string `toml:"message_template"` // text/template over messageData; empty = default message
+
+	// Container selectors (container and log rules only). Empty = all containers.
+	Project   string            `toml:"project"`   // compose project
+	Service   string            `toml:"service"`   // compose service (container name for non-compose)
+	Container string            `toml:"container"` // container name glob
+	Image     string            `toml:"image"`     // image glob
+	Labels    map[string]string `toml:"labels"`    // label -> value glob, all must match
+
+	// Interface filter (net rules only). Empty include = all interfaces.
+	Interfaces        []string `toml:"interfaces"`         // interface name globs to include
+	ExcludeInterfaces []string `toml:"exclude_interfaces"` // interface name globs to skip
+
+	// Escalation steps notify further channels while a firing alert stays
+	// unacknowledged, in order of increasing after.
+	Escalate []EscalationStep `toml:"escalate"`
+
+	// Limits for the docker:restart and docker:stop actions.
+	Remediate RemediateConfig `toml:"remediate"`
+
+	// Flap detection; disabled unless transitions is set.
+	Flapping FlappingConfig `toml:"flapping"`
+}
+
+// FlappingConfig marks an instance as flapping once it changes state
+// Transitions times within Window. It stops flapping after a full Window
+// without state changes.
+type FlappingConfig struct {
+	Transitions int      `toml:"transitions"` // fire + resolve count; 0 = disabled
+	Window      Duration `toml:"window"`      // default 10m
+}
+
+// RemediateConfig bounds automatic container remediation per alert instance.
+// Attempt n+1 waits Backoff * 2^(n-1) after attempt n.
+type RemediateConfig struct {
+	MaxAttempts int      `toml:"max_attempts"` // default 3
+	Backoff     Duration `toml:"backoff"`      // default 1m
+}
+
+// EscalationStep notifies channels once an alert has been firing
+// unacknowledged for After.
+type EscalationStep struct {
+	After    Duration `toml:"after"`
+	Channels []string `toml:"channels"` // empty = every channel
 }
 
 type NotifyConfig struct {
 	Email    EmailConfig     `toml:"email"`
 	Webhooks []WebhookConfig `toml:"webhooks"`
+
+	// Push services with native payloads, so they need no template.
+	Ntfy     []NtfyConfig     `toml:"ntfy"`
+	Gotify   []GotifyConfig   `toml:"gotify"`
+	Telegram []TelegramConfig `toml:"telegram"`
+
+	// Incident services. Alerts open an incident keyed by their instance and
+	// close it when they resolve; acknowledgements are mirrored.
+	PagerDuty []PagerDutyConfig `toml:"pagerduty"`
+	Opsgenie  []OpsgenieConfig  `toml:"opsgenie"`
+
+	// Chat services with rich formatting. When an alert they announced
+	// resolves, they post a follow-up referencing it.
+	Slack   []SlackConfig   `toml:"slack"`
+	Discord []DiscordConfig `toml:"discord"`
+	Matrix  []MatrixConfig  `toml:"matrix"`
+
+	// Grouping batches alert notifications that share the group_by fields
+	// ("rule", "project", "severity") into one combined notification.
+	// Disabled when group_by is empty.
+	GroupBy       []string `toml:"group_by"`
+	GroupWait     Duration `toml:"group_wait"`     // delay before a new group's first notification
+	GroupInterval Duration `toml:"group_interval"` // delay between notifications for an existing group
+
+	Digest DigestConfig `toml:"digest"`
+
+	// Routes pick the channels for rules using the plain "notify" action by
+	// severity. The first matching route wins; no match = every channel.
+	Routes []RouteConfig `toml:"routes"`
+
+	// Quiet hours hold back alert notifications during recurring windows.
+	QuietHours []QuietHoursConfig `toml:"quiet_hours"`
+}
+
+// RouteConfig sends alerts of one severity to the named channels.
+type RouteConfig struct {
+	Severity string   `toml:"severity"`
+	Channels []string `toml:"channels"`
+}
+
+// QuietHoursConfig holds back alert notifications to some channels or of
+// some severities during a recurring window. The window is defined as for
+// MaintenanceConfig. Deferred notifications are sent as one summary per
+// channel when the window ends; dropped ones are not sent at all.
+type QuietHoursConfig struct {
+	Name       string   `toml:"name"`
+	Schedule   string   `toml:"schedule"` // cron: minute hour dom month dow
+	Duration   Duration `toml:"duration"`
+	Days       []string `toml:"days"`       // mon..sun, empty = every day
+	Start      string   `toml:"start"`      // HH:MM
+	End        string   `toml:"end"`        // HH:MM
+	Timezone   string   `toml:"timezone"`   // IANA name, empty = local time
+	Severities []string `toml:"severities"` // "warning", "critical", empty = all
+	Channels   []string `toml:"channels"`   // channel names, empty = all
+	Action     string   `toml:"action"`     // "defer" (default) or "drop"
+}
+
+// DigestConfig enables a daily email summarizing the alerts that fired and
+// resolved over the previous 24 hours.
+type DigestConfig struct {
+	Enabled  bool   `toml:"enabled"`
+	At       string `toml:"at"`       // HH:MM, default "08:00"
+	Timezone string `toml:"timezone"` // IANA name, empty = local time
 }
 
 type EmailConfig struct {
+	Name     string   `toml:"name"` // channel name for notify:NAME actions, default "email"
 	Enabled  bool     `toml:"enabled"`
 	SMTPHost string   `toml:"smtp_host"`
 	SMTPPort int      `toml:"smtp_port"`
@@ -80,13 +246,89 @@ type EmailConfig struct {
 	Username string   `toml:"username"`
 	Password string   `toml:"password"`
 	TLS      string   `toml:"tls"` // "starttls", "tls", "none", or "" (same as "none")
+
+	LogLines     int    `toml:"log_lines"`     // container log lines in alert emails, default 20, 0 = none
+	HTMLTemplate string `toml:"html_template"` // html/template for the HTML part, default emailHTMLTemplate
+	TextTemplate string `toml:"text_template"` // text/template for the plain-text part, default emailTextTemplate
 }
 
 type WebhookConfig struct {
+	Name     string            `toml:"name"` // channel name for notify:NAME actions, default "webhook-N"
 	Enabled  bool              `toml:"enabled"`
 	URL      string            `toml:"url"`
 	Headers  map[string]string `toml:"headers"`
 	Template string            `toml:"template"`
+
+	// Secret, if set, signs each request; see package webhook.
+	Secret string `toml:"secret"`
+}
+
+// NtfyConfig publishes notifications to an ntfy topic. The priority and a
+// tag follow the alert's severity.
+type NtfyConfig struct {
+	Name    string   `toml:"name"` // channel name for notify:NAME actions, default "ntfy-N"
+	Enabled bool     `toml:"enabled"`
+	URL     string   `toml:"url"` // server, default "https://ntfy.sh"
+	Topic   string   `toml:"topic"`
+	Token   string   `toml:"token"` // access token, optional
+	Tags    []string `toml:"tags"`  // added to the severity tag
+}
+
+// GotifyConfig sends notifications to a Gotify server as an application.
+type GotifyConfig struct {
+	Name    string `toml:"name"` // channel name for notify:NAME actions, default "gotify-N"
+	Enabled bool   `toml:"enabled"`
+	URL     string `toml:"url"`
+	Token   string `toml:"token"` // application token
+}
+
+// TelegramConfig sends notifications to a Telegram chat through a bot.
+type TelegramConfig struct {
+	Name    string `toml:"name"` // channel name for notify:NAME actions, default "telegram-N"
+	Enabled bool   `toml:"enabled"`
+	Token   string `toml:"token"`   // bot token from @BotFather
+	ChatID  string `toml:"chat_id"` // numeric ID or "@channelname"
+	APIURL  string `toml:"api_url"` // default "https://api.telegram.org"
+}
+
+// PagerDutyConfig sends alerts to a PagerDuty service via the Events API v2.
+type PagerDutyConfig struct {
+	Name       string `toml:"name"` // channel name for notify:NAME actions, default "pagerduty-N"
+	Enabled    bool   `toml:"enabled"`
+	RoutingKey string `toml:"routing_key"` // integration key of an Events API v2 integration
+	URL        string `toml:"url"`         // default "https://events.pagerduty.com/v2/enqueue"
+}
+
+// OpsgenieConfig sends alerts to Opsgenie via the Alert API.
+type OpsgenieConfig struct {
+	Name    string   `toml:"name"` // channel name for notify:NAME actions, default "opsgenie-N"
+	Enabled bool     `toml:"enabled"`
+	APIKey  string   `toml:"api_key"` // API integration key
+	URL     string   `toml:"url"`     // default "https://api.opsgenie.com"; EU accounts use "https://api.eu.opsgenie.com"
+	Tags    []string `toml:"tags"`
+}
+
+// SlackConfig posts notifications to a Slack incoming webhook.
+type SlackConfig struct {
+	Name    string `toml:"name"` // channel name for notify:NAME actions, default "slack-N"
+	Enabled bool   `toml:"enabled"`
+	URL     string `toml:"url"` // incoming webhook URL
+}
+
+// DiscordConfig posts notifications to a Discord channel webhook as embeds.
+type DiscordConfig struct {
+	Name    string `toml:"name"` // channel name for notify:NAME actions, default "discord-N"
+	Enabled bool   `toml:"enabled"`
+	URL     string `toml:"url"` // channel webhook URL
+}
+
+// MatrixConfig sends notifications to a Matrix room as a user or bot.
+type MatrixConfig struct {
+	Name        string `toml:"name"` // channel name for notify:NAME actions, default "matrix-N"
+	Enabled     bool   `toml:"enabled"`
+	Homeserver  string `toml:"homeserver"`   // e.g. "https://matrix.example.org"
+	AccessToken string `toml:"access_token"` // of an account that has joined the room
+	RoomID      string `toml:"room_id"`      // "!opaque:server", not an alias
 }
 
 type StorageConfig struct {
@@ -160,17 +402,117 @@ func setDefaults(cfg *Config, md toml.MetaData) {
 	if cfg.Collect.Interval.Duration == 0 {
 		cfg.Collect.Interval.Duration = 10 * time.Second
 	}
-	for name, ac := range cfg.Alerts {
-		if !md.IsDefined("alerts", name, "cooldown") {
-			ac.Cooldown.Duration = 5 * time.Minute
+	if len(cfg.Notify.GroupBy) > 0 {
+		if !md.IsDefined("notify", "group_wait") {
+			cfg.Notify.GroupWait.Duration = 30 * time.Second
 		}
-		if !md.IsDefined("alerts", name, "notify_cooldown") {
-			ac.NotifyCooldown.Duration = 5 * time.Minute
+		if !md.IsDefined("notify", "group_interval") {
+			cfg.Notify.GroupInterval.Duration = 5 * time.Minute
+		}
+	}
+	if cfg.Notify.Email.Name == "" {
+		cfg.Notify.Email.Name = "email"
+	}
+	if !md.IsDefined("notify", "email", "log_lines") {
+		cfg.Notify.Email.LogLines = 20
+	}
+	for i := range cfg.Notify.Webhooks {
+		if cfg.Notify.Webhooks[i].Name == "" {
+			cfg.Notify.Webhooks[i].Name = fmt.Sprintf("webhook-%d", i+1)
+		}
+	}
+	for i := range cfg.Notify.Ntfy {
+		if cfg.Notify.Ntfy[i].Name == "" {
+			cfg.Notify.Ntfy[i].Name = fmt.Sprintf("ntfy-%d", i+1)
+		}
+		if cfg.Notify.Ntfy[i].URL == "" {
+			cfg.Notify.Ntfy[i].URL = "https://ntfy.sh"
+		}
+	}
+	for i := range cfg.Notify.Gotify {
+		if cfg.Notify.Gotify[i].Name == "" {
+			cfg.Notify.Gotify[i].Name = fmt.Sprintf("gotify-%d", i+1)
+		}
+	}
+	for i := range cfg.Notify.Telegram {
+		if cfg.Notify.Telegram[i].Name == "" {
+			cfg.Notify.Telegram[i].Name = fmt.Sprintf("telegram-%d", i+1)
 		}
+		if cfg.Notify.Telegram[i].APIURL == "" {
+			cfg.Notify.Telegram[i].APIURL = "https://api.telegram.org"
+		}
+	}
+	for i := range cfg.Notify.PagerDuty {
+		if cfg.Notify.PagerDuty[i].Name == "" {
+			cfg.Notify.PagerDuty[i].Name = fmt.Sprintf("pagerduty-%d", i+1)
+		}
+		if cfg.Notify.PagerDuty[i].URL == "" {
+			cfg.Notify.PagerDuty[i].URL = "https://events.pagerduty.com/v2/enqueue"
+		}
+	}
+	for i := range cfg.Notify.Opsgenie {
+		if cfg.Notify.Opsgenie[i].Name == "" {
+			cfg.Notify.Opsgenie[i].Name = fmt.Sprintf("opsgenie-%d", i+1)
+		}
+		if cfg.Notify.Opsgenie[i].URL == "" {
+			cfg.Notify.Opsgenie[i].URL = "https://api.opsgenie.com"
+		}
+	}
+	for i := range cfg.Notify.Slack {
+		if cfg.Notify.Slack[i].Name == "" {
+			cfg.Notify.Slack[i].Name = fmt.Sprintf("slack-%d", i+1)
+		}
+	}
+	for i := range cfg.Notify.Discord {
+		if cfg.Notify.Discord[i].Name == "" {
+			cfg.Notify.Discord[i].Name = fmt.Sprintf("discord-%d", i+1)
+		}
+	}
+	for i := range cfg.Notify.Matrix {
+		if cfg.Notify.Matrix[i].Name == "" {
+			cfg.Notify.Matrix[i].Name = fmt.Sprintf("matrix-%d", i+1)
+		}
+	}
+	if cfg.Notify.Digest.At == "" {
+		cfg.Notify.Digest.At = "08:00"
+	}
+	if cfg.Exec.Timeout.Duration == 0 {
+		cfg.Exec.Timeout.Duration = 30 * time.Second
+	}
+	if cfg.Exec.MaxConcurrent == 0 {
+		cfg.Exec.MaxConcurrent = 4
+	}
+	if cfg.Heartbeat.Interval.Duration == 0 {
+		cfg.Heartbeat.Interval.Duration = time.Minute
+	}
+	for name, ac := range cfg.Alerts {
+		setAlertDefaults(&ac, func(key string) bool { return md.IsDefined("alerts", name, key) })
 		cfg.Alerts[name] = ac
 	}
 }
 
+// setAlertDefaults fills in the per-rule defaults. defined reports whether a
+// key of the rule was set explicitly, so cooldown = "0s" is kept.
+func setAlertDefaults(ac *AlertConfig, defined func(key string) bool) {
+	if !defined("cooldown") {
+		ac.Cooldown.Duration = 5 * time.Minute
+	}
+	if !defined("notify_cooldown") {
+		ac.NotifyCooldown.Duration = 5 * time.Minute
+	}
+	if ac.Flapping.Transitions > 0 && ac.Flapping.Window.Duration == 0 {
+		ac.Flapping.Window.Duration = 10 * time.Minute
+	}
+	if remediationAction(ac.Actions) != "" {
+		if ac.Remediate.MaxAttempts == 0 {
+			ac.Remediate.MaxAttempts = 3
+		}
+		if ac.Remediate.Backoff.Duration == 0 {
+			ac.Remediate.Backoff.Duration = time.Minute
+		}
+	}
+}
+
 func validate(cfg *Config) error {
 	if cfg.Storage.RetentionDays < 1 {
 		return fmt.Errorf("retention_days must be >= 1, got %d", cfg.Storage.RetentionDays)
@@ -181,8 +523,18 @@ func validate(cfg *Config) error {
 	if cfg.Collect.Interval.Duration < 1*time.Second {
 		return fmt.Errorf("collect interval must be >= 1s, got %s", cfg.Collect.Interval.Duration)
 	}
+	channels, err := validateChannels(&cfg.Notify)
+	if err != nil {
+		return err
+	}
+	if err := validateQuietHours(cfg.Notify.QuietHours, channels); err != nil {
+		return err
+	}
+	if err := validateExec(&cfg.Exec); err != nil {
+		return err
+	}
 	for name, ac := range cfg.Alerts {
-		if err := validateAlert(name, &ac); err != nil {
+		if err := validateAlert(name, &ac, channels, &cfg.Exec); err != nil {
 			return err
 		}
 	}
@@ -194,6 +546,262 @@ func validate(cfg *Config) error {
 			return err
 		}
 	}
+	for i := range cfg.Notify.Ntfy {
+		if err := validateNtfy(i, &cfg.Notify.Ntfy[i]); err != nil {
+			return err
+		}
+	}
+	for i := range cfg.Notify.Gotify {
+		if err := validateGotify(i, &cfg.Notify.Gotify[i]); err != nil {
+			return err
+		}
+	}
+	for i := range cfg.Notify.Telegram {
+		if err := validateTelegram(i, &cfg.Notify.Telegram[i]); err != nil {
+			return err
+		}
+	}
+	for i := range cfg.Notify.PagerDuty {
+		if err := validatePagerDuty(i, &cfg.Notify.PagerDuty[i]); err != nil {
+			return err
+		}
+	}
+	for i := range cfg.Notify.Opsgenie {
+		if err := validateOpsgenie(i, &cfg.Notify.Opsgenie[i]); err != nil {
+			return err
+		}
+	}
+	for i := range cfg.Notify.Slack {
+		if c := &cfg.Notify.Slack[i]; c.Enabled {
+			if err := validateServiceURL(fmt.Sprintf("slack[%d]", i), "url", c.URL); err != nil {
+				return err
+			}
+		}
+	}
+	for i := range cfg.Notify.Discord {
+		if c := &cfg.Notify.Discord[i]; c.Enabled {
+			if err := validateServiceURL(fmt.Sprintf("discord[%d]", i), "url", c.URL); err != nil {
+				return err
+			}
+		}
+	}
+	for i := range cfg.Notify.Matrix {
+		if err := validateMatrix(i, &cfg.Notify.Matrix[i]); err != nil {
+			return err
+		}
+	}
+	if err := validateGrouping(&cfg.Notify); err != nil {
+		return err
+	}
+	if err := validateDigest(&cfg.Notify); err != nil {
+		return err
+	}
+	for i := range cfg.Inhibit {
+		if err := validateInhibit(cfg.Alerts, i, &cfg.Inhibit[i]); err != nil {
+			return err
+		}
+	}
+	if err := validateHeartbeat(&cfg.Heartbeat); err != nil {
+		return err
+	}
+	seen := make(map[string]bool, len(cfg.Maintenance))
+	for i := range cfg.Maintenance {
+		if err := validateMaintenance(&cfg.Maintenance[i], seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateMaintenance(mc *MaintenanceConfig, seen map[string]bool) error {
+	if _, err := newMaintenanceWindow(mc); err != nil {
+		if mc.Name == "" {
+			return fmt.Errorf("maintenance: %w", err)
+		}
+		return fmt.Errorf("maintenance %q: %w", mc.Name, err)
+	}
+	if seen[mc.Name] {
+		return fmt.Errorf("maintenance %q: duplicate name", mc.Name)
+	}
+	seen[mc.Name] = true
+	return nil
+}
+
+func validateQuietHours(qh []QuietHoursConfig, channels map[string]bool) error {
+	seen := make(map[string]bool, len(qh))
+	for i := range qh {
+		q := &qh[i]
+		if _, err := newQuietWindow(q); err != nil {
+			if q.Name == "" {
+				return fmt.Errorf("quiet_hours[%d]: %w", i, err)
+			}
+			return fmt.Errorf("quiet_hours %q: %w", q.Name, err)
+		}
+		if seen[q.Name] {
+			return fmt.Errorf("quiet_hours %q: duplicate name", q.Name)
+		}
+		seen[q.Name] = true
+		for _, ch := range q.Channels {
+			if !channels[ch] {
+				return fmt.Errorf("quiet_hours %q: unknown channel %q", q.Name, ch)
+			}
+		}
+	}
+	return nil
+}
+
+// validateRuleRefs checks the rules named by inhibit and maintenance entries
+// against rules, the config file's rules merged with the managed ones.
+func validateRuleRefs(cfg *Config, rules map[string]AlertConfig) error {
+	for i := range cfg.Inhibit {
+		ic := &cfg.Inhibit[i]
+		for _, name := range append([]string{ic.Source}, ic.Targets...) {
+			if _, ok := rules[name]; !ok {
+				return fmt.Errorf("inhibit[%d]: unknown rule %q", i, name)
+			}
+		}
+		if err := validateInhibit(rules, i, ic); err != nil {
+			return err
+		}
+	}
+	for i := range cfg.Maintenance {
+		mc := &cfg.Maintenance[i]
+		for _, r := range mc.Rules {
+			if _, ok := rules[r]; !ok {
+				return fmt.Errorf("maintenance %q: unknown rule %q", mc.Name, r)
+			}
+		}
+	}
+	return nil
+}
+
+// validateInhibit checks an inhibit entry against rules. A rule missing from
+// rules may be managed at runtime; validateRuleRefs checks it once the
+// managed rules are known.
+func validateInhibit(rules map[string]AlertConfig, idx int, ic *InhibitConfig) error {
+	scopeOf := func(name string) (string, error) {
+		ac, ok := rules[name]
+		if !ok {
+			return "", nil
+		}
+		cond, err := parseCondition(ac.Condition)
+		if err != nil {
+			return "", fmt.Errorf("inhibit[%d]: rule %q: %w", idx, name, err)
+		}
+		return cond.Scope(), nil
+	}
+	if ic.Source == "" {
+		return fmt.Errorf("inhibit[%d]: source is required", idx)
+	}
+	if len(ic.Targets) == 0 {
+		return fmt.Errorf("inhibit[%d]: at least one target required", idx)
+	}
+	if ic.Equal != "" && ic.Equal != "project" && ic.Equal != "container" {
+		return fmt.Errorf("inhibit[%d]: equal must be \"project\" or \"container\", got %q", idx, ic.Equal)
+	}
+	// project and container are only known for container and log instances.
+	lacksContainer := func(scope string) bool { return scope != "" && scope != "container" && scope != "log" }
+	scope, err := scopeOf(ic.Source)
+	if err != nil {
+		return err
+	}
+	if ic.Equal != "" && lacksContainer(scope) {
+		return fmt.Errorf("inhibit[%d]: equal = %q requires a container or log source rule", idx, ic.Equal)
+	}
+	for _, t := range ic.Targets {
+		if t == ic.Source {
+			return fmt.Errorf("inhibit[%d]: rule %q cannot inhibit itself", idx, t)
+		}
+		scope, err := scopeOf(t)
+		if err != nil {
+			return err
+		}
+		if ic.Equal != "" && lacksContainer(scope) {
+			return fmt.Errorf("inhibit[%d]: equal = %q requires container or log target rules, %q is %s", idx, ic.Equal, t, scope)
+		}
+	}
+	return nil
+}
+
+func validateGrouping(n *NotifyConfig) error {
+	seen := make(map[string]bool, len(n.GroupBy))
+	for _, f := range n.GroupBy {
+		if f != "rule" && f != "project" && f != "severity" {
+			return fmt.Errorf("notify: invalid group_by %q (must be rule, project or severity)", f)
+		}
+		if seen[f] {
+			return fmt.Errorf("notify: duplicate group_by %q", f)
+		}
+		seen[f] = true
+	}
+	if len(n.GroupBy) == 0 && (n.GroupWait.Duration != 0 || n.GroupInterval.Duration != 0) {
+		return fmt.Errorf("notify: group_wait and group_interval require group_by")
+	}
+	if n.GroupWait.Duration < 0 || n.GroupWait.Duration > time.Hour {
+		return fmt.Errorf("notify: group_wait must be between 0s and 1h, got %s", n.GroupWait.Duration)
+	}
+	if n.GroupInterval.Duration < 0 || n.GroupInterval.Duration > 24*time.Hour {
+		return fmt.Errorf("notify: group_interval must be between 0s and 24h, got %s", n.GroupInterval.Duration)
+	}
+	return nil
+}
+
+func validateDigest(n *NotifyConfig) error {
+	d := &n.Digest
+	if !d.Enabled {
+		return nil
+	}
+	if !n.Email.Enabled {
+		return fmt.Errorf("digest: requires notify.email to be enabled")
+	}
+	if _, err := parseClock(d.At); err != nil {
+		return fmt.Errorf("digest: at: %w", err)
+	}
+	if d.Timezone != "" {
+		if _, err := time.LoadLocation(d.Timezone); err != nil {
+			return fmt.Errorf("digest: invalid timezone %q", d.Timezone)
+		}
+	}
+	return nil
+}
+
+func validateExec(ex *ExecConfig) error {
+	for _, p := range ex.Allow {
+		if !filepath.IsAbs(p) || filepath.Clean(p) != p {
+			return fmt.Errorf("exec: allow: %q must be a clean absolute path", p)
+		}
+	}
+	if ex.Timeout.Duration < 0 {
+		return fmt.Errorf("exec: timeout must not be negative")
+	}
+	if ex.MaxConcurrent < 1 {
+		return fmt.Errorf("exec: max_concurrent must be at least 1")
+	}
+	return nil
+}
+
+func validateHeartbeat(h *HeartbeatConfig) error {
+	if !h.Enabled {
+		return nil
+	}
+	if h.URL == "" {
+		return fmt.Errorf("heartbeat: url is required when enabled")
+	}
+	u, err := url.Parse(h.URL)
+	if err != nil {
+		return fmt.Errorf("heartbeat: invalid url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("heartbeat: url scheme must be http or https")
+	}
+	if h.Interval.Duration < 10*time.Second {
+		return fmt.Errorf("heartbeat: interval must be at least 10s")
+	}
+	for key, val := range h.Headers {
+		if strings.ContainsAny(key, "\r\n") || strings.ContainsAny(val, "\r\n") {
+			return fmt.Errorf("heartbeat: header contains invalid characters")
+		}
+	}
 	return nil
 }
 
@@ -221,6 +829,19 @@ func validateEmail(e *EmailConfig) error {
 	if (e.Username != "") != (e.Password != "") {
 		return fmt.Errorf("email: username and password must both be set or both be empty")
 	}
+	if e.LogLines < 0 || e.LogLines > maxEmailLogLines {
+		return fmt.Errorf("email: log_lines must be between 0 and %d, got %d", maxEmailLogLines, e.LogLines)
+	}
+	if e.HTMLTemplate != "" {
+		if _, err := htmltemplate.New("").Parse(e.HTMLTemplate); err != nil {
+			return fmt.Errorf("email: invalid html_template: %w", err)
+		}
+	}
+	if e.TextTemplate != "" {
+		if _, err := template.New("").Parse(e.TextTemplate); err != nil {
+			return fmt.Errorf("email: invalid text_template: %w", err)
+		}
+	}
 	if e.Username != "" && e.TLS != "starttls" && e.TLS != "tls" {
 		return fmt.Errorf("email: auth requires tls to be \"starttls\" or \"tls\"")
 	}
@@ -248,6 +869,10 @@ func validateWebhook(idx int, wh *WebhookConfig) error {
 		if strings.ContainsAny(val, "\r\n") {
 			return fmt.Errorf("webhook[%d]: header value contains invalid characters", idx)
 		}
+		if wh.Secret != "" && (http.CanonicalHeaderKey(key) == webhook.TimestampHeader ||
+			http.CanonicalHeaderKey(key) == webhook.SignatureHeader) {
+			return fmt.Errorf("webhook[%d]: header %s is set by the signature", idx, key)
+		}
 	}
 	if wh.Template != "" {
 		if _, err := template.New("").Parse(wh.Template); err != nil {
@@ -257,12 +882,143 @@ func validateWebhook(idx int, wh *WebhookConfig) error {
 	return nil
 }
 
-func validateAlert(name string, ac *AlertConfig) error {
+// validNtfyTopic matches the topic names ntfy accepts.
+var validNtfyTopic = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// validTelegramChat matches a numeric chat ID or a public "@channelname".
+var validTelegramChat = regexp.MustCompile(`^(-?[0-9]+|@[A-Za-z0-9_]{5,32})$`)
+
+// validateServiceURL checks the server URL of a push channel.
+func validateServiceURL(kind, field, raw string) error {
+	if raw == "" {
+		return fmt.Errorf("%s: %s is required when enabled", kind, field)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("%s: invalid %s: %w", kind, field, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%s: %s scheme must be http or https", kind, field)
+	}
+	return nil
+}
+
+func validateNtfy(idx int, c *NtfyConfig) error {
+	if !c.Enabled {
+		return nil
+	}
+	kind := fmt.Sprintf("ntfy[%d]", idx)
+	if err := validateServiceURL(kind, "url", c.URL); err != nil {
+		return err
+	}
+	if !validNtfyTopic.MatchString(c.Topic) {
+		return fmt.Errorf("%s: topic must be 1-64 letters, digits, - or _, got %q", kind, c.Topic)
+	}
+	if strings.ContainsAny(c.Token, "\r\n") {
+		return fmt.Errorf("%s: token contains invalid characters", kind)
+	}
+	for _, tag := range c.Tags {
+		if tag == "" || strings.ContainsAny(tag, ",\r\n") {
+			return fmt.Errorf("%s: invalid tag %q", kind, tag)
+		}
+	}
+	return nil
+}
+
+func validateGotify(idx int, c *GotifyConfig) error {
+	if !c.Enabled {
+		return nil
+	}
+	kind := fmt.Sprintf("gotify[%d]", idx)
+	if err := validateServiceURL(kind, "url", c.URL); err != nil {
+		return err
+	}
+	if c.Token == "" {
+		return fmt.Errorf("%s: token is required when enabled", kind)
+	}
+	if strings.ContainsAny(c.Token, "\r\n") {
+		return fmt.Errorf("%s: token contains invalid characters", kind)
+	}
+	return nil
+}
+
+func validateTelegram(idx int, c *TelegramConfig) error {
+	if !c.Enabled {
+		return nil
+	}
+	kind := fmt.Sprintf("telegram[%d]", idx)
+	if err := validateServiceURL(kind, "api_url", c.APIURL); err != nil {
+		return err
+	}
+	// The token is part of the request path.
+	if c.Token == "" || strings.ContainsAny(c.Token, "/?# \r\n") {
+		return fmt.Errorf("%s: token must be the bot token from @BotFather", kind)
+	}
+	if !validTelegramChat.MatchString(c.ChatID) {
+		return fmt.Errorf("%s: chat_id must be a numeric chat ID or \"@channelname\", got %q", kind, c.ChatID)
+	}
+	return nil
+}
+
+// validMatrixRoom matches a room ID such as "!abcdef:example.org".
+var validMatrixRoom = regexp.MustCompile(`^![^:\s]+:[^\s]+$`)
+
+func validateMatrix(idx int, c *MatrixConfig) error {
+	if !c.Enabled {
+		return nil
+	}
+	kind := fmt.Sprintf("matrix[%d]", idx)
+	if err := validateServiceURL(kind, "homeserver", c.Homeserver); err != nil {
+		return err
+	}
+	if c.AccessToken == "" || strings.ContainsAny(c.AccessToken, " \t\r\n") {
+		return fmt.Errorf("%s: access_token is required when enabled", kind)
+	}
+	if !validMatrixRoom.MatchString(c.RoomID) {
+		return fmt.Errorf("%s: room_id must be a room ID like \"!abc:example.org\", got %q", kind, c.RoomID)
+	}
+	return nil
+}
+
+func validatePagerDuty(idx int, c *PagerDutyConfig) error {
+	if !c.Enabled {
+		return nil
+	}
+	kind := fmt.Sprintf("pagerduty[%d]", idx)
+	if err := validateServiceURL(kind, "url", c.URL); err != nil {
+		return err
+	}
+	if c.RoutingKey == "" || strings.ContainsAny(c.RoutingKey, " \t\r\n") {
+		return fmt.Errorf("%s: routing_key must be the integration key of an Events API v2 integration", kind)
+	}
+	return nil
+}
+
+func validateOpsgenie(idx int, c *OpsgenieConfig) error {
+	if !c.Enabled {
+		return nil
+	}
+	kind := fmt.Sprintf("opsgenie[%d]", idx)
+	if err := validateServiceURL(kind, "url", c.URL); err != nil {
+		return err
+	}
+	if c.APIKey == "" || strings.ContainsAny(c.APIKey, " \t\r\n") {
+		return fmt.Errorf("%s: api_key is required when enabled", kind)
+	}
+	for _, tag := range c.Tags {
+		if tag == "" || len(tag) > 50 {
+			return fmt.Errorf("%s: tags must be 1-50 characters, got %q", kind, tag)
+		}
+	}
+	return nil
+}
+
+func validateAlert(name string, ac *AlertConfig, channels map[string]bool, ex *ExecConfig) error {
 	cond, err := parseCondition(ac.Condition)
 	if err != nil {
 		return fmt.Errorf("alert %q: %w", name, err)
 	}
-	if cond.Scope == "log" {
+	if cond.Scope() == "log" {
 		if ac.Match == "" {
 			return fmt.Errorf("alert %q: match is required for log rules", name)
 		}
@@ -282,6 +1038,33 @@ func validateAlert(name string, ac *AlertConfig) error {
 			return fmt.Errorf("alert %q: window is only valid for log rules", name)
 		}
 	}
+	if ac.RunbookURL != "" {
+		u, err := url.Parse(ac.RunbookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("alert %q: runbook_url must be an http or https URL", name)
+		}
+	}
+	if ac.MessageTemplate != "" {
+		if _, err := parseMessageTemplate(ac.MessageTemplate); err != nil {
+			return fmt.Errorf("alert %q: message_template: %w", name, err)
+		}
+	}
+	if sel := newContainerSelector(ac); !sel.empty() {
+		if scope := cond.Scope(); scope != "container" && scope != "log" {
+			return fmt.Errorf("alert %q: project, service, container, image and labels are only valid for container and log rules", name)
+		}
+		if err := validateSelector(ac); err != nil {
+			return fmt.Errorf("alert %q: %w", name, err)
+		}
+	}
+	if len(ac.Interfaces) > 0 || len(ac.ExcludeInterfaces) > 0 {
+		if cond.Scope() != "net" {
+			return fmt.Errorf("alert %q: interfaces and exclude_interfaces are only valid for net rules", name)
+		}
+		if err := validateIfaceFilter(ac); err != nil {
+			return fmt.Errorf("alert %q: %w", name, err)
+		}
+	}
 	if ac.For.Duration < 0 {
 		return fmt.Errorf("alert %q: for must not be negative", name)
 	}
@@ -303,9 +1086,154 @@ func validateAlert(name string, ac *AlertConfig) error {
 		return fmt.Errorf("alert %q: at least one action required", name)
 	}
 	for _, a := range ac.Actions {
-		if a != "notify" {
-			return fmt.Errorf("alert %q: unknown action %q (must be \"notify\")", name, a)
+		if a == "notify" {
+			continue
+		}
+		if path, ok := strings.CutPrefix(a, "exec:"); ok {
+			if !slices.Contains(ex.Allow, path) {
+				return fmt.Errorf("alert %q: action %q: command not in exec.allow", name, a)
+			}
+			continue
+		}
+		if strings.HasPrefix(a, "docker:") {
+			if a != "docker:restart" && a != "docker:stop" {
+				return fmt.Errorf("alert %q: unknown action %q (must be \"docker:restart\" or \"docker:stop\")", name, a)
+			}
+			if cond.Scope() != "container" {
+				return fmt.Errorf("alert %q: action %q is only valid for container rules", name, a)
+			}
+			continue
+		}
+		ch, ok := strings.CutPrefix(a, "notify:")
+		if !ok {
+			return fmt.Errorf("alert %q: unknown action %q (must be \"notify\", \"notify:CHANNEL\", \"exec:PATH\" or \"docker:ACTION\")", name, a)
+		}
+		if !channels[ch] {
+			return fmt.Errorf("alert %q: action %q: unknown channel %q", name, a, ch)
+		}
+	}
+	if n := len(slices.DeleteFunc(slices.Clone(ac.Actions), func(a string) bool { return !strings.HasPrefix(a, "docker:") })); n > 1 {
+		return fmt.Errorf("alert %q: at most one docker action allowed", name)
+	}
+	if remediationAction(ac.Actions) == "" {
+		if ac.Remediate != (RemediateConfig{}) {
+			return fmt.Errorf("alert %q: remediate requires a docker:restart or docker:stop action", name)
+		}
+	} else {
+		if ac.Remediate.MaxAttempts < 1 || ac.Remediate.MaxAttempts > 10 {
+			return fmt.Errorf("alert %q: remediate.max_attempts must be between 1 and 10", name)
+		}
+		if ac.Remediate.Backoff.Duration < 10*time.Second {
+			return fmt.Errorf("alert %q: remediate.backoff must be at least 10s", name)
+		}
+	}
+	if ac.Flapping.Transitions == 0 {
+		if ac.Flapping.Window.Duration != 0 {
+			return fmt.Errorf("alert %q: flapping.window requires flapping.transitions", name)
+		}
+	} else {
+		if ac.Flapping.Transitions < 3 || ac.Flapping.Transitions > 100 {
+			return fmt.Errorf("alert %q: flapping.transitions must be between 3 and 100", name)
+		}
+		if ac.Flapping.Window.Duration < 0 {
+			return fmt.Errorf("alert %q: flapping.window must be positive", name)
+		}
+	}
+	for i, step := range ac.Escalate {
+		if step.After.Duration <= 0 {
+			return fmt.Errorf("alert %q: escalate[%d]: after must be positive", name, i)
+		}
+		if i > 0 && step.After.Duration <= ac.Escalate[i-1].After.Duration {
+			return fmt.Errorf("alert %q: escalate[%d]: after must be greater than the previous step's", name, i)
+		}
+		for _, ch := range step.Channels {
+			if !channels[ch] {
+				return fmt.Errorf("alert %q: escalate[%d]: unknown channel %q", name, i, ch)
+			}
 		}
 	}
 	return nil
 }
+
+// validChannelName matches notification channel names.
+var validChannelName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// validateChannels checks channel names and severity routes, and returns the
+// set of channel names. Disabled channels can still be referenced; they just
+// receive nothing.
+func validateChannels(n *NotifyConfig) (map[string]bool, error) {
+	names := make(map[string]bool, 1+len(n.Webhooks)+len(n.Ntfy)+len(n.Gotify)+len(n.Telegram)+len(n.PagerDuty)+len(n.Opsgenie)+
+		len(n.Slack)+len(n.Discord)+len(n.Matrix))
+	add := func(kind, name string) error {
+		if !validChannelName.MatchString(name) {
+			return fmt.Errorf("%s: invalid name %q (letters, digits, - and _ only)", kind, name)
+		}
+		if names[name] {
+			return fmt.Errorf("%s: duplicate channel name %q", kind, name)
+		}
+		names[name] = true
+		return nil
+	}
+	if err := add("email", n.Email.Name); err != nil {
+		return nil, err
+	}
+	for i := range n.Webhooks {
+		if err := add(fmt.Sprintf("webhook[%d]", i), n.Webhooks[i].Name); err != nil {
+			return nil, err
+		}
+	}
+	for i := range n.Ntfy {
+		if err := add(fmt.Sprintf("ntfy[%d]", i), n.Ntfy[i].Name); err != nil {
+			return nil, err
+		}
+	}
+	for i := range n.Gotify {
+		if err := add(fmt.Sprintf("gotify[%d]", i), n.Gotify[i].Name); err != nil {
+			return nil, err
+		}
+	}
+	for i := range n.Telegram {
+		if err := add(fmt.Sprintf("telegram[%d]", i), n.Telegram[i].Name); err != nil {
+			return nil, err
+		}
+	}
+	for i := range n.PagerDuty {
+		if err := add(fmt.Sprintf("pagerduty[%d]", i), n.PagerDuty[i].Name); err != nil {
+			return nil, err
+		}
+	}
+	for i := range n.Opsgenie {
+		if err := add(fmt.Sprintf("opsgenie[%d]", i), n.Opsgenie[i].Name); err != nil {
+			return nil, err
+		}
+	}
+	for i := range n.Slack {
+		if err := add(fmt.Sprintf("slack[%d]", i), n.Slack[i].Name); err != nil {
+			return nil, err
+		}
+	}
+	for i := range n.Discord {
+		if err := add(fmt.Sprintf("discord[%d]", i), n.Discord[i].Name); err != nil {
+			return nil, err
+		}
+	}
+	for i := range n.Matrix {
+		if err := add(fmt.Sprintf("matrix[%d]", i), n.Matrix[i].Name); err != nil {
+			return nil, err
+		}
+	}
+	for i, r := range n.Routes {
+		if r.Severity != "warning" && r.Severity != "critical" {
+			return nil, fmt.Errorf("route[%d]: severity must be \"warning\" or \"critical\", got %q", i, r.Severity)
+		}
+		if len(r.Channels) == 0 {
+			return nil, fmt.Errorf("route[%d]: at least one channel required", i)
+		}
+		for _, ch := range r.Channels {
+			if !names[ch] {
+				return nil, fmt.Errorf("route[%d]: unknown channel %q", i, ch)
+			}
+		}
+	}
+	return names, nil
+}
@@ -4,6 +4,7 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -274,6 +275,678 @@ actions = ["notify"]
 	}
 }
 
+func TestLoadConfigAlertCompoundCondition(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	os.WriteFile(path, []byte(`
+[alerts.unhealthy_running]
+condition = "container.state == 'running' AND (container.health == 'unhealthy' OR container.restart_count > 3)"
+severity = "critical"
+actions = ["notify"]
+`), 0644)
+
+	if _, err := LoadConfig(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestLoadConfigAlertMixedScopes(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	os.WriteFile(path, []byte(`
+[alerts.bad]
+condition = "host.cpu_percent > 90 OR container.cpu_percent > 90"
+severity = "warning"
+actions = ["notify"]
+`), 0644)
+
+	_, err := LoadConfig(path)
+	if err == nil {
+		t.Fatal("expected error for mixed scopes")
+	}
+	if !strings.Contains(err.Error(), `alert "bad"`) || !strings.Contains(err.Error(), "position 26") {
+		t.Errorf("error = %q, want alert name and position", err)
+	}
+}
+
+func TestLoadConfigAlertSelectors(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	os.WriteFile(path, []byte(`
+[alerts.shop_db]
+condition = "container.memory_percent > 90"
+project = "shop"
+service = "db"
+image = "postgres:*"
+labels = { tier = "backend" }
+severity = "critical"
+actions = ["notify"]
+`), 0644)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	ac := cfg.Alerts["shop_db"]
+	if ac.Project != "shop" || ac.Service != "db" || ac.Image != "postgres:*" {
+		t.Errorf("selectors = %q/%q/%q", ac.Project, ac.Service, ac.Image)
+	}
+	if ac.Labels["tier"] != "backend" {
+		t.Errorf("labels = %v", ac.Labels)
+	}
+}
+
+func TestLoadConfigAlertSelectorValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{
+			name: "host rule with selector",
+			config: `
+[alerts.bad]
+condition = "host.cpu_percent > 90"
+project = "shop"
+severity = "warning"
+`,
+			wantErr: "only valid for container and log rules",
+		},
+		{
+			name: "bad container glob",
+			config: `
+[alerts.bad]
+condition = "container.state == 'exited'"
+container = "db-["
+severity = "warning"
+`,
+			wantErr: "invalid container pattern",
+		},
+		{
+			name: "interfaces on container rule",
+			config: `
+[alerts.bad]
+condition = "container.state == 'exited'"
+interfaces = ["eth*"]
+severity = "warning"
+`,
+			wantErr: "only valid for net rules",
+		},
+		{
+			name: "bad interface glob",
+			config: `
+[alerts.bad]
+condition = "net.rx_errors_per_sec > 0"
+exclude_interfaces = ["veth["]
+severity = "warning"
+`,
+			wantErr: "invalid interface pattern",
+		},
+		{
+			name: "bad label glob",
+			config: `
+[alerts.bad]
+condition = "container.state == 'exited'"
+labels = { tier = "[" }
+severity = "warning"
+`,
+			wantErr: "label \"tier\"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			os.WriteFile(path, []byte(tt.config), 0644)
+
+			_, err := LoadConfig(path)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want substring %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfigMaintenance(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	os.WriteFile(path, []byte(`
+[alerts.exited]
+condition = "container.state == 'exited'"
+severity = "critical"
+actions = ["notify"]
+
+[[maintenance]]
+name = "backups"
+schedule = "0 3 * * *"
+duration = "30m"
+timezone = "Europe/Oslo"
+
+[[maintenance]]
+name = "deploys"
+days = ["sat", "sun"]
+start = "22:00"
+end = "02:00"
+rules = ["exited"]
+projects = ["shop"]
+`), 0644)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Maintenance) != 2 {
+		t.Fatalf("maintenance = %d, want 2", len(cfg.Maintenance))
+	}
+	m := cfg.Maintenance[0]
+	if m.Schedule != "0 3 * * *" || m.Duration.Duration != 30*time.Minute || m.Timezone != "Europe/Oslo" {
+		t.Errorf("backups = %+v", m)
+	}
+	m = cfg.Maintenance[1]
+	if m.Start != "22:00" || m.End != "02:00" || len(m.Days) != 2 || m.Rules[0] != "exited" || m.Projects[0] != "shop" {
+		t.Errorf("deploys = %+v", m)
+	}
+}
+
+func TestLoadConfigMaintenanceValidation(t *testing.T) {
+	const rule = `
+[alerts.exited]
+condition = "container.state == 'exited'"
+severity = "critical"
+actions = ["notify"]
+`
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{"missing name", `[[maintenance]]
+start = "02:00"
+end = "04:00"`, "maintenance: name is required"},
+		{"no schedule", `[[maintenance]]
+name = "m"`, "either schedule and duration, or start and end"},
+		{"schedule and range", `[[maintenance]]
+name = "m"
+schedule = "0 3 * * *"
+duration = "1h"
+start = "02:00"`, "cannot be combined"},
+		{"bad cron", `[[maintenance]]
+name = "m"
+schedule = "0 25 * * *"
+duration = "1h"`, "schedule: hour"},
+		{"missing duration", `[[maintenance]]
+name = "m"
+schedule = "0 3 * * *"`, "duration must be between"},
+		{"duration too long", `[[maintenance]]
+name = "m"
+schedule = "0 3 * * *"
+duration = "200h"`, "duration must be between"},
+		{"duration with range", `[[maintenance]]
+name = "m"
+start = "02:00"
+end = "04:00"
+duration = "1h"`, "duration is only valid with schedule"},
+		{"bad start", `[[maintenance]]
+name = "m"
+start = "2am"
+end = "04:00"`, "start: invalid time"},
+		{"equal start end", `[[maintenance]]
+name = "m"
+start = "02:00"
+end = "02:00"`, "start and end must differ"},
+		{"bad day", `[[maintenance]]
+name = "m"
+days = ["funday"]
+start = "02:00"
+end = "04:00"`, "invalid day \"funday\""},
+		{"bad timezone", `[[maintenance]]
+name = "m"
+start = "02:00"
+end = "04:00"
+timezone = "Mars/Olympus"`, "invalid timezone"},
+		{"duplicate name", `[[maintenance]]
+name = "m"
+start = "02:00"
+end = "04:00"
+
+[[maintenance]]
+name = "m"
+start = "05:00"
+end = "06:00"`, "maintenance \"m\": duplicate name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			os.WriteFile(path, []byte(rule+tt.config), 0644)
+
+			_, err := LoadConfig(path)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want substring %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfigQuietHours(t *testing.T) {
+	const channel = `
+[[notify.ntfy]]
+enabled = true
+name = "phone"
+topic = "tori-alerts"
+`
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{"valid", `[[notify.quiet_hours]]
+name = "night"
+start = "22:00"
+end = "07:00"
+timezone = "Europe/Oslo"
+severities = ["warning"]
+channels = ["phone"]
+
+[[notify.quiet_hours]]
+name = "weekend"
+schedule = "0 18 * * 5"
+duration = "60h"
+action = "drop"`, ""},
+		{"missing name", `[[notify.quiet_hours]]
+start = "22:00"
+end = "07:00"`, "quiet_hours[0]: name is required"},
+		{"no window", `[[notify.quiet_hours]]
+name = "q"`, "either schedule and duration, or start and end"},
+		{"bad action", `[[notify.quiet_hours]]
+name = "q"
+start = "22:00"
+end = "07:00"
+action = "mute"`, "invalid action \"mute\""},
+		{"bad severity", `[[notify.quiet_hours]]
+name = "q"
+start = "22:00"
+end = "07:00"
+severities = ["info"]`, "invalid severity \"info\""},
+		{"unknown channel", `[[notify.quiet_hours]]
+name = "q"
+start = "22:00"
+end = "07:00"
+channels = ["pager"]`, "unknown channel \"pager\""},
+		{"duplicate name", `[[notify.quiet_hours]]
+name = "q"
+start = "22:00"
+end = "07:00"
+
+[[notify.quiet_hours]]
+name = "q"
+start = "12:00"
+end = "13:00"`, "quiet_hours \"q\": duplicate name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			os.WriteFile(path, []byte(channel+tt.config), 0644)
+
+			cfg, err := LoadConfig(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(cfg.Notify.QuietHours) != 2 || cfg.Notify.QuietHours[1].Action != "drop" {
+					t.Errorf("quiet_hours = %+v", cfg.Notify.QuietHours)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want substring %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfigInhibitValidation(t *testing.T) {
+	const rules = `
+[alerts.host_mem]
+condition = "host.memory_percent > 90"
+severity = "critical"
+actions = ["notify"]
+
+[alerts.exited]
+condition = "container.state == 'exited'"
+severity = "warning"
+actions = ["notify"]
+
+[alerts.db_mem]
+condition = "container.memory_percent > 90"
+severity = "critical"
+actions = ["notify"]
+`
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{"valid", `[[inhibit]]
+source = "host_mem"
+targets = ["exited", "db_mem"]
+
+[[inhibit]]
+source = "db_mem"
+targets = ["exited"]
+equal = "project"`, ""},
+		{"missing source", `[[inhibit]]
+targets = ["exited"]`, "inhibit[0]: source is required"},
+		{"missing targets", `[[inhibit]]
+source = "host_mem"`, "at least one target required"},
+		{"self", `[[inhibit]]
+source = "exited"
+targets = ["exited"]`, "cannot inhibit itself"},
+		{"bad equal", `[[inhibit]]
+source = "db_mem"
+targets = ["exited"]
+equal = "host"`, "equal must be"},
+		{"equal on host source", `[[inhibit]]
+source = "host_mem"
+targets = ["exited"]
+equal = "project"`, "requires a container or log source rule"},
+		{"equal on host target", `[[inhibit]]
+source = "db_mem"
+targets = ["host_mem"]
+equal = "container"`, "\"host_mem\" is host"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			os.WriteFile(path, []byte(rules+tt.config), 0644)
+
+			cfg, err := LoadConfig(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(cfg.Inhibit) != 2 || cfg.Inhibit[1].Equal != "project" {
+					t.Errorf("inhibit = %+v", cfg.Inhibit)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want substring %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfigGrouping(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	os.WriteFile(path, []byte(`
+[notify]
+group_by = ["project", "severity"]
+group_interval = "10m"
+
+[notify.email]
+enabled = true
+smtp_host = "smtp.example.com"
+smtp_port = 25
+from = "tori@example.com"
+to = ["ops@example.com"]
+
+[notify.digest]
+enabled = true
+timezone = "Europe/Oslo"
+`), 0644)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := cfg.Notify
+	if len(n.GroupBy) != 2 || n.GroupWait.Duration != 30*time.Second || n.GroupInterval.Duration != 10*time.Minute {
+		t.Errorf("grouping = %v wait=%s interval=%s", n.GroupBy, n.GroupWait.Duration, n.GroupInterval.Duration)
+	}
+	if !n.Digest.Enabled || n.Digest.At != "08:00" || n.Digest.Timezone != "Europe/Oslo" {
+		t.Errorf("digest = %+v", n.Digest)
+	}
+}
+
+func TestLoadConfigGroupingValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{"bad field", "[notify]\ngroup_by = [\"host\"]", "invalid group_by \"host\""},
+		{"duplicate field", "[notify]\ngroup_by = [\"rule\", \"rule\"]", "duplicate group_by"},
+		{"wait without group_by", "[notify]\ngroup_wait = \"1m\"", "require group_by"},
+		{"wait too long", "[notify]\ngroup_by = [\"rule\"]\ngroup_wait = \"2h\"", "group_wait must be between"},
+		{"negative interval", "[notify]\ngroup_by = [\"rule\"]\ngroup_interval = \"-1m\"", "group_interval must be between"},
+		{"digest without email", "[notify.digest]\nenabled = true", "digest: requires notify.email"},
+		{"digest bad time", `[notify.email]
+enabled = true
+smtp_host = "smtp.example.com"
+smtp_port = 25
+from = "tori@example.com"
+to = ["ops@example.com"]
+
+[notify.digest]
+enabled = true
+at = "8am"`, "digest: at: invalid time"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			os.WriteFile(path, []byte(tt.config), 0644)
+
+			_, err := LoadConfig(path)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want substring %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPushChannelValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{
+			name: "valid channels",
+			config: `
+[[notify.ntfy]]
+enabled = true
+topic = "tori-alerts"
+tags = ["prod"]
+
+[[notify.gotify]]
+enabled = true
+url = "https://gotify.example.com"
+token = "AbCdEf"
+
+[[notify.telegram]]
+enabled = true
+token = "123456:ABC-DEF"
+chat_id = "-1001234567890"
+`,
+		},
+		{
+			name:   "disabled ntfy without topic",
+			config: "[[notify.ntfy]]\nenabled = false\n",
+		},
+		{
+			name:    "ntfy invalid topic",
+			config:  "[[notify.ntfy]]\nenabled = true\ntopic = \"a/b\"\n",
+			wantErr: "topic",
+		},
+		{
+			name:    "ntfy invalid url",
+			config:  "[[notify.ntfy]]\nenabled = true\ntopic = \"t\"\nurl = \"ftp://ntfy.example.com\"\n",
+			wantErr: "scheme",
+		},
+		{
+			name:    "ntfy empty tag",
+			config:  "[[notify.ntfy]]\nenabled = true\ntopic = \"t\"\ntags = [\"\"]\n",
+			wantErr: "tag",
+		},
+		{
+			name:    "gotify missing url",
+			config:  "[[notify.gotify]]\nenabled = true\ntoken = \"x\"\n",
+			wantErr: "url is required",
+		},
+		{
+			name:    "gotify missing token",
+			config:  "[[notify.gotify]]\nenabled = true\nurl = \"https://gotify.example.com\"\n",
+			wantErr: "token is required",
+		},
+		{
+			name:    "telegram missing token",
+			config:  "[[notify.telegram]]\nenabled = true\nchat_id = \"42\"\n",
+			wantErr: "token",
+		},
+		{
+			name:    "telegram token with path",
+			config:  "[[notify.telegram]]\nenabled = true\ntoken = \"1:a/b\"\nchat_id = \"42\"\n",
+			wantErr: "token",
+		},
+		{
+			name:    "telegram invalid chat",
+			config:  "[[notify.telegram]]\nenabled = true\ntoken = \"1:abc\"\nchat_id = \"me\"\n",
+			wantErr: "chat_id",
+		},
+		{
+			name:   "telegram channel username",
+			config: "[[notify.telegram]]\nenabled = true\ntoken = \"1:abc\"\nchat_id = \"@tori_alerts\"\n",
+		},
+		{
+			name:   "valid incident services",
+			config: "[[notify.pagerduty]]\nenabled = true\nrouting_key = \"R0123\"\n\n[[notify.opsgenie]]\nenabled = true\napi_key = \"k\"\ntags = [\"prod\"]\n",
+		},
+		{
+			name:    "pagerduty missing routing key",
+			config:  "[[notify.pagerduty]]\nenabled = true\n",
+			wantErr: "routing_key",
+		},
+		{
+			name:    "opsgenie missing api key",
+			config:  "[[notify.opsgenie]]\nenabled = true\n",
+			wantErr: "api_key",
+		},
+		{
+			name:    "opsgenie empty tag",
+			config:  "[[notify.opsgenie]]\nenabled = true\napi_key = \"k\"\ntags = [\"\"]\n",
+			wantErr: "tags",
+		},
+		{
+			name:   "valid chat services",
+			config: "[[notify.slack]]\nenabled = true\nurl = \"https://hooks.slack.com/services/T/B/x\"\n\n[[notify.discord]]\nenabled = true\nurl = \"https://discord.com/api/webhooks/1/x\"\n\n[[notify.matrix]]\nenabled = true\nhomeserver = \"https://matrix.example.org\"\naccess_token = \"syt_x\"\nroom_id = \"!abc:example.org\"\n",
+		},
+		{
+			name:    "slack missing url",
+			config:  "[[notify.slack]]\nenabled = true\n",
+			wantErr: "slack[0]: url is required",
+		},
+		{
+			name:    "matrix room alias",
+			config:  "[[notify.matrix]]\nenabled = true\nhomeserver = \"https://matrix.example.org\"\naccess_token = \"syt_x\"\nroom_id = \"#alerts:example.org\"\n",
+			wantErr: "room_id",
+		},
+		{
+			name:    "matrix missing token",
+			config:  "[[notify.matrix]]\nenabled = true\nhomeserver = \"https://matrix.example.org\"\nroom_id = \"!abc:example.org\"\n",
+			wantErr: "access_token",
+		},
+		{
+			name:    "duplicate channel name",
+			config:  "[[notify.ntfy]]\nname = \"phone\"\ntopic = \"t\"\n\n[[notify.gotify]]\nname = \"phone\"\n",
+			wantErr: "duplicate channel name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.toml")
+			os.WriteFile(path, []byte(tt.config), 0644)
+			_, err := LoadConfig(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPushChannelDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	os.WriteFile(path, []byte(`
+[[notify.ntfy]]
+topic = "t"
+
+[[notify.gotify]]
+url = "https://gotify.example.com"
+
+[[notify.telegram]]
+chat_id = "42"
+
+[[notify.pagerduty]]
+
+[[notify.opsgenie]]
+
+[[notify.slack]]
+
+[[notify.discord]]
+
+[[notify.matrix]]
+`), 0644)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := cfg.Notify
+	if n.Ntfy[0].Name != "ntfy-1" || n.Ntfy[0].URL != "https://ntfy.sh" {
+		t.Errorf("ntfy = %+v, want name ntfy-1 on ntfy.sh", n.Ntfy[0])
+	}
+	if n.Gotify[0].Name != "gotify-1" {
+		t.Errorf("gotify name = %q, want gotify-1", n.Gotify[0].Name)
+	}
+	if n.Telegram[0].Name != "telegram-1" || n.Telegram[0].APIURL != "https://api.telegram.org" {
+		t.Errorf("telegram = %+v, want name telegram-1 on api.telegram.org", n.Telegram[0])
+	}
+	if n.PagerDuty[0].Name != "pagerduty-1" || n.PagerDuty[0].URL != "https://events.pagerduty.com/v2/enqueue" {
+		t.Errorf("pagerduty = %+v, want name pagerduty-1 on the Events API", n.PagerDuty[0])
+	}
+	if n.Opsgenie[0].Name != "opsgenie-1" || n.Opsgenie[0].URL != "https://api.opsgenie.com" {
+		t.Errorf("opsgenie = %+v, want name opsgenie-1 on api.opsgenie.com", n.Opsgenie[0])
+	}
+	if n.Slack[0].Name != "slack-1" || n.Discord[0].Name != "discord-1" || n.Matrix[0].Name != "matrix-1" {
+		t.Errorf("chat names = %q, %q, %q", n.Slack[0].Name, n.Discord[0].Name, n.Matrix[0].Name)
+	}
+}
+
 func TestWebhookValidation(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -285,32 +958,52 @@ func TestWebhookValidation(t *testing.T) {
 			config: `
 [[notify.webhooks]]
 enabled = true
-url = "https://example.com/hook"
+url = "https://example.com/hook"
+`,
+		},
+		{
+			name: "disabled webhook no url",
+			config: `
+[[notify.webhooks]]
+enabled = false
+`,
+		},
+		{
+			name: "enabled webhook missing url",
+			config: `
+[[notify.webhooks]]
+enabled = true
 `,
+			wantErr: true,
 		},
 		{
-			name: "disabled webhook no url",
+			name: "webhook with custom headers",
 			config: `
 [[notify.webhooks]]
-enabled = false
+enabled = true
+url = "https://example.com/hook"
+headers = { "Authorization" = "Bearer xxx" }
 `,
 		},
 		{
-			name: "enabled webhook missing url",
+			name: "webhook with secret",
 			config: `
 [[notify.webhooks]]
 enabled = true
+url = "https://example.com/hook"
+secret = "s3cret"
 `,
-			wantErr: true,
 		},
 		{
-			name: "webhook with custom headers",
+			name: "webhook with secret and signature header",
 			config: `
 [[notify.webhooks]]
 enabled = true
 url = "https://example.com/hook"
-headers = { "Authorization" = "Bearer xxx" }
+secret = "s3cret"
+headers = { "x-tori-signature" = "sha256=0" }
 `,
+			wantErr: true,
 		},
 		{
 			name: "webhook with valid template",
@@ -378,6 +1071,28 @@ url = "http://example.com/hook"
 	}
 }
 
+func TestEmailLogLinesDefault(t *testing.T) {
+	tests := []struct {
+		config string
+		want   int
+	}{
+		{"", 20},
+		{"log_lines = 0", 0},
+		{"log_lines = 50", 50},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "config.toml")
+		os.WriteFile(path, []byte("[notify.email]\n"+tt.config+"\n"), 0644)
+		cfg, err := LoadConfig(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := cfg.Notify.Email.LogLines; got != tt.want {
+			t.Errorf("%q: log_lines = %d, want %d", tt.config, got, tt.want)
+		}
+	}
+}
+
 func TestEmailValidation(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -566,6 +1281,45 @@ to = ["admin@example.com"]
 username = "user@example.com"
 password = "secret"
 tls = "none"
+`,
+			wantErr: true,
+		},
+		{
+			name: "log_lines too high",
+			config: `
+[notify.email]
+enabled = true
+smtp_host = "localhost"
+smtp_port = 25
+from = "alerts@example.com"
+to = ["admin@example.com"]
+log_lines = 500
+`,
+			wantErr: true,
+		},
+		{
+			name: "valid templates",
+			config: `
+[notify.email]
+enabled = true
+smtp_host = "localhost"
+smtp_port = 25
+from = "alerts@example.com"
+to = ["admin@example.com"]
+html_template = '<h1 style="color:{{.Color}}">{{.Subject}}</h1>{{range .Alerts}}<p>{{.Body}}</p>{{end}}'
+text_template = '{{.Subject}}: {{.Body}}'
+`,
+		},
+		{
+			name: "invalid html_template",
+			config: `
+[notify.email]
+enabled = true
+smtp_host = "localhost"
+smtp_port = 25
+from = "alerts@example.com"
+to = ["admin@example.com"]
+html_template = '{{.Subject'
 `,
 			wantErr: true,
 		},
@@ -901,3 +1655,401 @@ func TestDurationUnmarshal(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadConfigChannels(t *testing.T) {
+	const channels = `
+[notify.email]
+enabled = false
+
+[[notify.webhooks]]
+name = "oncall"
+enabled = true
+url = "https://example.com/oncall"
+
+[[notify.webhooks]]
+enabled = true
+url = "https://example.com/other"
+`
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{"valid", `
+[[notify.routes]]
+severity = "warning"
+channels = ["email"]
+
+[alerts.disk]
+condition = "host.disk_percent > 90"
+severity = "critical"
+actions = ["notify:oncall", "notify:webhook-2"]`, ""},
+		{"unknown channel", `
+[alerts.disk]
+condition = "host.disk_percent > 90"
+severity = "critical"
+actions = ["notify:pager"]`, `unknown channel "pager"`},
+		{"unknown action", `
+[alerts.disk]
+condition = "host.disk_percent > 90"
+severity = "critical"
+actions = ["page"]`, `unknown action "page"`},
+		{"route unknown channel", `
+[[notify.routes]]
+severity = "critical"
+channels = ["pager"]`, `route[0]: unknown channel "pager"`},
+		{"route bad severity", `
+[[notify.routes]]
+severity = "info"
+channels = ["oncall"]`, "route[0]: severity must be"},
+		{"route without channels", `
+[[notify.routes]]
+severity = "critical"`, "route[0]: at least one channel required"},
+		{"duplicate name", `
+[[notify.webhooks]]
+name = "oncall"
+url = "https://example.com/dup"`, `webhook[2]: duplicate channel name "oncall"`},
+		{"invalid name", `
+[[notify.webhooks]]
+name = "on call"
+url = "https://example.com/bad"`, `webhook[2]: invalid name "on call"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			os.WriteFile(path, []byte(channels+tt.config), 0644)
+
+			cfg, err := LoadConfig(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if cfg.Notify.Email.Name != "email" || cfg.Notify.Webhooks[0].Name != "oncall" || cfg.Notify.Webhooks[1].Name != "webhook-2" {
+					t.Errorf("channel names = %q, %q, %q", cfg.Notify.Email.Name, cfg.Notify.Webhooks[0].Name, cfg.Notify.Webhooks[1].Name)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want substring %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfigEscalationValidation(t *testing.T) {
+	const base = `
+[[notify.webhooks]]
+name = "oncall"
+enabled = true
+url = "https://example.com/oncall"
+
+[alerts.cpu]
+condition = "host.cpu_percent > 90"
+severity = "critical"
+actions = ["notify:oncall"]
+`
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{"valid", `
+[[alerts.cpu.escalate]]
+after = "15m"
+channels = ["email"]
+
+[[alerts.cpu.escalate]]
+after = "1h"`, ""},
+		{"missing after", `
+[[alerts.cpu.escalate]]
+channels = ["email"]`, "escalate[0]: after must be positive"},
+		{"not increasing", `
+[[alerts.cpu.escalate]]
+after = "1h"
+
+[[alerts.cpu.escalate]]
+after = "15m"`, "escalate[1]: after must be greater"},
+		{"unknown channel", `
+[[alerts.cpu.escalate]]
+after = "15m"
+channels = ["pager"]`, `escalate[0]: unknown channel "pager"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			os.WriteFile(path, []byte(base+tt.config), 0644)
+
+			cfg, err := LoadConfig(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if esc := cfg.Alerts["cpu"].Escalate; len(esc) != 2 || esc[1].After.Duration != time.Hour || esc[1].Channels != nil {
+					t.Errorf("escalate = %+v", esc)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want substring %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfigHeartbeat(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{"valid", `[heartbeat]
+enabled = true
+url = "https://hc-ping.example.com/abc"`, ""},
+		{"missing url", `[heartbeat]
+enabled = true`, "heartbeat: url is required"},
+		{"bad scheme", `[heartbeat]
+enabled = true
+url = "ftp://example.com"`, "heartbeat: url scheme must be http or https"},
+		{"short interval", `[heartbeat]
+enabled = true
+url = "https://hc-ping.example.com/abc"
+interval = "1s"`, "heartbeat: interval must be at least 10s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			os.WriteFile(path, []byte(tt.config), 0644)
+
+			cfg, err := LoadConfig(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if cfg.Heartbeat.Interval.Duration != time.Minute {
+					t.Errorf("interval = %v, want 1m default", cfg.Heartbeat.Interval.Duration)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want substring %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfigExec(t *testing.T) {
+	const rule = `
+[alerts.disk]
+condition = "host.disk_percent > 90"
+severity = "critical"
+`
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{"valid", `[exec]
+allow = ["/usr/local/bin/on-disk-full"]
+` + rule + `actions = ["notify", "exec:/usr/local/bin/on-disk-full"]`, ""},
+		{"not allowed", `[exec]
+allow = ["/usr/local/bin/on-disk-full"]
+` + rule + `actions = ["exec:/bin/rm"]`, `action "exec:/bin/rm": command not in exec.allow`},
+		{"no allowlist", rule + `actions = ["exec:/usr/local/bin/on-disk-full"]`, "command not in exec.allow"},
+		{"relative allow", `[exec]
+allow = ["bin/cleanup"]`, `exec: allow: "bin/cleanup" must be a clean absolute path`},
+		{"unclean allow", `[exec]
+allow = ["/usr/local/../bin/sh"]`, "must be a clean absolute path"},
+		{"bad concurrency", `[exec]
+max_concurrent = -1`, "exec: max_concurrent must be at least 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			os.WriteFile(path, []byte(tt.config), 0644)
+
+			cfg, err := LoadConfig(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if cfg.Exec.Timeout.Duration != 30*time.Second || cfg.Exec.MaxConcurrent != 4 {
+					t.Errorf("exec defaults = %+v", cfg.Exec)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want substring %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfigRemediate(t *testing.T) {
+	const rule = `
+[alerts.down]
+condition = "container.state == 'exited'"
+severity = "critical"
+`
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{"defaults", rule + `actions = ["notify", "docker:restart"]`, ""},
+		{"stop", rule + `actions = ["docker:stop"]
+[alerts.down.remediate]
+max_attempts = 1
+backoff = "5m"`, ""},
+		{"unknown docker action", rule + `actions = ["docker:kill"]`, `unknown action "docker:kill"`},
+		{"host rule", `
+[alerts.cpu]
+condition = "host.cpu_percent > 90"
+severity = "warning"
+actions = ["docker:restart"]`, "only valid for container rules"},
+		{"log rule", `
+[alerts.panics]
+condition = "log.count > 0"
+match = "panic"
+window = "5m"
+severity = "critical"
+actions = ["docker:restart"]`, "only valid for container rules"},
+		{"two docker actions", rule + `actions = ["docker:restart", "docker:stop"]`, "at most one docker action"},
+		{"remediate without action", rule + `actions = ["notify"]
+[alerts.down.remediate]
+max_attempts = 2`, "remediate requires a docker:restart or docker:stop action"},
+		{"too many attempts", rule + `actions = ["docker:restart"]
+[alerts.down.remediate]
+max_attempts = 11`, "max_attempts must be between 1 and 10"},
+		{"short backoff", rule + `actions = ["docker:restart"]
+[alerts.down.remediate]
+backoff = "1s"`, "backoff must be at least 10s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			os.WriteFile(path, []byte(tt.config), 0644)
+
+			cfg, err := LoadConfig(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				rem := cfg.Alerts["down"].Remediate
+				if rem.MaxAttempts < 1 || rem.Backoff.Duration < time.Minute {
+					t.Errorf("remediate = %+v", rem)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want substring %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFlapping(t *testing.T) {
+	const rule = `
+[alerts.down]
+condition = "container.state == 'exited'"
+severity = "critical"
+actions = ["notify"]
+`
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{"default window", rule + `[alerts.down.flapping]
+transitions = 6`, ""},
+		{"too few", rule + `[alerts.down.flapping]
+transitions = 2`, "flapping.transitions must be between 3 and 100"},
+		{"window alone", rule + `[alerts.down.flapping]
+window = "5m"`, "flapping.window requires flapping.transitions"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			os.WriteFile(path, []byte(tt.config), 0644)
+
+			cfg, err := LoadConfig(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if w := cfg.Alerts["down"].Flapping.Window.Duration; w != 10*time.Minute {
+					t.Errorf("window = %s, want 10m", w)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want substring %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfigAnnotations(t *testing.T) {
+	const rule = `
+[alerts.cpu]
+condition = "host.cpu_percent > 90"
+severity = "warning"
+actions = ["notify"]
+`
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{"valid", rule + `description = "CPU saturated"
+runbook_url = "https://wiki.example.com/cpu"
+message_template = "{{.Rule}}: {{.Field}} {{.Value}} > {{.Threshold}}"`, ""},
+		{"relative runbook", rule + `runbook_url = "wiki/cpu"`, "runbook_url must be an http or https URL"},
+		{"template syntax", rule + `message_template = "{{.Rule"`, `alert "cpu": message_template:`},
+		{"unknown template field", rule + `message_template = "{{.Container}}"`, "can't evaluate field Container"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			os.WriteFile(path, []byte(tt.config), 0644)
+
+			_, err := LoadConfig(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want substring %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -92,6 +92,11 @@ func convertAlerts(src []Alert) []protocol.AlertMsg {
 			FiredAt:      s.FiredAt.Unix(),
 			Message:      s.Message,
 			Acknowledged: s.Acknowledged,
+			InhibitedBy:  s.InhibitedBy,
+			Remediations: convertRemediations(s.Remediations),
+			Flapping:     s.Flapping,
+			Description:  s.Description,
+			RunbookURL:   s.RunbookURL,
 		}
 		if s.ResolvedAt != nil {
 			out[i].ResolvedAt = s.ResolvedAt.Unix()
@@ -99,3 +104,91 @@ func convertAlerts(src []Alert) []protocol.AlertMsg {
 	}
 	return out
 }
+
+func convertRemediations(src []Remediation) []protocol.RemediationMsg {
+	if len(src) == 0 {
+		return nil
+	}
+	out := make([]protocol.RemediationMsg, len(src))
+	for i, r := range src {
+		out[i] = protocol.RemediationMsg{
+			Action:  r.Action,
+			Attempt: r.Attempt,
+			Time:    r.At.Unix(),
+			Error:   r.Error,
+		}
+	}
+	return out
+}
+
+func convertSilences(src []Silence) []protocol.SilenceMsg {
+	out := make([]protocol.SilenceMsg, len(src))
+	for i, s := range src {
+		out[i] = protocol.SilenceMsg{
+			ID:          s.ID,
+			RuleName:    s.RuleName,
+			InstanceKey: s.InstanceKey,
+			CreatedAt:   s.CreatedAt.Unix(),
+			ExpiresAt:   s.ExpiresAt.Unix(),
+			CreatedBy:   s.CreatedBy,
+			Comment:     s.Comment,
+		}
+	}
+	return out
+}
+
+func convertBacktest(res *BacktestResult) *protocol.QueryBacktestResp {
+	out := &protocol.QueryBacktestResp{
+		RuleName:  res.Rule,
+		Condition: res.Condition,
+		Start:     res.Start.Unix(),
+		End:       res.End.Unix(),
+		Samples:   res.Samples,
+		Intervals: make([]protocol.BacktestInterval, len(res.Intervals)),
+	}
+	for i, iv := range res.Intervals {
+		out.Intervals[i] = protocol.BacktestInterval{
+			InstanceKey: iv.InstanceKey,
+			Label:       iv.Label,
+			FiredAt:     iv.FiredAt.Unix(),
+		}
+		if !iv.ResolvedAt.IsZero() {
+			out.Intervals[i].ResolvedAt = iv.ResolvedAt.Unix()
+		}
+	}
+	return out
+}
+
+// disabledRuleInfo describes a disabled rule, which the alerter does not
+// hold. config is the TOML body of a managed rule, "" for a config file rule.
+func disabledRuleInfo(name string, ac AlertConfig, config string) protocol.AlertRuleInfo {
+	info := protocol.AlertRuleInfo{
+		Name:       name,
+		Condition:  ac.Condition,
+		Severity:   ac.Severity,
+		Actions:    ac.Actions,
+		Match:      ac.Match,
+		MatchRegex: ac.MatchRegex,
+
+		Description:     ac.Description,
+		RunbookURL:      ac.RunbookURL,
+		MessageTemplate: ac.MessageTemplate,
+
+		Managed:  config != "",
+		Disabled: true,
+		Config:   config,
+	}
+	if ac.For.Duration > 0 {
+		info.For = ac.For.Duration.String()
+	}
+	if ac.Cooldown.Duration > 0 {
+		info.Cooldown = ac.Cooldown.Duration.String()
+	}
+	if ac.NotifyCooldown.Duration > 0 {
+		info.NotifyCooldown = ac.NotifyCooldown.Duration.String()
+	}
+	if ac.Window.Duration > 0 {
+		info.Window = ac.Window.Duration.String()
+	}
+	return info
+}
@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxDigestAlerts caps the per-alert lines in a digest body.
+const maxDigestAlerts = 50
+
+// nextDigest returns the first digest time strictly after t. at is minutes
+// since midnight in loc.
+func nextDigest(t time.Time, at int, loc *time.Location) time.Time {
+	lt := t.In(loc)
+	y, m, d := lt.Date()
+	next := time.Date(y, m, d, 0, at, 0, 0, loc)
+	if !next.After(t) {
+		next = time.Date(y, m, d+1, 0, at, 0, 0, loc)
+	}
+	return next
+}
+
+// SendDigest emails a summary of the alerts that fired or resolved between
+// start and end.
+func (a *Alerter) SendDigest(ctx context.Context, start, end time.Time) error {
+	if a.notifier == nil || !a.notifier.HasChannels() {
+		return fmt.Errorf("no notification channels configured")
+	}
+	alerts, err := a.store.QueryAlertsFiredOrResolved(ctx, start.Unix(), end.Unix())
+	if err != nil {
+		return fmt.Errorf("query alerts: %w", err)
+	}
+	subject, body := buildDigest(alerts, start, end)
+	a.notifier.SendDigest(subject, body)
+	slog.Info("digest queued", "alerts", len(alerts))
+	return nil
+}
+
+// buildDigest formats the digest subject and plain-text body. alerts are
+// those fired or resolved in [start, end], newest first as returned by
+// QueryAlertsFiredOrResolved.
+func buildDigest(alerts []Alert, start, end time.Time) (subject, body string) {
+	var fired, resolved, open int
+	byRule := make(map[string]int)
+	for _, al := range alerts {
+		if al.FiredAt.Unix() >= start.Unix() {
+			fired++
+		}
+		switch {
+		case al.ResolvedAt == nil:
+			open++
+		case al.ResolvedAt.Unix() <= end.Unix():
+			resolved++
+		}
+		byRule[al.RuleName]++
+	}
+	subject = fmt.Sprintf("Alert digest: %d fired, %d resolved", fired, resolved)
+
+	var b strings.Builder
+	const layout = "Mon Jan 2 15:04"
+	fmt.Fprintf(&b, "Alerts fired or resolved from %s to %s (%s).\n\n", start.In(end.Location()).Format(layout), end.Format(layout), end.Format("MST"))
+	if len(alerts) == 0 {
+		b.WriteString("No alerts fired or resolved.\n")
+		return subject, b.String()
+	}
+	fmt.Fprintf(&b, "%d fired, %d resolved, %d still firing.\n\n", fired, resolved, open)
+
+	rules := make([]string, 0, len(byRule))
+	for name := range byRule {
+		rules = append(rules, name)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		if byRule[rules[i]] != byRule[rules[j]] {
+			return byRule[rules[i]] > byRule[rules[j]]
+		}
+		return rules[i] < rules[j]
+	})
+	b.WriteString("By rule:\n")
+	for _, name := range rules {
+		fmt.Fprintf(&b, "  %-24s %d\n", name, byRule[name])
+	}
+
+	b.WriteString("\nAlerts:\n")
+	for i, al := range alerts {
+		if i == maxDigestAlerts {
+			fmt.Fprintf(&b, "  ... and %d more\n", len(alerts)-maxDigestAlerts)
+			break
+		}
+		state := "still firing"
+		if al.ResolvedAt != nil {
+			state = "resolved after " + formatWindow(al.ResolvedAt.Sub(al.FiredAt).Truncate(time.Second))
+		}
+		fmt.Fprintf(&b, "  %s  %s — %s\n", al.FiredAt.In(end.Location()).Format(layout), al.Message, state)
+	}
+	return subject, b.String()
+}
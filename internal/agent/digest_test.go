@@ -0,0 +1,87 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNextDigest(t *testing.T) {
+	oslo, err := time.LoadLocation("Europe/Oslo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		now  time.Time
+		at   int
+		loc  *time.Location
+		want time.Time
+	}{
+		{"later today", time.Date(2025, 1, 1, 6, 0, 0, 0, time.UTC), 8 * 60, time.UTC, time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)},
+		{"exactly now is tomorrow", time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC), 8 * 60, time.UTC, time.Date(2025, 1, 2, 8, 0, 0, 0, time.UTC)},
+		{"past today", time.Date(2025, 1, 31, 9, 0, 0, 0, time.UTC), 8 * 60, time.UTC, time.Date(2025, 2, 1, 8, 0, 0, 0, time.UTC)},
+		{"timezone", time.Date(2025, 1, 1, 6, 30, 0, 0, time.UTC), 8 * 60, oslo, time.Date(2025, 1, 1, 7, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextDigest(tt.now, tt.at, tt.loc); !got.Equal(tt.want) {
+				t.Errorf("nextDigest = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildDigest(t *testing.T) {
+	end := time.Date(2025, 1, 2, 8, 0, 0, 0, time.UTC)
+	start := end.Add(-24 * time.Hour)
+
+	subject, body := buildDigest(nil, start, end)
+	if subject != "Alert digest: 0 fired, 0 resolved" || !strings.Contains(body, "No alerts fired or resolved.") {
+		t.Errorf("empty digest = %q / %q", subject, body)
+	}
+
+	fired := time.Date(2025, 1, 1, 9, 12, 0, 0, time.UTC)
+	resolved := fired.Add(5 * time.Minute)
+	// Fired the day before, resolved in the window.
+	carried := start.Add(-2 * time.Hour)
+	carriedResolved := start.Add(time.Hour)
+	alerts := []Alert{
+		{RuleName: "high_cpu", Message: "[warning] high_cpu: host.cpu_percent", FiredAt: fired.Add(time.Hour)},
+		{RuleName: "exited", Message: "[critical] exited: container.state (web)", FiredAt: fired, ResolvedAt: &resolved},
+		{RuleName: "exited", Message: "[critical] exited: container.state (api)", FiredAt: fired, ResolvedAt: &resolved},
+		{RuleName: "disk", Message: "[warning] disk: host.disk_percent", FiredAt: carried, ResolvedAt: &carriedResolved},
+	}
+	subject, body = buildDigest(alerts, start, end)
+	if subject != "Alert digest: 3 fired, 3 resolved" {
+		t.Errorf("subject = %q", subject)
+	}
+	for _, want := range []string{
+		"Alerts fired or resolved from Wed Jan 1 08:00 to Thu Jan 2 08:00 (UTC).",
+		"3 fired, 3 resolved, 1 still firing.",
+		"  exited                   2\n  disk                     1\n  high_cpu                 1\n",
+		"Wed Jan 1 06:00  [warning] disk: host.disk_percent — resolved after 3h",
+		"Wed Jan 1 10:12  [warning] high_cpu: host.cpu_percent — still firing",
+		"Wed Jan 1 09:12  [critical] exited: container.state (web) — resolved after 5m",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestSendDigestEmailOnly(t *testing.T) {
+	alerts := map[string]AlertConfig{
+		"exited": {Condition: "container.state == 'exited'", Severity: "critical", Actions: []string{"notify"}},
+	}
+	a, _, rec := testAlerterWithRecorder(t, alerts)
+	now := time.Now()
+	if err := a.SendDigest(context.Background(), now.Add(-24*time.Hour), now); err != nil {
+		t.Fatal(err)
+	}
+	a.notifier.Flush()
+	if calls := rec.Calls(); len(calls) != 0 {
+		t.Errorf("calls = %v, want digest skipped by non-email channel", calls)
+	}
+}
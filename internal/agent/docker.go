@@ -28,6 +28,10 @@ type DockerCollector struct {
 	projectMap map[string]string
 	mu         sync.RWMutex
 
+	// Cached container-ID-to-labels mapping, rebuilt each Collect with
+	// projectMap and protected by mu.
+	labelMap map[string]map[string]string
+
 	// Cached inspect results for non-running containers to avoid redundant API calls.
 	inspectCache map[string]inspectResult
 
@@ -84,6 +88,24 @@ func (d *DockerCollector) Client() *client.Client {
 	return d.client
 }
 
+// RestartContainer restarts a container using the daemon's default stop
+// timeout. Used by docker:restart alert actions.
+func (d *DockerCollector) RestartContainer(ctx context.Context, id string) error {
+	if err := d.client.ContainerRestart(ctx, id, container.StopOptions{}); err != nil {
+		return fmt.Errorf("restart container %s: %w", id, err)
+	}
+	return nil
+}
+
+// StopContainer stops a container using the daemon's default stop timeout.
+// Used by docker:stop alert actions.
+func (d *DockerCollector) StopContainer(ctx context.Context, id string) error {
+	if err := d.client.ContainerStop(ctx, id, container.StopOptions{}); err != nil {
+		return fmt.Errorf("stop container %s: %w", id, err)
+	}
+	return nil
+}
+
 // Containers returns a copy of the most recently discovered containers.
 func (d *DockerCollector) Containers() []Container {
 	d.mu.RLock()
@@ -101,6 +123,15 @@ func (d *DockerCollector) ContainerProject(id string) string {
 	return d.projectMap[id]
 }
 
+// ContainerLabels returns the labels of a container ID from the last
+// Collect; ok is false if the container is unknown.
+func (d *DockerCollector) ContainerLabels(id string) (labels map[string]string, ok bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	labels, ok = d.labelMap[id]
+	return labels, ok
+}
+
 // SetTracking updates the runtime tracking state.
 // If project is set, all known containers in that project are toggled.
 // If name is set, that single container is toggled.
@@ -189,6 +220,7 @@ const statsWorkers = 4
 type statsWork struct {
 	id, name, image  string
 	project, service string
+	labels           map[string]string
 	ir               inspectResult
 	diskUsage        uint64
 }
@@ -206,6 +238,7 @@ func (d *DockerCollector) Collect(ctx context.Context) ([]ContainerMetrics, []Co
 	var all []Container     // for lastContainers (TUI visibility)
 	var tracked []Container // returned for log sync / alert eval
 	var pending []statsWork // running tracked containers needing stats
+	lm := make(map[string]map[string]string, len(containers))
 
 	// Phase 1: Sequential — inspect, auto-track, categorize.
 	for _, c := range containers {
@@ -248,6 +281,7 @@ func (d *DockerCollector) Collect(ctx context.Context) ([]ContainerMetrics, []Co
 			ExitCode:     ir.exitCode,
 		}
 		all = append(all, ctr)
+		lm[c.ID] = c.Labels
 
 		// Auto-track on first discovery. Manual toggles (already in map)
 		// are never overridden.
@@ -286,6 +320,7 @@ func (d *DockerCollector) Collect(ctx context.Context) ([]ContainerMetrics, []Co
 				ExitCode:     ir.exitCode,
 				CPULimit:     ir.cpuLimit,
 				DiskUsage:    diskUsage,
+				Labels:       c.Labels,
 			})
 			continue
 		}
@@ -293,7 +328,7 @@ func (d *DockerCollector) Collect(ctx context.Context) ([]ContainerMetrics, []Co
 		pending = append(pending, statsWork{
 			id: c.ID, name: name, image: image,
 			project: idProject, service: idService,
-			ir: ir, diskUsage: diskUsage,
+			labels: c.Labels, ir: ir, diskUsage: diskUsage,
 		})
 	}
 
@@ -324,6 +359,7 @@ func (d *DockerCollector) Collect(ctx context.Context) ([]ContainerMetrics, []Co
 				m.ExitCode = w.ir.exitCode
 				m.CPULimit = w.ir.cpuLimit
 				m.DiskUsage = w.diskUsage
+				m.Labels = w.labels
 				if w.ir.memLimit > 0 {
 					m.MemLimit = uint64(w.ir.memLimit)
 				} else {
@@ -345,6 +381,7 @@ func (d *DockerCollector) Collect(ctx context.Context) ([]ContainerMetrics, []Co
 	d.mu.Lock()
 	d.lastContainers = all
 	d.projectMap = pm
+	d.labelMap = lm
 	d.mu.Unlock()
 
 	// Evict stale entries for containers no longer present.
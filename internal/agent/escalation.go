@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// escalate notifies the channels of due escalation steps for firing
+// instances that nobody has acknowledged. When several steps are due at
+// once, e.g. after the agent was down, only the last one is sent. Steps held
+// back by a silence, maintenance window or inhibition are sent once that
+// ends. Called with mu held.
+func (a *Alerter) escalate(ctx context.Context, now time.Time) {
+	if a.replay != nil || a.notifier == nil {
+		return
+	}
+	for key, inst := range a.instances {
+		if inst.state != stateFiring || inst.dbID == 0 {
+			continue
+		}
+		r := a.ruleForKey(key)
+		if r == nil || inst.escalated >= len(r.escalate) {
+			continue
+		}
+		due := inst.escalated
+		for due < len(r.escalate) && !now.Before(inst.firedAt.Add(r.escalate[due].After.Duration)) {
+			due++
+		}
+		if due == inst.escalated {
+			continue
+		}
+
+		acked, err := a.store.AlertAcknowledged(ctx, inst.dbID)
+		if err != nil {
+			slog.Error("check alert acknowledged", "id", inst.dbID, "error", err)
+			continue
+		}
+		if acked {
+			// Acknowledged: no further steps for this firing.
+			a.setEscalated(ctx, inst, len(r.escalate))
+			continue
+		}
+		ec := &evalContext{rule: r, key: key, containerID: inst.containerID, project: inst.project}
+		if inst.flapping || a.isSilenced(r.name, key) || a.inMaintenance(r.name, inst.project, now) != nil || a.inhibitedBy(r.name, ec) != "" {
+			continue
+		}
+
+		step := r.escalate[due-1]
+		slog.Warn("alert escalated", "rule", r.name, "key", key, "step", due)
+		a.setEscalated(ctx, inst, due)
+		note := alertNote{
+			rule:     r.name,
+			severity: r.severity,
+			status:   "firing",
+			subject:  fmt.Sprintf("Escalation: %s unacknowledged for %s", r.name, formatWindow(step.After.Duration)),
+			body:     r.noteBody(inst.message),
+			project:  inst.project,
+			at:       now,
+			targets:  step.Channels,
+			key:      key,
+
+			containerID: inst.containerID,
+			kind:        "escalated",
+		}
+		a.deferred = append(a.deferred, func() {
+			a.notifier.Notify(note)
+		})
+	}
+}
+
+func (a *Alerter) setEscalated(ctx context.Context, inst *alertInstance, step int) {
+	inst.escalated = step
+	if err := a.store.SetEscalationStep(ctx, inst.dbID, step); err != nil {
+		slog.Error("set escalation step", "id", inst.dbID, "error", err)
+	}
+}
@@ -28,6 +28,29 @@ type EventWatcher struct {
 	done chan struct{} // closed when Run() exits
 }
 
+// eventAttrs are the attributes Docker adds to container events besides the
+// container's labels.
+var eventAttrs = map[string]bool{
+	"name": true, "image": true, "exitCode": true, "signal": true, "execID": true, "exec_duration": true,
+}
+
+// containerLabels returns the labels of container id for label selectors.
+// They come from the last Collect; a container started since then falls
+// back to the event attributes without the ones Docker adds, which also
+// drops labels that happen to share their names.
+func (ew *EventWatcher) containerLabels(id string, attrs map[string]string) map[string]string {
+	if labels, ok := ew.docker.ContainerLabels(id); ok {
+		return labels
+	}
+	labels := make(map[string]string, len(attrs))
+	for k, v := range attrs {
+		if !eventAttrs[k] {
+			labels[k] = v
+		}
+	}
+	return labels
+}
+
 // NewEventWatcher creates an EventWatcher wired to the agent's components.
 func NewEventWatcher(docker *DockerCollector, hub *Hub) *EventWatcher {
 	ew := &EventWatcher{
@@ -196,13 +219,16 @@ func (ew *EventWatcher) handleEvent(ctx context.Context, msg events.Message) {
 		ew.alerterMu.RUnlock()
 
 		if alerter != nil {
+			idProject, idService := serviceIdentity(project, service, name)
 			cm := ContainerMetrics{
 				ID:      id,
 				Name:    name,
+				Image:   image,
 				State:   state,
 				Health:  health,
-				Project: project,
-				Service: service,
+				Project: idProject,
+				Service: idService,
+				Labels:  ew.containerLabels(id, attrs),
 			}
 			if isHealth {
 				cm.State = "running"
@@ -347,7 +347,7 @@ func TestEventStateChangeTriggersAlert(t *testing.T) {
 
 	// Create an alerter with a "container exited" rule.
 	s := testStore(t)
-	n := NewNotifier(&NotifyConfig{})
+	n := NewNotifier(&NotifyConfig{}, nil)
 	alerter, err := NewAlerter(map[string]AlertConfig{
 		"exited": {
 			Condition: "container.state == 'exited'",
@@ -392,7 +392,7 @@ func TestEventHealthStatusTriggersAlert(t *testing.T) {
 	ew, _, hub, src := testEventWatcher(t, nil, nil)
 
 	s := testStore(t)
-	n := NewNotifier(&NotifyConfig{})
+	n := NewNotifier(&NotifyConfig{}, nil)
 	alerter, err := NewAlerter(map[string]AlertConfig{
 		"unhealthy": {
 			Condition: "container.health == 'unhealthy'",
@@ -460,7 +460,7 @@ func TestEventNumericAlertNotAffected(t *testing.T) {
 	ew, _, _, src := testEventWatcher(t, nil, nil)
 
 	s := testStore(t)
-	n := NewNotifier(&NotifyConfig{})
+	n := NewNotifier(&NotifyConfig{}, nil)
 	alerter, err := NewAlerter(map[string]AlertConfig{
 		"high_cpu": {
 			Condition: "container.cpu_percent > 80",
@@ -578,3 +578,19 @@ func TestEventWatcherRunReconnect(t *testing.T) {
 		t.Fatal("Run did not exit after cancel")
 	}
 }
+
+func TestEventContainerLabels(t *testing.T) {
+	ew, dc, _, _ := testEventWatcher(t, nil, nil)
+	dc.labelMap = map[string]map[string]string{"abc123": {"team": "web"}}
+	attrs := map[string]string{"name": "web", "image": "nginx", "exitCode": "1", "team": "web", "tier": "front"}
+
+	// Known containers use the labels from the last collect.
+	if got := ew.containerLabels("abc123", attrs); len(got) != 1 || got["team"] != "web" {
+		t.Errorf("cached labels = %v", got)
+	}
+	// Unknown ones fall back to the event attributes minus Docker's own.
+	got := ew.containerLabels("def456", attrs)
+	if len(got) != 2 || got["team"] != "web" || got["tier"] != "front" {
+		t.Errorf("fallback labels = %v, want team and tier only", got)
+	}
+}
@@ -0,0 +1,134 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxExecOutput caps the stdout and stderr captured per command.
+const maxExecOutput = 64 << 10
+
+// executor runs exec:PATH alert actions. Commands run without a shell, with
+// a timeout, and at most cap(sem) at a time; further runs are dropped.
+type executor struct {
+	timeout time.Duration
+	sem     chan struct{}
+	wg      sync.WaitGroup
+}
+
+func newExecutor(cfg *ExecConfig) *executor {
+	return &executor{
+		timeout: cfg.Timeout.Duration,
+		sem:     make(chan struct{}, cfg.MaxConcurrent),
+	}
+}
+
+// execEvent is the alert transition passed to a command as TORI_* variables.
+type execEvent struct {
+	rule        string
+	severity    string
+	state       string // "firing" or "resolved"
+	key         string
+	containerID string
+	project     string
+	message     string
+	alertID     int64
+	firedAt     time.Time
+}
+
+func (e *execEvent) env() []string {
+	env := []string{
+		"PATH=" + os.Getenv("PATH"),
+		"TORI_RULE=" + e.rule,
+		"TORI_SEVERITY=" + e.severity,
+		"TORI_STATE=" + e.state,
+		"TORI_INSTANCE_KEY=" + e.key,
+		"TORI_CONTAINER_ID=" + e.containerID,
+		"TORI_PROJECT=" + e.project,
+		"TORI_MESSAGE=" + e.message,
+		"TORI_ALERT_ID=" + strconv.FormatInt(e.alertID, 10),
+	}
+	if !e.firedAt.IsZero() {
+		env = append(env, "TORI_FIRED_AT="+e.firedAt.UTC().Format(time.RFC3339))
+	}
+	return env
+}
+
+// run starts path in the background. It never blocks the caller.
+func (x *executor) run(path string, ev execEvent) {
+	select {
+	case x.sem <- struct{}{}:
+	default:
+		slog.Warn("exec action dropped, too many commands running", "cmd", path, "rule", ev.rule, "key", ev.key)
+		return
+	}
+	x.wg.Add(1)
+	go func() {
+		defer x.wg.Done()
+		defer func() { <-x.sem }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), x.timeout)
+		defer cancel()
+		cmd := exec.CommandContext(ctx, path)
+		cmd.Env = ev.env()
+		cmd.Dir = "/"
+		stdout := &limitedBuffer{max: maxExecOutput}
+		stderr := &limitedBuffer{max: maxExecOutput}
+		cmd.Stdout, cmd.Stderr = stdout, stderr
+		cmd.WaitDelay = time.Second // don't hang on children holding the pipes open
+
+		start := time.Now()
+		err := cmd.Run()
+		logExecOutput(path, "stdout", stdout)
+		logExecOutput(path, "stderr", stderr)
+		switch {
+		case ctx.Err() == context.DeadlineExceeded:
+			slog.Error("exec action timed out", "cmd", path, "rule", ev.rule, "key", ev.key, "timeout", x.timeout)
+		case err != nil:
+			slog.Error("exec action failed", "cmd", path, "rule", ev.rule, "key", ev.key, "error", err)
+		default:
+			slog.Info("exec action finished", "cmd", path, "rule", ev.rule, "key", ev.key, "state", ev.state, "duration", time.Since(start).Round(time.Millisecond))
+		}
+	}()
+}
+
+// wait blocks until all running commands have exited.
+func (x *executor) wait() {
+	x.wg.Wait()
+}
+
+func logExecOutput(path, stream string, b *limitedBuffer) {
+	sc := bufio.NewScanner(bytes.NewReader(b.buf.Bytes()))
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" {
+			slog.Info("exec output", "cmd", path, "stream", stream, "line", line)
+		}
+	}
+	if b.truncated {
+		slog.Warn("exec output truncated", "cmd", path, "stream", stream, "limit", maxExecOutput)
+	}
+}
+
+// limitedBuffer keeps the first max bytes written and discards the rest.
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (l *limitedBuffer) Write(p []byte) (int, error) {
+	if room := l.max - l.buf.Len(); room < len(p) {
+		l.truncated = true
+		l.buf.Write(p[:max(room, 0)])
+		return len(p), nil
+	}
+	return l.buf.Write(p)
+}
@@ -0,0 +1,99 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeScript creates an executable shell script in a temp dir.
+func writeScript(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "hook.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExecutorEnv(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	path := writeScript(t, `echo "$TORI_RULE $TORI_STATE $TORI_INSTANCE_KEY $TORI_CONTAINER_ID $TORI_ALERT_ID" > `+out+"\n")
+
+	x := newExecutor(&ExecConfig{Timeout: Duration{5 * time.Second}, MaxConcurrent: 1})
+	x.run(path, execEvent{rule: "exited", severity: "critical", state: "firing", key: "exited:abc", containerID: "abc", alertID: 7})
+	x.wait()
+
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "exited firing exited:abc abc 7\n"; string(got) != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestExecutorTimeout(t *testing.T) {
+	path := writeScript(t, "sleep 10\n")
+	x := newExecutor(&ExecConfig{Timeout: Duration{100 * time.Millisecond}, MaxConcurrent: 1})
+	start := time.Now()
+	x.run(path, execEvent{rule: "r"})
+	x.wait()
+	if d := time.Since(start); d > 3*time.Second {
+		t.Errorf("command ran %v, want it killed at the timeout", d)
+	}
+}
+
+func TestExecutorConcurrencyLimit(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	path := writeScript(t, "echo run >> "+out+"\nsleep 0.3\n")
+	x := newExecutor(&ExecConfig{Timeout: Duration{5 * time.Second}, MaxConcurrent: 1})
+	x.run(path, execEvent{rule: "r"})
+	x.run(path, execEvent{rule: "r"}) // dropped: the first is still running
+	x.wait()
+
+	got, _ := os.ReadFile(out)
+	if n := strings.Count(string(got), "run"); n != 1 {
+		t.Errorf("runs = %d, want 1", n)
+	}
+}
+
+func TestLimitedBuffer(t *testing.T) {
+	b := &limitedBuffer{max: 5}
+	b.Write([]byte("abc"))
+	b.Write([]byte("defg"))
+	if b.buf.String() != "abcde" || !b.truncated {
+		t.Errorf("buf = %q truncated=%v", b.buf.String(), b.truncated)
+	}
+}
+
+func TestExecActionFireResolve(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	path := writeScript(t, `echo "$TORI_STATE $TORI_MESSAGE" >> `+out+"\n")
+	s := testStore(t)
+	a, err := NewAlerter(map[string]AlertConfig{
+		"cpu": {Condition: "host.cpu_percent > 90", Severity: "warning", Actions: []string{"exec:" + path}},
+	}, s, NewNotifier(&NotifyConfig{}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.SetExec(&ExecConfig{Timeout: Duration{5 * time.Second}, MaxConcurrent: 4})
+	ctx := context.Background()
+
+	a.Evaluate(ctx, &MetricSnapshot{Host: &HostMetrics{CPUPercent: 95}})
+	a.exec.wait()
+	a.Evaluate(ctx, &MetricSnapshot{Host: &HostMetrics{CPUPercent: 10}})
+	a.Stop()
+
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "firing [warning] cpu: host.cpu_percent\nresolved [warning] cpu: host.cpu_percent\n"
+	if string(got) != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
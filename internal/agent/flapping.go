@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// recordTransition notes a fire or resolve of an instance and reports whether
+// the instance just started flapping. Called with mu held.
+func (a *Alerter) recordTransition(r *alertRule, inst *alertInstance, now time.Time) bool {
+	if r.flapLimit == 0 {
+		return false
+	}
+	cutoff := now.Add(-r.flapWindow)
+	kept := inst.transitions[:0]
+	for _, t := range inst.transitions {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	inst.transitions = append(kept, now)
+	if inst.flapping || len(inst.transitions) < r.flapLimit {
+		return false
+	}
+	inst.flapping = true
+	return true
+}
+
+// checkFlapping clears the flapping state of instances that have not changed
+// state for a full window and sends one notification with the state they
+// settled in. Called with mu held.
+func (a *Alerter) checkFlapping(ctx context.Context, now time.Time) {
+	if a.replay != nil {
+		return
+	}
+	for key, inst := range a.instances {
+		if !inst.flapping || len(inst.transitions) == 0 {
+			continue
+		}
+		r := a.ruleForKey(key)
+		if r == nil || now.Sub(inst.transitions[len(inst.transitions)-1]) < r.flapWindow {
+			continue
+		}
+		inst.flapping = false
+		inst.transitions = nil
+
+		status := "resolved"
+		if inst.state == stateFiring {
+			status = "firing"
+			if inst.dbID > 0 {
+				if err := a.store.SetAlertFlapping(ctx, inst.dbID, false); err != nil {
+					slog.Error("set alert flapping", "id", inst.dbID, "error", err)
+				}
+				a.publishFiring(ctx, r, key, inst)
+			}
+		}
+		slog.Info("alert stopped flapping", "rule", r.name, "key", key, "state", status)
+		a.notifyFlapping(r, key, inst, status, now,
+			"Stable: "+r.name,
+			fmt.Sprintf("%s\nStopped flapping and is now %s.", inst.message, status))
+	}
+}
+
+// notifyFlapping queues a flapping start or end notification unless the
+// instance is silenced, in maintenance or inhibited. Called with mu held.
+func (a *Alerter) notifyFlapping(r *alertRule, key string, inst *alertInstance, status string, now time.Time, subject, body string) {
+	if a.notifier == nil || !r.notifies() || a.isSilenced(r.name, key) || inst.inhibitedBy != "" {
+		return
+	}
+	if a.inMaintenance(r.name, inst.project, now) != nil {
+		return
+	}
+	a.lastNotified[r.name] = now
+	note := alertNote{
+		rule:     r.name,
+		severity: r.severity,
+		status:   status,
+		subject:  subject,
+		body:     body,
+		project:  inst.project,
+		at:       now,
+		targets:  a.notifier.route(r.channels, r.routed, r.severity),
+		key:      key,
+		kind:     "flapping",
+	}
+	a.deferred = append(a.deferred, func() {
+		a.notifier.Notify(note)
+	})
+}
+
+// flappingBody explains why notifications stop for a flapping instance.
+func flappingBody(r *alertRule, msg string) string {
+	return fmt.Sprintf("%s\nChanged state %d times within %s; further notifications are suppressed until it is stable for %s.",
+		msg, r.flapLimit, formatWindow(r.flapWindow), formatWindow(r.flapWindow))
+}
@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// heartbeatPayload is the JSON body of a heartbeat ping.
+type heartbeatPayload struct {
+	Agent         string `json:"agent"` // always "tori"
+	Version       string `json:"version"`
+	Hostname      string `json:"hostname"`
+	StartedAt     string `json:"started_at"` // RFC 3339
+	UptimeSeconds int64  `json:"uptime_seconds"`
+	FiringAlerts  int    `json:"firing_alerts"`
+	Time          string `json:"time"` // RFC 3339
+}
+
+// sendHeartbeat POSTs one heartbeat ping. Failures are not retried; the
+// next ping follows one interval later anyway.
+func sendHeartbeat(ctx context.Context, cfg *HeartbeatConfig, p heartbeatPayload) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range cfg.Headers {
+		req.Header.Set(sanitizeHeader(k), sanitizeHeader(v))
+	}
+	if req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("heartbeat returned %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSendHeartbeat(t *testing.T) {
+	var got heartbeatPayload
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := &HeartbeatConfig{Enabled: true, URL: srv.URL, Headers: map[string]string{"Authorization": "Bearer x"}}
+	want := heartbeatPayload{Agent: "tori", Version: "1.2.3", Hostname: "web1", UptimeSeconds: 90, FiringAlerts: 2}
+	if err := sendHeartbeat(context.Background(), cfg, want); err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("payload = %+v, want %+v", got, want)
+	}
+	if auth != "Bearer x" {
+		t.Errorf("authorization = %q", auth)
+	}
+}
+
+func TestSendHeartbeatError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	if err := sendHeartbeat(context.Background(), &HeartbeatConfig{URL: srv.URL}, heartbeatPayload{}); err == nil {
+		t.Error("expected error for 503")
+	}
+}
@@ -0,0 +1,161 @@
+package agent
+
+import (
+	"strings"
+	"time"
+)
+
+// sample is one observation of a metric field.
+type sample struct {
+	t time.Time
+	v float64
+}
+
+// seriesKey identifies one metric series: a subject ("host",
+// "disk:<mountpoint>", "container:<id>" or "net:<iface>") and a
+// "scope.field" target.
+type seriesKey struct {
+	subject string
+	target  string
+}
+
+// metricHistory keeps recent samples for the fields referenced by aggregate
+// conditions such as avg(host.cpu_percent, 5m). Only fields that some rule
+// aggregates are recorded, and each is kept for the longest window any rule
+// asks for. Not safe for concurrent use; the Alerter guards it with its mutex.
+type metricHistory struct {
+	windows map[string]time.Duration // "scope.field" -> longest window
+	series  map[seriesKey][]sample   // oldest first
+}
+
+func newMetricHistory() *metricHistory {
+	return &metricHistory{
+		windows: make(map[string]time.Duration),
+		series:  make(map[seriesKey][]sample),
+	}
+}
+
+// track registers a field so record keeps at least window of its samples.
+func (h *metricHistory) track(target string, window time.Duration) {
+	if window > h.windows[target] {
+		h.windows[target] = window
+	}
+}
+
+// record appends the snapshot's values and the current interface rates for
+// every tracked field, and drops samples that have fallen out of their window.
+func (h *metricHistory) record(snap *MetricSnapshot, nets map[string]*netRates, now time.Time) {
+	for target := range h.windows {
+		scope, field, _ := strings.Cut(target, ".")
+		switch {
+		case scope == "host" && field == "disk_percent":
+			for i := range snap.Disks {
+				h.add("disk:"+snap.Disks[i].Mountpoint, target, snap.Disks[i].Percent, now)
+			}
+		case scope == "host":
+			if snap.Host != nil {
+				h.add("host", target, hostFieldValue(snap.Host, field), now)
+			}
+		case scope == "container":
+			for i := range snap.Containers {
+				c := &snap.Containers[i]
+				h.add("container:"+c.ID, target, containerFieldNum(c, field), now)
+			}
+		case scope == "net":
+			for iface, r := range nets {
+				h.add("net:"+iface, target, netFieldValue(r, field), now)
+			}
+		}
+	}
+	h.prune(now)
+}
+
+func (h *metricHistory) add(subject, target string, v float64, now time.Time) {
+	k := seriesKey{subject, target}
+	h.series[k] = append(h.series[k], sample{now, v})
+}
+
+// prune drops samples older than their field's window and forgets series
+// with nothing left (removed containers and disks).
+func (h *metricHistory) prune(now time.Time) {
+	for k, samples := range h.series {
+		cutoff := now.Add(-h.windows[k.target])
+		i := 0
+		for i < len(samples) && samples[i].t.Before(cutoff) {
+			i++
+		}
+		if i == len(samples) {
+			delete(h.series, k)
+		} else if i > 0 {
+			h.series[k] = samples[i:]
+		}
+	}
+}
+
+// covers reports whether samples span window, so that right after a start,
+// reload or new container a few samples don't stand in for it. n samples one
+// interval apart cover n intervals; the interval is estimated from their
+// spacing, so a single sample covers nothing.
+func covers(samples []sample, window time.Duration) bool {
+	if len(samples) < 2 {
+		return false
+	}
+	span := samples[len(samples)-1].t.Sub(samples[0].t)
+	return span+span/time.Duration(len(samples)-1) >= window
+}
+
+// aggregate applies fn to the samples of one series that fall within window
+// of its newest sample. ok is false when there is not enough data: fewer than
+// two samples for rate and increase, and for avg, min and max, samples that
+// don't yet cover the whole window (see covers). A partial window is fine for
+// rate and increase, which can only grow as it fills.
+func (h *metricHistory) aggregate(subject, target, fn string, window time.Duration) (v float64, ok bool) {
+	samples := h.series[seriesKey{subject, target}]
+	if len(samples) == 0 {
+		return 0, false
+	}
+	if (fn == "avg" || fn == "min" || fn == "max") && !covers(samples, window) {
+		return 0, false
+	}
+	cutoff := samples[len(samples)-1].t.Add(-window)
+	for len(samples) > 0 && samples[0].t.Before(cutoff) {
+		samples = samples[1:]
+	}
+
+	switch fn {
+	case "avg":
+		var sum float64
+		for _, s := range samples {
+			sum += s.v
+		}
+		return sum / float64(len(samples)), true
+	case "min", "max":
+		v = samples[0].v
+		for _, s := range samples[1:] {
+			if fn == "min" && s.v < v || fn == "max" && s.v > v {
+				v = s.v
+			}
+		}
+		return v, true
+	case "increase", "rate":
+		if len(samples) < 2 {
+			return 0, false
+		}
+		for i := 1; i < len(samples); i++ {
+			if d := samples[i].v - samples[i-1].v; d >= 0 {
+				v += d
+			} else {
+				v += samples[i].v // counter reset
+			}
+		}
+		if fn == "rate" {
+			secs := samples[len(samples)-1].t.Sub(samples[0].t).Seconds()
+			if secs <= 0 {
+				return 0, false
+			}
+			v /= secs
+		}
+		return v, true
+	}
+	return 0, false
+}
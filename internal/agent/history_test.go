@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestMetricHistoryAggregate(t *testing.T) {
+	h := newMetricHistory()
+	h.track("container.restart_count", time.Minute)
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// 5, 7, reset to 1, 2 — every 20s.
+	for i, v := range []int{5, 7, 1, 2} {
+		h.record(&MetricSnapshot{Containers: []ContainerMetrics{{ID: "aaa", RestartCount: v}}}, nil, t0.Add(time.Duration(i)*20*time.Second))
+	}
+
+	tests := []struct {
+		fn     string
+		window time.Duration
+		want   float64
+		ok     bool
+	}{
+		{"avg", time.Minute, 3.75, true},
+		{"min", time.Minute, 1, true},
+		{"max", time.Minute, 7, true},
+		{"max", 20 * time.Second, 2, true},
+		{"increase", time.Minute, 2 + 1 + 1, true}, // 5→7, reset to 1, 1→2
+		{"rate", time.Minute, 4.0 / 60, true},
+		{"increase", 10 * time.Second, 0, false}, // single sample
+	}
+	for _, tt := range tests {
+		got, ok := h.aggregate("container:aaa", "container.restart_count", tt.fn, tt.window)
+		if ok != tt.ok || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s over %s = %v, %v; want %v, %v", tt.fn, tt.window, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	if _, ok := h.aggregate("container:bbb", "container.restart_count", "avg", time.Minute); ok {
+		t.Error("unknown subject should have no value")
+	}
+}
+
+func TestMetricHistoryWarmUp(t *testing.T) {
+	h := newMetricHistory()
+	h.track("host.cpu_percent", 5*time.Minute)
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// A spike right after startup must not stand in for a 5m average.
+	for i := 0; i < 30; i++ {
+		h.record(&MetricSnapshot{Host: &HostMetrics{CPUPercent: 95}}, nil, t0.Add(time.Duration(i)*10*time.Second))
+		v, ok := h.aggregate("host", "host.cpu_percent", "avg", 5*time.Minute)
+		if i < 29 && ok {
+			t.Fatalf("after %d samples: avg = %v, want no value until the window is covered", i+1, v)
+		}
+		if i == 29 && (!ok || v != 95) {
+			t.Fatalf("after 30 samples: avg = %v, %v; want 95, true", v, ok)
+		}
+	}
+	if _, ok := h.aggregate("host", "host.cpu_percent", "max", time.Minute); !ok {
+		t.Error("shorter window should be covered")
+	}
+}
+
+func TestMetricHistoryRecordsOnlyTrackedFields(t *testing.T) {
+	h := newMetricHistory()
+	h.track("host.cpu_percent", 5*time.Minute)
+	h.track("host.cpu_percent", time.Minute) // shorter window does not shrink retention
+	h.track("host.disk_percent", time.Minute)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := range 2 {
+		h.record(&MetricSnapshot{
+			Host:       &HostMetrics{CPUPercent: 10, MemPercent: 20},
+			Disks:      []DiskMetrics{{Mountpoint: "/", Percent: 50}, {Mountpoint: "/data", Percent: 70}},
+			Containers: []ContainerMetrics{{ID: "aaa", CPUPercent: 5}},
+		}, nil, now.Add(time.Duration(i)*30*time.Second))
+	}
+
+	if len(h.series) != 3 {
+		t.Errorf("series = %d, want 3 (host cpu + 2 disks)", len(h.series))
+	}
+	if h.windows["host.cpu_percent"] != 5*time.Minute {
+		t.Errorf("window = %s, want 5m", h.windows["host.cpu_percent"])
+	}
+	if v, ok := h.aggregate("disk:/data", "host.disk_percent", "avg", time.Minute); !ok || v != 70 {
+		t.Errorf("disk avg = %v, %v", v, ok)
+	}
+}
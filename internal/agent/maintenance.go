@@ -0,0 +1,273 @@
+package agent
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // timezone names must resolve in minimal container images
+)
+
+// maxMaintenanceDuration bounds cron-scheduled windows so activeAt only has
+// to look back a bounded number of minutes.
+const maxMaintenanceDuration = 7 * 24 * time.Hour
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// maintenanceWindow is a recurring period during which matching alerts are
+// recorded but not notified. It is either a cron schedule with a duration,
+// or a daily start-end time range limited to some weekdays.
+type maintenanceWindow struct {
+	name     string
+	schedule string // original schedule text, for display
+	loc      *time.Location
+
+	cron *cronSchedule // nil for time-range windows
+	dur  time.Duration
+
+	days       [7]bool // weekday the range starts on
+	start, end int     // minutes since midnight; end <= start wraps past midnight
+
+	rules    map[string]bool // empty = all rules
+	projects map[string]bool // empty = all instances
+}
+
+func newMaintenanceWindow(mc *MaintenanceConfig) (*maintenanceWindow, error) {
+	if mc.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	w := &maintenanceWindow{name: mc.Name, loc: time.Local}
+	if mc.Timezone != "" {
+		loc, err := time.LoadLocation(mc.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone %q", mc.Timezone)
+		}
+		w.loc = loc
+	}
+
+	hasRange := mc.Start != "" || mc.End != "" || len(mc.Days) > 0
+	switch {
+	case mc.Schedule != "" && hasRange:
+		return nil, fmt.Errorf("schedule cannot be combined with days, start and end")
+	case mc.Schedule != "":
+		cs, err := parseCron(mc.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("schedule: %w", err)
+		}
+		if mc.Duration.Duration < time.Minute || mc.Duration.Duration > maxMaintenanceDuration {
+			return nil, fmt.Errorf("duration must be between 1m and %s for schedule windows", formatWindow(maxMaintenanceDuration))
+		}
+		w.cron = cs
+		w.dur = mc.Duration.Duration
+		w.schedule = fmt.Sprintf("%s for %s", mc.Schedule, formatWindow(w.dur))
+	case mc.Start != "" && mc.End != "":
+		if mc.Duration.Duration != 0 {
+			return nil, fmt.Errorf("duration is only valid with schedule")
+		}
+		var err error
+		if w.start, err = parseClock(mc.Start); err != nil {
+			return nil, fmt.Errorf("start: %w", err)
+		}
+		if w.end, err = parseClock(mc.End); err != nil {
+			return nil, fmt.Errorf("end: %w", err)
+		}
+		if w.start == w.end {
+			return nil, fmt.Errorf("start and end must differ")
+		}
+		if len(mc.Days) == 0 {
+			w.days = [7]bool{true, true, true, true, true, true, true}
+		}
+		for _, d := range mc.Days {
+			wd, ok := weekdayNames[strings.ToLower(d)]
+			if !ok {
+				return nil, fmt.Errorf("invalid day %q (must be mon, tue, wed, thu, fri, sat or sun)", d)
+			}
+			w.days[wd] = true
+		}
+		days := "daily"
+		if len(mc.Days) > 0 {
+			days = strings.ToLower(strings.Join(mc.Days, ","))
+		}
+		w.schedule = fmt.Sprintf("%s %s-%s", days, mc.Start, mc.End)
+	default:
+		return nil, fmt.Errorf("either schedule and duration, or start and end, are required")
+	}
+	if mc.Timezone != "" {
+		w.schedule += " " + mc.Timezone
+	}
+
+	if len(mc.Rules) > 0 {
+		w.rules = make(map[string]bool, len(mc.Rules))
+		for _, r := range mc.Rules {
+			w.rules[r] = true
+		}
+	}
+	if len(mc.Projects) > 0 {
+		w.projects = make(map[string]bool, len(mc.Projects))
+		for _, p := range mc.Projects {
+			w.projects[p] = true
+		}
+	}
+	return w, nil
+}
+
+// covers reports whether the window applies to an instance of ruleName.
+// project is the compose project of the instance's container, "" for host,
+// disk and net instances; a window limited to projects never covers those.
+func (w *maintenanceWindow) covers(ruleName, project string) bool {
+	if len(w.rules) > 0 && !w.rules[ruleName] {
+		return false
+	}
+	return len(w.projects) == 0 || w.projects[project]
+}
+
+// activeAt reports whether t falls inside an occurrence of the window and,
+// if so, when that occurrence ends.
+func (w *maintenanceWindow) activeAt(t time.Time) (until time.Time, ok bool) {
+	t = t.In(w.loc)
+	if w.cron != nil {
+		// Find the latest start within dur before t.
+		m := t.Truncate(time.Minute)
+		for back := time.Duration(0); back < w.dur; back += time.Minute {
+			start := m.Add(-back)
+			if w.cron.matches(start) {
+				if end := start.Add(w.dur); t.Before(end) {
+					return end, true
+				}
+				return time.Time{}, false
+			}
+		}
+		return time.Time{}, false
+	}
+
+	y, mo, d := t.Date()
+	now := t.Hour()*60 + t.Minute()
+	at := func(day, minutes int) time.Time {
+		return time.Date(y, mo, d+day, 0, minutes, 0, 0, w.loc)
+	}
+	if w.start < w.end {
+		if w.days[t.Weekday()] && now >= w.start && now < w.end {
+			return at(0, w.end), true
+		}
+		return time.Time{}, false
+	}
+	// Wraps past midnight: started today, or started yesterday and not over yet.
+	if w.days[t.Weekday()] && now >= w.start {
+		return at(1, w.end), true
+	}
+	if w.days[(t.Weekday()+6)%7] && now < w.end {
+		return at(0, w.end), true
+	}
+	return time.Time{}, false
+}
+
+// parseClock parses "HH:MM" into minutes since midnight.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (must be HH:MM)", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// cronSchedule is a parsed five-field cron expression:
+// minute hour day-of-month month day-of-week.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64 // bit i set = value i allowed
+	domAny, dowAny                bool   // field was "*"
+}
+
+var cronFields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7}, // 0 and 7 are both Sunday
+}
+
+// parseCron parses a standard five-field cron expression. Each field accepts
+// "*", numbers, ranges ("1-5"), lists ("1,3,5") and steps ("*/15", "0-30/10").
+func parseCron(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields (minute hour day-of-month month day-of-week), got %d", len(fields))
+	}
+	var bits [5]uint64
+	for i, f := range fields {
+		b, err := parseCronField(f, cronFields[i].min, cronFields[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", cronFields[i].name, err)
+		}
+		bits[i] = b
+	}
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1 // 7 = Sunday
+	}
+	return &cronSchedule{
+		minute: bits[0], hour: bits[1], dom: bits[2], month: bits[3], dow: bits[4],
+		domAny: fields[2] == "*", dowAny: fields[4] == "*",
+	}, nil
+}
+
+func parseCronField(f string, lo, hi int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(f, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step %q", stepStr)
+			}
+			step = n
+		}
+		start, end := lo, hi
+		if rng != "*" {
+			a, b, isRange := strings.Cut(rng, "-")
+			var err error
+			if start, err = strconv.Atoi(a); err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			end = start
+			if isRange {
+				if end, err = strconv.Atoi(b); err != nil {
+					return 0, fmt.Errorf("invalid value %q", part)
+				}
+			} else if hasStep {
+				end = hi
+			}
+		}
+		if start < lo || end > hi || start > end {
+			return 0, fmt.Errorf("value %q out of range %d-%d", part, lo, hi)
+		}
+		for v := start; v <= end; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+// matches reports whether t (in the schedule's location) is a start minute.
+// As in cron, when both day fields are restricted either may match.
+func (c *cronSchedule) matches(t time.Time) bool {
+	if c.minute&(1<<t.Minute()) == 0 || c.hour&(1<<t.Hour()) == 0 || c.month&(1<<int(t.Month())) == 0 {
+		return false
+	}
+	domOK := c.dom&(1<<t.Day()) != 0
+	dowOK := c.dow&(1<<int(t.Weekday())) != 0
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dowOK
+	case c.dowAny:
+		return domOK
+	}
+	return domOK || dowOK
+}
@@ -0,0 +1,168 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		expr    string
+		at      time.Time
+		want    bool
+		wantErr string
+	}{
+		// 2025-01-05 is a Sunday.
+		{expr: "0 2 * * 0", at: time.Date(2025, 1, 5, 2, 0, 0, 0, time.UTC), want: true},
+		{expr: "0 2 * * 7", at: time.Date(2025, 1, 5, 2, 0, 0, 0, time.UTC), want: true},
+		{expr: "0 2 * * 1-5", at: time.Date(2025, 1, 5, 2, 0, 0, 0, time.UTC), want: false},
+		{expr: "*/15 * * * *", at: time.Date(2025, 1, 5, 9, 45, 0, 0, time.UTC), want: true},
+		{expr: "*/15 * * * *", at: time.Date(2025, 1, 5, 9, 46, 0, 0, time.UTC), want: false},
+		{expr: "0-30/10 3 * * *", at: time.Date(2025, 1, 5, 3, 20, 0, 0, time.UTC), want: true},
+		{expr: "0 0 1,15 * *", at: time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC), want: true},
+		{expr: "0 0 * 2 *", at: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), want: false},
+		// Both day fields restricted: either may match.
+		{expr: "0 0 1 * 0", at: time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC), want: true},
+		{expr: "0 0 1 * 0", at: time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC), want: false},
+
+		{expr: "0 2 * *", wantErr: "expected 5 fields"},
+		{expr: "60 2 * * *", wantErr: "minute: value \"60\" out of range"},
+		{expr: "0 2 0 * *", wantErr: "day of month"},
+		{expr: "0 2 * * mon", wantErr: "invalid value"},
+		{expr: "*/0 * * * *", wantErr: "invalid step"},
+		{expr: "5-1 * * * *", wantErr: "out of range"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			cs, err := parseCron(tt.expr)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := cs.matches(tt.at); got != tt.want {
+				t.Errorf("matches(%v) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaintenanceWindowActiveAt(t *testing.T) {
+	utc := func(day, hour, min int) time.Time {
+		return time.Date(2025, 1, day, hour, min, 0, 0, time.UTC) // Jan 5 2025 is a Sunday
+	}
+	tests := []struct {
+		name      string
+		cfg       MaintenanceConfig
+		at        time.Time
+		wantOK    bool
+		wantUntil time.Time
+	}{
+		{
+			name:      "range inside",
+			cfg:       MaintenanceConfig{Start: "02:00", End: "04:00", Timezone: "UTC"},
+			at:        utc(6, 3, 30),
+			wantOK:    true,
+			wantUntil: utc(6, 4, 0),
+		},
+		{
+			name: "range end is exclusive",
+			cfg:  MaintenanceConfig{Start: "02:00", End: "04:00", Timezone: "UTC"},
+			at:   utc(6, 4, 0),
+		},
+		{
+			name: "range on other day",
+			cfg:  MaintenanceConfig{Days: []string{"sun"}, Start: "02:00", End: "04:00", Timezone: "UTC"},
+			at:   utc(6, 3, 0),
+		},
+		{
+			name:      "wrap before midnight",
+			cfg:       MaintenanceConfig{Days: []string{"Sat"}, Start: "22:00", End: "02:00", Timezone: "UTC"},
+			at:        utc(4, 23, 0),
+			wantOK:    true,
+			wantUntil: utc(5, 2, 0),
+		},
+		{
+			name:      "wrap after midnight belongs to start day",
+			cfg:       MaintenanceConfig{Days: []string{"sat"}, Start: "22:00", End: "02:00", Timezone: "UTC"},
+			at:        utc(5, 1, 0),
+			wantOK:    true,
+			wantUntil: utc(5, 2, 0),
+		},
+		{
+			name: "wrap after midnight on wrong day",
+			cfg:  MaintenanceConfig{Days: []string{"sun"}, Start: "22:00", End: "02:00", Timezone: "UTC"},
+			at:   utc(5, 1, 0),
+		},
+		{
+			name:      "timezone",
+			cfg:       MaintenanceConfig{Start: "02:00", End: "04:00", Timezone: "Europe/Oslo"},
+			at:        utc(6, 1, 30), // 02:30 in Oslo
+			wantOK:    true,
+			wantUntil: utc(6, 3, 0),
+		},
+		{
+			name:      "cron occurrence",
+			cfg:       MaintenanceConfig{Schedule: "30 1 * * 0", Duration: Duration{2 * time.Hour}, Timezone: "UTC"},
+			at:        utc(5, 3, 29),
+			wantOK:    true,
+			wantUntil: utc(5, 3, 30),
+		},
+		{
+			name: "cron after occurrence",
+			cfg:  MaintenanceConfig{Schedule: "30 1 * * 0", Duration: Duration{2 * time.Hour}, Timezone: "UTC"},
+			at:   utc(5, 3, 30),
+		},
+		{
+			name:      "cron across midnight",
+			cfg:       MaintenanceConfig{Schedule: "0 23 * * *", Duration: Duration{3 * time.Hour}, Timezone: "UTC"},
+			at:        utc(7, 0, 15),
+			wantOK:    true,
+			wantUntil: utc(7, 2, 0),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Name = "w"
+			w, err := newMaintenanceWindow(&tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			until, ok := w.activeAt(tt.at)
+			if ok != tt.wantOK {
+				t.Fatalf("activeAt(%v) ok = %v, want %v", tt.at, ok, tt.wantOK)
+			}
+			if ok && !until.Equal(tt.wantUntil) {
+				t.Errorf("until = %v, want %v", until, tt.wantUntil)
+			}
+		})
+	}
+}
+
+func TestMaintenanceWindowCovers(t *testing.T) {
+	w, err := newMaintenanceWindow(&MaintenanceConfig{
+		Name: "w", Start: "02:00", End: "04:00", Rules: []string{"exited"}, Projects: []string{"shop"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		rule, project string
+		want          bool
+	}{
+		{"exited", "shop", true},
+		{"exited", "blog", false},
+		{"exited", "", false},
+		{"high_cpu", "shop", false},
+	}
+	for _, tt := range tests {
+		if got := w.covers(tt.rule, tt.project); got != tt.want {
+			t.Errorf("covers(%q, %q) = %v, want %v", tt.rule, tt.project, got, tt.want)
+		}
+	}
+}
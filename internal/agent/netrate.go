@@ -0,0 +1,140 @@
+package agent
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// netRates holds per-second rates for one interface, derived from two
+// consecutive NetMetrics readings.
+type netRates struct {
+	Iface           string
+	RxBytesPerSec   float64
+	TxBytesPerSec   float64
+	RxPacketsPerSec float64
+	TxPacketsPerSec float64
+	RxErrorsPerSec  float64
+	TxErrorsPerSec  float64
+}
+
+type netReading struct {
+	t time.Time
+	m NetMetrics
+}
+
+// netRateCalc turns cumulative interface counters into rates by diffing
+// against the previous reading of each interface.
+type netRateCalc struct {
+	prev map[string]netReading
+}
+
+func newNetRateCalc() *netRateCalc {
+	return &netRateCalc{prev: make(map[string]netReading)}
+}
+
+// update records nets and returns rates keyed by interface. Interfaces seen
+// for the first time, or whose counters went backwards (driver reset, counter
+// wrap), have no entry until the next reading.
+func (n *netRateCalc) update(nets []NetMetrics, now time.Time) map[string]*netRates {
+	rates := make(map[string]*netRates, len(nets))
+	present := make(map[string]bool, len(nets))
+	for _, m := range nets {
+		present[m.Iface] = true
+		p, ok := n.prev[m.Iface]
+		n.prev[m.Iface] = netReading{now, m}
+		if !ok {
+			continue
+		}
+		dt := now.Sub(p.t).Seconds()
+		if dt <= 0 || m.RxBytes < p.m.RxBytes || m.TxBytes < p.m.TxBytes ||
+			m.RxPackets < p.m.RxPackets || m.TxPackets < p.m.TxPackets ||
+			m.RxErrors < p.m.RxErrors || m.TxErrors < p.m.TxErrors {
+			continue
+		}
+		rates[m.Iface] = &netRates{
+			Iface:           m.Iface,
+			RxBytesPerSec:   float64(m.RxBytes-p.m.RxBytes) / dt,
+			TxBytesPerSec:   float64(m.TxBytes-p.m.TxBytes) / dt,
+			RxPacketsPerSec: float64(m.RxPackets-p.m.RxPackets) / dt,
+			TxPacketsPerSec: float64(m.TxPackets-p.m.TxPackets) / dt,
+			RxErrorsPerSec:  float64(m.RxErrors-p.m.RxErrors) / dt,
+			TxErrorsPerSec:  float64(m.TxErrors-p.m.TxErrors) / dt,
+		}
+	}
+	for iface := range n.prev {
+		if !present[iface] {
+			delete(n.prev, iface)
+		}
+	}
+	return rates
+}
+
+func netFieldValue(r *netRates, field string) float64 {
+	switch field {
+	case "rx_bytes_per_sec":
+		return r.RxBytesPerSec
+	case "tx_bytes_per_sec":
+		return r.TxBytesPerSec
+	case "rx_packets_per_sec":
+		return r.RxPacketsPerSec
+	case "tx_packets_per_sec":
+		return r.TxPacketsPerSec
+	case "rx_errors_per_sec":
+		return r.RxErrorsPerSec
+	case "tx_errors_per_sec":
+		return r.TxErrorsPerSec
+	}
+	return 0
+}
+
+// ifaceFilter restricts a net rule to a subset of interfaces. Zero value
+// matches every interface.
+type ifaceFilter struct {
+	include []string // globs; empty = all
+	exclude []string // globs; checked after include
+}
+
+func newIfaceFilter(ac *AlertConfig) ifaceFilter {
+	return ifaceFilter{include: ac.Interfaces, exclude: ac.ExcludeInterfaces}
+}
+
+func (f *ifaceFilter) matches(iface string) bool {
+	if len(f.include) > 0 && !matchAny(f.include, iface) {
+		return false
+	}
+	return !matchAny(f.exclude, iface)
+}
+
+func matchAny(patterns []string, s string) bool {
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// String renders the filter like a container selector, e.g.
+// "interfaces=eth*,ens* exclude=veth*". Empty for the zero value.
+func (f *ifaceFilter) String() string {
+	var parts []string
+	if len(f.include) > 0 {
+		parts = append(parts, "interfaces="+strings.Join(f.include, ","))
+	}
+	if len(f.exclude) > 0 {
+		parts = append(parts, "exclude="+strings.Join(f.exclude, ","))
+	}
+	return strings.Join(parts, " ")
+}
+
+// validateIfaceFilter checks glob syntax of the interface patterns.
+func validateIfaceFilter(ac *AlertConfig) error {
+	for _, p := range append(append([]string{}, ac.Interfaces...), ac.ExcludeInterfaces...) {
+		if _, err := filepath.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid interface pattern %q: %w", p, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNetRateCalc(t *testing.T) {
+	n := newNetRateCalc()
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	rates := n.update([]NetMetrics{{Iface: "eth0", RxBytes: 1000, TxBytes: 500}}, t0)
+	if len(rates) != 0 {
+		t.Fatalf("first reading should have no rates, got %v", rates)
+	}
+
+	rates = n.update([]NetMetrics{
+		{Iface: "eth0", RxBytes: 11000, TxBytes: 2500, RxErrors: 20},
+		{Iface: "eth1", RxBytes: 5},
+	}, t0.Add(10*time.Second))
+	r := rates["eth0"]
+	if r == nil {
+		t.Fatal("expected eth0 rates")
+	}
+	if r.RxBytesPerSec != 1000 || r.TxBytesPerSec != 200 || r.RxErrorsPerSec != 2 {
+		t.Errorf("rates = %+v", r)
+	}
+	if _, ok := rates["eth1"]; ok {
+		t.Error("eth1 is new and should have no rate")
+	}
+
+	// Counter reset: no rate this cycle, new baseline for the next.
+	rates = n.update([]NetMetrics{{Iface: "eth0", RxBytes: 100}}, t0.Add(20*time.Second))
+	if _, ok := rates["eth0"]; ok {
+		t.Error("counter reset should yield no rate")
+	}
+	rates = n.update([]NetMetrics{{Iface: "eth0", RxBytes: 600}}, t0.Add(30*time.Second))
+	if r := rates["eth0"]; r == nil || r.RxBytesPerSec != 50 {
+		t.Errorf("rate after reset = %+v", r)
+	}
+
+	// Interfaces that disappear are forgotten.
+	if _, ok := n.prev["eth1"]; ok {
+		t.Error("eth1 should have been dropped")
+	}
+}
+
+func TestIfaceFilter(t *testing.T) {
+	tests := []struct {
+		f     ifaceFilter
+		iface string
+		want  bool
+	}{
+		{ifaceFilter{}, "eth0", true},
+		{ifaceFilter{include: []string{"eth*", "ens*"}}, "ens3", true},
+		{ifaceFilter{include: []string{"eth*"}}, "wlan0", false},
+		{ifaceFilter{exclude: []string{"veth*", "docker0"}}, "veth12ab", false},
+		{ifaceFilter{exclude: []string{"veth*", "docker0"}}, "eth0", true},
+		{ifaceFilter{include: []string{"*"}, exclude: []string{"br-*"}}, "br-1234", false},
+	}
+	for _, tt := range tests {
+		if got := tt.f.matches(tt.iface); got != tt.want {
+			t.Errorf("%+v.matches(%q) = %v, want %v", tt.f, tt.iface, got, tt.want)
+		}
+	}
+
+	f := ifaceFilter{include: []string{"eth*", "ens*"}, exclude: []string{"veth*"}}
+	if got := f.String(); got != "interfaces=eth*,ens* exclude=veth*" {
+		t.Errorf("String() = %q", got)
+	}
+}
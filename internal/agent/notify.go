@@ -5,17 +5,22 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
+	htmltemplate "html/template"
 	"io"
 	"log/slog"
 	"net"
 	"net/http"
 	"net/smtp"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"text/template"
 	"time"
+
+	"github.com/thobiasn/tori-cli/webhook"
 )
 
 // webhookClient is a dedicated HTTP client for webhook notifications.
@@ -39,8 +44,30 @@ type Channel interface {
 type notification struct {
 	subject  string
 	body     string
-	severity string // "warning", "critical", or "" for non-alert notifications
-	status   string // "firing", "resolved", or "" for non-alert notifications
+	severity string      // "warning", "critical", or "" for non-alert notifications
+	status   string      // "firing", "resolved", or "" for non-alert notifications
+	alerts   []alertNote // alert transitions covered; several when grouped
+	digest   bool        // daily digest, delivered by email only
+	targets  []string    // channel names to deliver to; nil = every channel
+	incident bool        // resolve or acknowledge event, delivered to incident channels only
+	delivery string      // queued delivery being sent, "" when not queued
+}
+
+// alertNote is a single alert transition handed to the Notifier.
+type alertNote struct {
+	rule     string
+	severity string
+	status   string
+	subject  string
+	body     string
+	project  string // compose project, "" for host, disk and net alerts
+	at       time.Time
+	targets  []string // channel names, see Notifier.route
+	key      string   // instance key; incident channels dedup on it
+
+	containerID string       // container the alert is about, "" for other scopes
+	metrics     []noteMetric // the instance's values when it fired, for emails
+	kind        string       // "flapping", "escalated" or "remediation"; "" for a state transition
 }
 
 // Notifier sends alert notifications via configured channels.
@@ -48,34 +75,128 @@ type notification struct {
 // collect loop when channels are slow or unreachable.
 type Notifier struct {
 	channels []Channel
+	names    []string            // channel names, parallel to channels
+	routes   map[string][]string // severity → channel names, from notify.routes
+	group    *notifyGrouper      // nil = grouping disabled
 	queue    chan notification
+	incident bool           // an incident channel is enabled
 	wg       sync.WaitGroup // tracks run goroutine
 	pending  sync.WaitGroup // tracks queued-but-unprocessed items
 	stopOnce sync.Once
+
+	quiet []*quietWindow // from notify.quiet_hours
+
+	// Durable queue, used when created with a store: notifications are
+	// written to the notifications table and delivered from there by
+	// runQueue, retrying for hours. The in-memory queue above remains as a
+	// fallback for when the write fails.
+	store    *Store
+	wake     chan struct{}      // nudges runQueue after an enqueue
+	flushReq chan chan struct{} // see Flush
+	quit     chan struct{}      // closed by Stop
+	now      func() time.Time
 }
 
 // NewNotifier creates a Notifier from config. Safe to call with zero-value config;
 // Send becomes a no-op if no channels are enabled. If channels are configured,
 // a background goroutine is started to process the queue — call Stop to shut it down.
-func NewNotifier(cfg *NotifyConfig) *Notifier {
+// With a non-nil store, notifications are queued durably in it (see runQueue).
+func NewNotifier(cfg *NotifyConfig, store *Store) *Notifier {
 	var channels []Channel
+	var names []string
 	if cfg.Email.Enabled {
-		channels = append(channels, &emailChannel{cfg: cfg.Email})
+		channels = append(channels, newEmailChannel(cfg.Email, store))
+		names = append(names, cfg.Email.Name)
 	}
 	for i := range cfg.Webhooks {
 		wh := &cfg.Webhooks[i]
 		if wh.Enabled {
 			channels = append(channels, newWebhookChannel(*wh))
+			names = append(names, wh.Name)
+		}
+	}
+	for _, c := range cfg.Ntfy {
+		if c.Enabled {
+			channels = append(channels, &ntfyChannel{cfg: c})
+			names = append(names, c.Name)
+		}
+	}
+	for _, c := range cfg.Gotify {
+		if c.Enabled {
+			channels = append(channels, &gotifyChannel{cfg: c})
+			names = append(names, c.Name)
+		}
+	}
+	for _, c := range cfg.Telegram {
+		if c.Enabled {
+			channels = append(channels, &telegramChannel{cfg: c})
+			names = append(names, c.Name)
+		}
+	}
+	for _, c := range cfg.PagerDuty {
+		if c.Enabled {
+			channels = append(channels, newPagerDutyChannel(c))
+			names = append(names, c.Name)
+		}
+	}
+	for _, c := range cfg.Opsgenie {
+		if c.Enabled {
+			channels = append(channels, newOpsgenieChannel(c))
+			names = append(names, c.Name)
+		}
+	}
+	for _, c := range cfg.Slack {
+		if c.Enabled {
+			channels = append(channels, &slackChannel{cfg: c, refs: chatRefs{store: store, channel: c.Name}})
+			names = append(names, c.Name)
+		}
+	}
+	for _, c := range cfg.Discord {
+		if c.Enabled {
+			channels = append(channels, &discordChannel{cfg: c, refs: chatRefs{store: store, channel: c.Name}})
+			names = append(names, c.Name)
+		}
+	}
+	for _, c := range cfg.Matrix {
+		if c.Enabled {
+			channels = append(channels, newMatrixChannel(c, store))
+			names = append(names, c.Name)
 		}
 	}
 	n := &Notifier{
 		channels: channels,
+		names:    names,
 		queue:    make(chan notification, 64),
 	}
+	for _, ch := range channels {
+		if _, ok := ch.(incidentChannel); ok {
+			n.incident = true
+		}
+	}
+	for _, r := range cfg.Routes {
+		if n.routes == nil {
+			n.routes = make(map[string][]string)
+		}
+		if _, ok := n.routes[r.Severity]; !ok { // first matching route wins
+			n.routes[r.Severity] = r.Channels
+		}
+	}
+	for i := range cfg.QuietHours {
+		// Windows were already validated at config load time.
+		if w, err := newQuietWindow(&cfg.QuietHours[i]); err == nil {
+			n.quiet = append(n.quiet, w)
+		}
+	}
+	if len(cfg.GroupBy) > 0 {
+		n.group = newNotifyGrouper(cfg.GroupBy, cfg.GroupWait.Duration, cfg.GroupInterval.Duration, n.send)
+	}
 	if len(channels) > 0 {
 		n.wg.Add(1)
 		go n.run()
 	}
+	if store != nil {
+		n.startQueue(store)
+	}
 	return n
 }
 
@@ -87,13 +208,39 @@ func (n *Notifier) HasChannels() bool {
 func (n *Notifier) run() {
 	defer n.wg.Done()
 	for msg := range n.queue {
-		for _, ch := range n.channels {
-			sendWithRetry(context.Background(), ch, msg)
+		for _, i := range n.recipients(msg) {
+			// Without a store there is nowhere to hold deferred
+			// notifications, so quiet hours drop them.
+			if len(n.quiet) > 0 {
+				if _, _, quiet := n.quietAt(n.names[i], msg, time.Now()); quiet {
+					slog.Info("notification suppressed (quiet hours)", "channel", n.names[i], "subject", msg.subject)
+					continue
+				}
+			}
+			sendWithRetry(context.Background(), n.channels[i], msg)
 		}
 		n.pending.Done()
 	}
 }
 
+// recipients returns the indexes of the channels msg is delivered to.
+func (n *Notifier) recipients(msg notification) []int {
+	var out []int
+	for i, ch := range n.channels {
+		if _, ok := ch.(*emailChannel); msg.digest && !ok {
+			continue
+		}
+		if _, ok := ch.(incidentChannel); msg.incident && !ok {
+			continue
+		}
+		if msg.targets != nil && (i >= len(n.names) || !slices.Contains(msg.targets, n.names[i])) {
+			continue
+		}
+		out = append(out, i)
+	}
+	return out
+}
+
 // Send queues a notification for async delivery. If the queue is full, the
 // notification is dropped with a warning. This never blocks the caller.
 func (n *Notifier) Send(subject, body string) {
@@ -102,13 +249,92 @@ func (n *Notifier) Send(subject, body string) {
 
 // SendAlert queues an alert notification with severity and status metadata.
 func (n *Notifier) SendAlert(subject, body, severity, status string) {
-	n.send(notification{subject: subject, body: body, severity: severity, status: status})
+	n.SendAlertTo(nil, subject, body, severity, status)
+}
+
+// SendAlertTo is SendAlert limited to the named channels; nil targets
+// means every channel.
+func (n *Notifier) SendAlertTo(targets []string, subject, body, severity, status string) {
+	n.send(notification{subject: subject, body: body, severity: severity, status: status, targets: targets})
+}
+
+// route returns the channel names an alert of the given severity goes to.
+// explicit are the rule's notify:NAME targets; routed is set when the rule
+// also has a plain "notify" action, which follows notify.routes or, without
+// a matching route, reaches every channel. nil means every channel.
+func (n *Notifier) route(explicit []string, routed bool, severity string) []string {
+	if !routed {
+		return explicit
+	}
+	r, ok := n.routes[severity]
+	if !ok {
+		return nil
+	}
+	out := slices.Clone(r)
+	for _, name := range explicit {
+		if !slices.Contains(out, name) {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// Targets returns the names of the enabled channels an alert would be
+// delivered to, in config order. See route for the arguments.
+func (n *Notifier) Targets(explicit []string, routed bool, severity string) []string {
+	targets := n.route(explicit, routed, severity)
+	var out []string
+	for _, name := range n.names {
+		if targets == nil || slices.Contains(targets, name) {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// Notify queues an alert transition. With grouping enabled the transition is
+// held and delivered together with the others in its group; otherwise it is
+// sent on its own.
+func (n *Notifier) Notify(note alertNote) {
+	if len(n.channels) == 0 {
+		return
+	}
+	if n.group != nil {
+		n.group.add(note)
+		return
+	}
+	n.send(singleNotification(note))
+}
+
+// Incident queues the resolve or acknowledge event of an alert for the
+// channels that follow alerts after they fire (see incidentChannel). Other
+// channels don't notify on these, so the event bypasses grouping and is
+// dropped if none is enabled.
+func (n *Notifier) Incident(note alertNote) {
+	if !n.incident {
+		return
+	}
+	msg := singleNotification(note)
+	msg.incident = true
+	n.send(msg)
+}
+
+// SendDigest queues a daily digest. Digests go to the email channel only.
+func (n *Notifier) SendDigest(subject, body string) {
+	n.send(notification{subject: subject, body: body, digest: true})
 }
 
 func (n *Notifier) send(msg notification) {
 	if len(n.channels) == 0 {
 		return
 	}
+	if n.store != nil {
+		err := n.enqueue(msg)
+		if err == nil {
+			return
+		}
+		slog.Error("failed to queue notification, sending from memory", "subject", msg.subject, "error", err)
+	}
 	n.pending.Add(1)
 	select {
 	case n.queue <- msg:
@@ -118,9 +344,18 @@ func (n *Notifier) send(msg notification) {
 	}
 }
 
-// Flush waits for all queued notifications to be processed.
+// Flush waits for all queued notifications to be processed. Durably queued
+// notifications count as processed after their first attempt.
 func (n *Notifier) Flush() {
 	n.pending.Wait()
+	if n.flushReq != nil {
+		reply := make(chan struct{})
+		select {
+		case n.flushReq <- reply:
+			<-reply
+		case <-n.quit:
+		}
+	}
 }
 
 // Stop closes the notification queue and waits for remaining items to drain.
@@ -129,7 +364,15 @@ func (n *Notifier) Stop() {
 	if len(n.channels) == 0 {
 		return
 	}
-	n.stopOnce.Do(func() { close(n.queue) })
+	n.stopOnce.Do(func() {
+		if n.group != nil {
+			n.group.stop() // deliver held groups before the queue closes
+		}
+		close(n.queue)
+		if n.quit != nil {
+			close(n.quit)
+		}
+	})
 	n.wg.Wait()
 }
 
@@ -156,10 +399,15 @@ func sendWithRetry(ctx context.Context, ch Channel, msg notification) {
 	slog.Error("notification failed after 3 attempts", "error", err)
 }
 
-// emailChannel sends notifications via SMTP.
+// emailChannel sends notifications via SMTP as multipart emails with a
+// plain-text and an HTML part (see notify_email.go).
 type emailChannel struct {
 	cfg       EmailConfig
 	tlsConfig *tls.Config // nil = use default (ServerName from cfg.SMTPHost); injectable for tests
+
+	store *Store                 // source of container logs; nil = no logs
+	html  *htmltemplate.Template // nil = defaultEmailHTML
+	text  *template.Template     // nil = defaultEmailText
 }
 
 func (e *emailChannel) Send(ctx context.Context, n notification) error {
@@ -172,12 +420,10 @@ func (e *emailChannel) Send(ctx context.Context, n notification) error {
 	for i, t := range e.cfg.To {
 		to[i] = sanitizeHeader(t)
 	}
-	subject := sanitizeHeader(n.subject)
-	body := n.body
-
-	toHeader := strings.Join(to, ", ")
-	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\n\r\n%s",
-		from, toHeader, subject, time.Now().Format(time.RFC1123Z), body)
+	msg, err := e.message(ctx, n, from, to, time.Now())
+	if err != nil {
+		return err
+	}
 
 	tlsCfg := e.tlsConfig
 	if tlsCfg == nil {
@@ -185,7 +431,6 @@ func (e *emailChannel) Send(ctx context.Context, n notification) error {
 	}
 
 	var conn net.Conn
-	var err error
 
 	switch e.cfg.TLS {
 	case "tls":
@@ -237,7 +482,7 @@ func (e *emailChannel) Send(ctx context.Context, n notification) error {
 	if err != nil {
 		return fmt.Errorf("smtp data: %w", err)
 	}
-	if _, err := w.Write([]byte(msg)); err != nil {
+	if _, err := w.Write(msg); err != nil {
 		return fmt.Errorf("smtp write: %w", err)
 	}
 	if err := w.Close(); err != nil {
@@ -265,6 +510,18 @@ type webhookData struct {
 	Body     string
 	Severity string
 	Status   string
+	Alerts   []webhookAlert // one entry per alert; several for grouped notifications
+}
+
+// webhookAlert describes one alert in webhookData.Alerts.
+type webhookAlert struct {
+	Rule     string
+	Severity string
+	Status   string
+	Subject  string
+	Body     string
+	Project  string
+	Time     string // RFC 3339
 }
 
 // jsonEscape escapes a string for safe embedding inside a JSON string value.
@@ -295,6 +552,17 @@ func (w *webhookChannel) Send(ctx context.Context, n notification) error {
 			Severity: jsonEscape(n.severity),
 			Status:   jsonEscape(n.status),
 		}
+		for _, a := range n.alerts {
+			data.Alerts = append(data.Alerts, webhookAlert{
+				Rule:     jsonEscape(a.rule),
+				Severity: jsonEscape(a.severity),
+				Status:   jsonEscape(a.status),
+				Subject:  jsonEscape(a.subject),
+				Body:     jsonEscape(a.body),
+				Project:  jsonEscape(a.project),
+				Time:     a.at.UTC().Format(time.RFC3339),
+			})
+		}
 		if err := w.tmpl.Execute(&buf, data); err != nil {
 			return fmt.Errorf("template execute: %w", err)
 		}
@@ -325,6 +593,9 @@ func (w *webhookChannel) Send(ctx context.Context, n notification) error {
 	if req.Header.Get("Content-Type") == "" {
 		req.Header.Set("Content-Type", "application/json")
 	}
+	if w.cfg.Secret != "" {
+		webhook.SignRequest(req.Header, w.cfg.Secret, payload, time.Now())
+	}
 
 	resp, err := webhookClient.Do(req)
 	if err != nil {
@@ -338,3 +609,166 @@ func (w *webhookChannel) Send(ctx context.Context, n notification) error {
 	}
 	return nil
 }
+
+// postJSON posts payload as JSON to endpoint and fails on a non-2xx response,
+// quoting the start of the response body. service names the remote in errors.
+func postJSON(ctx context.Context, service, endpoint string, header http.Header, payload any) error {
+	return doJSON(ctx, service, http.MethodPost, endpoint, header, payload, nil)
+}
+
+// doJSON is postJSON with a request method, decoding a successful response
+// into out if it is non-nil. The request has been delivered once the status
+// is 2xx, so a response that doesn't decode leaves out unchanged instead of
+// failing, which would make sendWithRetry deliver it again.
+func doJSON(ctx context.Context, service, method, endpoint string, header http.Header, payload, out any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		if s := strings.TrimSpace(string(msg)); s != "" {
+			return fmt.Errorf("%s returned %d: %s", service, resp.StatusCode, s)
+		}
+		return fmt.Errorf("%s returned %d", service, resp.StatusCode)
+	}
+	if out != nil {
+		json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(out)
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// ntfyChannel publishes notifications to an ntfy topic.
+type ntfyChannel struct {
+	cfg NtfyConfig
+}
+
+// ntfyPriority maps a notification to an ntfy priority (1-5) and tag.
+// Tags that name an emoji are shown as one in the ntfy apps.
+func ntfyPriority(n notification) (int, string) {
+	switch {
+	case n.status == "resolved":
+		return 3, "white_check_mark"
+	case n.severity == "critical":
+		return 5, "rotating_light"
+	case n.severity == "warning":
+		return 4, "warning"
+	}
+	return 3, "bell"
+}
+
+func (c *ntfyChannel) Send(ctx context.Context, n notification) error {
+	priority, tag := ntfyPriority(n)
+	payload := map[string]any{
+		"topic":    c.cfg.Topic,
+		"title":    n.subject,
+		"message":  n.body,
+		"priority": priority,
+		"tags":     append([]string{tag}, c.cfg.Tags...),
+	}
+	header := http.Header{}
+	if c.cfg.Token != "" {
+		header.Set("Authorization", "Bearer "+c.cfg.Token)
+	}
+	// Publishing JSON goes to the server root; the topic is in the body.
+	return postJSON(ctx, "ntfy", strings.TrimSuffix(c.cfg.URL, "/")+"/", header, payload)
+}
+
+// gotifyChannel sends notifications to a Gotify server.
+type gotifyChannel struct {
+	cfg GotifyConfig
+}
+
+// gotifyPriority maps a notification to a Gotify priority. The Gotify apps
+// pop up and play a sound from 8, play a sound from 4, and stay silent below.
+func gotifyPriority(n notification) int {
+	switch {
+	case n.status == "resolved":
+		return 2
+	case n.severity == "critical":
+		return 8
+	}
+	return 5
+}
+
+func (c *gotifyChannel) Send(ctx context.Context, n notification) error {
+	payload := map[string]any{
+		"title":    n.subject,
+		"message":  n.body,
+		"priority": gotifyPriority(n),
+	}
+	header := http.Header{}
+	header.Set("X-Gotify-Key", c.cfg.Token)
+	return postJSON(ctx, "gotify", strings.TrimSuffix(c.cfg.URL, "/")+"/message", header, payload)
+}
+
+// telegramChannel sends notifications to a Telegram chat via the Bot API.
+type telegramChannel struct {
+	cfg TelegramConfig
+}
+
+// telegramMaxBody caps the body before escaping, so the message stays under
+// Telegram's 4096 character limit even if every character is escaped.
+const telegramMaxBody = 1800
+
+// telegramEscaper escapes the characters that are special in MarkdownV2.
+var telegramEscaper = strings.NewReplacer(
+	"\\", "\\\\", "_", "\\_", "*", "\\*", "[", "\\[", "]", "\\]", "(", "\\(", ")", "\\)",
+	"~", "\\~", "`", "\\`", ">", "\\>", "#", "\\#", "+", "\\+", "-", "\\-", "=", "\\=",
+	"|", "\\|", "{", "\\{", "}", "\\}", ".", "\\.", "!", "\\!",
+)
+
+// telegramText formats a notification as MarkdownV2: the subject in bold,
+// then the body.
+func telegramText(n notification) string {
+	subject := truncateRunes(n.subject, 200)
+	body := truncateRunes(n.body, telegramMaxBody)
+	text := "*" + telegramEscaper.Replace(subject) + "*"
+	if body != "" {
+		text += "\n\n" + telegramEscaper.Replace(body)
+	}
+	return text
+}
+
+// truncateRunes shortens s to at most max runes, marking the cut with "…".
+func truncateRunes(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max-1]) + "…"
+}
+
+func (c *telegramChannel) Send(ctx context.Context, n notification) error {
+	payload := map[string]any{
+		"chat_id":                  c.cfg.ChatID,
+		"text":                     telegramText(n),
+		"parse_mode":               "MarkdownV2",
+		"disable_web_page_preview": true,
+	}
+	endpoint := strings.TrimSuffix(c.cfg.APIURL, "/") + "/bot" + c.cfg.Token + "/sendMessage"
+	if err := postJSON(ctx, "telegram", endpoint, nil, payload); err != nil {
+		// Transport errors quote the URL, which contains the bot token.
+		return errors.New(strings.ReplaceAll(err.Error(), c.cfg.Token, "<token>"))
+	}
+	return nil
+}
@@ -0,0 +1,391 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// chatRef records the message that announced an alert, so the follow-up
+// posted when the alert resolves can reference it.
+type chatRef struct {
+	subject string
+	at      time.Time
+	id      string // Matrix event ID to reply to, "" for other services
+}
+
+// maxChatRefs bounds the announced alerts a chat channel remembers without a
+// store. Entries are removed when the alert resolves, so this only matters
+// for alerts whose rule was removed while firing.
+const maxChatRefs = 1000
+
+// chatRefs remembers the message that announced each firing alert instance.
+// With a store the refs are kept in the database, so an alert that resolves
+// after an agent restart still gets its follow-up.
+type chatRefs struct {
+	store   *Store
+	channel string
+
+	mu   sync.Mutex
+	refs map[string]chatRef
+}
+
+// remember records ref for the firing alerts in n. A new firing replaces
+// the ref of an earlier one whose resolve was never posted; escalations and
+// remediation reports keep the message that announced the alert.
+func (r *chatRefs) remember(ctx context.Context, n notification, ref chatRef) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, note := range n.alerts {
+		if note.key == "" || note.status != "firing" {
+			continue
+		}
+		ref.at = note.at
+		replace := note.kind == ""
+		if r.store != nil {
+			if err := r.store.SaveChatRef(ctx, r.channel, note.key, ref, replace); err != nil {
+				slog.Error("failed to save chat message ref", "channel", r.channel, "key", note.key, "error", err)
+			}
+			continue
+		}
+		_, ok := r.refs[note.key]
+		if ok && !replace {
+			continue
+		}
+		if r.refs == nil {
+			r.refs = make(map[string]chatRef)
+		}
+		if !ok && len(r.refs) >= maxChatRefs {
+			for k := range r.refs {
+				delete(r.refs, k)
+				break
+			}
+		}
+		r.refs[note.key] = ref
+	}
+}
+
+// lookup returns the message that announced the alert instance key.
+func (r *chatRefs) lookup(ctx context.Context, key string) (chatRef, bool, error) {
+	if r.store != nil {
+		return r.store.ChatRef(ctx, r.channel, key)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ref, ok := r.refs[key]
+	return ref, ok, nil
+}
+
+// forget removes the ref of the alert instance key.
+func (r *chatRefs) forget(ctx context.Context, key string) {
+	if r.store != nil {
+		if err := r.store.DeleteChatRef(ctx, r.channel, key); err != nil {
+			slog.Error("failed to delete chat message ref", "channel", r.channel, "key", key, "error", err)
+		}
+		return
+	}
+	r.mu.Lock()
+	delete(r.refs, key)
+	r.mu.Unlock()
+}
+
+// followUps calls post for each resolved alert in an incident event that
+// the channel announced, and forgets the alert once post succeeds. i is the
+// alert's index in n.alerts. Alerts the channel never announced, e.g.
+// because they were silenced, get no follow-up.
+func (r *chatRefs) followUps(ctx context.Context, n notification, post func(i int, note alertNote, ref chatRef) error) error {
+	var errs []error
+	for i, note := range n.alerts {
+		if note.status != "resolved" {
+			continue
+		}
+		ref, ok, err := r.lookup(ctx, note.key)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("look up chat message ref: %w", err))
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if err := post(i, note, ref); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		r.forget(ctx, note.key)
+	}
+	return errors.Join(errs...)
+}
+
+// chatColor returns the accent color of a notification as 0xRRGGBB.
+func chatColor(severity, status string) int {
+	switch {
+	case status == "resolved":
+		return 0x2eb67d
+	case severity == "critical":
+		return 0xe01e5a
+	case severity == "warning":
+		return 0xecb22e
+	}
+	return 0x868e96
+}
+
+// notificationTime returns when the first alert of n changed state, or now
+// for notifications that are not about an alert.
+func notificationTime(n notification) time.Time {
+	if len(n.alerts) > 0 && !n.alerts[0].at.IsZero() {
+		return n.alerts[0].at
+	}
+	return time.Now()
+}
+
+// slackChannel posts notifications to a Slack incoming webhook using Block
+// Kit inside an attachment, which carries the severity color.
+type slackChannel struct {
+	cfg  SlackConfig
+	refs chatRefs
+}
+
+func (c *slackChannel) incidents() {}
+
+// slackEscaper escapes the characters that are special in Slack mrkdwn.
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// slackMaxAlerts caps the per-alert fields of a grouped message; Slack allows
+// 10 fields per section and 50 blocks per message.
+const slackMaxAlerts = 40
+
+// slackPayload builds the message for a notification: the subject as header,
+// then a field per alert if several are grouped, else the body, and footer
+// as context line.
+func slackPayload(n notification, color int, footer string) map[string]any {
+	blocks := []any{map[string]any{
+		"type": "header",
+		"text": map[string]any{"type": "plain_text", "text": truncateRunes(n.subject, 150)},
+	}}
+	if len(n.alerts) > 1 {
+		var fields []any
+		for i, note := range n.alerts {
+			if i == slackMaxAlerts {
+				break
+			}
+			fields = append(fields, map[string]string{
+				"type": "mrkdwn",
+				"text": "*" + slackEscaper.Replace(truncateRunes(note.subject, 100)) + "*\n" +
+					slackEscaper.Replace(truncateRunes(note.body, 1500)),
+			})
+			if len(fields) == 10 || i == len(n.alerts)-1 || i == slackMaxAlerts-1 {
+				blocks = append(blocks, map[string]any{"type": "section", "fields": fields})
+				fields = nil
+			}
+		}
+		if more := len(n.alerts) - slackMaxAlerts; more > 0 {
+			footer = strings.TrimPrefix(footer+fmt.Sprintf(" · and %d more", more), " · ")
+		}
+	} else if n.body != "" {
+		blocks = append(blocks, map[string]any{
+			"type": "section",
+			"text": map[string]string{"type": "mrkdwn", "text": slackEscaper.Replace(truncateRunes(n.body, 2900))},
+		})
+	}
+	if footer != "" {
+		blocks = append(blocks, map[string]any{
+			"type":     "context",
+			"elements": []any{map[string]string{"type": "mrkdwn", "text": footer}},
+		})
+	}
+	return map[string]any{
+		"text": n.subject, // shown in push notifications
+		"attachments": []any{map[string]any{
+			"color":  fmt.Sprintf("#%06x", color),
+			"blocks": blocks,
+		}},
+	}
+}
+
+// slackDate formats t for Slack, which shows it in the reader's time zone.
+func slackDate(t time.Time) string {
+	return fmt.Sprintf("<!date^%d^{date_short_pretty} at {time}|%s>", t.Unix(), t.UTC().Format(time.RFC1123))
+}
+
+func (c *slackChannel) Send(ctx context.Context, n notification) error {
+	if n.incident {
+		// Incoming webhooks return no message ID to thread on, so the
+		// follow-up quotes the original message instead.
+		return c.refs.followUps(ctx, n, func(_ int, note alertNote, ref chatRef) error {
+			msg := notification{subject: note.subject, body: note.body}
+			footer := fmt.Sprintf("Fired %s: *%s*", slackDate(ref.at), slackEscaper.Replace(ref.subject))
+			return postJSON(ctx, "slack", c.cfg.URL, nil, slackPayload(msg, chatColor(note.severity, note.status), footer))
+		})
+	}
+	if err := postJSON(ctx, "slack", c.cfg.URL, nil, slackPayload(n, chatColor(n.severity, n.status), "")); err != nil {
+		return err
+	}
+	c.refs.remember(ctx, n, chatRef{subject: n.subject})
+	return nil
+}
+
+// discordChannel posts notifications to a Discord channel webhook as embeds.
+type discordChannel struct {
+	cfg  DiscordConfig
+	refs chatRefs
+}
+
+func (c *discordChannel) incidents() {}
+
+// discordEscaper escapes the characters that are special in Discord markdown.
+var discordEscaper = strings.NewReplacer(
+	"\\", "\\\\", "*", "\\*", "_", "\\_", "~", "\\~", "`", "\\`", "|", "\\|", ">", "\\>",
+)
+
+// discordMaxAlerts caps the per-alert fields of a grouped embed. Discord
+// allows 25 fields and 6000 characters per embed.
+const discordMaxAlerts = 20
+
+// discordEmbed builds the embed for a notification: the subject as title,
+// then a field per alert if several are grouped, else the body.
+func discordEmbed(n notification, color int) map[string]any {
+	embed := map[string]any{
+		"title":     truncateRunes(n.subject, 256),
+		"color":     color,
+		"timestamp": notificationTime(n).UTC().Format(time.RFC3339),
+		"footer":    map[string]string{"text": "tori"},
+	}
+	if len(n.alerts) > 1 {
+		var fields []any
+		for i, note := range n.alerts {
+			if i == discordMaxAlerts {
+				embed["description"] = fmt.Sprintf("and %d more", len(n.alerts)-discordMaxAlerts)
+				break
+			}
+			value := discordEscaper.Replace(truncateRunes(note.body, 150))
+			if value == "" {
+				value = "–" // Discord rejects empty fields
+			}
+			fields = append(fields, map[string]any{"name": truncateRunes(note.subject, 100), "value": value})
+		}
+		embed["fields"] = fields
+	} else if n.body != "" {
+		embed["description"] = discordEscaper.Replace(truncateRunes(n.body, 3000))
+	}
+	return embed
+}
+
+func (c *discordChannel) Send(ctx context.Context, n notification) error {
+	if n.incident {
+		// Webhook messages can't reply, so the follow-up names the original
+		// alert and when it fired.
+		return c.refs.followUps(ctx, n, func(_ int, note alertNote, ref chatRef) error {
+			embed := discordEmbed(notification{subject: note.subject, body: note.body}, chatColor(note.severity, note.status))
+			embed["fields"] = []any{map[string]any{
+				"name":  "Alert",
+				"value": fmt.Sprintf("%s, fired <t:%d:f>", discordEscaper.Replace(ref.subject), ref.at.Unix()),
+			}}
+			return postJSON(ctx, "discord", c.cfg.URL, nil, map[string]any{"embeds": []any{embed}})
+		})
+	}
+	payload := map[string]any{"embeds": []any{discordEmbed(n, chatColor(n.severity, n.status))}}
+	if err := postJSON(ctx, "discord", c.cfg.URL, nil, payload); err != nil {
+		return err
+	}
+	c.refs.remember(ctx, n, chatRef{subject: n.subject})
+	return nil
+}
+
+// matrixChannel sends notifications to a Matrix room via the client-server
+// API. Messages are notices with an HTML body; follow-ups are replies.
+type matrixChannel struct {
+	cfg   MatrixConfig
+	refs  chatRefs
+	start int64        // makes transaction IDs unique across agent restarts
+	txn   atomic.Int64 // transaction counter
+}
+
+func newMatrixChannel(cfg MatrixConfig, store *Store) *matrixChannel {
+	return &matrixChannel{cfg: cfg, refs: chatRefs{store: store, channel: cfg.Name}, start: time.Now().UnixNano()}
+}
+
+func (c *matrixChannel) incidents() {}
+
+// matrixHTML formats a notification: the subject as heading in the severity
+// color, then the alerts as a list if several are grouped, else the body.
+func matrixHTML(n notification, color int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<h4><font data-mx-color="#%06x">●</font> %s</h4>`, color, html.EscapeString(n.subject))
+	if len(n.alerts) > 1 {
+		b.WriteString("<ul>")
+		for _, note := range n.alerts {
+			fmt.Fprintf(&b, "<li><b>%s</b><br>%s</li>", html.EscapeString(note.subject), matrixLines(note.body))
+		}
+		b.WriteString("</ul>")
+	} else if n.body != "" {
+		fmt.Fprintf(&b, "<p>%s</p>", matrixLines(n.body))
+	}
+	return b.String()
+}
+
+// matrixLines escapes s for HTML, keeping its line breaks.
+func matrixLines(s string) string {
+	return strings.ReplaceAll(html.EscapeString(s), "\n", "<br>")
+}
+
+// txnID returns the transaction ID of the i-th message sent for n. Queued
+// deliveries derive it from the delivery, so the homeserver drops the
+// messages a retry sends again.
+func (c *matrixChannel) txnID(n notification, i int) string {
+	if n.delivery != "" {
+		return fmt.Sprintf("tori-%s-%d", n.delivery, i)
+	}
+	return fmt.Sprintf("tori-%d-%d", c.start, c.txn.Add(1))
+}
+
+// send posts one message to the room and returns its event ID.
+func (c *matrixChannel) send(ctx context.Context, txn string, content map[string]any) (string, error) {
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		strings.TrimSuffix(c.cfg.Homeserver, "/"), url.PathEscape(c.cfg.RoomID), txn)
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+c.cfg.AccessToken)
+	var resp struct {
+		EventID string `json:"event_id"`
+	}
+	if err := doJSON(ctx, "matrix", http.MethodPut, endpoint, header, content, &resp); err != nil {
+		return "", err
+	}
+	return resp.EventID, nil
+}
+
+func (c *matrixChannel) Send(ctx context.Context, n notification) error {
+	if n.incident {
+		return c.refs.followUps(ctx, n, func(i int, note alertNote, ref chatRef) error {
+			content := map[string]any{
+				"msgtype":        "m.notice",
+				"body":           note.subject + "\n" + note.body,
+				"format":         "org.matrix.custom.html",
+				"formatted_body": matrixHTML(notification{subject: note.subject, body: note.body}, chatColor(note.severity, note.status)),
+			}
+			if ref.id != "" {
+				content["m.relates_to"] = map[string]any{"m.in_reply_to": map[string]string{"event_id": ref.id}}
+			}
+			_, err := c.send(ctx, c.txnID(n, i), content)
+			return err
+		})
+	}
+	id, err := c.send(ctx, c.txnID(n, 0), map[string]any{
+		"msgtype":        "m.notice",
+		"body":           strings.TrimSuffix(n.subject+"\n"+n.body, "\n"),
+		"format":         "org.matrix.custom.html",
+		"formatted_body": matrixHTML(n, chatColor(n.severity, n.status)),
+	})
+	if err != nil {
+		return err
+	}
+	c.refs.remember(ctx, n, chatRef{subject: n.subject, id: id})
+	return nil
+}
@@ -0,0 +1,312 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	// maxEmailLogLines caps email.log_lines.
+	maxEmailLogLines = 200
+	// emailLogWindow is how far before an alert an email looks for logs.
+	emailLogWindow = time.Hour
+	// emailLogAlerts caps the alerts of a grouped email that get logs.
+	emailLogAlerts = 10
+)
+
+// emailTextTemplate is the default plain-text part: the notification body.
+const emailTextTemplate = `{{.Body}}`
+
+// emailHTMLTemplate is the default HTML part: a header in the severity color,
+// then for each alert its message, metrics and recent container logs. Styles
+// are inline because most mail clients drop style sheets.
+const emailHTMLTemplate = `<!DOCTYPE html>
+<html>
+<body style="margin:0;padding:16px;background:#f4f5f7;font-family:-apple-system,'Segoe UI',Helvetica,Arial,sans-serif;color:#1f2328">
+<table role="presentation" width="100%" cellpadding="0" cellspacing="0" style="max-width:680px;margin:0 auto;background:#ffffff">
+<tr><td style="background:{{.Color}};color:#ffffff;padding:16px 20px;font-size:18px;font-weight:bold">{{.Subject}}</td></tr>
+<tr><td style="padding:16px 20px">
+{{- range .Alerts}}
+<p style="margin:0 0 4px;font-size:15px;font-weight:bold"><span style="color:{{.Color}}">&#9679;</span> {{.Subject}}</p>
+<p style="margin:0 0 8px;color:#59636e;font-size:12px">{{.Status}} · {{.Severity}} · {{.Time}}</p>
+<pre style="margin:0 0 12px;white-space:pre-wrap;font-family:inherit">{{.Body}}</pre>
+{{- if .Metrics}}
+<table cellpadding="0" cellspacing="0" style="margin:0 0 12px;font-size:13px">
+{{- range .Metrics}}
+<tr><td style="padding:2px 16px 2px 0;color:#59636e">{{.Name}}</td><td style="padding:2px 0">{{.Value}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Logs}}
+<p style="margin:0 0 4px;color:#59636e;font-size:12px">Last {{len .Logs}} log lines</p>
+<pre style="margin:0 0 16px;padding:8px;background:#f6f8fa;font-size:12px;white-space:pre-wrap">{{range .Logs}}{{.Time}} {{.Message}}
+{{end}}</pre>
+{{- end}}
+{{- else}}
+<pre style="margin:0;white-space:pre-wrap;font-family:inherit">{{.Body}}</pre>
+{{- end}}
+</td></tr>
+<tr><td style="padding:8px 20px 16px;color:#59636e;font-size:12px">Sent by tori on {{.Hostname}} at {{.Time}}</td></tr>
+</table>
+</body>
+</html>
+`
+
+var (
+	defaultEmailHTML = htmltemplate.Must(htmltemplate.New("email").Parse(emailHTMLTemplate))
+	defaultEmailText = template.Must(template.New("email").Parse(emailTextTemplate))
+)
+
+// emailData is the data passed to email templates.
+type emailData struct {
+	Subject  string
+	Body     string
+	Severity string
+	Status   string
+	Color    string // header color for the severity, "#rrggbb"
+	Hostname string
+	Time     string
+	Alerts   []emailAlert // one entry per alert; several for grouped notifications
+}
+
+// emailAlert describes one alert in emailData.Alerts.
+type emailAlert struct {
+	Rule     string
+	Severity string
+	Status   string
+	Subject  string
+	Body     string
+	Project  string
+	Color    string
+	Time     string
+	Metrics  []emailMetric
+	Logs     []emailLog // oldest first
+}
+
+type emailMetric struct {
+	Name  string
+	Value string
+}
+
+type emailLog struct {
+	Time    string
+	Stream  string
+	Message string
+}
+
+// noteMetric is a named value of the instance an alert is about.
+type noteMetric struct {
+	name  string
+	value string
+}
+
+// noteMetrics lists the values an alert was evaluated against, for email
+// notifications. It returns nil for a nil env.
+func noteMetrics(env *condEnv) []noteMetric {
+	if env == nil {
+		return nil
+	}
+	var m []noteMetric
+	add := func(name, format string, args ...any) {
+		m = append(m, noteMetric{name: name, value: fmt.Sprintf(format, args...)})
+	}
+	if d := env.disk; d != nil {
+		add("Mountpoint", "%s", d.Mountpoint)
+		add("Disk used", "%.1f%% (%s of %s)", d.Percent, formatNoteBytes(d.Used), formatNoteBytes(d.Total))
+		add("Disk free", "%s", formatNoteBytes(d.Free))
+	}
+	if h := env.host; h != nil {
+		add("CPU", "%.1f%%", h.CPUPercent)
+		add("Memory", "%.1f%% (%s of %s)", h.MemPercent, formatNoteBytes(h.MemUsed), formatNoteBytes(h.MemTotal))
+		if h.SwapTotal > 0 {
+			add("Swap", "%.1f%% (%s of %s)", hostFieldValue(h, "swap_percent"), formatNoteBytes(h.SwapUsed), formatNoteBytes(h.SwapTotal))
+		}
+		add("Load", "%.2f %.2f %.2f", h.Load1, h.Load5, h.Load15)
+	}
+	if c := env.container; c != nil {
+		add("Container", "%s", c.Name)
+		add("Image", "%s", c.Image)
+		state := c.State
+		if c.Health != "" {
+			state += " (" + c.Health + ")"
+		}
+		add("State", "%s", state)
+		if c.State == "exited" {
+			add("Exit code", "%d", c.ExitCode)
+		}
+		add("CPU", "%.1f%%", c.CPUPercent)
+		if c.MemLimit > 0 {
+			add("Memory", "%.1f%% (%s of %s)", c.MemPercent, formatNoteBytes(c.MemUsage), formatNoteBytes(c.MemLimit))
+		} else {
+			add("Memory", "%s", formatNoteBytes(c.MemUsage))
+		}
+		add("Restarts", "%d", c.RestartCount)
+		add("PIDs", "%d", c.PIDs)
+	}
+	if n := env.net; n != nil {
+		add("Interface", "%s", n.Iface)
+		add("Receive", "%s/s", formatNoteBytes(uint64(n.RxBytesPerSec)))
+		add("Transmit", "%s/s", formatNoteBytes(uint64(n.TxBytesPerSec)))
+		if n.RxErrorsPerSec > 0 || n.TxErrorsPerSec > 0 {
+			add("Errors", "%.1f/s in, %.1f/s out", n.RxErrorsPerSec, n.TxErrorsPerSec)
+		}
+	}
+	if env.logCount > 0 {
+		add("Matching log lines", "%.0f", env.logCount)
+	}
+	return m
+}
+
+// formatNoteBytes formats a byte count with binary units, e.g. "1.5 GiB".
+func formatNoteBytes(b uint64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := uint64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
+
+func newEmailChannel(cfg EmailConfig, store *Store) *emailChannel {
+	e := &emailChannel{cfg: cfg, store: store}
+	// Templates were already validated at config load time.
+	if cfg.HTMLTemplate != "" {
+		e.html = htmltemplate.Must(htmltemplate.New("email").Parse(cfg.HTMLTemplate))
+	}
+	if cfg.TextTemplate != "" {
+		e.text = template.Must(template.New("email").Parse(cfg.TextTemplate))
+	}
+	return e
+}
+
+// message builds the email for n: headers, then a multipart/alternative body
+// with a plain-text and an HTML part.
+func (e *emailChannel) message(ctx context.Context, n notification, from string, to []string, now time.Time) ([]byte, error) {
+	data := e.data(ctx, n, now)
+
+	textTmpl, htmlTmpl := e.text, e.html
+	if textTmpl == nil {
+		textTmpl = defaultEmailText
+	}
+	if htmlTmpl == nil {
+		htmlTmpl = defaultEmailHTML
+	}
+	var text, html bytes.Buffer
+	if err := textTmpl.Execute(&text, data); err != nil {
+		return nil, fmt.Errorf("text template execute: %w", err)
+	}
+	if err := htmlTmpl.Execute(&html, data); err != nil {
+		return nil, fmt.Errorf("html template execute: %w", err)
+	}
+
+	var b bytes.Buffer
+	mw := multipart.NewWriter(&b)
+	fmt.Fprintf(&b, "From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nMIME-Version: 1.0\r\n",
+		from, strings.Join(to, ", "), mime.QEncoding.Encode("utf-8", sanitizeHeader(n.subject)), now.Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+	for _, part := range []struct {
+		contentType string
+		body        []byte
+	}{
+		{"text/plain; charset=utf-8", text.Bytes()},
+		{"text/html; charset=utf-8", html.Bytes()},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write(part.body); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// data builds the template data for n, loading the recent logs of the
+// containers its alerts are about.
+func (e *emailChannel) data(ctx context.Context, n notification, now time.Time) emailData {
+	const timeFormat = "2006-01-02 15:04:05 MST"
+	d := emailData{
+		Subject:  n.subject,
+		Body:     n.body,
+		Severity: n.severity,
+		Status:   n.status,
+		Color:    fmt.Sprintf("#%06x", chatColor(n.severity, n.status)),
+		Hostname: agentHostname(),
+		Time:     now.Format(timeFormat),
+	}
+	for i, note := range n.alerts {
+		a := emailAlert{
+			Rule:     note.rule,
+			Severity: note.severity,
+			Status:   note.status,
+			Subject:  note.subject,
+			Body:     note.body,
+			Project:  note.project,
+			Color:    fmt.Sprintf("#%06x", chatColor(note.severity, note.status)),
+			Time:     note.at.Format(timeFormat),
+		}
+		for _, m := range note.metrics {
+			a.Metrics = append(a.Metrics, emailMetric{Name: m.name, Value: m.value})
+		}
+		if i < emailLogAlerts {
+			a.Logs = e.logs(ctx, note.containerID, note.at)
+		}
+		d.Alerts = append(d.Alerts, a)
+	}
+	return d
+}
+
+// logs returns the last cfg.LogLines log lines of a container up to at,
+// oldest first. Logs are best-effort: the email is sent without them if
+// they can't be loaded.
+func (e *emailChannel) logs(ctx context.Context, containerID string, at time.Time) []emailLog {
+	if e.store == nil || e.cfg.LogLines <= 0 || containerID == "" {
+		return nil
+	}
+	entries, err := e.store.QueryLogs(ctx, LogFilter{
+		Start:        at.Add(-emailLogWindow).Unix(),
+		End:          at.Unix(),
+		ContainerIDs: []string{containerID},
+		Limit:        e.cfg.LogLines,
+	})
+	if err != nil {
+		slog.Warn("failed to load logs for email", "container", containerID, "error", err)
+		return nil
+	}
+	slices.Reverse(entries)
+	logs := make([]emailLog, len(entries))
+	for i, entry := range entries {
+		logs[i] = emailLog{
+			Time:    entry.Timestamp.Format("15:04:05"),
+			Stream:  entry.Stream,
+			Message: truncateRunes(entry.Message, 500),
+		}
+	}
+	return logs
+}
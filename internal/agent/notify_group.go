@@ -0,0 +1,196 @@
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// notifyGrouper batches alert notes that share the configured group_by
+// fields. A new group is delivered group_wait after its first note; later
+// notes for the same group wait until group_interval after the previous
+// delivery.
+type notifyGrouper struct {
+	by       []string
+	wait     time.Duration
+	interval time.Duration
+	deliver  func(notification) // must not block; called with mu held
+
+	mu      sync.Mutex
+	groups  map[string]*notifyGroup
+	stopped bool
+}
+
+type notifyGroup struct {
+	label     string // e.g. "project=shop, severity=critical"
+	notes     []alertNote
+	timer     *time.Timer // pending delivery, nil when none is scheduled
+	lastFlush time.Time
+}
+
+func newNotifyGrouper(by []string, wait, interval time.Duration, deliver func(notification)) *notifyGrouper {
+	return &notifyGrouper{
+		by:       by,
+		wait:     wait,
+		interval: interval,
+		deliver:  deliver,
+		groups:   make(map[string]*notifyGroup),
+	}
+}
+
+// key returns the group key and display label for a note.
+func (g *notifyGrouper) key(note alertNote) (key, label string) {
+	parts := make([]string, len(g.by))
+	for i, f := range g.by {
+		var v string
+		switch f {
+		case "rule":
+			v = note.rule
+		case "project":
+			v = note.project
+		case "severity":
+			v = note.severity
+		}
+		parts[i] = f + "=" + v
+	}
+	label = strings.Join(parts, ", ")
+	// Notes for different channels can't share a delivery.
+	if note.targets != nil {
+		parts = append(parts, "targets="+strings.Join(note.targets, ","))
+	}
+	return strings.Join(parts, "\x00"), label
+}
+
+func (g *notifyGrouper) add(note alertNote) {
+	key, label := g.key(note)
+	now := time.Now()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.stopped {
+		return
+	}
+
+	// Forget idle groups so the map doesn't grow with every project seen.
+	for k, grp := range g.groups {
+		if grp.timer == nil && len(grp.notes) == 0 && now.Sub(grp.lastFlush) >= g.interval {
+			delete(g.groups, k)
+		}
+	}
+
+	grp := g.groups[key]
+	if grp == nil {
+		grp = &notifyGroup{label: label}
+		g.groups[key] = grp
+	}
+	grp.notes = append(grp.notes, note)
+	if grp.timer != nil {
+		return // delivery already scheduled
+	}
+	delay := g.wait
+	if !grp.lastFlush.IsZero() {
+		delay = max(delay, grp.lastFlush.Add(g.interval).Sub(now))
+	}
+	grp.timer = time.AfterFunc(delay, func() { g.flush(key) })
+}
+
+func (g *notifyGrouper) flush(key string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	grp := g.groups[key]
+	if g.stopped || grp == nil {
+		return
+	}
+	grp.timer = nil
+	if len(grp.notes) == 0 {
+		return
+	}
+	g.deliver(groupNotification(grp.label, grp.notes))
+	grp.notes = nil
+	grp.lastFlush = time.Now()
+}
+
+// stop delivers every held group immediately and drops later notes.
+func (g *notifyGrouper) stop() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.stopped {
+		return
+	}
+	g.stopped = true
+
+	keys := make([]string, 0, len(g.groups))
+	for k := range g.groups {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		grp := g.groups[k]
+		if grp.timer != nil {
+			grp.timer.Stop()
+		}
+		if len(grp.notes) > 0 {
+			g.deliver(groupNotification(grp.label, grp.notes))
+		}
+	}
+	g.groups = nil
+}
+
+// singleNotification builds the notification for one alert note.
+func singleNotification(note alertNote) notification {
+	return notification{
+		subject:  note.subject,
+		body:     note.body,
+		severity: note.severity,
+		status:   note.status,
+		alerts:   []alertNote{note},
+		targets:  note.targets,
+	}
+}
+
+// groupNotification combines the notes of one group. The severity is the
+// highest among them and the status is "firing" if any alert is firing.
+func groupNotification(label string, notes []alertNote) notification {
+	if len(notes) == 1 {
+		return singleNotification(notes[0])
+	}
+	var firing, resolved int
+	severity := "warning"
+	var body strings.Builder
+	for _, n := range notes {
+		if n.status == "firing" {
+			firing++
+		} else {
+			resolved++
+		}
+		if n.severity == "critical" {
+			severity = "critical"
+		}
+		fmt.Fprintf(&body, "%s %s", n.at.Format("15:04:05"), n.body)
+		if n.status != "firing" {
+			fmt.Fprintf(&body, " (%s)", n.status)
+		}
+		body.WriteByte('\n')
+	}
+	status := "resolved"
+	if firing > 0 {
+		status = "firing"
+	}
+	subject := fmt.Sprintf("Alerts: %d firing", firing)
+	if resolved > 0 {
+		subject += fmt.Sprintf(", %d resolved", resolved)
+	}
+	if label != "" {
+		subject += " (" + label + ")"
+	}
+	return notification{
+		subject:  subject,
+		body:     strings.TrimSuffix(body.String(), "\n"),
+		severity: severity,
+		status:   status,
+		alerts:   notes,
+		targets:  notes[0].targets, // same for every note in a group
+	}
+}
@@ -0,0 +1,205 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// incidentChannel is a Channel that follows alerts after they fire, such as
+// an incident service or a chat posting follow-ups. Besides notifications it
+// receives resolve and acknowledge events (see Notifier.Incident), matched
+// to the alert by its instance key.
+type incidentChannel interface {
+	Channel
+	incidents()
+}
+
+// maxIncidentKey is the longest dedup key sent upstream; PagerDuty allows 255.
+const maxIncidentKey = 255
+
+// incidentKey returns the upstream dedup key of an alert instance. The host
+// name keeps agents on different servers from merging their incidents.
+func incidentKey(host, key string) string {
+	k := "tori/" + host + "/" + key
+	if len(k) > maxIncidentKey {
+		sum := sha256.Sum256([]byte(k))
+		k = "tori/" + hex.EncodeToString(sum[:])
+	}
+	return k
+}
+
+// incidentNotes returns the alerts a notification covers. Notifications that
+// are not about an alert, e.g. a test, become one note without a key.
+func incidentNotes(n notification) []alertNote {
+	if len(n.alerts) > 0 {
+		return n.alerts
+	}
+	return []alertNote{{subject: n.subject, body: n.body, severity: n.severity, status: n.status}}
+}
+
+// agentHostname returns the host name used as incident source.
+func agentHostname() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return "tori"
+	}
+	return host
+}
+
+// pagerDutyChannel sends alerts to the PagerDuty Events API v2.
+type pagerDutyChannel struct {
+	cfg  PagerDutyConfig
+	host string
+}
+
+func newPagerDutyChannel(cfg PagerDutyConfig) *pagerDutyChannel {
+	return &pagerDutyChannel{cfg: cfg, host: agentHostname()}
+}
+
+func (c *pagerDutyChannel) incidents() {}
+
+// pagerDutySeverity maps an alert severity to a PagerDuty event severity.
+func pagerDutySeverity(severity string) string {
+	switch severity {
+	case "critical", "warning":
+		return severity
+	}
+	return "info"
+}
+
+func (c *pagerDutyChannel) Send(ctx context.Context, n notification) error {
+	var errs []error
+	for _, note := range incidentNotes(n) {
+		if note.kind != "" {
+			// Flapping, escalation and remediation notes share the
+			// incident's dedup key; a trigger would reopen it.
+			continue
+		}
+		event := map[string]any{"routing_key": c.cfg.RoutingKey}
+		if note.key != "" {
+			event["dedup_key"] = incidentKey(c.host, note.key)
+		}
+		switch note.status {
+		case "resolved":
+			if note.key == "" {
+				continue
+			}
+			event["event_action"] = "resolve"
+		case "acknowledged":
+			if note.key == "" {
+				continue
+			}
+			event["event_action"] = "acknowledge"
+		default:
+			event["event_action"] = "trigger"
+			payload := map[string]any{
+				"summary":  truncateRunes(note.subject, 1024),
+				"source":   c.host,
+				"severity": pagerDutySeverity(note.severity),
+				"custom_details": map[string]string{
+					"body":     note.body,
+					"instance": note.key,
+				},
+			}
+			if note.rule != "" {
+				payload["group"] = note.rule
+			}
+			if note.project != "" {
+				payload["component"] = note.project
+			}
+			event["payload"] = payload
+		}
+		if err := postJSON(ctx, "pagerduty", c.cfg.URL, nil, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// opsgenieChannel sends alerts to the Opsgenie Alert API.
+type opsgenieChannel struct {
+	cfg  OpsgenieConfig
+	host string
+}
+
+func newOpsgenieChannel(cfg OpsgenieConfig) *opsgenieChannel {
+	return &opsgenieChannel{cfg: cfg, host: agentHostname()}
+}
+
+func (c *opsgenieChannel) incidents() {}
+
+// opsgeniePriority maps an alert severity to an Opsgenie priority.
+func opsgeniePriority(severity string) string {
+	switch severity {
+	case "critical":
+		return "P1"
+	case "warning":
+		return "P3"
+	}
+	return "P5"
+}
+
+func (c *opsgenieChannel) Send(ctx context.Context, n notification) error {
+	base := strings.TrimSuffix(c.cfg.URL, "/") + "/v2/alerts"
+	header := http.Header{}
+	header.Set("Authorization", "GenieKey "+c.cfg.APIKey)
+
+	var errs []error
+	for _, note := range incidentNotes(n) {
+		alias := ""
+		if note.key != "" {
+			alias = incidentKey(c.host, note.key)
+		}
+		var err error
+		switch {
+		case note.kind != "":
+			// Flapping, escalation and remediation notes annotate the
+			// alert; creating it again would reopen a closed one.
+			if alias == "" {
+				continue
+			}
+			endpoint := base + "/" + url.PathEscape(alias) + "/notes?identifierType=alias"
+			err = postJSON(ctx, "opsgenie", endpoint, header, map[string]string{
+				"note":   truncateRunes(note.subject+"\n\n"+note.body, 25000),
+				"source": c.host,
+			})
+		case note.status == "resolved" || note.status == "acknowledged":
+			if alias == "" {
+				continue
+			}
+			action := "close"
+			if note.status == "acknowledged" {
+				action = "acknowledge"
+			}
+			endpoint := base + "/" + url.PathEscape(alias) + "/" + action + "?identifierType=alias"
+			err = postJSON(ctx, "opsgenie", endpoint, header, map[string]string{"source": c.host})
+		default:
+			alert := map[string]any{
+				"message":     truncateRunes(note.subject, 130),
+				"description": truncateRunes(note.body, 15000),
+				"priority":    opsgeniePriority(note.severity),
+				"source":      c.host,
+				"entity":      c.host,
+				"tags":        append([]string{"tori"}, c.cfg.Tags...),
+			}
+			if alias != "" {
+				alert["alias"] = alias
+			}
+			if note.rule != "" {
+				alert["details"] = map[string]string{"rule": note.rule, "instance": note.key}
+			}
+			err = postJSON(ctx, "opsgenie", base, header, alert)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", note.subject, err))
+		}
+	}
+	return errors.Join(errs...)
+}
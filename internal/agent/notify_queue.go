@@ -0,0 +1,312 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const (
+	// queueRetryFor is how long a failing delivery is retried before it is
+	// marked failed, counted from when it was queued.
+	queueRetryFor = 24 * time.Hour
+	// queueBackoffMin and queueBackoffMax bound the exponential backoff
+	// between attempts: 30s, 1m, 2m, ... up to 30m.
+	queueBackoffMin = 30 * time.Second
+	queueBackoffMax = 30 * time.Minute
+	// queueBatch is the number of due deliveries loaded at once.
+	queueBatch = 50
+	// queuePoll is the longest runQueue sleeps without checking the store.
+	queuePoll = time.Minute
+	// queueSendTimeout bounds one delivery attempt.
+	queueSendTimeout = 30 * time.Second
+)
+
+// queuedNotification is the stored form of a notification. Targets are not
+// stored; each delivery row already names its channel.
+type queuedNotification struct {
+	Subject  string        `json:"subject"`
+	Body     string        `json:"body"`
+	Severity string        `json:"severity,omitempty"`
+	Status   string        `json:"status,omitempty"`
+	Digest   bool          `json:"digest,omitempty"`
+	Incident bool          `json:"incident,omitempty"`
+	Alerts   []queuedAlert `json:"alerts,omitempty"`
+}
+
+type queuedAlert struct {
+	Rule     string    `json:"rule"`
+	Severity string    `json:"severity"`
+	Status   string    `json:"status"`
+	Subject  string    `json:"subject"`
+	Body     string    `json:"body"`
+	Project  string    `json:"project,omitempty"`
+	At       time.Time `json:"at"`
+	Key      string    `json:"key,omitempty"`
+
+	ContainerID string         `json:"container_id,omitempty"`
+	Metrics     []queuedMetric `json:"metrics,omitempty"`
+	Kind        string         `json:"kind,omitempty"`
+}
+
+type queuedMetric struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func encodeQueued(n notification) ([]byte, error) {
+	q := queuedNotification{
+		Subject:  n.subject,
+		Body:     n.body,
+		Severity: n.severity,
+		Status:   n.status,
+		Digest:   n.digest,
+		Incident: n.incident,
+	}
+	for _, a := range n.alerts {
+		qa := queuedAlert{
+			Rule:        a.rule,
+			Severity:    a.severity,
+			Status:      a.status,
+			Subject:     a.subject,
+			Body:        a.body,
+			Project:     a.project,
+			At:          a.at,
+			Key:         a.key,
+			ContainerID: a.containerID,
+			Kind:        a.kind,
+		}
+		for _, m := range a.metrics {
+			qa.Metrics = append(qa.Metrics, queuedMetric{Name: m.name, Value: m.value})
+		}
+		q.Alerts = append(q.Alerts, qa)
+	}
+	return json.Marshal(q)
+}
+
+func decodeQueued(data []byte) (notification, error) {
+	var q queuedNotification
+	if err := json.Unmarshal(data, &q); err != nil {
+		return notification{}, err
+	}
+	n := notification{
+		subject:  q.Subject,
+		body:     q.Body,
+		severity: q.Severity,
+		status:   q.Status,
+		digest:   q.Digest,
+		incident: q.Incident,
+	}
+	for _, a := range q.Alerts {
+		note := alertNote{
+			rule:        a.Rule,
+			severity:    a.Severity,
+			status:      a.Status,
+			subject:     a.Subject,
+			body:        a.Body,
+			project:     a.Project,
+			at:          a.At,
+			key:         a.Key,
+			containerID: a.ContainerID,
+			kind:        a.Kind,
+		}
+		for _, m := range a.Metrics {
+			note.metrics = append(note.metrics, noteMetric{name: m.Name, value: m.Value})
+		}
+		n.alerts = append(n.alerts, note)
+	}
+	return n, nil
+}
+
+// queueBackoff returns the delay before retrying after the given number of
+// failed attempts.
+func queueBackoff(attempts int) time.Duration {
+	d := queueBackoffMin
+	for i := 1; i < attempts && d < queueBackoffMax; i++ {
+		d *= 2
+	}
+	return min(d, queueBackoffMax)
+}
+
+// startQueue switches the notifier to the durable queue in store. Queued
+// deliveries to channels that are no longer configured are failed; the
+// rest, including those replayed from a previous run, are delivered by
+// runQueue.
+func (n *Notifier) startQueue(store *Store) {
+	if err := store.FailRemovedChannels(context.Background(), n.names); err != nil {
+		slog.Warn("failed to expire notifications for removed channels", "error", err)
+	}
+	if len(n.channels) == 0 {
+		return
+	}
+	n.store = store
+	n.wake = make(chan struct{}, 1)
+	n.flushReq = make(chan chan struct{})
+	n.quit = make(chan struct{})
+	if n.now == nil {
+		n.now = time.Now
+	}
+	n.wg.Add(1)
+	go n.runQueue()
+}
+
+// enqueue writes one delivery of msg per recipient channel to the store.
+// Deliveries that fall in quiet hours are deferred or dropped instead.
+func (n *Notifier) enqueue(msg notification) error {
+	now := n.now()
+	var names []string
+	deferred := make(map[string]time.Time)
+	for _, i := range n.recipients(msg) {
+		name := n.names[i]
+		until, drop, quiet := n.quietAt(name, msg, now)
+		switch {
+		case !quiet:
+			names = append(names, name)
+		case drop:
+			slog.Info("notification suppressed (quiet hours)", "channel", name, "subject", msg.subject)
+		default:
+			deferred[name] = until
+		}
+	}
+	if len(names) == 0 && len(deferred) == 0 {
+		return nil
+	}
+	payload, err := encodeQueued(msg)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	for name, until := range deferred {
+		if err := n.store.DeferNotification(ctx, name, msg.subject, payload, now, until); err != nil {
+			return err
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	if err := n.store.EnqueueNotification(ctx, names, msg.subject, payload, now); err != nil {
+		return err
+	}
+	select {
+	case n.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// runQueue delivers due notifications from the store until Stop, sleeping
+// until the next retry is due, quiet hours end or a new notification is
+// queued.
+func (n *Notifier) runQueue() {
+	defer n.wg.Done()
+	for {
+		n.releaseDeferred()
+		for n.deliverDue() {
+		}
+		timer := time.NewTimer(n.nextDue())
+		select {
+		case <-n.quit:
+			timer.Stop()
+			return
+		case <-n.wake:
+		case reply := <-n.flushReq:
+			n.releaseDeferred()
+			for n.deliverDue() {
+			}
+			close(reply)
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// deliverDue makes one attempt at each due delivery and reports whether more
+// may be due. Once a delivery to a channel fails, the channel's remaining
+// deliveries wait for its retry.
+func (n *Notifier) deliverDue() bool {
+	ctx := context.Background()
+	due, err := n.store.DueNotifications(ctx, n.names, n.now(), queueBatch)
+	if err != nil {
+		slog.Error("failed to load queued notifications", "error", err)
+		return false
+	}
+	down := make(map[string]bool)
+	for _, d := range due {
+		select {
+		case <-n.quit:
+			return false
+		default:
+		}
+		if !down[d.Channel] && !n.deliver(ctx, &d) {
+			down[d.Channel] = true
+		}
+	}
+	return len(due) == queueBatch
+}
+
+// nextDue returns how long runQueue may sleep before a delivery is due.
+func (n *Notifier) nextDue() time.Duration {
+	next, ok, err := n.store.NextNotificationDue(context.Background(), n.names)
+	if err != nil || !ok {
+		return queuePoll
+	}
+	return min(max(next.Sub(n.now()), 0), queuePoll)
+}
+
+// deliver makes one attempt at a queued delivery and records the outcome.
+// It reports false if the channel failed.
+func (n *Notifier) deliver(ctx context.Context, d *NotificationDelivery) bool {
+	claimed, err := n.store.ClaimNotification(ctx, d.ID)
+	if err != nil {
+		slog.Error("failed to claim notification", "id", d.ID, "error", err)
+		return false
+	}
+	if !claimed {
+		return true
+	}
+	msg, err := decodeQueued(d.Payload)
+	if err != nil {
+		if err := n.store.FailNotification(ctx, d.ID, "decode: "+err.Error()); err != nil {
+			slog.Error("failed to update notification", "id", d.ID, "error", err)
+		}
+		return true
+	}
+	idx := -1
+	for i, name := range n.names {
+		if name == d.Channel {
+			idx = i
+			break
+		}
+	}
+
+	msg.delivery = fmt.Sprintf("%d-%d", d.CreatedAt.Unix(), d.ID)
+
+	sendCtx, cancel := context.WithTimeout(ctx, queueSendTimeout)
+	sendErr := n.channels[idx].Send(sendCtx, msg)
+	cancel()
+
+	now := n.now()
+	attempt := d.Attempts + 1
+	switch {
+	case sendErr == nil:
+		if attempt > 1 {
+			slog.Info("notification sent after retry", "channel", d.Channel, "subject", d.Subject, "attempt", attempt)
+		}
+		err = n.store.MarkNotificationSent(ctx, d.ID, now)
+	case now.Sub(d.CreatedAt) >= queueRetryFor:
+		slog.Error("notification failed, giving up", "channel", d.Channel, "subject", d.Subject,
+			"attempts", attempt, "error", sendErr)
+		err = n.store.FailNotification(ctx, d.ID, sendErr.Error())
+	default:
+		next := now.Add(queueBackoff(attempt))
+		slog.Warn("notification failed, will retry", "channel", d.Channel, "subject", d.Subject,
+			"attempt", attempt, "retry_at", next, "error", sendErr)
+		err = n.store.RetryNotification(ctx, d.ID, d.Channel, sendErr.Error(), next)
+	}
+	if err != nil {
+		slog.Error("failed to update notification", "id", d.ID, "error", err)
+	}
+	return sendErr == nil
+}
@@ -0,0 +1,240 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyChannel fails while down is set and records what it delivered.
+type flakyChannel struct {
+	mu       sync.Mutex
+	down     bool
+	attempts int
+	sent     []string
+}
+
+func (f *flakyChannel) Send(_ context.Context, n notification) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.attempts++
+	if f.down {
+		return errors.New("connection refused")
+	}
+	f.sent = append(f.sent, n.subject)
+	return nil
+}
+
+func (f *flakyChannel) setDown(down bool) {
+	f.mu.Lock()
+	f.down = down
+	f.mu.Unlock()
+}
+
+func (f *flakyChannel) state() (int, []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.attempts, slices.Clone(f.sent)
+}
+
+// testClock is a settable clock safe to read from the queue goroutine.
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+// testQueueNotifier returns a notifier queueing durably in s, delivering to
+// ch under the name "ch".
+func testQueueNotifier(t *testing.T, s *Store, ch Channel, clock *testClock) *Notifier {
+	t.Helper()
+	n := &Notifier{
+		channels: []Channel{ch},
+		names:    []string{"ch"},
+		queue:    make(chan notification, 64),
+		now:      clock.Now,
+	}
+	n.wg.Add(1)
+	go n.run()
+	n.startQueue(s)
+	t.Cleanup(n.Stop)
+	return n
+}
+
+func notificationStatuses(t *testing.T, s *Store) []string {
+	t.Helper()
+	deliveries, _, _, err := s.QueryNotifications(context.Background(), "", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []string
+	for _, d := range deliveries {
+		out = append(out, d.Subject+":"+d.Status)
+	}
+	return out
+}
+
+func TestNotifyQueueRetry(t *testing.T) {
+	s := testStore(t)
+	clock := &testClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	ch := &flakyChannel{down: true}
+	n := testQueueNotifier(t, s, ch, clock)
+
+	n.Send("first", "body")
+	n.Send("second", "body")
+	n.Flush()
+
+	// The first delivery failed; the second waits for the channel's retry.
+	if attempts, _ := ch.state(); attempts != 1 {
+		t.Fatalf("attempts = %d, want 1", attempts)
+	}
+	deliveries, queued, failed, err := s.QueryNotifications(context.Background(), "queued", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 2 || queued != 2 || failed != 0 {
+		t.Fatalf("queued = %d (%d), failed = %d, want 2 queued", len(deliveries), queued, failed)
+	}
+	first := deliveries[1]
+	if first.Attempts != 1 || first.Error != "connection refused" || !first.NextAttempt.Equal(clock.Now().Add(30*time.Second)) {
+		t.Errorf("first = %+v, want 1 attempt retried in 30s", first)
+	}
+	if second := deliveries[0]; second.Attempts != 0 || !second.NextAttempt.Equal(first.NextAttempt) {
+		t.Errorf("second = %+v, want held back until %v", second, first.NextAttempt)
+	}
+
+	// Not due yet.
+	clock.Advance(10 * time.Second)
+	n.Flush()
+	if attempts, _ := ch.state(); attempts != 1 {
+		t.Fatalf("attempts = %d before the retry is due, want 1", attempts)
+	}
+
+	ch.setDown(false)
+	clock.Advance(20 * time.Second)
+	n.Flush()
+	if _, sent := ch.state(); !slices.Equal(sent, []string{"first", "second"}) {
+		t.Errorf("sent = %v, want both in order", sent)
+	}
+	if got := notificationStatuses(t, s); !slices.Equal(got, []string{"second:sent", "first:sent"}) {
+		t.Errorf("statuses = %v", got)
+	}
+}
+
+func TestNotifyQueueGivesUp(t *testing.T) {
+	s := testStore(t)
+	clock := &testClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	ch := &flakyChannel{down: true}
+	n := testQueueNotifier(t, s, ch, clock)
+
+	n.Send("lost", "body")
+	n.Flush()
+	clock.Advance(queueRetryFor)
+	n.Flush()
+
+	deliveries, queued, failed, err := s.QueryNotifications(context.Background(), "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queued != 0 || failed != 1 || deliveries[0].Status != "failed" || deliveries[0].Attempts != 2 ||
+		deliveries[0].Error != "connection refused" {
+		t.Errorf("deliveries = %+v, queued = %d, failed = %d", deliveries, queued, failed)
+	}
+}
+
+func TestNotifyQueueReplay(t *testing.T) {
+	s := testStore(t)
+	ctx := context.Background()
+	clock := &testClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	// A previous run queued two deliveries and stopped mid-send of one; one
+	// went to a channel that has since been removed.
+	payload, err := encodeQueued(notification{subject: "pending", body: "b", severity: "critical", status: "firing",
+		alerts: []alertNote{{rule: "cpu", status: "firing", subject: "pending", key: "cpu",
+			containerID: "c1", metrics: []noteMetric{{name: "CPU", value: "97.0%"}}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.EnqueueNotification(ctx, []string{"ch", "ch", "old"}, "pending", payload, clock.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := s.ClaimNotification(ctx, 1); err != nil || !ok {
+		t.Fatalf("claim = %v, %v", ok, err)
+	}
+	if n, err := s.RequeueNotifications(ctx); err != nil || n != 3 {
+		t.Fatalf("requeued = %d, %v; want 3 queued", n, err)
+	}
+
+	rec := &recordingChannel{}
+	n := testQueueNotifier(t, s, rec, clock)
+	n.Flush()
+
+	notices := rec.Notifications()
+	if len(notices) != 2 {
+		t.Fatalf("delivered %d, want 2", len(notices))
+	}
+	if got := notices[0]; got.severity != "critical" || len(got.alerts) != 1 || got.alerts[0].key != "cpu" ||
+		got.alerts[0].containerID != "c1" || len(got.alerts[0].metrics) != 1 || got.alerts[0].metrics[0].value != "97.0%" {
+		t.Errorf("replayed notification = %+v", got)
+	}
+	deliveries, _, _, err := s.QueryNotifications(ctx, "failed", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 || deliveries[0].Channel != "old" || deliveries[0].Error != "channel removed from config" {
+		t.Errorf("failed = %+v, want the delivery to the removed channel", deliveries)
+	}
+}
+
+func TestNotifyQueueClaimOnce(t *testing.T) {
+	s := testStore(t)
+	ctx := context.Background()
+	if err := s.EnqueueNotification(ctx, []string{"ch"}, "s", []byte(`{}`), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	// Two notifiers overlap while the alerter is replaced; only one sends.
+	first, err := s.ClaimNotification(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.ClaimNotification(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !first || second {
+		t.Errorf("claims = %v, %v; want true, false", first, second)
+	}
+}
+
+func TestQueueBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{6, 16 * time.Minute},
+		{7, 30 * time.Minute},
+		{100, 30 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := queueBackoff(tt.attempts); got != tt.want {
+			t.Errorf("queueBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+)
+
+// quietWindow is a recurring period during which alert notifications to some
+// channels, or of some severities, are deferred or dropped.
+type quietWindow struct {
+	win        *maintenanceWindow
+	severities map[string]bool // empty = all severities
+	channels   map[string]bool // empty = all channels
+	drop       bool            // drop instead of defer
+}
+
+func newQuietWindow(q *QuietHoursConfig) (*quietWindow, error) {
+	win, err := newMaintenanceWindow(&MaintenanceConfig{
+		Name:     q.Name,
+		Schedule: q.Schedule,
+		Duration: q.Duration,
+		Days:     q.Days,
+		Start:    q.Start,
+		End:      q.End,
+		Timezone: q.Timezone,
+	})
+	if err != nil {
+		return nil, err
+	}
+	w := &quietWindow{win: win}
+	switch q.Action {
+	case "", "defer":
+	case "drop":
+		w.drop = true
+	default:
+		return nil, fmt.Errorf("invalid action %q (must be defer or drop)", q.Action)
+	}
+	if len(q.Severities) > 0 {
+		w.severities = make(map[string]bool, len(q.Severities))
+		for _, s := range q.Severities {
+			if s != "warning" && s != "critical" {
+				return nil, fmt.Errorf("invalid severity %q (must be warning or critical)", s)
+			}
+			w.severities[s] = true
+		}
+	}
+	if len(q.Channels) > 0 {
+		w.channels = make(map[string]bool, len(q.Channels))
+		for _, ch := range q.Channels {
+			w.channels[ch] = true
+		}
+	}
+	return w, nil
+}
+
+// covers reports whether the window applies to notifications of severity
+// sent to channel.
+func (w *quietWindow) covers(channel, severity string) bool {
+	if len(w.channels) > 0 && !w.channels[channel] {
+		return false
+	}
+	return len(w.severities) == 0 || w.severities[severity]
+}
+
+// quietAt reports whether delivering msg to channel at now falls in quiet
+// hours and, if so, whether it is dropped or deferred until when. Only alert
+// notifications are held back, not test notifications sent on request or
+// resolve and acknowledge events for incident channels, which would otherwise
+// leave an incident open upstream. When
+// several windows apply, dropping wins; otherwise the notification waits for
+// the last of them to end.
+func (n *Notifier) quietAt(channel string, msg notification, now time.Time) (until time.Time, drop, ok bool) {
+	if msg.severity == "" || msg.digest || msg.incident || msg.status == "test" {
+		return time.Time{}, false, false
+	}
+	for _, w := range n.quiet {
+		if !w.covers(channel, msg.severity) {
+			continue
+		}
+		end, active := w.win.activeAt(now)
+		if !active {
+			continue
+		}
+		if w.drop {
+			return time.Time{}, true, true
+		}
+		ok = true
+		if end.After(until) {
+			until = end
+		}
+	}
+	return until, false, ok
+}
+
+// releaseDeferred replaces the deferred deliveries whose quiet hours have
+// ended with one queued summary per channel.
+func (n *Notifier) releaseDeferred() {
+	ctx := context.Background()
+	due, err := n.store.DueDeferred(ctx, n.names, n.now())
+	if err != nil {
+		slog.Error("failed to load deferred notifications", "error", err)
+		return
+	}
+	byChannel := make(map[string][]NotificationDelivery)
+	var order []string
+	for _, d := range due {
+		if _, ok := byChannel[d.Channel]; !ok {
+			order = append(order, d.Channel)
+		}
+		byChannel[d.Channel] = append(byChannel[d.Channel], d)
+	}
+	for _, channel := range order {
+		ds := byChannel[channel]
+		var ids []int64
+		var notes []alertNote
+		for _, d := range ds {
+			msg, err := decodeQueued(d.Payload)
+			if err != nil {
+				if err := n.store.FailNotification(ctx, d.ID, "decode: "+err.Error()); err != nil {
+					slog.Error("failed to update notification", "id", d.ID, "error", err)
+				}
+				continue
+			}
+			ids = append(ids, d.ID)
+			notes = append(notes, incidentNotes(msg)...)
+		}
+		if len(notes) == 0 {
+			continue
+		}
+		msg := quietSummary(notes)
+		payload, err := encodeQueued(msg)
+		if err != nil {
+			slog.Error("failed to encode quiet hours summary", "channel", channel, "error", err)
+			continue
+		}
+		if _, err := n.store.SummarizeDeferred(ctx, ids, channel, msg.subject, payload, n.now()); err != nil {
+			slog.Error("failed to queue quiet hours summary", "channel", channel, "error", err)
+		}
+	}
+}
+
+// quietSummaryOrder is the order of the counts in a quiet hours summary
+// subject; other labels follow in the order they first appear.
+var quietSummaryOrder = []string{"firing", "resolved", "acknowledged", "flapping", "escalated", "remediation"}
+
+// quietSummary combines the alert notifications deferred during quiet hours
+// into one notification. Only the last state transition of each alert
+// instance is kept, so an alert that fired and resolved while quiet shows as
+// resolved; flapping, escalation and remediation notes are listed on their
+// own, labeled with their kind.
+func quietSummary(notes []alertNote) notification {
+	last := make(map[string]int)
+	var kept []alertNote
+	for _, note := range notes {
+		if note.key != "" {
+			k := note.kind + "\x00" + note.key
+			if i, ok := last[k]; ok {
+				kept[i] = note
+				continue
+			}
+			last[k] = len(kept)
+		}
+		kept = append(kept, note)
+	}
+
+	counts := make(map[string]int)
+	labels := slices.Clone(quietSummaryOrder)
+	var body strings.Builder
+	for i := range kept {
+		note := &kept[i]
+		note.targets = nil
+		label := note.kind
+		if label == "" {
+			label = note.status
+		}
+		if !slices.Contains(labels, label) {
+			labels = append(labels, label)
+		}
+		counts[label]++
+		fmt.Fprintf(&body, "%s %s", note.at.Format("15:04:05"), note.body)
+		if label != "firing" {
+			fmt.Fprintf(&body, " (%s)", label)
+		}
+		body.WriteByte('\n')
+	}
+	var parts []string
+	for _, label := range labels {
+		if counts[label] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[label], label))
+		}
+	}
+
+	msg := groupNotification("", kept)
+	msg.subject = "Quiet hours ended: " + strings.Join(parts, ", ")
+	msg.body = strings.TrimSuffix(body.String(), "\n")
+	return msg
+}
@@ -0,0 +1,149 @@
+package agent
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestNotifyQuietHours(t *testing.T) {
+	s := testStore(t)
+	// Wednesday 00:00 UTC, inside the 22:00-07:00 window.
+	clock := &testClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	ch := &flakyChannel{}
+	n := testQueueNotifier(t, s, ch, clock)
+	w, err := newQuietWindow(&QuietHoursConfig{
+		Name: "night", Start: "22:00", End: "07:00", Timezone: "UTC", Severities: []string{"warning"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.quiet = []*quietWindow{w}
+
+	note := func(key, severity, status string) alertNote {
+		return alertNote{rule: key, severity: severity, status: status, subject: key + " " + status,
+			body: key + " " + status, key: key, at: clock.Now()}
+	}
+	n.Notify(note("cpu", "warning", "firing"))
+	n.Notify(note("disk", "critical", "firing"))
+	n.Notify(note("mem", "warning", "firing"))
+	n.Notify(note("cpu", "warning", "resolved"))
+	n.Flush()
+
+	// Only the critical alert is sent during quiet hours.
+	if _, sent := ch.state(); !slices.Equal(sent, []string{"disk firing"}) {
+		t.Fatalf("sent during quiet hours = %v", sent)
+	}
+	want := []string{"cpu resolved:deferred", "mem firing:deferred", "disk firing:sent", "cpu firing:deferred"}
+	if got := notificationStatuses(t, s); !slices.Equal(got, want) {
+		t.Fatalf("statuses = %v, want %v", got, want)
+	}
+
+	// When the window ends, the deferred notifications go out as one
+	// summary with the last transition of each alert.
+	clock.Advance(7 * time.Hour)
+	n.Flush()
+	summary := "Quiet hours ended: 1 firing, 1 resolved"
+	if _, sent := ch.state(); !slices.Equal(sent, []string{"disk firing", summary}) {
+		t.Fatalf("sent after quiet hours = %v", sent)
+	}
+	want = []string{summary + ":sent", "cpu resolved:summarized", "mem firing:summarized",
+		"disk firing:sent", "cpu firing:summarized"}
+	if got := notificationStatuses(t, s); !slices.Equal(got, want) {
+		t.Errorf("statuses = %v, want %v", got, want)
+	}
+}
+
+func TestNotifyQuietHoursDrop(t *testing.T) {
+	s := testStore(t)
+	clock := &testClock{now: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)}
+	ch := &flakyChannel{}
+	n := testQueueNotifier(t, s, ch, clock)
+	w, err := newQuietWindow(&QuietHoursConfig{
+		Name: "lunch", Start: "11:00", End: "13:00", Timezone: "UTC", Channels: []string{"ch"}, Action: "drop",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.quiet = []*quietWindow{w}
+
+	n.SendAlert("cpu firing", "body", "critical", "firing")
+	n.Send("test", "plain notifications are not held back")
+	n.Flush()
+	if _, sent := ch.state(); !slices.Equal(sent, []string{"test"}) {
+		t.Errorf("sent = %v, want only the plain notification", sent)
+	}
+	if got := notificationStatuses(t, s); !slices.Equal(got, []string{"test:sent"}) {
+		t.Errorf("statuses = %v, want the dropped alert unrecorded", got)
+	}
+}
+
+func TestQuietAt(t *testing.T) {
+	mk := func(q QuietHoursConfig) *quietWindow {
+		t.Helper()
+		w, err := newQuietWindow(&q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return w
+	}
+	n := &Notifier{quiet: []*quietWindow{
+		mk(QuietHoursConfig{Name: "a", Start: "22:00", End: "07:00", Timezone: "UTC", Channels: []string{"email"}}),
+		mk(QuietHoursConfig{Name: "b", Start: "23:00", End: "08:00", Timezone: "UTC", Severities: []string{"warning"}}),
+		mk(QuietHoursConfig{Name: "c", Start: "23:00", End: "23:30", Timezone: "UTC", Channels: []string{"ntfy"}, Action: "drop"}),
+	}}
+	night := time.Date(2025, 1, 1, 23, 15, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		channel string
+		msg     notification
+		at      time.Time
+		quiet   bool
+		drop    bool
+		until   time.Time
+	}{
+		{"latest end wins", "email", notification{severity: "warning"}, night, true, false, time.Date(2025, 1, 2, 8, 0, 0, 0, time.UTC)},
+		{"severity filter", "email", notification{severity: "critical"}, night, true, false, time.Date(2025, 1, 2, 7, 0, 0, 0, time.UTC)},
+		{"channel filter", "slack", notification{severity: "critical"}, night, false, false, time.Time{}},
+		{"drop wins", "ntfy", notification{severity: "warning"}, night, true, true, time.Time{}},
+		{"outside windows", "email", notification{severity: "warning"}, night.Add(-2 * time.Hour), false, false, time.Time{}},
+		{"plain notification", "email", notification{}, night, false, false, time.Time{}},
+		{"digest", "email", notification{severity: "warning", digest: true}, night, false, false, time.Time{}},
+		{"test notification", "email", notification{severity: "warning", status: "test"}, night, false, false, time.Time{}},
+		{"incident event", "email", notification{severity: "warning", status: "resolved", incident: true}, night, false, false, time.Time{}},
+		{"incident event dropped window", "ntfy", notification{severity: "warning", status: "acknowledged", incident: true}, night, false, false, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			until, drop, quiet := n.quietAt(tt.channel, tt.msg, tt.at)
+			if quiet != tt.quiet || drop != tt.drop || !until.Equal(tt.until) {
+				t.Errorf("quietAt = %v, %v, %v; want %v, %v, %v", until, drop, quiet, tt.until, tt.drop, tt.quiet)
+			}
+		})
+	}
+}
+
+func TestQuietSummary(t *testing.T) {
+	at := time.Date(2025, 1, 1, 3, 0, 0, 0, time.UTC)
+	notes := []alertNote{
+		{rule: "cpu", severity: "warning", status: "firing", body: "cpu high", key: "cpu", at: at},
+		{rule: "cpu", severity: "warning", status: "firing", body: "cpu escalated", key: "cpu", at: at, kind: "escalated"},
+		{rule: "cpu", severity: "warning", status: "resolved", body: "cpu ok", key: "cpu", at: at},
+		{rule: "web", severity: "critical", status: "firing", body: "web down", key: "web", at: at},
+		{rule: "web", severity: "critical", status: "firing", body: "web restarted", key: "web", at: at, kind: "remediation"},
+		{rule: "disk", severity: "warning", status: "resolved", body: "disk flapping", key: "disk", at: at, kind: "flapping"},
+		{rule: "mem", severity: "warning", status: "acknowledged", body: "mem high", key: "mem", at: at},
+	}
+	msg := quietSummary(notes)
+	if want := "Quiet hours ended: 1 firing, 1 resolved, 1 acknowledged, 1 flapping, 1 escalated, 1 remediation"; msg.subject != want {
+		t.Errorf("subject = %q, want %q", msg.subject, want)
+	}
+	wantBody := "03:00:00 cpu ok (resolved)\n03:00:00 cpu escalated (escalated)\n03:00:00 web down\n" +
+		"03:00:00 web restarted (remediation)\n03:00:00 disk flapping (flapping)\n03:00:00 mem high (acknowledged)"
+	if msg.body != wantBody {
+		t.Errorf("body = %q, want %q", msg.body, wantBody)
+	}
+	if msg.severity != "critical" || msg.status != "firing" || len(msg.alerts) != 6 {
+		t.Errorf("summary = %+v", msg)
+	}
+}
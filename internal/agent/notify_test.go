@@ -14,18 +14,24 @@ import (
 	"fmt"
 	"io"
 	"math/big"
+	"mime"
+	"mime/multipart"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/mail"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/thobiasn/tori-cli/webhook"
 )
 
 func TestSendNoChannels(t *testing.T) {
-	n := NewNotifier(&NotifyConfig{})
+	n := NewNotifier(&NotifyConfig{}, nil)
 	// Should not panic with no channels enabled.
 	n.Send("test", "body")
 	n.Stop()
@@ -50,7 +56,7 @@ func TestWebhookPayload(t *testing.T) {
 
 	n := NewNotifier(&NotifyConfig{
 		Webhooks: []WebhookConfig{{Enabled: true, URL: srv.URL}},
-	})
+	}, nil)
 
 	n.Send("Alert: high_cpu", "CPU is at 95%")
 	n.Stop()
@@ -72,7 +78,7 @@ func TestWebhookErrorStatus(t *testing.T) {
 
 	n := NewNotifier(&NotifyConfig{
 		Webhooks: []WebhookConfig{{Enabled: true, URL: srv.URL}},
-	})
+	}, nil)
 
 	// Should not panic — errors are logged, not returned.
 	n.Send("test", "body")
@@ -99,7 +105,7 @@ func TestWebhookCustomHeaders(t *testing.T) {
 				"X-Custom":      "myvalue",
 			},
 		}},
-	})
+	}, nil)
 
 	n.Send("test", "body")
 	n.Stop()
@@ -112,6 +118,43 @@ func TestWebhookCustomHeaders(t *testing.T) {
 	}
 }
 
+func TestWebhookSigned(t *testing.T) {
+	var gotHeader http.Header
+	var gotBody []byte
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Clone()
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	n := NewNotifier(&NotifyConfig{
+		Webhooks: []WebhookConfig{
+			{Enabled: true, URL: srv.URL, Secret: "s3cret"},
+		},
+	}, nil)
+	n.Send("test", "body")
+	n.Stop()
+
+	if err := webhook.Verify("s3cret", gotHeader, gotBody, webhook.DefaultTolerance); err != nil {
+		t.Errorf("Verify = %v", err)
+	}
+	if err := webhook.Verify("other", gotHeader, gotBody, webhook.DefaultTolerance); err == nil {
+		t.Error("Verify with the wrong secret should fail")
+	}
+
+	// Without a secret the request is unsigned.
+	n = NewNotifier(&NotifyConfig{
+		Webhooks: []WebhookConfig{{Enabled: true, URL: srv.URL}},
+	}, nil)
+	n.Send("test", "body")
+	n.Stop()
+	if got := gotHeader.Get(webhook.SignatureHeader); got != "" {
+		t.Errorf("unsigned webhook sent %s = %q", webhook.SignatureHeader, got)
+	}
+}
+
 func TestWebhookCustomTemplate(t *testing.T) {
 	var gotBody string
 
@@ -128,7 +171,7 @@ func TestWebhookCustomTemplate(t *testing.T) {
 			URL:      srv.URL,
 			Template: `{"summary":"{{.Subject}}","detail":"{{.Body}}"}`,
 		}},
-	})
+	}, nil)
 
 	n.Send("CPU alert", "CPU is high")
 	n.Stop()
@@ -231,7 +274,7 @@ func TestWebhookTemplateJSONEscape(t *testing.T) {
 					URL:      srv.URL,
 					Template: tmpl,
 				}},
-			})
+			}, nil)
 
 			n.SendAlert(tt.subject, tt.body, tt.severity, tt.status)
 			n.Stop()
@@ -303,7 +346,7 @@ func TestWebhookSlackAttachmentTemplate(t *testing.T) {
 					URL:      srv.URL,
 					Template: tmpl,
 				}},
-			})
+			}, nil)
 
 			n.SendAlert(tt.subject, tt.body, tt.severity, tt.status)
 			n.Stop()
@@ -339,7 +382,7 @@ func TestMultipleWebhooks(t *testing.T) {
 			{Enabled: true, URL: srv1.URL},
 			{Enabled: true, URL: srv2.URL},
 		},
-	})
+	}, nil)
 
 	n.Send("test", "body")
 	n.Stop()
@@ -363,7 +406,7 @@ func TestWebhookDisabledSkipped(t *testing.T) {
 		Webhooks: []WebhookConfig{
 			{Enabled: false, URL: srv.URL},
 		},
-	})
+	}, nil)
 
 	n.Send("test", "body")
 	n.Stop()
@@ -467,7 +510,7 @@ func TestWebhookHeaderSanitization(t *testing.T) {
 				"X-Injected": "safe\r\nEvil-Header: injected",
 			},
 		}},
-	})
+	}, nil)
 
 	n.Send("test", "body")
 	n.Stop()
@@ -493,7 +536,7 @@ func TestWebhookTemplateSeverityStatus(t *testing.T) {
 			URL:      srv.URL,
 			Template: `{"sev":"{{.Severity}}","status":"{{.Status}}","msg":"{{.Subject}}"}`,
 		}},
-	})
+	}, nil)
 
 	n.SendAlert("CPU alert", "CPU is high", "critical", "firing")
 	n.Stop()
@@ -520,7 +563,7 @@ func TestWebhookTemplateSeverityEmpty(t *testing.T) {
 			URL:      srv.URL,
 			Template: `{"sev":"{{.Severity}}","status":"{{.Status}}","msg":"{{.Subject}}"}`,
 		}},
-	})
+	}, nil)
 
 	// Plain Send (no severity/status) — fields render as empty strings.
 	n.Send("test", "body")
@@ -850,3 +893,848 @@ func TestEmailSendNoAuth(t *testing.T) {
 		t.Fatal("timeout waiting for fake SMTP session")
 	}
 }
+
+// sendFakeEmail sends n through ch to a fake SMTP server and returns the session.
+func sendFakeEmail(t *testing.T, ch *emailChannel, n notification) smtpSession {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	result := make(chan smtpSession, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		result <- runFakeSMTP(conn, nil)
+	}()
+
+	ch.cfg.SMTPHost = "127.0.0.1"
+	ch.cfg.SMTPPort = ln.Addr().(*net.TCPAddr).Port
+	if err := ch.Send(context.Background(), n); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	select {
+	case s := <-result:
+		if s.err != nil {
+			t.Fatalf("fake smtp: %v", s.err)
+		}
+		return s
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for fake SMTP session")
+	}
+	return smtpSession{}
+}
+
+// emailParts parses a multipart/alternative email into its decoded parts by
+// content type.
+func emailParts(t *testing.T, data string) (*mail.Message, map[string]string) {
+	t.Helper()
+	msg, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, %v", msg.Header.Get("Content-Type"), err)
+	}
+	parts := make(map[string]string)
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ct, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		parts[ct] = string(b)
+	}
+	return msg, parts
+}
+
+func TestEmailMultipart(t *testing.T) {
+	s := testStore(t)
+	ctx := context.Background()
+	at := time.Now().Truncate(time.Second)
+	var entries []LogEntry
+	for i := range 30 {
+		entries = append(entries, LogEntry{
+			Timestamp:   at.Add(time.Duration(i-30) * time.Second),
+			ContainerID: "c1",
+			Stream:      "stderr",
+			Message:     fmt.Sprintf("line %d", i),
+		})
+	}
+	entries = append(entries, LogEntry{Timestamp: at, ContainerID: "c2", Stream: "stdout", Message: "other container"})
+	if err := s.InsertLogs(ctx, entries); err != nil {
+		t.Fatal(err)
+	}
+
+	ch := newEmailChannel(EmailConfig{Enabled: true, From: "from@test.com", To: []string{"to@test.com"}, LogLines: 5}, s)
+	sess := sendFakeEmail(t, ch, notification{
+		subject:  "Alert: web_down ✗",
+		body:     "[critical] web is <b>down</b>",
+		severity: "critical",
+		status:   "firing",
+		alerts: []alertNote{{
+			rule: "web_down", severity: "critical", status: "firing", subject: "Alert: web_down",
+			body: "[critical] web is <b>down</b>", at: at, containerID: "c1",
+			metrics: []noteMetric{{name: "Container", value: "web"}, {name: "CPU", value: "97.0%"}},
+		}},
+	})
+
+	msg, parts := emailParts(t, sess.data)
+	if subj, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject")); err != nil || subj != "Alert: web_down ✗" {
+		t.Errorf("Subject = %q, %v", subj, err)
+	}
+	if got := parts["text/plain"]; got != "[critical] web is <b>down</b>" {
+		t.Errorf("text part = %q", got)
+	}
+	html := parts["text/html"]
+	for _, want := range []string{
+		"#e01e5a",                                // critical header color
+		"web is &lt;b&gt;down&lt;/b&gt;",         // body is escaped
+		"<td style=\"padding:2px 0\">97.0%</td>", // metrics table
+		"line 25", "line 29",                     // last 5 log lines of c1
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("html part missing %q:\n%s", want, html)
+		}
+	}
+	for _, unwanted := range []string{"line 24", "other container"} {
+		if strings.Contains(html, unwanted) {
+			t.Errorf("html part contains %q", unwanted)
+		}
+	}
+	if strings.Index(html, "line 25") > strings.Index(html, "line 29") {
+		t.Error("log lines should be oldest first")
+	}
+}
+
+func TestEmailCustomTemplates(t *testing.T) {
+	ch := newEmailChannel(EmailConfig{
+		Enabled:      true,
+		From:         "from@test.com",
+		To:           []string{"to@test.com"},
+		HTMLTemplate: `<h1>{{.Subject}}</h1>{{range .Alerts}}<p>{{.Rule}}: {{.Status}}</p>{{end}}`,
+		TextTemplate: `{{.Subject}} on {{.Hostname}}`,
+	}, nil)
+	sess := sendFakeEmail(t, ch, notification{
+		subject: "Alert: disk_full",
+		body:    "disk is full",
+		alerts:  []alertNote{{rule: "disk_full", status: "firing", at: time.Now()}},
+	})
+
+	_, parts := emailParts(t, sess.data)
+	if got, want := parts["text/html"], "<h1>Alert: disk_full</h1><p>disk_full: firing</p>"; got != want {
+		t.Errorf("html part = %q, want %q", got, want)
+	}
+	if got, want := parts["text/plain"], "Alert: disk_full on "+agentHostname(); got != want {
+		t.Errorf("text part = %q, want %q", got, want)
+	}
+}
+
+func TestNoteMetrics(t *testing.T) {
+	env := &condEnv{
+		host: &HostMetrics{CPUPercent: 12.5, MemPercent: 50, MemUsed: 2 << 30, MemTotal: 4 << 30, Load1: 1, Load5: 0.5, Load15: 0.25},
+		disk: &DiskMetrics{Mountpoint: "/", Percent: 95, Used: 95 << 30, Total: 100 << 30, Free: 5 << 30},
+	}
+	want := []noteMetric{
+		{"Mountpoint", "/"},
+		{"Disk used", "95.0% (95.0 GiB of 100.0 GiB)"},
+		{"Disk free", "5.0 GiB"},
+		{"CPU", "12.5%"},
+		{"Memory", "50.0% (2.0 GiB of 4.0 GiB)"},
+		{"Load", "1.00 0.50 0.25"},
+	}
+	if got := noteMetrics(env); !slices.Equal(got, want) {
+		t.Errorf("noteMetrics = %v, want %v", got, want)
+	}
+
+	got := noteMetrics(&condEnv{container: &ContainerMetrics{Name: "web", Image: "nginx", State: "exited", ExitCode: 137, MemUsage: 512}})
+	for _, m := range []noteMetric{{"State", "exited"}, {"Exit code", "137"}, {"Memory", "512 B"}} {
+		if !slices.Contains(got, m) {
+			t.Errorf("container metrics %v missing %v", got, m)
+		}
+	}
+	if noteMetrics(nil) != nil {
+		t.Error("noteMetrics(nil) should be nil")
+	}
+}
+
+// waitCalls polls rec until it has recorded n notifications or the deadline passes.
+func waitCalls(t *testing.T, rec *recordingChannel, n int) []notification {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if notes := rec.Notifications(); len(notes) >= n {
+			return notes
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d notifications, got %d", n, len(rec.Notifications()))
+	return nil
+}
+
+func TestNotifyGrouping(t *testing.T) {
+	rec := &recordingChannel{}
+	n := &Notifier{channels: []Channel{rec}, queue: make(chan notification, 64)}
+	n.group = newNotifyGrouper([]string{"project"}, 30*time.Millisecond, time.Hour, n.send)
+	n.wg.Add(1)
+	go n.run()
+
+	at := time.Date(2025, 1, 1, 3, 4, 5, 0, time.UTC)
+	n.Notify(alertNote{rule: "exited", severity: "warning", status: "firing", subject: "Alert: exited", body: "web exited", project: "shop", at: at})
+	n.Notify(alertNote{rule: "oom", severity: "critical", status: "firing", subject: "Alert: oom", body: "db oom", project: "shop", at: at})
+	n.Notify(alertNote{rule: "exited", severity: "warning", status: "firing", subject: "Alert: exited", body: "blog exited", project: "blog", at: at})
+
+	notes := waitCalls(t, rec, 2)
+	bySubject := map[string]notification{}
+	for _, nt := range notes {
+		bySubject[nt.subject] = nt
+	}
+	shop, ok := bySubject["Alerts: 2 firing (project=shop)"]
+	if !ok {
+		t.Fatalf("subjects = %v", rec.Calls())
+	}
+	if shop.severity != "critical" || shop.status != "firing" || len(shop.alerts) != 2 {
+		t.Errorf("shop = %+v", shop)
+	}
+	if shop.body != "03:04:05 web exited\n03:04:05 db oom" {
+		t.Errorf("shop body = %q", shop.body)
+	}
+	// A group of one keeps the original subject.
+	if _, ok := bySubject["Alert: exited"]; !ok {
+		t.Errorf("subjects = %v, want single blog notification", rec.Calls())
+	}
+
+	// Within group_interval the next note is held until Stop flushes it.
+	n.Notify(alertNote{rule: "exited", severity: "warning", status: "firing", subject: "Alert: exited", body: "api exited", project: "shop", at: at})
+	time.Sleep(60 * time.Millisecond)
+	if c := len(rec.Calls()); c != 2 {
+		t.Fatalf("calls = %d before stop, want 2", c)
+	}
+	n.Stop()
+	if calls := rec.Calls(); len(calls) != 3 || calls[2] != "Alert: exited" {
+		t.Errorf("calls = %v after stop", calls)
+	}
+}
+
+func TestGroupNotification(t *testing.T) {
+	at := time.Date(2025, 1, 1, 3, 4, 5, 0, time.UTC)
+	notes := []alertNote{
+		{rule: "a", severity: "warning", status: "resolved", body: "a ok", at: at},
+		{rule: "b", severity: "warning", status: "resolved", body: "b ok", at: at},
+	}
+	n := groupNotification("rule=a", notes)
+	if n.subject != "Alerts: 0 firing, 2 resolved (rule=a)" || n.status != "resolved" || n.severity != "warning" {
+		t.Errorf("got %+v", n)
+	}
+	if n.body != "03:04:05 a ok (resolved)\n03:04:05 b ok (resolved)" {
+		t.Errorf("body = %q", n.body)
+	}
+}
+
+func TestWebhookTemplateAlerts(t *testing.T) {
+	var bodies []string
+	var mu sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(b))
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	n := NewNotifier(&NotifyConfig{
+		Webhooks: []WebhookConfig{{
+			Enabled:  true,
+			URL:      srv.URL,
+			Template: `{"alerts":[{{range $i, $a := .Alerts}}{{if $i}},{{end}}"{{$a.Rule}}@{{$a.Project}} {{$a.Time}}"{{end}}]}`,
+		}},
+		GroupBy:       []string{"severity"},
+		GroupWait:     Duration{time.Hour},
+		GroupInterval: Duration{time.Hour},
+	}, nil)
+	at := time.Date(2025, 1, 1, 3, 4, 5, 0, time.UTC)
+	n.Notify(alertNote{rule: "exited", severity: "warning", status: "firing", project: "shop", at: at})
+	n.Notify(alertNote{rule: "disk \"full\"", severity: "warning", status: "firing", at: at})
+	n.Stop() // delivers the held group
+
+	want := `{"alerts":["exited@shop 2025-01-01T03:04:05Z","disk \"full\"@ 2025-01-01T03:04:05Z"]}`
+	if len(bodies) != 1 || bodies[0] != want {
+		t.Errorf("bodies = %q, want [%q]", bodies, want)
+	}
+}
+
+func TestNtfyChannel(t *testing.T) {
+	tests := []struct {
+		name         string
+		n            notification
+		wantPriority float64
+		wantTags     []any
+	}{
+		{"critical", notification{subject: "Alert: disk", body: "disk full", severity: "critical", status: "firing"}, 5, []any{"rotating_light", "prod"}},
+		{"warning", notification{subject: "Alert: cpu", body: "cpu high", severity: "warning", status: "firing"}, 4, []any{"warning", "prod"}},
+		{"resolved", notification{subject: "Resolved: cpu", body: "ok", severity: "critical", status: "resolved"}, 3, []any{"white_check_mark", "prod"}},
+		{"test", notification{subject: "Test", body: "test"}, 3, []any{"bell", "prod"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got map[string]any
+			var auth, path string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				auth = r.Header.Get("Authorization")
+				path = r.URL.Path
+				json.NewDecoder(r.Body).Decode(&got)
+			}))
+			defer srv.Close()
+
+			ch := &ntfyChannel{cfg: NtfyConfig{URL: srv.URL, Topic: "alerts", Token: "tk_secret", Tags: []string{"prod"}}}
+			if err := ch.Send(context.Background(), tt.n); err != nil {
+				t.Fatal(err)
+			}
+			if path != "/" {
+				t.Errorf("path = %q, want /", path)
+			}
+			if auth != "Bearer tk_secret" {
+				t.Errorf("authorization = %q", auth)
+			}
+			if got["topic"] != "alerts" || got["title"] != tt.n.subject || got["message"] != tt.n.body {
+				t.Errorf("payload = %v", got)
+			}
+			if got["priority"] != tt.wantPriority {
+				t.Errorf("priority = %v, want %v", got["priority"], tt.wantPriority)
+			}
+			tags, _ := got["tags"].([]any)
+			if fmt.Sprint(tags) != fmt.Sprint(tt.wantTags) {
+				t.Errorf("tags = %v, want %v", tags, tt.wantTags)
+			}
+		})
+	}
+}
+
+func TestGotifyChannel(t *testing.T) {
+	var got map[string]any
+	var key, path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key = r.Header.Get("X-Gotify-Key")
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer srv.Close()
+
+	ch := &gotifyChannel{cfg: GotifyConfig{URL: srv.URL + "/", Token: "AppToken"}}
+	n := notification{subject: "Alert: disk", body: "disk full", severity: "critical", status: "firing"}
+	if err := ch.Send(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/message" || key != "AppToken" {
+		t.Errorf("path = %q, key = %q", path, key)
+	}
+	if got["title"] != "Alert: disk" || got["message"] != "disk full" || got["priority"] != float64(8) {
+		t.Errorf("payload = %v", got)
+	}
+
+	for _, tt := range []struct {
+		n    notification
+		want int
+	}{
+		{notification{severity: "warning", status: "firing"}, 5},
+		{notification{severity: "critical", status: "resolved"}, 2},
+		{notification{}, 5},
+	} {
+		if p := gotifyPriority(tt.n); p != tt.want {
+			t.Errorf("gotifyPriority(%s/%s) = %d, want %d", tt.n.severity, tt.n.status, p, tt.want)
+		}
+	}
+}
+
+func TestTelegramChannel(t *testing.T) {
+	var got map[string]any
+	var path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	ch := &telegramChannel{cfg: TelegramConfig{APIURL: srv.URL, Token: "123:abc", ChatID: "-10042"}}
+	n := notification{subject: "Alert: high_cpu", body: "CPU at 95.5% (web-1)", severity: "critical", status: "firing"}
+	if err := ch.Send(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/bot123:abc/sendMessage" {
+		t.Errorf("path = %q", path)
+	}
+	if got["chat_id"] != "-10042" || got["parse_mode"] != "MarkdownV2" {
+		t.Errorf("payload = %v", got)
+	}
+	want := "*Alert: high\\_cpu*\n\nCPU at 95\\.5% \\(web\\-1\\)"
+	if got["text"] != want {
+		t.Errorf("text = %q, want %q", got["text"], want)
+	}
+}
+
+func TestTelegramTextTruncated(t *testing.T) {
+	text := telegramText(notification{subject: "s", body: strings.Repeat(".", 10000)})
+	if n := len([]rune(text)); n > 4096 {
+		t.Errorf("text is %d characters, want at most 4096", n)
+	}
+	if !strings.HasSuffix(text, "…") {
+		t.Error("truncated text should end with …")
+	}
+}
+
+func TestTelegramErrorDescription(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`))
+	}))
+	defer srv.Close()
+
+	ch := &telegramChannel{cfg: TelegramConfig{APIURL: srv.URL, Token: "123:abc", ChatID: "42"}}
+	err := ch.Send(context.Background(), notification{subject: "s", body: "b"})
+	if err == nil || !strings.Contains(err.Error(), "chat not found") {
+		t.Fatalf("error = %v, want the API description", err)
+	}
+}
+
+func TestPushChannelsRetry(t *testing.T) {
+	var mu sync.Mutex
+	attempts := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts[r.URL.Path]++
+		n := attempts[r.URL.Path]
+		mu.Unlock()
+		if n == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	n := NewNotifier(&NotifyConfig{
+		Ntfy:     []NtfyConfig{{Name: "ntfy-1", Enabled: true, URL: srv.URL, Topic: "t"}},
+		Gotify:   []GotifyConfig{{Name: "gotify-1", Enabled: true, URL: srv.URL, Token: "x"}},
+		Telegram: []TelegramConfig{{Name: "telegram-1", Enabled: true, APIURL: srv.URL, Token: "1:a", ChatID: "42"}},
+	}, nil)
+	n.Send("test", "body")
+	n.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, p := range []string{"/", "/message", "/bot1:a/sendMessage"} {
+		if attempts[p] != 2 {
+			t.Errorf("%s: %d attempts, want 2 (one retry)", p, attempts[p])
+		}
+	}
+}
+
+func TestTelegramErrorHidesToken(t *testing.T) {
+	// Nothing listens on this port; the transport error quotes the URL.
+	ch := &telegramChannel{cfg: TelegramConfig{APIURL: "http://127.0.0.1:1", Token: "123:secret", ChatID: "42"}}
+	err := ch.Send(context.Background(), notification{subject: "s", body: "b"})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if strings.Contains(err.Error(), "secret") {
+		t.Errorf("error %q leaks the bot token", err)
+	}
+}
+
+func TestPagerDutyChannel(t *testing.T) {
+	var events []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ev map[string]any
+		json.NewDecoder(r.Body).Decode(&ev)
+		events = append(events, ev)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	ch := &pagerDutyChannel{cfg: PagerDutyConfig{URL: srv.URL, RoutingKey: "R0123"}, host: "web1"}
+	notes := []notification{
+		{alerts: []alertNote{{rule: "cpu", severity: "critical", status: "firing", subject: "Alert: cpu", body: "cpu high", project: "shop", key: "cpu"}}},
+		{alerts: []alertNote{{rule: "cpu", severity: "critical", status: "acknowledged", subject: "Acknowledged: cpu", key: "cpu"}}},
+		{alerts: []alertNote{{rule: "cpu", severity: "critical", status: "resolved", subject: "Resolved: cpu", key: "cpu"}}},
+		{subject: "Test", body: "test"},
+		// Notes that are not state transitions must not reopen the incident.
+		{alerts: []alertNote{
+			{rule: "cpu", severity: "critical", status: "firing", subject: "Flapping: cpu", key: "cpu", kind: "flapping"},
+			{rule: "cpu", severity: "critical", status: "firing", subject: "Escalated: cpu", key: "cpu", kind: "escalated"},
+			{rule: "cpu", severity: "critical", status: "firing", subject: "Remediation: cpu", key: "cpu", kind: "remediation"},
+		}},
+	}
+	for _, n := range notes {
+		if err := ch.Send(context.Background(), n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(events) != 4 {
+		t.Fatalf("events = %d, want 4", len(events))
+	}
+
+	trigger := events[0]
+	if trigger["routing_key"] != "R0123" || trigger["event_action"] != "trigger" || trigger["dedup_key"] != "tori/web1/cpu" {
+		t.Errorf("trigger = %v", trigger)
+	}
+	payload, _ := trigger["payload"].(map[string]any)
+	if payload["summary"] != "Alert: cpu" || payload["source"] != "web1" || payload["severity"] != "critical" ||
+		payload["group"] != "cpu" || payload["component"] != "shop" {
+		t.Errorf("payload = %v", payload)
+	}
+	for i, want := range map[int]string{1: "acknowledge", 2: "resolve"} {
+		if events[i]["event_action"] != want || events[i]["dedup_key"] != "tori/web1/cpu" || events[i]["payload"] != nil {
+			t.Errorf("events[%d] = %v, want %s of tori/web1/cpu", i, events[i], want)
+		}
+	}
+	// Notifications that are not about an alert open an incident of their own.
+	test := events[3]
+	if _, ok := test["dedup_key"]; ok || test["event_action"] != "trigger" {
+		t.Errorf("test event = %v, want trigger without dedup_key", test)
+	}
+	if p, _ := test["payload"].(map[string]any); p["severity"] != "info" {
+		t.Errorf("test severity = %v, want info", p["severity"])
+	}
+}
+
+func TestOpsgenieChannel(t *testing.T) {
+	type request struct {
+		path, query, auth string
+		body              map[string]any
+	}
+	var reqs []request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := request{path: r.URL.EscapedPath(), query: r.URL.RawQuery, auth: r.Header.Get("Authorization")}
+		json.NewDecoder(r.Body).Decode(&req.body)
+		reqs = append(reqs, req)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	ch := &opsgenieChannel{cfg: OpsgenieConfig{URL: srv.URL, APIKey: "k", Tags: []string{"prod"}}, host: "web1"}
+	note := alertNote{rule: "exited", severity: "warning", subject: "Alert: exited", body: "web exited", key: "exited:abc"}
+	for _, status := range []string{"firing", "acknowledged", "resolved"} {
+		note.status = status
+		if err := ch.Send(context.Background(), notification{alerts: []alertNote{note}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	escalated := note
+	escalated.status, escalated.kind, escalated.subject = "firing", "escalated", "Escalated: exited"
+	if err := ch.Send(context.Background(), notification{alerts: []alertNote{escalated}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(reqs) != 4 {
+		t.Fatalf("requests = %d, want 4", len(reqs))
+	}
+	for _, r := range reqs {
+		if r.auth != "GenieKey k" {
+			t.Errorf("%s authorization = %q", r.path, r.auth)
+		}
+	}
+
+	create := reqs[0]
+	if create.path != "/v2/alerts" {
+		t.Errorf("create path = %q", create.path)
+	}
+	b := create.body
+	if b["message"] != "Alert: exited" || b["description"] != "web exited" || b["alias"] != "tori/web1/exited:abc" ||
+		b["priority"] != "P3" || b["entity"] != "web1" {
+		t.Errorf("create body = %v", b)
+	}
+	if tags, _ := b["tags"].([]any); fmt.Sprint(tags) != "[tori prod]" {
+		t.Errorf("tags = %v, want [tori prod]", tags)
+	}
+
+	alias := "tori%2Fweb1%2Fexited:abc"
+	if reqs[1].path != "/v2/alerts/"+alias+"/acknowledge" || reqs[1].query != "identifierType=alias" {
+		t.Errorf("acknowledge = %s?%s", reqs[1].path, reqs[1].query)
+	}
+	if reqs[2].path != "/v2/alerts/"+alias+"/close" || reqs[2].query != "identifierType=alias" {
+		t.Errorf("close = %s?%s", reqs[2].path, reqs[2].query)
+	}
+	// An escalation adds a note to the closed alert instead of creating it again.
+	if reqs[3].path != "/v2/alerts/"+alias+"/notes" || reqs[3].query != "identifierType=alias" ||
+		reqs[3].body["note"] != "Escalated: exited\n\nweb exited" {
+		t.Errorf("note = %s?%s %v", reqs[3].path, reqs[3].query, reqs[3].body)
+	}
+}
+
+func TestIncidentKey(t *testing.T) {
+	if got := incidentKey("web1", "cpu"); got != "tori/web1/cpu" {
+		t.Errorf("incidentKey = %q", got)
+	}
+	long := incidentKey("web1", strings.Repeat("x", 300))
+	if len(long) > maxIncidentKey {
+		t.Errorf("len = %d, want <= %d", len(long), maxIncidentKey)
+	}
+	if long == incidentKey("web2", strings.Repeat("x", 300)) {
+		t.Error("hashed keys of different hosts collide")
+	}
+}
+
+// chatServer records the JSON bodies posted to it.
+func chatServer(t *testing.T, reply string) (*httptest.Server, *[]map[string]any, *[]*http.Request) {
+	t.Helper()
+	var bodies []map[string]any
+	var reqs []*http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		reqs = append(reqs, r)
+		io.WriteString(w, reply)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &bodies, &reqs
+}
+
+func chatTestNotes() (firing, grouped, resolved notification) {
+	at := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	cpu := alertNote{rule: "cpu", severity: "critical", status: "firing", subject: "Alert: cpu", body: "cpu at 95%", at: at, key: "cpu"}
+	mem := alertNote{rule: "mem", severity: "warning", status: "firing", subject: "Alert: mem", body: "mem <90%>", at: at, key: "mem"}
+	firing = singleNotification(cpu)
+	grouped = groupNotification("", []alertNote{cpu, mem})
+	res := cpu
+	res.status, res.subject, res.body = "resolved", "Resolved: cpu", "cpu back to normal"
+	resolved = singleNotification(res)
+	resolved.incident = true
+	return firing, grouped, resolved
+}
+
+func TestSlackChannel(t *testing.T) {
+	srv, bodies, _ := chatServer(t, "ok")
+	ch := &slackChannel{cfg: SlackConfig{URL: srv.URL}}
+	firing, grouped, resolved := chatTestNotes()
+	ctx := context.Background()
+
+	// Not announced yet: no follow-up.
+	if err := ch.Send(ctx, resolved); err != nil {
+		t.Fatal(err)
+	}
+	if len(*bodies) != 0 {
+		t.Fatalf("posted %d messages for an unannounced alert, want 0", len(*bodies))
+	}
+
+	for _, n := range []notification{grouped, resolved, firing, resolved} {
+		if err := ch.Send(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(*bodies) != 4 {
+		t.Fatalf("posted %d messages, want 4", len(*bodies))
+	}
+	attachment := func(i int) map[string]any {
+		atts, _ := (*bodies)[i]["attachments"].([]any)
+		if len(atts) != 1 {
+			t.Fatalf("message %d attachments = %v", i, (*bodies)[i]["attachments"])
+		}
+		return atts[0].(map[string]any)
+	}
+
+	g := attachment(0)
+	if g["color"] != "#e01e5a" || (*bodies)[0]["text"] != grouped.subject {
+		t.Errorf("grouped message = %v", (*bodies)[0])
+	}
+	blocks, _ := g["blocks"].([]any)
+	if len(blocks) != 2 {
+		t.Fatalf("grouped blocks = %v", blocks)
+	}
+	fields, _ := blocks[1].(map[string]any)["fields"].([]any)
+	if len(fields) != 2 {
+		t.Fatalf("fields = %v, want one per alert", fields)
+	}
+	if text := fields[1].(map[string]any)["text"]; text != "*Alert: mem*\nmem &lt;90%&gt;" {
+		t.Errorf("field = %q", text)
+	}
+
+	follow := attachment(1)
+	if follow["color"] != "#2eb67d" {
+		t.Errorf("follow-up color = %v", follow["color"])
+	}
+	blocks, _ = follow["blocks"].([]any)
+	ctxBlock, _ := blocks[len(blocks)-1].(map[string]any)
+	elems, _ := ctxBlock["elements"].([]any)
+	if len(elems) != 1 || !strings.Contains(fmt.Sprint(elems[0]), "<!date^1735732800^") ||
+		!strings.Contains(fmt.Sprint(elems[0]), "*"+grouped.subject+"*") {
+		t.Errorf("follow-up context = %v, want the grouped message and its time", ctxBlock)
+	}
+
+	// The alert fired again after resolving; the follow-up quotes the new message.
+	blocks, _ = attachment(3)["blocks"].([]any)
+	if last := fmt.Sprint(blocks[len(blocks)-1]); !strings.Contains(last, "*Alert: cpu*") {
+		t.Errorf("second follow-up context = %s", last)
+	}
+}
+
+func TestDiscordChannel(t *testing.T) {
+	srv, bodies, _ := chatServer(t, "")
+	ch := &discordChannel{cfg: DiscordConfig{URL: srv.URL}}
+	firing, _, resolved := chatTestNotes()
+	ctx := context.Background()
+	for _, n := range []notification{firing, resolved, resolved} {
+		if err := ch.Send(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(*bodies) != 2 {
+		t.Fatalf("posted %d messages, want 2 (one follow-up)", len(*bodies))
+	}
+	embed := func(i int) map[string]any {
+		embeds, _ := (*bodies)[i]["embeds"].([]any)
+		if len(embeds) != 1 {
+			t.Fatalf("message %d embeds = %v", i, (*bodies)[i]["embeds"])
+		}
+		return embeds[0].(map[string]any)
+	}
+	e := embed(0)
+	if e["title"] != "Alert: cpu" || e["description"] != "cpu at 95%" || e["color"] != float64(0xe01e5a) ||
+		e["timestamp"] != "2025-01-01T12:00:00Z" {
+		t.Errorf("embed = %v", e)
+	}
+	f := embed(1)
+	if f["title"] != "Resolved: cpu" || f["color"] != float64(0x2eb67d) {
+		t.Errorf("follow-up = %v", f)
+	}
+	if fields := fmt.Sprint(f["fields"]); !strings.Contains(fields, "Alert: cpu, fired <t:1735732800:f>") {
+		t.Errorf("follow-up fields = %s", fields)
+	}
+}
+
+func TestMatrixChannel(t *testing.T) {
+	srv, bodies, reqs := chatServer(t, `{"event_id":"$ev1"}`)
+	ch := newMatrixChannel(MatrixConfig{Homeserver: srv.URL, AccessToken: "syt_secret", RoomID: "!room:example.org"}, nil)
+	_, grouped, resolved := chatTestNotes()
+	ctx := context.Background()
+	for _, n := range []notification{grouped, resolved} {
+		if err := ch.Send(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(*bodies) != 2 {
+		t.Fatalf("sent %d events, want 2", len(*bodies))
+	}
+	paths := map[string]bool{}
+	for _, r := range *reqs {
+		if r.Method != http.MethodPut || r.Header.Get("Authorization") != "Bearer syt_secret" {
+			t.Errorf("%s %s auth %q", r.Method, r.URL.Path, r.Header.Get("Authorization"))
+		}
+		if !strings.HasPrefix(r.URL.EscapedPath(), "/_matrix/client/v3/rooms/%21room:example.org/send/m.room.message/tori-") {
+			t.Errorf("path = %s", r.URL.EscapedPath())
+		}
+		paths[r.URL.Path] = true
+	}
+	if len(paths) != 2 {
+		t.Error("transaction IDs repeat")
+	}
+
+	msg := (*bodies)[0]
+	if msg["msgtype"] != "m.notice" || msg["format"] != "org.matrix.custom.html" {
+		t.Errorf("message = %v", msg)
+	}
+	html, _ := msg["formatted_body"].(string)
+	if !strings.Contains(html, `data-mx-color="#e01e5a"`) || !strings.Contains(html, "<li><b>Alert: mem</b><br>mem &lt;90%&gt;</li>") {
+		t.Errorf("formatted_body = %s", html)
+	}
+	rel := fmt.Sprint((*bodies)[1]["m.relates_to"])
+	if rel != "map[m.in_reply_to:map[event_id:$ev1]]" {
+		t.Errorf("follow-up relation = %s, want reply to $ev1", rel)
+	}
+}
+
+func TestMatrixChannelPersistsRefs(t *testing.T) {
+	var paths []string
+	var bodies []map[string]any
+	fail := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		paths = append(paths, r.URL.Path)
+		bodies = append(bodies, body)
+		if _, ok := body["m.relates_to"]; ok && fail {
+			fail = false
+			http.Error(w, "unavailable", http.StatusBadGateway)
+			return
+		}
+		io.WriteString(w, `{"event_id":"$ev1"}`)
+	}))
+	t.Cleanup(srv.Close)
+	s := testStore(t)
+	cfg := MatrixConfig{Name: "matrix-1", Homeserver: srv.URL, AccessToken: "tok", RoomID: "!room:example.org"}
+	firing, _, resolved := chatTestNotes()
+	ctx := context.Background()
+
+	firing.delivery = "1735732800-1"
+	if err := newMatrixChannel(cfg, s).Send(ctx, firing); err != nil {
+		t.Fatal(err)
+	}
+
+	// After a restart the follow-up still replies to the announcement, and
+	// the retry of a failed follow-up reuses its transaction ID.
+	ch := newMatrixChannel(cfg, s)
+	resolved.delivery = "1735736400-2"
+	if err := ch.Send(ctx, resolved); err == nil {
+		t.Fatal("first follow-up should fail")
+	}
+	if err := ch.Send(ctx, resolved); err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 3 {
+		t.Fatalf("sent %d events, want 3", len(paths))
+	}
+	if !strings.HasSuffix(paths[0], "/tori-1735732800-1-0") || paths[1] != paths[2] {
+		t.Errorf("transaction paths = %v", paths)
+	}
+	if rel := fmt.Sprint(bodies[2]["m.relates_to"]); rel != "map[m.in_reply_to:map[event_id:$ev1]]" {
+		t.Errorf("follow-up relation = %s, want reply to $ev1", rel)
+	}
+
+	// The ref is gone once the follow-up is sent.
+	if _, ok, err := s.ChatRef(ctx, "matrix-1", "cpu"); err != nil || ok {
+		t.Errorf("ChatRef after follow-up = %v, %v", ok, err)
+	}
+	if err := ch.Send(ctx, resolved); err != nil || len(paths) != 3 {
+		t.Errorf("second resolve sent %d events, err %v; want no follow-up", len(paths)-3, err)
+	}
+}
+
+func TestChatRefsReplaceStale(t *testing.T) {
+	for _, stored := range []bool{false, true} {
+		t.Run(fmt.Sprintf("store=%v", stored), func(t *testing.T) {
+			r := &chatRefs{channel: "matrix-1"}
+			if stored {
+				r.store = testStore(t)
+			}
+			ctx := context.Background()
+			t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+			note := func(kind string, at time.Time) notification {
+				return notification{alerts: []alertNote{{rule: "cpu", status: "firing", key: "cpu", kind: kind, at: at}}}
+			}
+
+			// The first firing's resolve was never posted, so its ref is stale.
+			r.remember(ctx, note("", t0), chatRef{subject: "Alert: cpu", id: "$old"})
+			r.remember(ctx, note("", t0.Add(time.Hour)), chatRef{subject: "Alert: cpu", id: "$new"})
+			r.remember(ctx, note("escalated", t0.Add(2*time.Hour)), chatRef{subject: "Escalated: cpu", id: "$esc"})
+
+			ref, ok, err := r.lookup(ctx, "cpu")
+			if err != nil || !ok {
+				t.Fatalf("lookup = %v, %v", ok, err)
+			}
+			if ref.id != "$new" || !ref.at.Equal(t0.Add(time.Hour)) {
+				t.Errorf("ref = %+v, want $new fired at %v", ref, t0.Add(time.Hour))
+			}
+		})
+	}
+}
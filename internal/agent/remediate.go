@@ -0,0 +1,226 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// remediationTimeout bounds a single restart or stop call. Docker waits up to
+// the container's stop timeout (10s by default) before killing it.
+const remediationTimeout = 2 * time.Minute
+
+// containerController restarts and stops containers for docker:restart and
+// docker:stop actions. Implemented by DockerCollector.
+type containerController interface {
+	RestartContainer(ctx context.Context, id string) error
+	StopContainer(ctx context.Context, id string) error
+}
+
+// remediationState tracks the attempts made for one alert instance. It
+// outlives a single firing, so a container that fails again right after a
+// successful restart still runs out of attempts instead of restarting forever.
+type remediationState struct {
+	attempts int
+	last     time.Time
+	inFlight bool
+}
+
+// remediationJob is one attempt, carried from remediate to finishRemediation.
+type remediationJob struct {
+	key         string
+	action      string // "restart" or "stop"
+	containerID string
+	attempt     int
+	alertID     int64
+}
+
+// remediationAction returns "restart" or "stop" for a rule's docker action,
+// or "" when it has none.
+func remediationAction(actions []string) string {
+	for _, action := range actions {
+		if name, ok := strings.CutPrefix(action, "docker:"); ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// SetDocker enables docker:restart and docker:stop actions. Without it those
+// actions are ignored.
+func (a *Alerter) SetDocker(c containerController) {
+	a.docker = c
+	a.remediateCtx, a.remediateStop = context.WithCancel(context.Background())
+}
+
+// backoffAfter returns how long to wait after the given attempt before the
+// next one: backoff, then doubling.
+func (r *alertRule) backoffAfter(attempt int) time.Duration {
+	return r.backoff << (attempt - 1)
+}
+
+// remediate starts due docker actions for firing container instances. The
+// first attempt runs as soon as the instance fires, later ones after the
+// rule's backoff, up to max_attempts. Attempts are held back during
+// maintenance windows but, like exec actions, not by silences or inhibition.
+// Called with mu held.
+func (a *Alerter) remediate(now time.Time) {
+	if a.replay != nil || a.docker == nil {
+		return
+	}
+	for key, st := range a.remediations {
+		inst := a.instances[key]
+		if st.inFlight || (inst != nil && inst.state == stateFiring) {
+			continue
+		}
+		// Forget the attempts once the instance has stayed quiet for twice
+		// the backoff the next attempt would have needed.
+		if r := a.ruleForKey(key); r == nil || now.Sub(st.last) >= 2*r.backoffAfter(st.attempts) {
+			delete(a.remediations, key)
+		}
+	}
+
+	for key, inst := range a.instances {
+		if inst.state != stateFiring || inst.containerID == "" {
+			continue
+		}
+		r := a.ruleForKey(key)
+		if r == nil || r.remediate == "" {
+			continue
+		}
+		st := a.remediations[key]
+		if st == nil {
+			st = &remediationState{}
+			a.remediations[key] = st
+		}
+		if st.inFlight || st.attempts >= r.maxAttempts {
+			continue
+		}
+		if st.attempts > 0 && now.Before(st.last.Add(r.backoffAfter(st.attempts))) {
+			continue
+		}
+		if w := a.inMaintenance(r.name, inst.project, now); w != nil {
+			continue
+		}
+
+		st.attempts++
+		st.last = now
+		st.inFlight = true
+		job := remediationJob{
+			key:         key,
+			action:      r.remediate,
+			containerID: inst.containerID,
+			attempt:     st.attempts,
+			alertID:     inst.dbID,
+		}
+		slog.Warn("remediating alert", "rule", r.name, "key", key, "action", job.action, "attempt", job.attempt)
+		a.remediating.Add(1)
+		a.deferred = append(a.deferred, func() {
+			go a.runRemediation(job)
+		})
+	}
+}
+
+// runRemediation performs one docker call without holding mu, then records
+// the result.
+func (a *Alerter) runRemediation(job remediationJob) {
+	defer a.remediating.Done()
+
+	ctx, cancel := context.WithTimeout(a.remediateCtx, remediationTimeout)
+	var err error
+	switch job.action {
+	case "restart":
+		err = a.docker.RestartContainer(ctx, job.containerID)
+	case "stop":
+		err = a.docker.StopContainer(ctx, job.containerID)
+	default:
+		err = fmt.Errorf("unknown docker action %q", job.action)
+	}
+	cancel()
+
+	a.mu.Lock()
+	a.deferred = a.deferred[:0]
+	a.finishRemediation(job, a.now(), err)
+	a.runDeferred()
+}
+
+// finishRemediation stores the attempt, republishes the alert so clients see
+// it, and notifies the rule's channels. Called with mu held.
+func (a *Alerter) finishRemediation(job remediationJob, now time.Time, err error) {
+	ctx := context.Background()
+	if st := a.remediations[job.key]; st != nil {
+		st.inFlight = false
+	}
+	r := a.ruleForKey(job.key)
+	if r == nil {
+		return
+	}
+
+	rem := &Remediation{AlertID: job.alertID, Action: job.action, Attempt: job.attempt, At: now}
+	if err != nil {
+		rem.Error = err.Error()
+		slog.Error("remediation failed", "rule", r.name, "key", job.key, "action", job.action, "attempt", job.attempt, "error", err)
+	} else {
+		slog.Info("remediation done", "rule", r.name, "key", job.key, "action", job.action, "attempt", job.attempt)
+	}
+	if job.alertID > 0 {
+		if err := a.store.InsertRemediation(ctx, rem); err != nil {
+			slog.Error("insert remediation", "error", err)
+		}
+	}
+
+	inst := a.instances[job.key]
+	if inst != nil && inst.state == stateFiring && inst.dbID == job.alertID {
+		a.publishFiring(ctx, r, job.key, inst)
+	}
+
+	if a.notifier == nil || !r.notifies() || a.isSilenced(r.name, job.key) {
+		return
+	}
+	project := ""
+	if inst != nil {
+		project = inst.project
+		if inst.inhibitedBy != "" || a.inMaintenance(r.name, project, now) != nil {
+			return
+		}
+	}
+	note := alertNote{
+		rule:     r.name,
+		severity: r.severity,
+		status:   "firing",
+		subject:  fmt.Sprintf("Remediation: docker %s for %s", job.action, r.name),
+		body:     remediationBody(job, r.maxAttempts, err),
+		project:  project,
+		at:       now,
+		targets:  a.notifier.route(r.channels, r.routed, r.severity),
+		key:      job.key,
+		kind:     "remediation",
+	}
+	a.deferred = append(a.deferred, func() {
+		a.notifier.Notify(note)
+	})
+}
+
+func remediationBody(job remediationJob, maxAttempts int, err error) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "docker %s of container %s (%s), attempt %d/%d: ", job.action, shortID(job.containerID), job.key, job.attempt, maxAttempts)
+	if err != nil {
+		b.WriteString("failed: " + err.Error())
+	} else {
+		b.WriteString("ok")
+	}
+	if job.attempt >= maxAttempts {
+		b.WriteString("\nNo attempts left; giving up until the alert stays resolved.")
+	}
+	return b.String()
+}
+
+// shortID truncates a container ID to the 12 characters docker shows.
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
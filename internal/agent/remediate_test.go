@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeController struct {
+	mu    sync.Mutex
+	calls []string
+	err   error
+}
+
+func (f *fakeController) RestartContainer(_ context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, "restart "+id)
+	return f.err
+}
+
+func (f *fakeController) StopContainer(_ context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, "stop "+id)
+	return f.err
+}
+
+func (f *fakeController) Calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.calls...)
+}
+
+func TestRemediationAttemptsAndBackoff(t *testing.T) {
+	a, s, rec := testAlerterWithRecorder(t, map[string]AlertConfig{
+		"down": {
+			Condition: "container.state == 'exited'",
+			Severity:  "critical",
+			Actions:   []string{"notify", "docker:restart"},
+			Remediate: RemediateConfig{MaxAttempts: 2, Backoff: Duration{time.Minute}},
+		},
+	})
+	ctrl := &fakeController{}
+	a.SetDocker(ctrl)
+	var published []*Alert
+	a.onStateChange = func(alert *Alert, state string) { published = append(published, alert) }
+
+	ctx := context.Background()
+	now := time.Now()
+	a.now = func() time.Time { return now }
+	snap := &MetricSnapshot{Containers: []ContainerMetrics{{ID: "c1", Name: "web", State: "exited"}}}
+	step := func(d time.Duration) {
+		now = now.Add(d)
+		a.Evaluate(ctx, snap)
+		a.remediating.Wait()
+	}
+
+	step(0)
+	if got := ctrl.Calls(); len(got) != 1 || got[0] != "restart c1" {
+		t.Fatalf("calls after fire = %v", got)
+	}
+	ctrl.err = errors.New("no such container")
+	step(30 * time.Second) // inside backoff
+	step(31 * time.Second) // first backoff elapsed
+	step(10 * time.Minute) // attempts exhausted
+	if got := ctrl.Calls(); len(got) != 2 {
+		t.Fatalf("calls = %v, want 2", got)
+	}
+
+	firing, err := s.QueryFiringAlerts(ctx)
+	if err != nil || len(firing) != 1 {
+		t.Fatalf("firing = %v, err = %v", firing, err)
+	}
+	if err := s.AttachRemediations(ctx, firing); err != nil {
+		t.Fatal(err)
+	}
+	rems := firing[0].Remediations
+	if len(rems) != 2 || rems[0].Attempt != 1 || rems[0].Error != "" || rems[1].Attempt != 2 || !strings.Contains(rems[1].Error, "no such container") {
+		t.Errorf("remediations = %+v", rems)
+	}
+	if last := published[len(published)-1]; len(last.Remediations) != 2 {
+		t.Errorf("last published alert has %d remediations, want 2", len(last.Remediations))
+	}
+
+	a.notifier.Flush()
+	var subjects []string
+	var lastBody string
+	for _, n := range rec.Notifications() {
+		subjects = append(subjects, n.subject)
+		lastBody = n.body
+	}
+	want := []string{"Alert: down", "Remediation: docker restart for down", "Remediation: docker restart for down"}
+	if !sameStrings(subjects, want) {
+		t.Errorf("subjects = %v, want %v", subjects, want)
+	}
+	if !strings.Contains(lastBody, "attempt 2/2: failed") || !strings.Contains(lastBody, "giving up") {
+		t.Errorf("last body = %q", lastBody)
+	}
+}
+
+func TestRemediationSkipsMaintenance(t *testing.T) {
+	a, _, _ := testAlerterWithRecorder(t, map[string]AlertConfig{
+		"down": {
+			Condition: "container.state == 'exited'",
+			Severity:  "critical",
+			Actions:   []string{"docker:stop"},
+			Remediate: RemediateConfig{MaxAttempts: 3, Backoff: Duration{time.Minute}},
+		},
+	})
+	err := a.SetMaintenance([]MaintenanceConfig{{Name: "deploys", Start: "02:00", End: "04:00", Timezone: "UTC"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctrl := &fakeController{}
+	a.SetDocker(ctrl)
+	now := time.Date(2025, 1, 1, 3, 0, 0, 0, time.UTC)
+	a.now = func() time.Time { return now }
+
+	a.Evaluate(context.Background(), &MetricSnapshot{Containers: []ContainerMetrics{{ID: "c1", State: "exited"}}})
+	a.remediating.Wait()
+	if got := ctrl.Calls(); len(got) != 0 {
+		t.Errorf("calls during maintenance = %v", got)
+	}
+
+	// Held-back attempts run once the window ends.
+	now = time.Date(2025, 1, 1, 4, 1, 0, 0, time.UTC)
+	a.Evaluate(context.Background(), &MetricSnapshot{Containers: []ContainerMetrics{{ID: "c1", State: "exited"}}})
+	a.remediating.Wait()
+	if got := ctrl.Calls(); len(got) != 1 || got[0] != "stop c1" {
+		t.Errorf("calls after maintenance = %v", got)
+	}
+}
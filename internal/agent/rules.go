@@ -0,0 +1,314 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/thobiasn/tori-cli/internal/protocol"
+)
+
+// maxRuleConfigLen caps the TOML body of a managed alert rule.
+const maxRuleConfigLen = 16 << 10
+
+// validRuleName matches the names of rules created over the socket. Rule
+// names end up in instance keys ("rule:container"), so no colons.
+var validRuleName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ruleOp is the kind of a runtime rule change.
+type ruleOp int
+
+const (
+	ruleCreate ruleOp = iota
+	ruleUpdate
+	ruleDisable
+	ruleDelete
+)
+
+func (op ruleOp) String() string {
+	switch op {
+	case ruleCreate:
+		return "create"
+	case ruleUpdate:
+		return "update"
+	case ruleDisable:
+		return "disable"
+	case ruleDelete:
+		return "delete"
+	}
+	return fmt.Sprintf("ruleOp(%d)", int(op))
+}
+
+// ruleChange is an edit of the alert rules made over the socket. The Run loop
+// applies it and answers on reply.
+type ruleChange struct {
+	op       ruleOp
+	name     string
+	config   string // TOML body, for create and update
+	disabled bool   // for disable; false re-enables the rule
+	reply    chan error
+}
+
+// ruleCatalog tells the socket what the alerter does not know about the
+// rules: which were created at runtime, and the disabled ones.
+type ruleCatalog struct {
+	managed  map[string]string // rule name -> TOML body of managed rules
+	disabled []protocol.AlertRuleInfo
+}
+
+// parseManagedRule decodes the TOML body of a managed rule, fills in the
+// defaults a rule in the config file gets, and validates it against cfg's
+// notification channels and exec allowlist. Any socket client may create
+// managed rules, so they can't use docker actions, nor exec actions unless
+// exec.allow_managed is set.
+func parseManagedRule(cfg *Config, name, body string) (AlertConfig, error) {
+	var ac AlertConfig
+	md, err := toml.Decode(body, &ac)
+	if err != nil {
+		return ac, fmt.Errorf("alert %q: parse: %w", name, err)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return ac, fmt.Errorf("alert %q: unknown field %q", name, undecoded[0].String())
+	}
+	setAlertDefaults(&ac, func(key string) bool { return md.IsDefined(key) })
+
+	channels, err := validateChannels(&cfg.Notify)
+	if err != nil {
+		return ac, err
+	}
+	if err := validateAlert(name, &ac, channels, &cfg.Exec); err != nil {
+		return ac, err
+	}
+	for _, a := range ac.Actions {
+		if strings.HasPrefix(a, "docker:") {
+			return ac, fmt.Errorf("alert %q: action %q is only allowed in the config file", name, a)
+		}
+		if strings.HasPrefix(a, "exec:") && !cfg.Exec.AllowManaged {
+			return ac, fmt.Errorf("alert %q: action %q requires exec.allow_managed", name, a)
+		}
+	}
+	return ac, nil
+}
+
+// mergeAlertRules combines the config file's rules with the managed ones.
+// It returns the rules to evaluate, the disabled rules, and the TOML bodies
+// of the managed rules in use. A managed rule with the name of a rule in the
+// config file, or one that no longer validates after a config change, is
+// skipped with a warning.
+func mergeAlertRules(cfg *Config, managed []ManagedRule) (active, disabled map[string]AlertConfig, bodies map[string]string) {
+	active = make(map[string]AlertConfig, len(cfg.Alerts)+len(managed))
+	for name, ac := range cfg.Alerts {
+		active[name] = ac
+	}
+	disabled = make(map[string]AlertConfig)
+	bodies = make(map[string]string)
+	for _, mr := range managed {
+		if mr.Config == "" {
+			// A rule from the config file that was disabled at runtime.
+			if ac, ok := active[mr.Name]; ok && mr.Disabled {
+				delete(active, mr.Name)
+				disabled[mr.Name] = ac
+			}
+			continue
+		}
+		if _, ok := cfg.Alerts[mr.Name]; ok {
+			slog.Warn("managed alert rule shadowed by the config file, ignoring", "rule", mr.Name)
+			continue
+		}
+		ac, err := parseManagedRule(cfg, mr.Name, mr.Config)
+		if err != nil {
+			slog.Warn("invalid managed alert rule, ignoring", "rule", mr.Name, "error", err)
+			continue
+		}
+		bodies[mr.Name] = mr.Config
+		if mr.Disabled {
+			disabled[mr.Name] = ac
+		} else {
+			active[mr.Name] = ac
+		}
+	}
+	return active, disabled, bodies
+}
+
+// allRules returns the active and the disabled rules in one map. Disabled
+// rules may still be named by inhibit and maintenance entries.
+func allRules(active, disabled map[string]AlertConfig) map[string]AlertConfig {
+	all := maps.Clone(active)
+	maps.Copy(all, disabled)
+	return all
+}
+
+// editRules hands a rule change to the Run loop and waits for the result.
+// Used by the socket server.
+func (a *Agent) editRules(ctx context.Context, ch ruleChange) error {
+	ch.reply = make(chan error, 1)
+	select {
+	case a.ruleChanges <- ch:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-ch.reply:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// applyRuleChange validates and stores a rule change, then rebuilds the
+// alerter. Called from the Run loop.
+func (a *Agent) applyRuleChange(ctx context.Context, ch ruleChange) error {
+	managed, err := a.store.QueryManagedRules(ctx)
+	if err != nil {
+		return fmt.Errorf("load managed rules: %w", err)
+	}
+	var row *ManagedRule
+	for i := range managed {
+		if managed[i].Name == ch.name {
+			row = &managed[i]
+		}
+	}
+	_, inFile := a.cfg.Alerts[ch.name]
+	managedRule := row != nil && row.Config != ""
+
+	now := time.Now()
+	var save *ManagedRule // row to store; nil deletes the rule's row
+	switch ch.op {
+	case ruleCreate, ruleUpdate:
+		if inFile {
+			return fmt.Errorf("rule %q is defined in the config file", ch.name)
+		}
+		if ch.op == ruleCreate && managedRule {
+			return fmt.Errorf("rule %q already exists", ch.name)
+		}
+		if ch.op == ruleUpdate && !managedRule {
+			return fmt.Errorf("rule %q not found", ch.name)
+		}
+		if _, err := parseManagedRule(a.cfg, ch.name, ch.config); err != nil {
+			return err
+		}
+		save = &ManagedRule{Name: ch.name, Config: ch.config, UpdatedAt: now}
+		if row != nil {
+			save.Disabled = row.Disabled
+		}
+	case ruleDisable:
+		if !inFile && !managedRule {
+			return fmt.Errorf("rule %q not found", ch.name)
+		}
+		if row != nil && row.Disabled == ch.disabled {
+			return nil
+		}
+		if !managedRule && !ch.disabled {
+			// Re-enabling a config file rule drops its row.
+			if row == nil {
+				return nil
+			}
+			break
+		}
+		save = &ManagedRule{Name: ch.name, Disabled: ch.disabled, UpdatedAt: now}
+		if row != nil {
+			save.Config = row.Config
+		}
+	case ruleDelete:
+		if inFile {
+			return fmt.Errorf("rule %q is defined in the config file; disable it instead", ch.name)
+		}
+		if !managedRule {
+			return fmt.Errorf("rule %q not found", ch.name)
+		}
+	default:
+		return fmt.Errorf("unknown rule change %d", ch.op)
+	}
+
+	// Inhibit and maintenance entries may name the rule; check them against
+	// the rules as they will be before storing the change.
+	next := slices.DeleteFunc(slices.Clone(managed), func(mr ManagedRule) bool { return mr.Name == ch.name })
+	if save != nil {
+		next = append(next, *save)
+	}
+	active, disabled, _ := mergeAlertRules(a.cfg, next)
+	if err := validateRuleRefs(a.cfg, allRules(active, disabled)); err != nil {
+		return err
+	}
+	if save != nil {
+		err = a.store.SaveManagedRule(ctx, save)
+	} else {
+		err = a.store.DeleteManagedRule(ctx, ch.name)
+	}
+	if err != nil {
+		return fmt.Errorf("save rule: %w", err)
+	}
+	slog.Info("alert rule changed", "rule", ch.name, "op", ch.op.String())
+	return a.applyRules(ctx, ch.name)
+}
+
+// applyRules updates the alerter after the rule named changed was edited at
+// runtime. Unlike a config reload, only that rule is swapped: the other rules
+// keep their firing alerts, pending timers, aggregate windows, cooldowns and
+// remediation state, and the notifier keeps its buffers. An alerter is only
+// built when the first rule is enabled, and stopped when the last one goes.
+func (a *Agent) applyRules(ctx context.Context, changed string) error {
+	active, catalog, err := a.loadAlertRules(ctx, a.cfg)
+	if err != nil {
+		return err
+	}
+	switch {
+	case a.alerter != nil && len(active) > 0:
+		if err := a.alerter.ReplaceRule(ctx, changed, active); err != nil {
+			return err
+		}
+		a.socket.SetRuleCatalog(catalog)
+		return nil
+	case a.alerter != nil:
+		a.alerter.ResolveAll(ctx)
+		a.alerter.Stop()
+		a.setAlerter(nil, catalog)
+		return nil
+	case len(active) == 0:
+		a.socket.SetRuleCatalog(catalog)
+		return nil
+	}
+	alerter, err := a.newAlerter(a.cfg, active)
+	if err != nil {
+		return err
+	}
+	if err := alerter.AdoptFiring(ctx); err != nil {
+		slog.Warn("failed to adopt firing alerts", "error", err)
+	}
+	if err := alerter.LoadSilences(ctx); err != nil {
+		slog.Warn("failed to load silences", "error", err)
+	}
+	a.setAlerter(alerter, catalog)
+	return nil
+}
+
+// loadAlertRules merges cfg's rules with the managed rules in the store and
+// returns the rules to evaluate plus the catalog for the socket.
+func (a *Agent) loadAlertRules(ctx context.Context, cfg *Config) (map[string]AlertConfig, ruleCatalog, error) {
+	managed, err := a.store.QueryManagedRules(ctx)
+	if err != nil {
+		return nil, ruleCatalog{}, fmt.Errorf("load managed rules: %w", err)
+	}
+	active, disabled, bodies := mergeAlertRules(cfg, managed)
+	if err := validateRuleRefs(cfg, allRules(active, disabled)); err != nil {
+		return nil, ruleCatalog{}, err
+	}
+
+	names := make([]string, 0, len(disabled))
+	for name := range disabled {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	catalog := ruleCatalog{managed: bodies}
+	for _, name := range names {
+		catalog.disabled = append(catalog.disabled, disabledRuleInfo(name, disabled[name], bodies[name]))
+	}
+	return active, catalog, nil
+}
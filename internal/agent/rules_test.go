@@ -0,0 +1,292 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseManagedRule(t *testing.T) {
+	cfg := &Config{
+		Notify: NotifyConfig{
+			Email:    EmailConfig{Name: "email"},
+			Webhooks: []WebhookConfig{{Name: "ops", Enabled: true, URL: "http://example.com"}},
+		},
+		Exec: ExecConfig{Allow: []string{"/usr/local/bin/fix"}},
+	}
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{"valid", "condition = \"host.cpu_percent > 90\"\nseverity = \"warning\"\nactions = [\"notify:ops\"]\n", ""},
+		{"syntax", "condition = ", "parse"},
+		{"unknown field", "condition = \"host.cpu_percent > 90\"\nseverity = \"warning\"\nactions = [\"notify\"]\nfour = \"5m\"\n", "unknown field \"four\""},
+		{"unknown channel", "condition = \"host.cpu_percent > 90\"\nseverity = \"warning\"\nactions = [\"notify:pager\"]\n", "pager"},
+		{"exec not allowed", "condition = \"host.cpu_percent > 90\"\nseverity = \"warning\"\nactions = [\"exec:/bin/sh\"]\n", "/bin/sh"},
+		{"bad condition", "condition = \"host.nope > 1\"\nseverity = \"warning\"\nactions = [\"notify\"]\n", "alert \"r\""},
+		{"docker action", "condition = \"container.state == 'running'\"\nseverity = \"warning\"\nactions = [\"docker:stop\"]\n", "only allowed in the config file"},
+		{"exec without opt-in", "condition = \"host.cpu_percent > 90\"\nseverity = \"warning\"\nactions = [\"exec:/usr/local/bin/fix\"]\n", "exec.allow_managed"},
+		{"exec with opt-in", "condition = \"host.cpu_percent > 90\"\nseverity = \"warning\"\nactions = [\"exec:/usr/local/bin/fix\"]\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.Exec.AllowManaged = tt.name == "exec with opt-in"
+			_, err := parseManagedRule(cfg, "r", tt.body)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseManagedRuleDefaults(t *testing.T) {
+	cfg := &Config{Notify: NotifyConfig{Email: EmailConfig{Name: "email"}}}
+	ac, err := parseManagedRule(cfg, "r", "condition = \"host.cpu_percent > 90\"\nseverity = \"warning\"\nactions = [\"notify\"]\nnotify_cooldown = \"0s\"\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ac.Cooldown.Duration != 5*time.Minute {
+		t.Errorf("cooldown = %s, want 5m", ac.Cooldown.Duration)
+	}
+	if ac.NotifyCooldown.Duration != 0 {
+		t.Errorf("notify_cooldown = %s, want 0s (explicit)", ac.NotifyCooldown.Duration)
+	}
+}
+
+// testRuleAgent returns an agent with one rule in its config file and no
+// alerter running.
+func testRuleAgent(t *testing.T) *Agent {
+	t.Helper()
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	store, err := OpenStore(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	docker := &DockerCollector{
+		prevCPU: make(map[string]cpuPrev),
+		tracked: make(map[string]bool),
+	}
+	hub := NewHub()
+	a := &Agent{
+		cfg: &Config{
+			Storage: StorageConfig{Path: dbPath, RetentionDays: 7},
+			Notify:  NotifyConfig{Email: EmailConfig{Name: "email"}},
+			Alerts: map[string]AlertConfig{
+				"high_cpu": {Condition: "host.cpu_percent > 90", Severity: "critical", Actions: []string{"notify"}},
+			},
+		},
+		store:  store,
+		docker: docker,
+		hub:    hub,
+		events: &EventWatcher{docker: docker, hub: hub, done: make(chan struct{})},
+		socket: NewSocketServer(hub, store, docker, nil, 7, "test"),
+	}
+	t.Cleanup(func() {
+		if a.alerter != nil {
+			a.alerter.Stop()
+		}
+	})
+	return a
+}
+
+func TestApplyRuleChange(t *testing.T) {
+	a := testRuleAgent(t)
+	ctx := context.Background()
+	body := "condition = \"host.memory_percent > 95\"\nseverity = \"warning\"\nactions = [\"notify\"]\n"
+	stopAll := "condition = \"container.state == 'running'\"\nseverity = \"warning\"\nactions = [\"docker:stop\"]\n"
+
+	steps := []struct {
+		name    string
+		change  ruleChange
+		wantErr string
+	}{
+		{"create", ruleChange{op: ruleCreate, name: "high_mem", config: body}, ""},
+		{"create again", ruleChange{op: ruleCreate, name: "high_mem", config: body}, "already exists"},
+		{"create over file rule", ruleChange{op: ruleCreate, name: "high_cpu", config: body}, "config file"},
+		{"update file rule", ruleChange{op: ruleUpdate, name: "high_cpu", config: body}, "config file"},
+		{"update missing", ruleChange{op: ruleUpdate, name: "nope", config: body}, "not found"},
+		{"update invalid", ruleChange{op: ruleUpdate, name: "high_mem", config: "condition = \"x\"\n"}, "alert \"high_mem\""},
+		{"create docker action", ruleChange{op: ruleCreate, name: "stop_all", config: stopAll}, "docker:stop"},
+		{"update docker action", ruleChange{op: ruleUpdate, name: "high_mem", config: stopAll}, "docker:stop"},
+		{"update", ruleChange{op: ruleUpdate, name: "high_mem", config: strings.Replace(body, "95", "97", 1)}, ""},
+		{"disable file rule", ruleChange{op: ruleDisable, name: "high_cpu", disabled: true}, ""},
+		{"delete file rule", ruleChange{op: ruleDelete, name: "high_cpu"}, "disable it instead"},
+	}
+	for _, st := range steps {
+		err := a.applyRuleChange(ctx, st.change)
+		if st.wantErr == "" {
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", st.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), st.wantErr) {
+			t.Fatalf("%s: error = %v, want containing %q", st.name, err, st.wantErr)
+		}
+	}
+
+	if a.alerter == nil {
+		t.Fatal("alerter should be running")
+	}
+	if a.alerter.HasRule("high_cpu") {
+		t.Error("disabled high_cpu should not be evaluated")
+	}
+	if !a.alerter.HasRule("high_mem") {
+		t.Error("managed high_mem should be evaluated")
+	}
+	cat := a.socket.ruleCatalog
+	if !strings.Contains(cat.managed["high_mem"], "97") {
+		t.Errorf("catalog body = %q, want the updated rule", cat.managed["high_mem"])
+	}
+	if len(cat.disabled) != 1 || cat.disabled[0].Name != "high_cpu" || cat.disabled[0].Managed {
+		t.Errorf("catalog disabled = %+v, want high_cpu from the config file", cat.disabled)
+	}
+
+	// Re-enabling the file rule drops its row; deleting the managed rule
+	// leaves only the file rule.
+	if err := a.applyRuleChange(ctx, ruleChange{op: ruleDisable, name: "high_cpu"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.applyRuleChange(ctx, ruleChange{op: ruleDelete, name: "high_mem"}); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := a.store.QueryManagedRules(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 0 {
+		t.Errorf("managed rows = %+v, want none", rows)
+	}
+	if !a.alerter.HasRule("high_cpu") || a.alerter.HasRule("high_mem") {
+		t.Error("want only high_cpu after enable and delete")
+	}
+}
+
+func TestApplyRuleChangeKeepsOtherAlertsFiring(t *testing.T) {
+	a := testRuleAgent(t)
+	ctx := context.Background()
+	if err := a.applyRules(ctx, ""); err != nil {
+		t.Fatal(err)
+	}
+	a.alerter.Evaluate(ctx, &MetricSnapshot{Host: &HostMetrics{CPUPercent: 99}})
+	if a.alerter.FiringCount() != 1 {
+		t.Fatalf("firing = %d, want 1", a.alerter.FiringCount())
+	}
+
+	body := "condition = \"host.memory_percent > 95\"\nseverity = \"warning\"\nactions = [\"notify\"]\n"
+	if err := a.applyRuleChange(ctx, ruleChange{op: ruleCreate, name: "high_mem", config: body}); err != nil {
+		t.Fatal(err)
+	}
+	if a.alerter.FiringCount() != 1 {
+		t.Errorf("firing = %d after adding a rule, want high_cpu still firing", a.alerter.FiringCount())
+	}
+
+	if err := a.applyRuleChange(ctx, ruleChange{op: ruleDisable, name: "high_cpu", disabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	if a.alerter.FiringCount() != 0 {
+		t.Errorf("firing = %d after disabling high_cpu, want 0", a.alerter.FiringCount())
+	}
+	firing, err := a.store.QueryFiringAlerts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(firing) != 0 {
+		t.Errorf("stored firing alerts = %d, want the high_cpu alert resolved", len(firing))
+	}
+}
+
+func TestApplyRuleChangeKeepsOtherRuleState(t *testing.T) {
+	a := testRuleAgent(t)
+	ctx := context.Background()
+	a.cfg.Alerts["high_cpu"] = AlertConfig{Condition: "host.cpu_percent > 90", Severity: "critical",
+		Actions: []string{"notify"}, For: Duration{time.Minute}}
+	a.cfg.Alerts["cpu_avg"] = AlertConfig{Condition: "avg(host.cpu_percent, 30s) > 80", Severity: "warning",
+		Actions: []string{"notify"}}
+	if err := a.applyRules(ctx, ""); err != nil {
+		t.Fatal(err)
+	}
+	alerter := a.alerter
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := t0
+	alerter.now = func() time.Time { return now }
+	// eval samples 99% CPU every 10s up to t0+at.
+	eval := func(at time.Duration) {
+		for ; !now.After(t0.Add(at)); now = now.Add(10 * time.Second) {
+			alerter.Evaluate(ctx, &MetricSnapshot{Host: &HostMetrics{CPUPercent: 99}})
+		}
+	}
+	eval(10 * time.Second)
+
+	// Editing another rule keeps high_cpu's pending timer and the samples
+	// of cpu_avg's window.
+	body := "condition = \"host.memory_percent > 95\"\nseverity = \"warning\"\nactions = [\"notify\"]\n"
+	if err := a.applyRuleChange(ctx, ruleChange{op: ruleCreate, name: "high_mem", config: body}); err != nil {
+		t.Fatal(err)
+	}
+	if a.alerter != alerter || !alerter.HasRule("high_mem") {
+		t.Fatal("want high_mem added to the running alerter")
+	}
+	eval(20 * time.Second)
+	if n := alerter.FiringCount(); n != 1 {
+		t.Errorf("firing = %d at 20s, want cpu_avg firing", n)
+	}
+	eval(time.Minute)
+	if n := alerter.FiringCount(); n != 2 {
+		t.Errorf("firing = %d at 1m, want high_cpu firing a minute after it went pending", n)
+	}
+
+	// Updating high_cpu starts its state over, and only its alert resolves.
+	a.cfg.Alerts["high_cpu"] = AlertConfig{Condition: "host.cpu_percent > 95", Severity: "critical",
+		Actions: []string{"notify"}, For: Duration{time.Minute}}
+	if err := a.applyRules(ctx, "high_cpu"); err != nil {
+		t.Fatal(err)
+	}
+	if n := alerter.FiringCount(); n != 1 {
+		t.Errorf("firing = %d after updating high_cpu, want cpu_avg still firing", n)
+	}
+	eval(time.Minute + 10*time.Second)
+	if inst := alerter.instances["high_cpu"]; inst == nil || inst.state != statePending {
+		t.Errorf("high_cpu instance = %+v, want pending again", inst)
+	}
+}
+
+func TestRuleRefsToManagedRules(t *testing.T) {
+	a := testRuleAgent(t)
+	ctx := context.Background()
+	a.cfg.Inhibit = []InhibitConfig{{Source: "high_cpu", Targets: []string{"high_mem"}}}
+	a.cfg.Maintenance = []MaintenanceConfig{{Name: "m", Start: "02:00", End: "04:00", Rules: []string{"high_mem"}}}
+
+	if _, _, err := a.loadAlertRules(ctx, a.cfg); err == nil || !strings.Contains(err.Error(), `unknown rule "high_mem"`) {
+		t.Fatalf("load without high_mem: error = %v, want unknown rule", err)
+	}
+	body := "condition = \"host.memory_percent > 95\"\nseverity = \"warning\"\nactions = [\"notify\"]\n"
+	if err := a.applyRuleChange(ctx, ruleChange{op: ruleCreate, name: "high_mem", config: body}); err != nil {
+		t.Fatalf("create a rule named by inhibit and maintenance: %v", err)
+	}
+	if err := a.applyRuleChange(ctx, ruleChange{op: ruleDisable, name: "high_mem", disabled: true}); err != nil {
+		t.Fatalf("disable: %v", err)
+	}
+	err := a.applyRuleChange(ctx, ruleChange{op: ruleDelete, name: "high_mem"})
+	if err == nil || !strings.Contains(err.Error(), `unknown rule "high_mem"`) {
+		t.Fatalf("delete a referenced rule: error = %v, want unknown rule", err)
+	}
+	rows, err := a.store.QueryManagedRules(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].Name != "high_mem" {
+		t.Errorf("managed rows = %+v, want high_mem kept", rows)
+	}
+}
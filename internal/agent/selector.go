@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// containerSelector restricts a container or log rule to a subset of
+// containers. Zero value matches every container.
+type containerSelector struct {
+	project string            // compose project (exact)
+	service string            // compose service, or container name for non-compose containers (exact)
+	name    string            // container name glob
+	image   string            // image glob
+	labels  map[string]string // label -> value glob; all must match
+}
+
+func newContainerSelector(ac *AlertConfig) containerSelector {
+	return containerSelector{
+		project: ac.Project,
+		service: ac.Service,
+		name:    ac.Container,
+		image:   ac.Image,
+		labels:  ac.Labels,
+	}
+}
+
+func (s *containerSelector) empty() bool {
+	return s.project == "" && s.service == "" && s.name == "" && s.image == "" && len(s.labels) == 0
+}
+
+// matches reports whether the container satisfies every configured selector.
+func (s *containerSelector) matches(c *ContainerMetrics) bool {
+	if s.project != "" && c.Project != s.project {
+		return false
+	}
+	if s.service != "" && c.Service != s.service {
+		return false
+	}
+	if s.name != "" {
+		if ok, _ := filepath.Match(s.name, c.Name); !ok {
+			return false
+		}
+	}
+	if s.image != "" {
+		if ok, _ := filepath.Match(s.image, c.Image); !ok {
+			return false
+		}
+	}
+	for key, pattern := range s.labels {
+		val, ok := c.Labels[key]
+		if !ok {
+			return false
+		}
+		if matched, _ := filepath.Match(pattern, val); !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// String renders the selector as space-separated key=value pairs, e.g.
+// "project=shop name=db-* label:tier=backend". Empty for the zero value.
+func (s *containerSelector) String() string {
+	var parts []string
+	if s.project != "" {
+		parts = append(parts, "project="+s.project)
+	}
+	if s.service != "" {
+		parts = append(parts, "service="+s.service)
+	}
+	if s.name != "" {
+		parts = append(parts, "name="+s.name)
+	}
+	if s.image != "" {
+		parts = append(parts, "image="+s.image)
+	}
+	keys := make([]string, 0, len(s.labels))
+	for k := range s.labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		parts = append(parts, "label:"+k+"="+s.labels[k])
+	}
+	return strings.Join(parts, " ")
+}
+
+// validateSelector checks glob syntax of the selector fields.
+func validateSelector(ac *AlertConfig) error {
+	if _, err := filepath.Match(ac.Container, ""); err != nil {
+		return fmt.Errorf("invalid container pattern %q: %w", ac.Container, err)
+	}
+	if _, err := filepath.Match(ac.Image, ""); err != nil {
+		return fmt.Errorf("invalid image pattern %q: %w", ac.Image, err)
+	}
+	for key, pattern := range ac.Labels {
+		if key == "" {
+			return fmt.Errorf("label key must not be empty")
+		}
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q for label %q: %w", pattern, key, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package agent
+
+import "testing"
+
+func TestContainerSelectorMatches(t *testing.T) {
+	db := &ContainerMetrics{
+		Name: "shop-db-1", Image: "postgres:16", Project: "shop", Service: "db",
+		Labels: map[string]string{"tier": "backend", "team": "payments"},
+	}
+	plain := &ContainerMetrics{Name: "redis", Image: "redis:7", Service: "redis"}
+
+	tests := []struct {
+		name string
+		sel  containerSelector
+		c    *ContainerMetrics
+		want bool
+	}{
+		{"empty matches all", containerSelector{}, db, true},
+		{"project", containerSelector{project: "shop"}, db, true},
+		{"wrong project", containerSelector{project: "blog"}, db, false},
+		{"project and service", containerSelector{project: "shop", service: "db"}, db, true},
+		{"wrong service", containerSelector{project: "shop", service: "web"}, db, false},
+		{"non-compose service is name", containerSelector{service: "redis"}, plain, true},
+		{"name glob", containerSelector{name: "shop-*"}, db, true},
+		{"name glob miss", containerSelector{name: "blog-*"}, db, false},
+		{"image glob", containerSelector{image: "postgres:*"}, db, true},
+		{"image glob miss", containerSelector{image: "postgres:*"}, plain, false},
+		{"label", containerSelector{labels: map[string]string{"tier": "backend"}}, db, true},
+		{"label glob", containerSelector{labels: map[string]string{"team": "pay*"}}, db, true},
+		{"all labels must match", containerSelector{labels: map[string]string{"tier": "backend", "team": "search"}}, db, false},
+		{"missing label", containerSelector{labels: map[string]string{"tier": "*"}}, plain, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.sel.matches(tt.c); got != tt.want {
+				t.Errorf("matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContainerSelectorString(t *testing.T) {
+	sel := containerSelector{
+		project: "shop", service: "db", name: "db-*", image: "postgres:*",
+		labels: map[string]string{"tier": "backend", "team": "payments"},
+	}
+	want := "project=shop service=db name=db-* image=postgres:* label:team=payments label:tier=backend"
+	if got := sel.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if got := (&containerSelector{}).String(); got != "" {
+		t.Errorf("empty String() = %q", got)
+	}
+}
@@ -9,7 +9,9 @@ import (
 	"log/slog"
 	"net"
 	"os"
+	"os/user"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -26,12 +28,22 @@ const defaultMaxQueryRange = 24 * 60 * 60
 // maxSilenceDuration is the maximum silence duration (30 days in seconds).
 const maxSilenceDuration = 30 * 24 * 60 * 60
 
+// maxCommentLen caps the comment attached to a silence.
+const maxCommentLen = 512
+
 // maxDownsamplePoints caps the Points parameter in downsampled queries.
 const maxDownsamplePoints = 4096
 
 // maxLogLimit caps the Limit parameter in log queries.
 const maxLogLimit = 10000
 
+// defaultNotificationLimit and maxNotificationLimit bound the Limit
+// parameter in notification queries.
+const (
+	defaultNotificationLimit = 100
+	maxNotificationLimit     = 1000
+)
+
 // maxSearchLen caps the Search string in log queries and subscriptions.
 const maxSearchLen = 512
 
@@ -52,6 +64,10 @@ type SocketServer struct {
 	alerterMu      sync.RWMutex
 	alerter        *Alerter
 	lastTestNotify atomic.Int64 // unix timestamp of last test notification
+
+	rulesMu     sync.RWMutex
+	editRules   func(context.Context, ruleChange) error // nil = rules cannot be changed at runtime
+	ruleCatalog ruleCatalog
 }
 
 // NewSocketServer creates a SocketServer. Call Start to begin accepting connections.
@@ -76,6 +92,21 @@ func (ss *SocketServer) SetAlerter(a *Alerter) {
 	ss.alerter = a
 }
 
+// SetRuleEditor sets the function that applies runtime rule changes.
+func (ss *SocketServer) SetRuleEditor(fn func(context.Context, ruleChange) error) {
+	ss.rulesMu.Lock()
+	defer ss.rulesMu.Unlock()
+	ss.editRules = fn
+}
+
+// SetRuleCatalog updates the managed and disabled rules reported by
+// query:alert_rules.
+func (ss *SocketServer) SetRuleCatalog(c ruleCatalog) {
+	ss.rulesMu.Lock()
+	defer ss.rulesMu.Unlock()
+	ss.ruleCatalog = c
+}
+
 // SetRetentionDays updates the retention days used for query range limits.
 func (ss *SocketServer) SetRetentionDays(days int) {
 	ss.retentionDays.Store(int32(days))
@@ -178,6 +209,7 @@ func (ss *SocketServer) handleConn(conn net.Conn) {
 		conn: conn,
 		ctx:  ctx,
 		subs: make(map[string]*subscription),
+		peer: peerName(conn),
 	}
 	defer c.cleanup()
 	defer slog.Info("client disconnected", "remote", conn.RemoteAddr())
@@ -207,6 +239,24 @@ type connState struct {
 	ctx     context.Context // cancelled when connection closes
 	writeMu sync.Mutex
 	subs    map[string]*subscription // topic -> subscription
+
+	peer string // local user on the other end, see peerName
+}
+
+// peerName names the local user that opened conn, taken from the socket's
+// peer credentials so clients can't claim to be someone else. Remote
+// clients are the user they logged in as over SSH. It returns "" if the
+// credentials are unavailable.
+func peerName(conn net.Conn) string {
+	uid, ok := peerUID(conn)
+	if !ok {
+		return ""
+	}
+	id := strconv.FormatUint(uint64(uid), 10)
+	if u, err := user.LookupId(id); err == nil {
+		return truncate(u.Username, maxNameLen)
+	}
+	return "uid " + id
 }
 
 func (c *connState) cleanup() {
@@ -306,6 +356,10 @@ func (c *connState) dispatch(env *protocol.Envelope) {
 		c.ackAlert(env)
 	case protocol.TypeActionSilence:
 		c.silenceAlert(env)
+	case protocol.TypeActionUnsilence:
+		c.unsilence(env)
+	case protocol.TypeQuerySilences:
+		c.querySilences(env.ID)
 	case protocol.TypeActionSetTracking:
 		c.setTracking(env)
 	case protocol.TypeActionTestNotify:
@@ -314,6 +368,20 @@ func (c *connState) dispatch(env *protocol.Envelope) {
 		c.queryTracking(env)
 	case protocol.TypeQueryAlertRules:
 		c.queryAlertRules(env.ID)
+	case protocol.TypeActionCreateAlertRule:
+		c.saveAlertRule(env, ruleCreate)
+	case protocol.TypeActionUpdateAlertRule:
+		c.saveAlertRule(env, ruleUpdate)
+	case protocol.TypeActionDisableAlertRule:
+		c.disableAlertRule(env)
+	case protocol.TypeActionDeleteAlertRule:
+		c.deleteAlertRule(env)
+	case protocol.TypeQueryBacktest:
+		c.queryBacktest(env)
+	case protocol.TypeQueryAlertStats:
+		c.queryAlertStats(env)
+	case protocol.TypeQueryNotifications:
+		c.queryNotifications(env)
 
 	default:
 		c.sendError(env.ID, fmt.Sprintf("unknown message type: %s", env.Type))
@@ -416,6 +484,9 @@ func (c *connState) subscribeAlerts() {
 		slog.Warn("query firing alerts for snapshot", "error", err)
 		return
 	}
+	if err := c.ss.store.AttachRemediations(c.ctx, alerts); err != nil {
+		slog.Warn("attach remediations for snapshot", "error", err)
+	}
 	for _, a := range alerts {
 		event := &protocol.AlertEvent{
 			ID:           a.ID,
@@ -427,6 +498,11 @@ func (c *connState) subscribeAlerts() {
 			Message:      a.Message,
 			State:        "firing",
 			Acknowledged: a.Acknowledged,
+			InhibitedBy:  a.InhibitedBy,
+			Remediations: convertRemediations(a.Remediations),
+			Flapping:     a.Flapping,
+			Description:  a.Description,
+			RunbookURL:   a.RunbookURL,
 		}
 		env, err := protocol.NewEnvelope(protocol.TypeAlertEvent, 0, event)
 		if err != nil {
@@ -657,11 +733,89 @@ func (c *connState) queryAlerts(env *protocol.Envelope) {
 		c.sendError(env.ID, "query failed")
 		return
 	}
+	if err := c.ss.store.AttachRemediations(c.ctx, alerts); err != nil {
+		slog.Error("attach remediations", "error", err)
+	}
 
 	resp := protocol.QueryAlertsResp{Alerts: convertAlerts(alerts)}
 	c.sendResponse(env.ID, &resp)
 }
 
+func (c *connState) queryAlertStats(env *protocol.Envelope) {
+	var req protocol.QueryAlertStatsReq
+	if err := protocol.DecodeBody(env.Body, &req); err != nil {
+		c.sendError(env.ID, "invalid query body")
+		return
+	}
+	if !c.checkTimeRange(env.ID, req.Start, req.End) {
+		return
+	}
+
+	incidents, err := c.ss.store.QueryAlertIncidents(c.ctx, req.Start, req.End)
+	if err != nil {
+		slog.Error("query alert stats", "error", err)
+		c.sendError(env.ID, "query failed")
+		return
+	}
+	// Alerts still firing count up to now, not to a future end.
+	end := time.Unix(req.End, 0)
+	if now := time.Now(); now.Before(end) {
+		end = now
+	}
+	c.sendResponse(env.ID, alertStats(incidents, time.Unix(req.Start, 0), end))
+}
+
+func (c *connState) queryNotifications(env *protocol.Envelope) {
+	var req protocol.QueryNotificationsReq
+	if env.Body != nil {
+		if err := protocol.DecodeBody(env.Body, &req); err != nil {
+			c.sendError(env.ID, "invalid query body")
+			return
+		}
+	}
+	switch req.Status {
+	case "", "queued", "sending", "sent", "failed", "deferred", "summarized":
+	default:
+		c.sendError(env.ID, fmt.Sprintf("invalid status %q", req.Status))
+		return
+	}
+	if req.Limit <= 0 {
+		req.Limit = defaultNotificationLimit
+	}
+	req.Limit = min(req.Limit, maxNotificationLimit)
+
+	deliveries, queued, failed, err := c.ss.store.QueryNotifications(c.ctx, req.Status, req.Limit)
+	if err != nil {
+		slog.Error("query notifications", "error", err)
+		c.sendError(env.ID, "query failed")
+		return
+	}
+	resp := protocol.QueryNotificationsResp{
+		Notifications: make([]protocol.NotificationMsg, len(deliveries)),
+		Queued:        queued,
+		Failed:        failed,
+	}
+	for i, d := range deliveries {
+		m := protocol.NotificationMsg{
+			ID:        d.ID,
+			Channel:   d.Channel,
+			Subject:   d.Subject,
+			Status:    d.Status,
+			Attempts:  d.Attempts,
+			CreatedAt: d.CreatedAt.Unix(),
+			Error:     d.Error,
+		}
+		if d.Status == "queued" || d.Status == "deferred" {
+			m.NextAttempt = d.NextAttempt.Unix()
+		}
+		if d.SentAt != nil {
+			m.SentAt = d.SentAt.Unix()
+		}
+		resp.Notifications[i] = m
+	}
+	c.sendResponse(env.ID, &resp)
+}
+
 func (c *connState) queryContainers(id uint32) {
 	containers := c.ss.docker.Containers()
 	resp := protocol.QueryContainersResp{
@@ -693,10 +847,16 @@ func (c *connState) ackAlert(env *protocol.Envelope) {
 		c.sendError(env.ID, "invalid body")
 		return
 	}
-	if err := c.ss.store.AckAlert(c.ctx, req.AlertID); err != nil {
+	if err := c.ss.store.AckAlert(c.ctx, req.AlertID, time.Now()); err != nil {
 		c.sendError(env.ID, "alert not found")
 		return
 	}
+	c.ss.alerterMu.RLock()
+	alerter := c.ss.alerter
+	c.ss.alerterMu.RUnlock()
+	if alerter != nil {
+		alerter.Acknowledge(req.AlertID)
+	}
 	c.sendResult(env.ID, &protocol.Result{OK: true, Message: "acknowledged"})
 }
 
@@ -717,16 +877,87 @@ func (c *connState) silenceAlert(env *protocol.Envelope) {
 		c.sendError(env.ID, fmt.Sprintf("duration must be 0-%d seconds", maxSilenceDuration))
 		return
 	}
-	if !alerter.HasRule(req.RuleName) {
+	if req.RuleName == "" && req.InstanceKey == "" {
+		c.sendError(env.ID, "rule_name or instance_key must be set")
+		return
+	}
+	if len(req.RuleName) > maxNameLen || len(req.InstanceKey) > maxNameLen {
+		c.sendError(env.ID, "invalid rule name or instance key")
+		return
+	}
+	if req.RuleName != "" && !alerter.HasRule(req.RuleName) {
 		c.sendError(env.ID, "unknown rule name")
 		return
 	}
-	alerter.Silence(req.RuleName, time.Duration(req.Duration)*time.Second)
-	msg := "silenced"
+	if req.InstanceKey != "" {
+		rule := alerter.ruleNameForKey(req.InstanceKey)
+		if rule == "" {
+			c.sendError(env.ID, "unknown instance key")
+			return
+		}
+		if req.RuleName != "" && rule != req.RuleName {
+			c.sendError(env.ID, "instance key does not belong to rule")
+			return
+		}
+	}
+
+	// Zero duration lifts the silences created with the same fields.
 	if req.Duration == 0 {
-		msg = "unsilenced"
+		for _, sl := range alerter.Silences() {
+			if sl.RuleName == req.RuleName && sl.InstanceKey == req.InstanceKey {
+				if err := alerter.Unsilence(c.ctx, sl.ID); err != nil {
+					slog.Warn("unsilence", "id", sl.ID, "error", err)
+				}
+			}
+		}
+		c.sendResult(env.ID, &protocol.Result{OK: true, Message: "unsilenced"})
+		return
+	}
+
+	sl, err := alerter.AddSilence(c.ctx, Silence{
+		RuleName:    req.RuleName,
+		InstanceKey: req.InstanceKey,
+		CreatedBy:   c.peer,
+		Comment:     truncate(req.Comment, maxCommentLen),
+	}, time.Duration(req.Duration)*time.Second)
+	if err != nil {
+		slog.Error("add silence", "error", err)
+		c.sendError(env.ID, "failed to save silence")
+		return
 	}
-	c.sendResult(env.ID, &protocol.Result{OK: true, Message: msg})
+	c.sendResult(env.ID, &protocol.Result{OK: true, Message: fmt.Sprintf("silenced (id %d)", sl.ID)})
+}
+
+func (c *connState) unsilence(env *protocol.Envelope) {
+	var req protocol.UnsilenceReq
+	if err := protocol.DecodeBody(env.Body, &req); err != nil {
+		c.sendError(env.ID, "invalid body")
+		return
+	}
+	c.ss.alerterMu.RLock()
+	alerter := c.ss.alerter
+	c.ss.alerterMu.RUnlock()
+	if alerter == nil {
+		c.sendError(env.ID, "alerter not configured")
+		return
+	}
+	if err := alerter.Unsilence(c.ctx, req.SilenceID); err != nil {
+		c.sendError(env.ID, "silence not found")
+		return
+	}
+	c.sendResult(env.ID, &protocol.Result{OK: true, Message: "unsilenced"})
+}
+
+func (c *connState) querySilences(id uint32) {
+	c.ss.alerterMu.RLock()
+	alerter := c.ss.alerter
+	c.ss.alerterMu.RUnlock()
+
+	silences := []protocol.SilenceMsg{}
+	if alerter != nil {
+		silences = convertSilences(alerter.Silences())
+	}
+	c.sendResponse(id, &protocol.QuerySilencesResp{Silences: silences})
 }
 
 func (c *connState) testNotify(env *protocol.Envelope) {
@@ -828,7 +1059,14 @@ func (c *connState) queryAlertRules(id uint32) {
 				Condition:   rs.Condition,
 				Severity:    rs.Severity,
 				Actions:     rs.Actions,
+				Channels:    rs.Channels,
 				FiringCount: rs.FiringCount,
+				Selector:    rs.Selector,
+				Aggregates:  rs.Aggregates,
+
+				Description:     rs.Description,
+				RunbookURL:      rs.RunbookURL,
+				MessageTemplate: rs.MessageTemplate,
 			}
 			if rs.For > 0 {
 				info.For = rs.For.String()
@@ -849,13 +1087,136 @@ func (c *connState) queryAlertRules(id uint32) {
 			if rs.Window > 0 {
 				info.Window = rs.Window.String()
 			}
+			if rs.Maintenance != "" {
+				info.Maintenance = rs.Maintenance
+				info.MaintenanceEnd = rs.MaintenanceEnd.Unix()
+			}
 			rules = append(rules, info)
 		}
 	}
+	c.ss.rulesMu.RLock()
+	catalog := c.ss.ruleCatalog
+	c.ss.rulesMu.RUnlock()
+	for i := range rules {
+		if config, ok := catalog.managed[rules[i].Name]; ok {
+			rules[i].Managed = true
+			rules[i].Config = config
+		}
+	}
+	rules = append(rules, catalog.disabled...)
 	if rules == nil {
 		rules = []protocol.AlertRuleInfo{}
 	}
-	c.sendResponse(id, &protocol.QueryAlertRulesResp{Rules: rules})
+	resp := &protocol.QueryAlertRulesResp{Rules: rules}
+	if alerter != nil {
+		for _, ms := range alerter.Maintenance() {
+			mi := protocol.MaintenanceInfo{Name: ms.Name, Schedule: ms.Schedule, Active: ms.Active}
+			if ms.Active {
+				mi.Until = ms.Until.Unix()
+			}
+			resp.Maintenance = append(resp.Maintenance, mi)
+		}
+	}
+	c.sendResponse(id, resp)
+}
+
+func (c *connState) saveAlertRule(env *protocol.Envelope, op ruleOp) {
+	var req protocol.SaveAlertRuleReq
+	if err := protocol.DecodeBody(env.Body, &req); err != nil {
+		c.sendError(env.ID, "invalid body")
+		return
+	}
+	if len(req.Config) > maxRuleConfigLen {
+		c.sendError(env.ID, fmt.Sprintf("config exceeds %d bytes", maxRuleConfigLen))
+		return
+	}
+	c.changeRule(env.ID, ruleChange{op: op, name: req.Name, config: req.Config})
+}
+
+func (c *connState) disableAlertRule(env *protocol.Envelope) {
+	var req protocol.DisableAlertRuleReq
+	if err := protocol.DecodeBody(env.Body, &req); err != nil {
+		c.sendError(env.ID, "invalid body")
+		return
+	}
+	c.changeRule(env.ID, ruleChange{op: ruleDisable, name: req.Name, disabled: req.Disabled})
+}
+
+func (c *connState) deleteAlertRule(env *protocol.Envelope) {
+	var req protocol.DeleteAlertRuleReq
+	if err := protocol.DecodeBody(env.Body, &req); err != nil {
+		c.sendError(env.ID, "invalid body")
+		return
+	}
+	c.changeRule(env.ID, ruleChange{op: ruleDelete, name: req.Name})
+}
+
+// changeRule applies a rule change through the agent and reports the result.
+func (c *connState) changeRule(id uint32, ch ruleChange) {
+	if len(ch.name) > maxNameLen || !validRuleName.MatchString(ch.name) {
+		c.sendError(id, "invalid rule name (letters, digits, - and _ only)")
+		return
+	}
+	c.ss.rulesMu.RLock()
+	edit := c.ss.editRules
+	c.ss.rulesMu.RUnlock()
+	if edit == nil {
+		c.sendError(id, "rule changes not supported")
+		return
+	}
+	if err := edit(c.ctx, ch); err != nil {
+		c.sendError(id, err.Error())
+		return
+	}
+	var msg string
+	switch {
+	case ch.op == ruleCreate:
+		msg = "rule created"
+	case ch.op == ruleUpdate:
+		msg = "rule updated"
+	case ch.op == ruleDelete:
+		msg = "rule deleted"
+	case ch.disabled:
+		msg = "rule disabled"
+	default:
+		msg = "rule enabled"
+	}
+	c.sendResult(id, &protocol.Result{OK: true, Message: msg})
+}
+
+func (c *connState) queryBacktest(env *protocol.Envelope) {
+	var req protocol.QueryBacktestReq
+	if err := protocol.DecodeBody(env.Body, &req); err != nil {
+		c.sendError(env.ID, "invalid query body")
+		return
+	}
+	if len(req.RuleName) == 0 || len(req.RuleName) > maxNameLen {
+		c.sendError(env.ID, "invalid rule name")
+		return
+	}
+	if !c.checkTimeRange(env.ID, req.Start, req.End) {
+		return
+	}
+
+	c.ss.alerterMu.RLock()
+	alerter := c.ss.alerter
+	c.ss.alerterMu.RUnlock()
+	if alerter == nil {
+		c.sendError(env.ID, "alerter not configured")
+		return
+	}
+	if !alerter.HasRule(req.RuleName) {
+		c.sendError(env.ID, "unknown rule name")
+		return
+	}
+
+	res, err := alerter.Backtest(c.ctx, req.RuleName, time.Unix(req.Start, 0), time.Unix(req.End, 0))
+	if err != nil {
+		slog.Warn("backtest", "rule", req.RuleName, "error", err)
+		c.sendError(env.ID, err.Error())
+		return
+	}
+	c.sendResponse(env.ID, convertBacktest(res))
 }
 
 // validLogLevel returns true if the level is a recognized log level or empty.
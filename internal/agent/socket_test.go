@@ -1,8 +1,11 @@
 package agent
 
 import (
+	"context"
+	"fmt"
 	"net"
 	"os"
+	"os/user"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -355,7 +358,7 @@ func TestSocketQueryAlertsResolvedAndAcked(t *testing.T) {
 	}
 	resolved := ts.Add(30 * time.Second)
 	s.ResolveAlert(ctx, id, resolved)
-	s.AckAlert(ctx, id)
+	s.AckAlert(ctx, id, time.Now())
 
 	_, _, path := testSocketServer(t, s)
 	conn := dial(t, path)
@@ -534,7 +537,7 @@ func TestSocketSilenceWithAlerter(t *testing.T) {
 	if !result.OK {
 		t.Errorf("result.OK = false")
 	}
-	if !alerter.isSilenced("high_cpu") {
+	if !alerter.isSilenced("high_cpu", "high_cpu") {
 		t.Error("expected high_cpu to be silenced")
 	}
 }
@@ -543,6 +546,7 @@ func TestSocketSilenceValidation(t *testing.T) {
 	s := testStore(t)
 	alerter, _ := testAlerter(t, map[string]AlertConfig{
 		"high_cpu": {Condition: "host.cpu_percent > 90", Severity: "warning", Actions: []string{"notify"}},
+		"exited":   {Condition: "container.state == 'exited'", Severity: "critical", Actions: []string{"notify"}},
 	})
 	_, _, path := testSocketServerWithAlerter(t, s, alerter)
 
@@ -554,6 +558,9 @@ func TestSocketSilenceValidation(t *testing.T) {
 		{"negative duration", protocol.SilenceAlertReq{RuleName: "high_cpu", Duration: -1}, "duration"},
 		{"too long", protocol.SilenceAlertReq{RuleName: "high_cpu", Duration: maxSilenceDuration + 1}, "duration"},
 		{"unknown rule", protocol.SilenceAlertReq{RuleName: "nonexistent", Duration: 60}, "unknown rule"},
+		{"no target", protocol.SilenceAlertReq{Duration: 60}, "must be set"},
+		{"unknown instance", protocol.SilenceAlertReq{InstanceKey: "nonexistent:aaa", Duration: 60}, "unknown instance key"},
+		{"instance of other rule", protocol.SilenceAlertReq{RuleName: "high_cpu", InstanceKey: "exited:aaa", Duration: 60}, "does not belong"},
 	}
 
 	for _, tt := range tests {
@@ -584,6 +591,74 @@ func TestSocketSilenceValidation(t *testing.T) {
 	}
 }
 
+func TestSocketQuerySilencesAndUnsilence(t *testing.T) {
+	s := testStore(t)
+	alerter, _ := testAlerter(t, map[string]AlertConfig{
+		"exited": {Condition: "container.state == 'exited'", Severity: "critical", Actions: []string{"notify"}},
+	})
+	_, _, path := testSocketServerWithAlerter(t, s, alerter)
+	conn := dial(t, path)
+
+	request := func(id uint32, typ protocol.MsgType, body any) *protocol.Envelope {
+		t.Helper()
+		env, err := protocol.NewEnvelope(typ, id, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := protocol.WriteMsg(conn, env); err != nil {
+			t.Fatal(err)
+		}
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		resp, err := protocol.ReadMsg(conn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := request(1, protocol.TypeActionSilence, &protocol.SilenceAlertReq{
+		InstanceKey: "exited:aaa", Duration: 3600, Comment: "planned upgrade",
+	})
+	if resp.Type != protocol.TypeResult {
+		t.Fatalf("silence: expected result, got %q", resp.Type)
+	}
+
+	resp = request(2, protocol.TypeQuerySilences, nil)
+	var list protocol.QuerySilencesResp
+	if err := protocol.DecodeBody(resp.Body, &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Silences) != 1 {
+		t.Fatalf("silences = %d, want 1", len(list.Silences))
+	}
+	got := list.Silences[0]
+	// The creator is the user on the other end of the socket.
+	me, err := user.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.InstanceKey != "exited:aaa" || got.RuleName != "" || got.CreatedBy != me.Username || got.Comment != "planned upgrade" {
+		t.Errorf("silence = %+v", got)
+	}
+	if got.ExpiresAt-got.CreatedAt != 3600 {
+		t.Errorf("duration = %ds, want 3600", got.ExpiresAt-got.CreatedAt)
+	}
+
+	resp = request(3, protocol.TypeActionUnsilence, &protocol.UnsilenceReq{SilenceID: got.ID})
+	if resp.Type != protocol.TypeResult {
+		t.Fatalf("unsilence: expected result, got %q", resp.Type)
+	}
+	if alerter.isSilenced("exited", "exited:aaa") {
+		t.Error("exited:aaa still silenced after unsilence")
+	}
+
+	// A second unsilence of the same ID fails.
+	resp = request(4, protocol.TypeActionUnsilence, &protocol.UnsilenceReq{SilenceID: got.ID})
+	if resp.Type != protocol.TypeError {
+		t.Fatalf("second unsilence: expected error, got %q", resp.Type)
+	}
+}
+
 func TestSocketStreamMetrics(t *testing.T) {
 	s := testStore(t)
 	_, hub, path := testSocketServer(t, s)
@@ -1779,7 +1854,7 @@ func TestSocketTestNotifyNoChannels(t *testing.T) {
 	s := testStore(t)
 	alerter, err := NewAlerter(map[string]AlertConfig{
 		"test_rule": {Condition: "host.cpu_percent > 90", Severity: "warning", Actions: []string{"notify"}},
-	}, s, NewNotifier(&NotifyConfig{}))
+	}, s, NewNotifier(&NotifyConfig{}, nil))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1815,3 +1890,229 @@ func TestSocketTestNotifyNoChannels(t *testing.T) {
 		t.Errorf("error = %q, want 'no notification channels'", errResult.Error)
 	}
 }
+
+func TestSocketAlertRuleChanges(t *testing.T) {
+	s := testStore(t)
+	alerter, _ := testAlerter(t, map[string]AlertConfig{
+		"high_mem": {Condition: "host.memory_percent > 95", Severity: "warning", Actions: []string{"notify"}},
+	})
+	ss, _, path := testSocketServerWithAlerter(t, s, alerter)
+	var got []ruleChange
+	ss.SetRuleEditor(func(_ context.Context, ch ruleChange) error {
+		if ch.name == "broken" {
+			return fmt.Errorf("alert %q: bad condition", ch.name)
+		}
+		got = append(got, ch)
+		return nil
+	})
+	ss.SetRuleCatalog(ruleCatalog{
+		managed:  map[string]string{"high_mem": "condition = \"host.memory_percent > 95\""},
+		disabled: []protocol.AlertRuleInfo{{Name: "high_cpu", Condition: "host.cpu_percent > 90", Disabled: true}},
+	})
+	conn := dial(t, path)
+
+	request := func(id uint32, typ protocol.MsgType, body any) *protocol.Envelope {
+		t.Helper()
+		env, err := protocol.NewEnvelope(typ, id, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := protocol.WriteMsg(conn, env); err != nil {
+			t.Fatal(err)
+		}
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		resp, err := protocol.ReadMsg(conn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	tests := []struct {
+		typ     protocol.MsgType
+		body    any
+		wantErr string
+	}{
+		{protocol.TypeActionCreateAlertRule, &protocol.SaveAlertRuleReq{Name: "disk", Config: "condition = \"disk.percent > 90\""}, ""},
+		{protocol.TypeActionUpdateAlertRule, &protocol.SaveAlertRuleReq{Name: "disk", Config: "condition = \"disk.percent > 95\""}, ""},
+		{protocol.TypeActionDisableAlertRule, &protocol.DisableAlertRuleReq{Name: "disk", Disabled: true}, ""},
+		{protocol.TypeActionDeleteAlertRule, &protocol.DeleteAlertRuleReq{Name: "disk"}, ""},
+		{protocol.TypeActionCreateAlertRule, &protocol.SaveAlertRuleReq{Name: "a:b"}, "invalid rule name"},
+		{protocol.TypeActionDeleteAlertRule, &protocol.DeleteAlertRuleReq{}, "invalid rule name"},
+		{protocol.TypeActionCreateAlertRule, &protocol.SaveAlertRuleReq{Name: "big", Config: strings.Repeat("#", maxRuleConfigLen+1)}, "exceeds"},
+		{protocol.TypeActionCreateAlertRule, &protocol.SaveAlertRuleReq{Name: "broken"}, "bad condition"},
+	}
+	for i, tt := range tests {
+		resp := request(uint32(i+1), tt.typ, tt.body)
+		if tt.wantErr == "" {
+			if resp.Type != protocol.TypeResult {
+				t.Errorf("%s: got %q, want result", tt.typ, resp.Type)
+			}
+			continue
+		}
+		var errResult protocol.ErrorResult
+		if err := protocol.DecodeBody(resp.Body, &errResult); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Type != protocol.TypeError || !strings.Contains(errResult.Error, tt.wantErr) {
+			t.Errorf("%s: got %q %q, want error containing %q", tt.typ, resp.Type, errResult.Error, tt.wantErr)
+		}
+	}
+	wantOps := []ruleOp{ruleCreate, ruleUpdate, ruleDisable, ruleDelete}
+	if len(got) != len(wantOps) {
+		t.Fatalf("changes = %+v, want %v", got, wantOps)
+	}
+	for i, op := range wantOps {
+		if got[i].op != op || got[i].name != "disk" {
+			t.Errorf("change %d = %s %q, want %s disk", i, got[i].op, got[i].name, op)
+		}
+	}
+	if !got[2].disabled {
+		t.Error("disable change should carry disabled = true")
+	}
+
+	// The catalog marks managed rules and lists the disabled ones.
+	env := protocol.NewEnvelopeNoBody(protocol.TypeQueryAlertRules, 99)
+	if err := protocol.WriteMsg(conn, env); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := protocol.ReadMsg(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rules protocol.QueryAlertRulesResp
+	if err := protocol.DecodeBody(resp.Body, &rules); err != nil {
+		t.Fatal(err)
+	}
+	if len(rules.Rules) != 2 {
+		t.Fatalf("rules = %+v, want high_mem and disabled high_cpu", rules.Rules)
+	}
+	if r := rules.Rules[0]; r.Name != "high_mem" || !r.Managed || r.Config == "" || r.Disabled {
+		t.Errorf("rules[0] = %+v, want managed high_mem", r)
+	}
+	if r := rules.Rules[1]; r.Name != "high_cpu" || !r.Disabled {
+		t.Errorf("rules[1] = %+v, want disabled high_cpu", r)
+	}
+}
+
+func TestSocketQueryAlertStats(t *testing.T) {
+	s := testStore(t)
+	ctx := t.Context()
+
+	ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, key := range []string{"exited:abc", "exited:abc", "exited:def"} {
+		fired := ts.Add(time.Duration(i) * time.Hour)
+		id, err := s.InsertAlert(ctx, &Alert{
+			RuleName: "exited", Severity: "critical", Condition: "container.state == 'exited'",
+			InstanceKey: key, FiredAt: fired, Message: "exited",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.AckAlert(ctx, id, fired.Add(2*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+		// A second ack keeps the first time.
+		if err := s.AckAlert(ctx, id, fired.Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.ResolveAlert(ctx, id, fired.Add(10*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, _, path := testSocketServer(t, s)
+	conn := dial(t, path)
+
+	req := protocol.QueryAlertStatsReq{Start: ts.Unix(), End: ts.Add(24 * time.Hour).Unix()}
+	env, err := protocol.NewEnvelope(protocol.TypeQueryAlertStats, 1, &req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := protocol.WriteMsg(conn, env); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := protocol.ReadMsg(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Type != protocol.TypeResult {
+		t.Fatalf("type = %q, want result", resp.Type)
+	}
+
+	var stats protocol.QueryAlertStatsResp
+	if err := protocol.DecodeBody(resp.Body, &stats); err != nil {
+		t.Fatal(err)
+	}
+	want := protocol.AlertStats{Fired: 3, Acknowledged: 3, Resolved: 3, FiringSecs: 1800, MTTASecs: 120, MTTRSecs: 600}
+	if stats.Total != want {
+		t.Errorf("total = %+v, want %+v", stats.Total, want)
+	}
+	if len(stats.Containers) != 2 || stats.Containers[0].Name != "abc" || stats.Containers[0].Fired != 2 {
+		t.Errorf("containers = %+v, want abc (2) first", stats.Containers)
+	}
+}
+
+func TestSocketQueryNotifications(t *testing.T) {
+	s := testStore(t)
+	ctx := t.Context()
+	at := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := s.EnqueueNotification(ctx, []string{"email", "slack-1"}, "Alert: cpu", []byte(`{}`), at); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.MarkNotificationSent(ctx, 1, at.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RetryNotification(ctx, 2, "slack-1", "slack returned 500", at.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, path := testSocketServer(t, s)
+	conn := dial(t, path)
+
+	query := func(id uint32, req protocol.QueryNotificationsReq) *protocol.Envelope {
+		t.Helper()
+		env, err := protocol.NewEnvelope(protocol.TypeQueryNotifications, id, &req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := protocol.WriteMsg(conn, env); err != nil {
+			t.Fatal(err)
+		}
+		resp, err := protocol.ReadMsg(conn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := query(1, protocol.QueryNotificationsReq{})
+	var r protocol.QueryNotificationsResp
+	if err := protocol.DecodeBody(resp.Body, &r); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Notifications) != 2 || r.Queued != 1 || r.Failed != 0 {
+		t.Fatalf("resp = %+v", r)
+	}
+	want := protocol.NotificationMsg{ID: 2, Channel: "slack-1", Subject: "Alert: cpu", Status: "queued", Attempts: 1,
+		CreatedAt: at.Unix(), NextAttempt: at.Add(time.Minute).Unix(), Error: "slack returned 500"}
+	if r.Notifications[0] != want {
+		t.Errorf("newest = %+v, want %+v", r.Notifications[0], want)
+	}
+	if sent := r.Notifications[1]; sent.Status != "sent" || sent.SentAt != at.Add(time.Second).Unix() || sent.NextAttempt != 0 {
+		t.Errorf("sent = %+v", sent)
+	}
+
+	resp = query(2, protocol.QueryNotificationsReq{Status: "sent"})
+	r = protocol.QueryNotificationsResp{}
+	if err := protocol.DecodeBody(resp.Body, &r); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Notifications) != 1 || r.Notifications[0].Channel != "email" {
+		t.Errorf("sent only = %+v", r.Notifications)
+	}
+
+	if resp := query(3, protocol.QueryNotificationsReq{Status: "bogus"}); resp.Type != protocol.TypeError {
+		t.Errorf("invalid status type = %q, want error", resp.Type)
+	}
+}
@@ -132,17 +132,81 @@ CREATE TABLE IF NOT EXISTS alerts (
 	fired_at     INTEGER NOT NULL,
 	resolved_at  INTEGER,
 	message      TEXT    NOT NULL,
-	acknowledged INTEGER NOT NULL DEFAULT 0
+	acknowledged INTEGER NOT NULL DEFAULT 0,
+	inhibited_by TEXT    NOT NULL DEFAULT '',
+	escalation_step INTEGER NOT NULL DEFAULT 0,
+	flapping     INTEGER NOT NULL DEFAULT 0,
+	description  TEXT    NOT NULL DEFAULT '',
+	runbook_url  TEXT    NOT NULL DEFAULT '',
+	acknowledged_at INTEGER
 );
 CREATE INDEX IF NOT EXISTS idx_alerts_fired ON alerts(fired_at);
 CREATE INDEX IF NOT EXISTS idx_alerts_unresolved ON alerts(fired_at) WHERE resolved_at IS NULL;
 
+CREATE TABLE IF NOT EXISTS remediations (
+	id       INTEGER PRIMARY KEY AUTOINCREMENT,
+	alert_id INTEGER NOT NULL,
+	action   TEXT    NOT NULL,
+	attempt  INTEGER NOT NULL,
+	at       INTEGER NOT NULL,
+	error    TEXT    NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_remediations_alert ON remediations(alert_id);
+
+CREATE TABLE IF NOT EXISTS silences (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	rule_name    TEXT    NOT NULL DEFAULT '',
+	instance_key TEXT    NOT NULL DEFAULT '',
+	created_at   INTEGER NOT NULL,
+	expires_at   INTEGER NOT NULL,
+	created_by   TEXT    NOT NULL DEFAULT '',
+	comment      TEXT    NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_silences_expires ON silences(expires_at);
+
+CREATE TABLE IF NOT EXISTS agent_runs (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	started_at INTEGER NOT NULL,
+	stopped_at INTEGER
+);
+
 CREATE TABLE IF NOT EXISTS tracking_state (
 	kind    TEXT    NOT NULL,
 	name    TEXT    NOT NULL,
 	tracked INTEGER NOT NULL DEFAULT 1,
 	UNIQUE(kind, name)
 );
+
+CREATE TABLE IF NOT EXISTS alert_rules (
+	name       TEXT    PRIMARY KEY,
+	config     TEXT    NOT NULL DEFAULT '',
+	disabled   INTEGER NOT NULL DEFAULT 0,
+	updated_at INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS notifications (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	channel      TEXT    NOT NULL,
+	subject      TEXT    NOT NULL,
+	payload      TEXT    NOT NULL,
+	status       TEXT    NOT NULL DEFAULT 'queued',
+	attempts     INTEGER NOT NULL DEFAULT 0,
+	created_at   INTEGER NOT NULL,
+	next_attempt INTEGER NOT NULL,
+	sent_at      INTEGER,
+	error        TEXT    NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_notifications_due ON notifications(status, next_attempt);
+CREATE INDEX IF NOT EXISTS idx_notifications_created ON notifications(created_at);
+
+CREATE TABLE IF NOT EXISTS chat_refs (
+	channel      TEXT    NOT NULL,
+	instance_key TEXT    NOT NULL,
+	subject      TEXT    NOT NULL,
+	fired_at     INTEGER NOT NULL,
+	event_id     TEXT    NOT NULL DEFAULT '',
+	PRIMARY KEY (channel, instance_key)
+);
 `
 
 // Store manages SQLite persistence for metrics and logs.
@@ -440,6 +504,12 @@ func (s *Store) ensureColumns() {
 		"ALTER TABLE logs ADD COLUMN level TEXT NOT NULL DEFAULT ''",
 		"ALTER TABLE logs ADD COLUMN display_msg TEXT NOT NULL DEFAULT ''",
 		"ALTER TABLE tracking_state ADD COLUMN tracked INTEGER NOT NULL DEFAULT 1",
+		"ALTER TABLE alerts ADD COLUMN inhibited_by TEXT NOT NULL DEFAULT ''",
+		"ALTER TABLE alerts ADD COLUMN escalation_step INTEGER NOT NULL DEFAULT 0",
+		"ALTER TABLE alerts ADD COLUMN flapping INTEGER NOT NULL DEFAULT 0",
+		"ALTER TABLE alerts ADD COLUMN description TEXT NOT NULL DEFAULT ''",
+		"ALTER TABLE alerts ADD COLUMN runbook_url TEXT NOT NULL DEFAULT ''",
+		"ALTER TABLE alerts ADD COLUMN acknowledged_at INTEGER",
 	}
 	indexes := []string{
 		"CREATE INDEX IF NOT EXISTS idx_logs_svc ON logs(project, service, timestamp)",
@@ -470,19 +540,20 @@ func (s *Store) ensureColumns() {
 
 // HostMetrics represents a single host metrics snapshot.
 type HostMetrics struct {
-	CPUPercent float64
-	CPUs       int // number of logical CPU cores (live-only, not persisted)
-	MemTotal   uint64
-	MemUsed    uint64
-	MemPercent float64
-	MemCached  uint64
-	MemFree    uint64
-	SwapTotal  uint64
-	SwapUsed   uint64
-	Load1      float64
-	Load5      float64
-	Load15     float64
-	Uptime     float64
+	CPUPercent  float64
+	CPUs        int // number of logical CPU cores (live-only, not persisted)
+	MemTotal    uint64
+	MemUsed     uint64
+	MemPercent  float64
+	MemCached   uint64
+	MemFree     uint64
+	SwapTotal   uint64
+	SwapUsed    uint64
+	Load1       float64
+	Load5       float64
+	Load15      float64
+	Uptime      float64
+	AgentUptime float64 // seconds since the agent started (live-only, not persisted)
 }
 
 // DiskMetrics represents disk usage for a single mountpoint.
@@ -529,19 +600,91 @@ type ContainerMetrics struct {
 	BlockWrite   uint64
 	PIDs         uint64
 	DiskUsage    uint64
+	Labels       map[string]string // container labels (live-only, used by alert selectors)
 }
 
 // Alert represents a fired alert stored in the database.
 type Alert struct {
-	ID           int64
-	RuleName     string
-	Severity     string
-	Condition    string
-	InstanceKey  string
-	FiredAt      time.Time
-	ResolvedAt   *time.Time
-	Message      string
-	Acknowledged bool
+	ID             int64
+	RuleName       string
+	Severity       string
+	Condition      string
+	InstanceKey    string
+	FiredAt        time.Time
+	ResolvedAt     *time.Time
+	Message        string
+	Acknowledged   bool
+	InhibitedBy    string        // source rule that suppressed notifications, if any
+	EscalationStep int           // escalation steps already taken
+	Flapping       bool          // instance was flapping; notifications suppressed
+	Description    string        // rule annotation at fire time
+	RunbookURL     string        // rule annotation at fire time
+	Remediations   []Remediation // filled by AttachRemediations, not stored in the alerts row
+}
+
+// Remediation is one docker:restart or docker:stop attempt for an alert.
+type Remediation struct {
+	AlertID int64
+	Action  string // "restart" or "stop"
+	Attempt int    // 1-based, per alert instance
+	At      time.Time
+	Error   string // "" = succeeded
+}
+
+// AlertIncident is the timeline of one stored alert, as used for alert
+// statistics.
+type AlertIncident struct {
+	RuleName       string
+	Condition      string
+	InstanceKey    string
+	FiredAt        time.Time
+	ResolvedAt     *time.Time
+	Acknowledged   bool
+	AcknowledgedAt *time.Time // nil for alerts acknowledged before the time was recorded
+}
+
+// Silence suppresses notifications for alerts matching a rule, an instance
+// key, or both. An empty field matches anything.
+type Silence struct {
+	ID          int64
+	RuleName    string
+	InstanceKey string
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+	CreatedBy   string
+	Comment     string
+}
+
+// matches reports whether the silence applies to the given alert instance.
+func (s *Silence) matches(ruleName, instanceKey string) bool {
+	if s.RuleName != "" && s.RuleName != ruleName {
+		return false
+	}
+	return s.InstanceKey == "" || s.InstanceKey == instanceKey
+}
+
+// ManagedRule is an alert rule row managed over the socket. Config holds the
+// TOML body of a rule created at runtime; it is empty for a rule from the
+// config file that was only disabled.
+type ManagedRule struct {
+	Name      string
+	Config    string
+	Disabled  bool
+	UpdatedAt time.Time
+}
+
+// NotificationDelivery is one notification queued for one channel.
+type NotificationDelivery struct {
+	ID          int64
+	Channel     string
+	Subject     string
+	Payload     []byte // encoded notification, see queuedNotification
+	Status      string // "queued", "sending", "sent" or "failed"
+	Attempts    int
+	CreatedAt   time.Time
+	NextAttempt time.Time
+	SentAt      *time.Time
+	Error       string // last send error; kept while queued for a retry
 }
 
 // LogEntry represents a single log line from a container.
@@ -2,6 +2,7 @@ package agent
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"runtime/debug"
 	"strings"
@@ -122,9 +123,10 @@ func (s *Store) InsertLogs(ctx context.Context, entries []LogEntry) error {
 
 func (s *Store) InsertAlert(ctx context.Context, a *Alert) (int64, error) {
 	res, err := s.db.ExecContext(ctx,
-		`INSERT INTO alerts (rule_name, severity, condition, instance_key, fired_at, message)
-		 VALUES (?, ?, ?, ?, ?, ?)`,
-		a.RuleName, a.Severity, a.Condition, a.InstanceKey, a.FiredAt.Unix(), a.Message,
+		`INSERT INTO alerts (rule_name, severity, condition, instance_key, fired_at, message, inhibited_by, flapping, description, runbook_url)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		a.RuleName, a.Severity, a.Condition, a.InstanceKey, a.FiredAt.Unix(), a.Message, a.InhibitedBy, a.Flapping,
+		a.Description, a.RunbookURL,
 	)
 	if err != nil {
 		return 0, err
@@ -153,7 +155,8 @@ func (s *Store) ResolveOrphanedAlerts(ctx context.Context, resolvedAt time.Time)
 // QueryFiringAlerts returns all currently firing (unresolved) alerts.
 func (s *Store) QueryFiringAlerts(ctx context.Context) ([]Alert, error) {
 	rows, err := s.readDB.QueryContext(ctx,
-		`SELECT id, rule_name, severity, condition, instance_key, fired_at, resolved_at, message, acknowledged
+		`SELECT id, rule_name, severity, condition, instance_key, fired_at, resolved_at, message, acknowledged, inhibited_by, escalation_step, flapping,
+		        description, runbook_url
 		 FROM alerts WHERE resolved_at IS NULL ORDER BY fired_at DESC LIMIT 1000`)
 	if err != nil {
 		return nil, err
@@ -165,9 +168,10 @@ func (s *Store) QueryFiringAlerts(ctx context.Context) ([]Alert, error) {
 		var a Alert
 		var firedAt int64
 		var resolvedAt *int64
-		var ack int
+		var ack, flapping int
 		if err := rows.Scan(&a.ID, &a.RuleName, &a.Severity, &a.Condition, &a.InstanceKey,
-			&firedAt, &resolvedAt, &a.Message, &ack); err != nil {
+			&firedAt, &resolvedAt, &a.Message, &ack, &a.InhibitedBy, &a.EscalationStep, &flapping,
+			&a.Description, &a.RunbookURL); err != nil {
 			return nil, err
 		}
 		a.FiredAt = time.Unix(firedAt, 0)
@@ -176,6 +180,7 @@ func (s *Store) QueryFiringAlerts(ctx context.Context) ([]Alert, error) {
 			a.ResolvedAt = &t
 		}
 		a.Acknowledged = ack != 0
+		a.Flapping = flapping != 0
 		result = append(result, a)
 	}
 	return result, rows.Err()
@@ -452,6 +457,28 @@ func (s *Store) CountLogMatches(ctx context.Context, pattern string, isRegex boo
 	return counts, rows.Err()
 }
 
+// QueryLogContainers returns the distinct containers that logged within
+// [start, end]. Only ID, Name, Project and Service are set.
+func (s *Store) QueryLogContainers(ctx context.Context, start, end int64) ([]ContainerMetrics, error) {
+	rows, err := s.readDB.QueryContext(ctx,
+		`SELECT container_id, MAX(container_name), MAX(project), MAX(service)
+		 FROM logs WHERE timestamp >= ? AND timestamp <= ? GROUP BY container_id ORDER BY container_id`, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []ContainerMetrics
+	for rows.Next() {
+		var c ContainerMetrics
+		if err := rows.Scan(&c.ID, &c.Name, &c.Project, &c.Service); err != nil {
+			return nil, err
+		}
+		result = append(result, c)
+	}
+	return result, rows.Err()
+}
+
 // CountLogs returns the total number of log entries matching the scope filter
 // (container/project + time range). Search, Level, and Limit are excluded so
 // the count represents the total scope, not the filtered subset.
@@ -515,11 +542,32 @@ const maxAlertResults = 10000
 
 func (s *Store) QueryAlerts(ctx context.Context, start, end int64) ([]Alert, error) {
 	rows, err := s.readDB.QueryContext(ctx,
-		`SELECT id, rule_name, severity, condition, instance_key, fired_at, resolved_at, message, acknowledged
+		`SELECT id, rule_name, severity, condition, instance_key, fired_at, resolved_at, message, acknowledged, inhibited_by, escalation_step, flapping,
+		        description, runbook_url
 		 FROM alerts WHERE fired_at >= ? AND fired_at <= ? ORDER BY fired_at DESC LIMIT ?`, start, end, maxAlertResults)
 	if err != nil {
 		return nil, err
 	}
+	return scanAlerts(rows)
+}
+
+// QueryAlertsFiredOrResolved returns the alerts that fired or resolved
+// between start and end, newest first by fired_at.
+func (s *Store) QueryAlertsFiredOrResolved(ctx context.Context, start, end int64) ([]Alert, error) {
+	rows, err := s.readDB.QueryContext(ctx,
+		`SELECT id, rule_name, severity, condition, instance_key, fired_at, resolved_at, message, acknowledged, inhibited_by, escalation_step, flapping,
+		        description, runbook_url
+		 FROM alerts WHERE (fired_at >= ? AND fired_at <= ?) OR (resolved_at >= ? AND resolved_at <= ?)
+		 ORDER BY fired_at DESC LIMIT ?`, start, end, start, end, maxAlertResults)
+	if err != nil {
+		return nil, err
+	}
+	return scanAlerts(rows)
+}
+
+// scanAlerts reads alert rows selected with the columns of QueryAlerts and
+// closes rows.
+func scanAlerts(rows *sql.Rows) ([]Alert, error) {
 	defer rows.Close()
 
 	var result []Alert
@@ -527,9 +575,10 @@ func (s *Store) QueryAlerts(ctx context.Context, start, end int64) ([]Alert, err
 		var a Alert
 		var firedAt int64
 		var resolvedAt *int64
-		var ack int
+		var ack, flapping int
 		if err := rows.Scan(&a.ID, &a.RuleName, &a.Severity, &a.Condition, &a.InstanceKey,
-			&firedAt, &resolvedAt, &a.Message, &ack); err != nil {
+			&firedAt, &resolvedAt, &a.Message, &ack, &a.InhibitedBy, &a.EscalationStep, &flapping,
+			&a.Description, &a.RunbookURL); err != nil {
 			return nil, err
 		}
 		a.FiredAt = time.Unix(firedAt, 0)
@@ -538,14 +587,18 @@ func (s *Store) QueryAlerts(ctx context.Context, start, end int64) ([]Alert, err
 			a.ResolvedAt = &t
 		}
 		a.Acknowledged = ack != 0
+		a.Flapping = flapping != 0
 		result = append(result, a)
 	}
 	return result, rows.Err()
 }
 
-// AckAlert marks an alert as acknowledged.
-func (s *Store) AckAlert(ctx context.Context, id int64) error {
-	res, err := s.db.ExecContext(ctx, `UPDATE alerts SET acknowledged = 1 WHERE id = ?`, id)
+// AckAlert marks an alert as acknowledged. The first acknowledgement time is
+// kept.
+func (s *Store) AckAlert(ctx context.Context, id int64, at time.Time) error {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE alerts SET acknowledged = 1, acknowledged_at = COALESCE(acknowledged_at, ?) WHERE id = ?`,
+		at.Unix(), id)
 	if err != nil {
 		return err
 	}
@@ -559,6 +612,223 @@ func (s *Store) AckAlert(ctx context.Context, id int64) error {
 	return nil
 }
 
+// QueryAlertIncidents returns the timeline of every alert fired within
+// [start, end], oldest first. Unlike QueryAlerts it is not capped, since
+// statistics need all of them.
+func (s *Store) QueryAlertIncidents(ctx context.Context, start, end int64) ([]AlertIncident, error) {
+	rows, err := s.readDB.QueryContext(ctx,
+		`SELECT rule_name, condition, instance_key, fired_at, resolved_at, acknowledged, acknowledged_at
+		 FROM alerts WHERE fired_at >= ? AND fired_at <= ? ORDER BY fired_at`, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []AlertIncident
+	for rows.Next() {
+		var inc AlertIncident
+		var firedAt int64
+		var resolvedAt, ackedAt *int64
+		var ack int
+		if err := rows.Scan(&inc.RuleName, &inc.Condition, &inc.InstanceKey, &firedAt, &resolvedAt, &ack, &ackedAt); err != nil {
+			return nil, err
+		}
+		inc.FiredAt = time.Unix(firedAt, 0)
+		if resolvedAt != nil {
+			t := time.Unix(*resolvedAt, 0)
+			inc.ResolvedAt = &t
+		}
+		inc.Acknowledged = ack != 0
+		if ackedAt != nil {
+			t := time.Unix(*ackedAt, 0)
+			inc.AcknowledgedAt = &t
+		}
+		result = append(result, inc)
+	}
+	return result, rows.Err()
+}
+
+// InsertRemediation records a remediation attempt.
+func (s *Store) InsertRemediation(ctx context.Context, r *Remediation) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO remediations (alert_id, action, attempt, at, error) VALUES (?, ?, ?, ?, ?)`,
+		r.AlertID, r.Action, r.Attempt, r.At.Unix(), r.Error)
+	return err
+}
+
+// AttachRemediations fills the Remediations of each alert, oldest first.
+func (s *Store) AttachRemediations(ctx context.Context, alerts []Alert) error {
+	if len(alerts) == 0 {
+		return nil
+	}
+	idx := make(map[int64]int, len(alerts))
+	args := make([]any, len(alerts))
+	for i := range alerts {
+		idx[alerts[i].ID] = i
+		args[i] = alerts[i].ID
+	}
+	rows, err := s.readDB.QueryContext(ctx,
+		`SELECT alert_id, action, attempt, at, error FROM remediations
+		 WHERE alert_id IN (?`+strings.Repeat(",?", len(alerts)-1)+`) ORDER BY at, id`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r Remediation
+		var at int64
+		if err := rows.Scan(&r.AlertID, &r.Action, &r.Attempt, &at, &r.Error); err != nil {
+			return err
+		}
+		r.At = time.Unix(at, 0)
+		i := idx[r.AlertID]
+		alerts[i].Remediations = append(alerts[i].Remediations, r)
+	}
+	return rows.Err()
+}
+
+// AlertAcknowledged reports whether an alert has been acknowledged.
+func (s *Store) AlertAcknowledged(ctx context.Context, id int64) (bool, error) {
+	var ack int
+	if err := s.readDB.QueryRowContext(ctx, `SELECT acknowledged FROM alerts WHERE id = ?`, id).Scan(&ack); err != nil {
+		return false, err
+	}
+	return ack != 0, nil
+}
+
+// SetEscalationStep records how many escalation steps an alert has taken.
+func (s *Store) SetEscalationStep(ctx context.Context, id int64, step int) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE alerts SET escalation_step = ? WHERE id = ?`, step, id)
+	return err
+}
+
+// SetAlertFlapping records whether an alert's instance is flapping.
+func (s *Store) SetAlertFlapping(ctx context.Context, id int64, flapping bool) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE alerts SET flapping = ? WHERE id = ?`, flapping, id)
+	return err
+}
+
+// AgentRun is one agent process lifetime.
+type AgentRun struct {
+	ID        int64
+	StartedAt time.Time
+	StoppedAt *time.Time // nil while running, or if the process died
+}
+
+// maxAgentRuns is how many runs BeginRun keeps.
+const maxAgentRuns = 100
+
+// BeginRun records the start of an agent run. It returns the new run's ID
+// and the previous run, nil on first start.
+func (s *Store) BeginRun(ctx context.Context, startedAt time.Time) (int64, *AgentRun, error) {
+	var prev *AgentRun
+	var r AgentRun
+	var started int64
+	var stopped *int64
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, started_at, stopped_at FROM agent_runs ORDER BY id DESC LIMIT 1`).Scan(&r.ID, &started, &stopped)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return 0, nil, err
+	default:
+		r.StartedAt = time.Unix(started, 0)
+		if stopped != nil {
+			t := time.Unix(*stopped, 0)
+			r.StoppedAt = &t
+		}
+		prev = &r
+	}
+
+	res, err := s.db.ExecContext(ctx, `INSERT INTO agent_runs (started_at) VALUES (?)`, startedAt.Unix())
+	if err != nil {
+		return 0, nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, nil, err
+	}
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM agent_runs WHERE id <= ?`, id-maxAgentRuns); err != nil {
+		return 0, nil, err
+	}
+	return id, prev, nil
+}
+
+// EndRun records a clean stop of the agent run.
+func (s *Store) EndRun(ctx context.Context, id int64, stoppedAt time.Time) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE agent_runs SET stopped_at = ? WHERE id = ?`, stoppedAt.Unix(), id)
+	return err
+}
+
+// LastHostSample returns the time of the newest host metrics row, zero if
+// there is none.
+func (s *Store) LastHostSample(ctx context.Context) (time.Time, error) {
+	var ts *int64
+	if err := s.readDB.QueryRowContext(ctx, `SELECT MAX(timestamp) FROM host_metrics`).Scan(&ts); err != nil {
+		return time.Time{}, err
+	}
+	if ts == nil {
+		return time.Time{}, nil
+	}
+	return time.Unix(*ts, 0), nil
+}
+
+// InsertSilence stores a silence and returns its ID.
+func (s *Store) InsertSilence(ctx context.Context, sl *Silence) (int64, error) {
+	res, err := s.db.ExecContext(ctx,
+		`INSERT INTO silences (rule_name, instance_key, created_at, expires_at, created_by, comment)
+		 VALUES (?, ?, ?, ?, ?, ?)`,
+		sl.RuleName, sl.InstanceKey, sl.CreatedAt.Unix(), sl.ExpiresAt.Unix(), sl.CreatedBy, sl.Comment)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// ExpireSilence ends an active silence at the given time. Expired silences
+// are kept until pruned so the history of who silenced what remains.
+func (s *Store) ExpireSilence(ctx context.Context, id int64, at time.Time) error {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE silences SET expires_at = ? WHERE id = ? AND expires_at > ?`, at.Unix(), id, at.Unix())
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("silence %d not found", id)
+	}
+	return nil
+}
+
+// QueryActiveSilences returns silences that have not expired at now,
+// ordered by expiry.
+func (s *Store) QueryActiveSilences(ctx context.Context, now time.Time) ([]Silence, error) {
+	rows, err := s.readDB.QueryContext(ctx,
+		`SELECT id, rule_name, instance_key, created_at, expires_at, created_by, comment
+		 FROM silences WHERE expires_at > ? ORDER BY expires_at, id`, now.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []Silence
+	for rows.Next() {
+		var sl Silence
+		var createdAt, expiresAt int64
+		if err := rows.Scan(&sl.ID, &sl.RuleName, &sl.InstanceKey, &createdAt, &expiresAt,
+			&sl.CreatedBy, &sl.Comment); err != nil {
+			return nil, err
+		}
+		sl.CreatedAt = time.Unix(createdAt, 0)
+		sl.ExpiresAt = time.Unix(expiresAt, 0)
+		result = append(result, sl)
+	}
+	return result, rows.Err()
+}
+
 // SaveTracking persists the current tracking state (full snapshot).
 // The map contains both tracked (true) and explicitly-untracked (false) entries.
 func (s *Store) SaveTracking(ctx context.Context, state map[string]bool) error {
@@ -611,6 +881,345 @@ func (s *Store) LoadTracking(ctx context.Context) (map[string]bool, error) {
 	return state, rows.Err()
 }
 
+// QueryManagedRules returns the managed alert rule rows ordered by name.
+func (s *Store) QueryManagedRules(ctx context.Context) ([]ManagedRule, error) {
+	rows, err := s.readDB.QueryContext(ctx,
+		`SELECT name, config, disabled, updated_at FROM alert_rules ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []ManagedRule
+	for rows.Next() {
+		var mr ManagedRule
+		var disabled int
+		var updatedAt int64
+		if err := rows.Scan(&mr.Name, &mr.Config, &disabled, &updatedAt); err != nil {
+			return nil, err
+		}
+		mr.Disabled = disabled != 0
+		mr.UpdatedAt = time.Unix(updatedAt, 0)
+		result = append(result, mr)
+	}
+	return result, rows.Err()
+}
+
+// SaveManagedRule inserts or replaces a managed alert rule row.
+func (s *Store) SaveManagedRule(ctx context.Context, mr *ManagedRule) error {
+	disabled := 0
+	if mr.Disabled {
+		disabled = 1
+	}
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO alert_rules (name, config, disabled, updated_at) VALUES (?, ?, ?, ?)
+		 ON CONFLICT(name) DO UPDATE SET config = excluded.config, disabled = excluded.disabled,
+		 updated_at = excluded.updated_at`,
+		mr.Name, mr.Config, disabled, mr.UpdatedAt.Unix())
+	return err
+}
+
+// DeleteManagedRule removes a managed alert rule row.
+func (s *Store) DeleteManagedRule(ctx context.Context, name string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM alert_rules WHERE name = ?`, name)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("rule %q not found", name)
+	}
+	return nil
+}
+
+// EnqueueNotification queues one delivery of a notification per channel.
+func (s *Store) EnqueueNotification(ctx context.Context, channels []string, subject string, payload []byte, at time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, ch := range channels {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO notifications (channel, subject, payload, created_at, next_attempt) VALUES (?, ?, ?, ?, ?)`,
+			ch, subject, string(payload), at.Unix(), at.Unix()); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// DeferNotification queues a delivery that is held back until until, when it
+// is merged with the channel's other deferred deliveries into one summary
+// (see SummarizeDeferred).
+func (s *Store) DeferNotification(ctx context.Context, channel, subject string, payload []byte, at, until time.Time) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO notifications (channel, subject, payload, status, created_at, next_attempt) VALUES (?, ?, ?, 'deferred', ?, ?)`,
+		channel, subject, string(payload), at.Unix(), until.Unix())
+	return err
+}
+
+// DueDeferred returns the deferred deliveries to the given channels that are
+// due at now, oldest first.
+func (s *Store) DueDeferred(ctx context.Context, channels []string, now time.Time) ([]NotificationDelivery, error) {
+	if len(channels) == 0 {
+		return nil, nil
+	}
+	in, args := inChannels(channels)
+	args = append([]any{now.Unix()}, args...)
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, channel, subject, payload, status, attempts, created_at, next_attempt, sent_at, error
+		 FROM notifications WHERE status = 'deferred' AND next_attempt <= ?`+in+` ORDER BY id LIMIT 1000`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanNotifications(rows)
+}
+
+// SummarizeDeferred replaces the deferred deliveries ids to channel with one
+// queued summary delivery. It reports false, changing nothing, if any of them
+// is no longer deferred, e.g. because another notifier summarized them while
+// the alerter was being replaced.
+func (s *Store) SummarizeDeferred(ctx context.Context, ids []int64, channel, subject string, payload []byte, at time.Time) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	for _, id := range ids {
+		res, err := tx.ExecContext(ctx,
+			`UPDATE notifications SET status = 'summarized', sent_at = ? WHERE id = ? AND status = 'deferred'`, at.Unix(), id)
+		if err != nil {
+			return false, err
+		}
+		if n, err := res.RowsAffected(); err != nil || n != 1 {
+			return false, err
+		}
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO notifications (channel, subject, payload, created_at, next_attempt) VALUES (?, ?, ?, ?, ?)`,
+		channel, subject, string(payload), at.Unix(), at.Unix()); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// inChannels returns an "AND channel IN (...)" clause and its arguments.
+func inChannels(channels []string) (string, []any) {
+	placeholders := make([]string, len(channels))
+	args := make([]any, len(channels))
+	for i, ch := range channels {
+		placeholders[i] = "?"
+		args[i] = ch
+	}
+	return ` AND channel IN (` + strings.Join(placeholders, ",") + `)`, args
+}
+
+// DueNotifications returns up to limit queued deliveries to the given
+// channels that are due at now, oldest first.
+func (s *Store) DueNotifications(ctx context.Context, channels []string, now time.Time, limit int) ([]NotificationDelivery, error) {
+	if len(channels) == 0 {
+		return nil, nil
+	}
+	in, args := inChannels(channels)
+	args = append([]any{now.Unix()}, append(args, limit)...)
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, channel, subject, payload, status, attempts, created_at, next_attempt, sent_at, error
+		 FROM notifications WHERE status = 'queued' AND next_attempt <= ?`+in+` ORDER BY id LIMIT ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanNotifications(rows)
+}
+
+// NextNotificationDue returns when the next queued or deferred delivery to
+// the given channels is due; ok is false if there is none.
+func (s *Store) NextNotificationDue(ctx context.Context, channels []string) (next time.Time, ok bool, err error) {
+	if len(channels) == 0 {
+		return time.Time{}, false, nil
+	}
+	in, args := inChannels(channels)
+	var ts *int64
+	if err := s.db.QueryRowContext(ctx,
+		`SELECT MIN(next_attempt) FROM notifications WHERE status IN ('queued', 'deferred')`+in, args...).Scan(&ts); err != nil {
+		return time.Time{}, false, err
+	}
+	if ts == nil {
+		return time.Time{}, false, nil
+	}
+	return time.Unix(*ts, 0), true, nil
+}
+
+// ClaimNotification marks a queued delivery as being sent. It reports false
+// if the delivery is no longer queued, e.g. because another notifier claimed
+// it while the alerter was being replaced.
+func (s *Store) ClaimNotification(ctx context.Context, id int64) (bool, error) {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE notifications SET status = 'sending' WHERE id = ? AND status = 'queued'`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// MarkNotificationSent records a successful delivery.
+func (s *Store) MarkNotificationSent(ctx context.Context, id int64, at time.Time) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE notifications SET status = 'sent', attempts = attempts + 1, sent_at = ?, error = '' WHERE id = ?`,
+		at.Unix(), id)
+	return err
+}
+
+// RetryNotification records a failed attempt and queues the delivery again
+// at next. The channel's other queued deliveries are held back until then
+// too, so they keep their order and don't hammer a channel that is down.
+func (s *Store) RetryNotification(ctx context.Context, id int64, channel, sendErr string, next time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE notifications SET status = 'queued', attempts = attempts + 1, next_attempt = ?, error = ? WHERE id = ?`,
+		next.Unix(), sendErr, id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE notifications SET next_attempt = ? WHERE channel = ? AND status = 'queued' AND next_attempt < ?`,
+		next.Unix(), channel, next.Unix()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// FailNotification gives up on a delivery after a failed attempt.
+func (s *Store) FailNotification(ctx context.Context, id int64, sendErr string) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE notifications SET status = 'failed', attempts = attempts + 1, error = ? WHERE id = ?`, sendErr, id)
+	return err
+}
+
+// FailRemovedChannels gives up on the queued and deferred deliveries to
+// channels that are not in channels, i.e. that were removed from the config.
+func (s *Store) FailRemovedChannels(ctx context.Context, channels []string) error {
+	query := `UPDATE notifications SET status = 'failed', error = 'channel removed from config' WHERE status IN ('queued', 'deferred')`
+	var args []any
+	if len(channels) > 0 {
+		in, inArgs := inChannels(channels)
+		query += strings.Replace(in, " IN ", " NOT IN ", 1)
+		args = inArgs
+	}
+	_, err := s.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// RequeueNotifications queues deliveries that were being sent when the agent
+// stopped again and returns the number of queued deliveries. Call on start,
+// before any notifier runs.
+func (s *Store) RequeueNotifications(ctx context.Context) (int, error) {
+	if _, err := s.db.ExecContext(ctx, `UPDATE notifications SET status = 'queued' WHERE status = 'sending'`); err != nil {
+		return 0, err
+	}
+	var n int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM notifications WHERE status = 'queued'`).Scan(&n)
+	return n, err
+}
+
+// QueryNotifications returns the newest deliveries, optionally only those
+// with the given status, without their payloads, and the number of queued
+// (including deferred) and failed deliveries overall.
+func (s *Store) QueryNotifications(ctx context.Context, status string, limit int) (result []NotificationDelivery, queued, failed int, err error) {
+	query := `SELECT id, channel, subject, '', status, attempts, created_at, next_attempt, sent_at, error FROM notifications`
+	var args []any
+	if status != "" {
+		query += ` WHERE status = ?`
+		args = append(args, status)
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+	rows, err := s.readDB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer rows.Close()
+	if result, err = scanNotifications(rows); err != nil {
+		return nil, 0, 0, err
+	}
+	err = s.readDB.QueryRowContext(ctx,
+		`SELECT COUNT(*) FILTER (WHERE status IN ('queued', 'deferred', 'sending')), COUNT(*) FILTER (WHERE status = 'failed')
+		 FROM notifications`).Scan(&queued, &failed)
+	return result, queued, failed, err
+}
+
+func scanNotifications(rows *sql.Rows) ([]NotificationDelivery, error) {
+	var result []NotificationDelivery
+	for rows.Next() {
+		var d NotificationDelivery
+		var payload string
+		var created, next int64
+		var sent *int64
+		if err := rows.Scan(&d.ID, &d.Channel, &d.Subject, &payload, &d.Status, &d.Attempts,
+			&created, &next, &sent, &d.Error); err != nil {
+			return nil, err
+		}
+		if payload != "" {
+			d.Payload = []byte(payload)
+		}
+		d.CreatedAt = time.Unix(created, 0)
+		d.NextAttempt = time.Unix(next, 0)
+		if sent != nil {
+			t := time.Unix(*sent, 0)
+			d.SentAt = &t
+		}
+		result = append(result, d)
+	}
+	return result, rows.Err()
+}
+
+// SaveChatRef records the message that announced an alert instance on a
+// chat channel. With replace false, an existing ref is kept.
+func (s *Store) SaveChatRef(ctx context.Context, channel, key string, ref chatRef, replace bool) error {
+	q := `INSERT OR IGNORE INTO chat_refs (channel, instance_key, subject, fired_at, event_id) VALUES (?, ?, ?, ?, ?)`
+	if replace {
+		q = `INSERT INTO chat_refs (channel, instance_key, subject, fired_at, event_id) VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT(channel, instance_key) DO UPDATE SET
+				subject = excluded.subject, fired_at = excluded.fired_at, event_id = excluded.event_id`
+	}
+	_, err := s.db.ExecContext(ctx, q, channel, key, ref.subject, ref.at.Unix(), ref.id)
+	return err
+}
+
+// ChatRef returns the message that announced an alert instance on a chat
+// channel, if any.
+func (s *Store) ChatRef(ctx context.Context, channel, key string) (chatRef, bool, error) {
+	var ref chatRef
+	var firedAt int64
+	err := s.readDB.QueryRowContext(ctx,
+		`SELECT subject, fired_at, event_id FROM chat_refs WHERE channel = ? AND instance_key = ?`,
+		channel, key).Scan(&ref.subject, &firedAt, &ref.id)
+	if err == sql.ErrNoRows {
+		return chatRef{}, false, nil
+	}
+	if err != nil {
+		return chatRef{}, false, err
+	}
+	ref.at = time.Unix(firedAt, 0)
+	return ref, true, nil
+}
+
+// DeleteChatRef forgets the message that announced an alert instance on a
+// chat channel.
+func (s *Store) DeleteChatRef(ctx context.Context, channel, key string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM chat_refs WHERE channel = ? AND instance_key = ?`, channel, key)
+	return err
+}
+
 // pruneBatchSize limits the number of rows deleted per batch to avoid long-running
 // transactions that block other database operations (inserts, queries).
 const pruneBatchSize = 5000
@@ -628,6 +1237,18 @@ func (s *Store) Prune(ctx context.Context, retentionDays int) error {
 	if err := s.pruneTable(ctx, "alerts", "fired_at", cutoff); err != nil {
 		return fmt.Errorf("prune alerts: %w", err)
 	}
+	if err := s.pruneTable(ctx, "remediations", "at", cutoff); err != nil {
+		return fmt.Errorf("prune remediations: %w", err)
+	}
+	if err := s.pruneTable(ctx, "silences", "expires_at", cutoff); err != nil {
+		return fmt.Errorf("prune silences: %w", err)
+	}
+	if err := s.pruneTable(ctx, "notifications", "created_at", cutoff); err != nil {
+		return fmt.Errorf("prune notifications: %w", err)
+	}
+	if err := s.pruneTable(ctx, "chat_refs", "fired_at", cutoff); err != nil {
+		return fmt.Errorf("prune chat refs: %w", err)
+	}
 
 	// Checkpoint WAL, refresh query planner stats, then ask Go to release memory.
 	s.db.ExecContext(ctx, "PRAGMA wal_checkpoint(PASSIVE)")
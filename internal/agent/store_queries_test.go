@@ -476,6 +476,46 @@ func TestQueryAlerts(t *testing.T) {
 	}
 }
 
+func TestQueryAlertsFiredOrResolved(t *testing.T) {
+	s := testStore(t)
+	ctx := context.Background()
+
+	end := time.Date(2025, 1, 2, 8, 0, 0, 0, time.UTC)
+	start := end.Add(-24 * time.Hour)
+	insert := func(key string, fired time.Time, resolved *time.Time) {
+		t.Helper()
+		id, err := s.InsertAlert(ctx, &Alert{
+			RuleName: "exited", Severity: "critical", Condition: "container.state == 'exited'",
+			InstanceKey: key, FiredAt: fired, Message: key,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resolved != nil {
+			if err := s.ResolveAlert(ctx, id, *resolved); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	ptr := func(t time.Time) *time.Time { return &t }
+	insert("before", start.Add(-2*time.Hour), ptr(start.Add(-time.Hour)))
+	insert("carried", start.Add(-time.Hour), ptr(start.Add(time.Hour)))
+	insert("fired", start.Add(2*time.Hour), nil)
+	insert("after", end.Add(time.Hour), nil)
+
+	results, err := s.QueryAlertsFiredOrResolved(ctx, start.Unix(), end.Unix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, al := range results {
+		got = append(got, al.InstanceKey)
+	}
+	if fmt.Sprint(got) != "[fired carried]" {
+		t.Errorf("alerts = %v, want [fired carried]", got)
+	}
+}
+
 func TestAckAlert(t *testing.T) {
 	s := testStore(t)
 	ctx := context.Background()
@@ -488,7 +528,7 @@ func TestAckAlert(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if err := s.AckAlert(ctx, id); err != nil {
+	if err := s.AckAlert(ctx, id, time.Now()); err != nil {
 		t.Fatal(err)
 	}
 
@@ -518,7 +558,7 @@ func TestQueryAlertsResolvedAndAcknowledged(t *testing.T) {
 	if err := s.ResolveAlert(ctx, id, resolved); err != nil {
 		t.Fatal(err)
 	}
-	if err := s.AckAlert(ctx, id); err != nil {
+	if err := s.AckAlert(ctx, id, time.Now()); err != nil {
 		t.Fatal(err)
 	}
 
@@ -543,7 +583,7 @@ func TestQueryAlertsResolvedAndAcknowledged(t *testing.T) {
 
 func TestAckAlertNotFound(t *testing.T) {
 	s := testStore(t)
-	err := s.AckAlert(context.Background(), 9999)
+	err := s.AckAlert(context.Background(), 9999, time.Now())
 	if err == nil {
 		t.Fatal("expected error for non-existent alert")
 	}
@@ -1347,6 +1387,54 @@ func TestQueryFiringAlerts(t *testing.T) {
 	}
 }
 
+func TestSilencesInsertExpireQuery(t *testing.T) {
+	s := testStore(t)
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	id1, err := s.InsertSilence(ctx, &Silence{
+		RuleName: "high_cpu", CreatedAt: now, ExpiresAt: now.Add(time.Hour),
+		CreatedBy: "alice", Comment: "load test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.InsertSilence(ctx, &Silence{InstanceKey: "exited:aaa", CreatedAt: now, ExpiresAt: now.Add(10 * time.Minute)})
+	s.InsertSilence(ctx, &Silence{RuleName: "old", CreatedAt: now.Add(-time.Hour), ExpiresAt: now.Add(-time.Minute)})
+
+	active, err := s.QueryActiveSilences(ctx, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(active) != 2 {
+		t.Fatalf("active = %d, want 2", len(active))
+	}
+	if active[0].InstanceKey != "exited:aaa" || active[1].ID != id1 {
+		t.Errorf("order = %+v, want soonest expiry first", active)
+	}
+	if active[1].CreatedBy != "alice" || active[1].Comment != "load test" || !active[1].ExpiresAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("silence = %+v", active[1])
+	}
+
+	if err := s.ExpireSilence(ctx, id1, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ExpireSilence(ctx, id1, now); err == nil {
+		t.Error("expiring an expired silence should fail")
+	}
+	active, _ = s.QueryActiveSilences(ctx, now)
+	if len(active) != 1 {
+		t.Errorf("active after expire = %d, want 1", len(active))
+	}
+
+	// Expired rows are kept for history.
+	var total int
+	s.db.QueryRow("SELECT COUNT(*) FROM silences").Scan(&total)
+	if total != 3 {
+		t.Errorf("rows = %d, want 3", total)
+	}
+}
+
 func TestQueryLogsRecomputesDisplayMsg(t *testing.T) {
 	s := testStore(t)
 	ctx := context.Background()
@@ -1642,3 +1730,56 @@ func TestQueryContainerMetricsGrouped(t *testing.T) {
 		}
 	}
 }
+
+func TestAgentRuns(t *testing.T) {
+	s := testStore(t)
+	ctx := context.Background()
+	t0 := time.Unix(1700000000, 0)
+
+	id1, prev, err := s.BeginRun(ctx, t0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prev != nil {
+		t.Fatalf("first run prev = %+v, want nil", prev)
+	}
+
+	// Run 1 dies without EndRun.
+	id2, prev, err := s.BeginRun(ctx, t0.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prev == nil || prev.ID != id1 || !prev.StartedAt.Equal(t0) || prev.StoppedAt != nil {
+		t.Fatalf("prev = %+v, want unstopped run %d", prev, id1)
+	}
+
+	if err := s.EndRun(ctx, id2, t0.Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	_, prev, err = s.BeginRun(ctx, t0.Add(3*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prev == nil || prev.ID != id2 || prev.StoppedAt == nil || !prev.StoppedAt.Equal(t0.Add(2*time.Hour)) {
+		t.Fatalf("prev = %+v, want run %d stopped", prev, id2)
+	}
+}
+
+func TestLastHostSample(t *testing.T) {
+	s := testStore(t)
+	ctx := context.Background()
+
+	last, err := s.LastHostSample(ctx)
+	if err != nil || !last.IsZero() {
+		t.Fatalf("empty store = %v, %v; want zero", last, err)
+	}
+	ts := time.Unix(1700000000, 0)
+	for _, at := range []time.Time{ts.Add(-time.Minute), ts} {
+		if err := s.InsertHostMetrics(ctx, at, &HostMetrics{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if last, err = s.LastHostSample(ctx); err != nil || !last.Equal(ts) {
+		t.Errorf("last = %v, %v; want %v", last, err, ts)
+	}
+}
@@ -41,7 +41,7 @@ func TestSchemaCreation(t *testing.T) {
 	s := testStore(t)
 
 	// Verify all tables exist
-	tables := []string{"host_metrics", "disk_metrics", "net_metrics", "container_metrics", "logs", "alerts", "tracking_state"}
+	tables := []string{"host_metrics", "disk_metrics", "net_metrics", "container_metrics", "logs", "alerts", "silences", "tracking_state"}
 	for _, table := range tables {
 		var name string
 		err := s.db.QueryRow("SELECT name FROM sqlite_master WHERE type='table' AND name=?", table).Scan(&name)
@@ -18,19 +18,28 @@ const (
 	TypeContainerEvent      MsgType = "container:event"
 
 	// Request-response.
-	TypeHello              MsgType = "hello"
-	TypeQueryMetrics       MsgType = "query:metrics"
-	TypeQueryLogs          MsgType = "query:logs"
-	TypeQueryAlerts        MsgType = "query:alerts"
-	TypeQueryContainers    MsgType = "query:containers"
-	TypeActionAckAlert     MsgType = "action:ack_alert"
-	TypeActionSilence      MsgType = "action:silence_alert"
-	TypeActionSetTracking  MsgType = "action:set_tracking"
-	TypeActionTestNotify   MsgType = "action:test_notify"
-	TypeQueryTracking      MsgType = "query:tracking"
-	TypeQueryAlertRules    MsgType = "query:alert_rules"
-	TypeResult             MsgType = "result"
-	TypeError              MsgType = "error"
+	TypeHello                  MsgType = "hello"
+	TypeQueryMetrics           MsgType = "query:metrics"
+	TypeQueryLogs              MsgType = "query:logs"
+	TypeQueryAlerts            MsgType = "query:alerts"
+	TypeQueryContainers        MsgType = "query:containers"
+	TypeActionAckAlert         MsgType = "action:ack_alert"
+	TypeActionSilence          MsgType = "action:silence_alert"
+	TypeActionSetTracking      MsgType = "action:set_tracking"
+	TypeActionTestNotify       MsgType = "action:test_notify"
+	TypeQueryTracking          MsgType = "query:tracking"
+	TypeQueryAlertRules        MsgType = "query:alert_rules"
+	TypeQueryBacktest          MsgType = "query:backtest"
+	TypeQuerySilences          MsgType = "query:silences"
+	TypeActionUnsilence        MsgType = "action:unsilence"
+	TypeActionCreateAlertRule  MsgType = "action:create_alert_rule"
+	TypeActionUpdateAlertRule  MsgType = "action:update_alert_rule"
+	TypeActionDisableAlertRule MsgType = "action:disable_alert_rule"
+	TypeActionDeleteAlertRule  MsgType = "action:delete_alert_rule"
+	TypeQueryAlertStats        MsgType = "query:alert_stats"
+	TypeQueryNotifications     MsgType = "query:notifications"
+	TypeResult                 MsgType = "result"
+	TypeError                  MsgType = "error"
 )
 
 // ProtocolVersion is incremented on breaking protocol changes.
@@ -81,16 +90,29 @@ type LogEntryMsg struct {
 
 // AlertEvent is pushed on alert state transitions.
 type AlertEvent struct {
-	ID           int64  `msgpack:"id"`
-	RuleName     string `msgpack:"rule_name"`
-	Severity     string `msgpack:"severity"`
-	Condition    string `msgpack:"condition"`
-	InstanceKey  string `msgpack:"instance_key"`
-	FiredAt      int64  `msgpack:"fired_at"`
-	ResolvedAt   int64  `msgpack:"resolved_at,omitempty"`
-	Message      string `msgpack:"message"`
-	State        string `msgpack:"state"` // "firing" or "resolved"
-	Acknowledged bool   `msgpack:"acknowledged,omitempty"`
+	ID           int64            `msgpack:"id"`
+	RuleName     string           `msgpack:"rule_name"`
+	Severity     string           `msgpack:"severity"`
+	Condition    string           `msgpack:"condition"`
+	InstanceKey  string           `msgpack:"instance_key"`
+	FiredAt      int64            `msgpack:"fired_at"`
+	ResolvedAt   int64            `msgpack:"resolved_at,omitempty"`
+	Message      string           `msgpack:"message"`
+	State        string           `msgpack:"state"` // "firing" or "resolved"
+	Acknowledged bool             `msgpack:"acknowledged,omitempty"`
+	InhibitedBy  string           `msgpack:"inhibited_by,omitempty"` // source rule that suppressed notifications
+	Remediations []RemediationMsg `msgpack:"remediations,omitempty"`
+	Flapping     bool             `msgpack:"flapping,omitempty"` // notifications suppressed until stable
+	Description  string           `msgpack:"description,omitempty"`
+	RunbookURL   string           `msgpack:"runbook_url,omitempty"`
+}
+
+// RemediationMsg records one docker:restart or docker:stop attempt for an alert.
+type RemediationMsg struct {
+	Action  string `msgpack:"action"` // "restart" or "stop"
+	Attempt int    `msgpack:"attempt"`
+	Time    int64  `msgpack:"time"`
+	Error   string `msgpack:"error,omitempty"` // empty on success
 }
 
 // ContainerEvent is pushed on container lifecycle changes (start, die, etc.).
@@ -174,15 +196,20 @@ type QueryAlertsResp struct {
 
 // AlertMsg represents an alert in query responses.
 type AlertMsg struct {
-	ID           int64  `msgpack:"id"`
-	RuleName     string `msgpack:"rule_name"`
-	Severity     string `msgpack:"severity"`
-	Condition    string `msgpack:"condition"`
-	InstanceKey  string `msgpack:"instance_key"`
-	FiredAt      int64  `msgpack:"fired_at"`
-	ResolvedAt   int64  `msgpack:"resolved_at,omitempty"`
-	Message      string `msgpack:"message"`
-	Acknowledged bool   `msgpack:"acknowledged"`
+	ID           int64            `msgpack:"id"`
+	RuleName     string           `msgpack:"rule_name"`
+	Severity     string           `msgpack:"severity"`
+	Condition    string           `msgpack:"condition"`
+	InstanceKey  string           `msgpack:"instance_key"`
+	FiredAt      int64            `msgpack:"fired_at"`
+	ResolvedAt   int64            `msgpack:"resolved_at,omitempty"`
+	Message      string           `msgpack:"message"`
+	Acknowledged bool             `msgpack:"acknowledged"`
+	InhibitedBy  string           `msgpack:"inhibited_by,omitempty"`
+	Remediations []RemediationMsg `msgpack:"remediations,omitempty"`
+	Flapping     bool             `msgpack:"flapping,omitempty"`
+	Description  string           `msgpack:"description,omitempty"`
+	RunbookURL   string           `msgpack:"runbook_url,omitempty"`
 }
 
 // QueryContainersResp is the response for TypeQueryContainers.
@@ -224,10 +251,36 @@ type AckAlertReq struct {
 	AlertID int64 `msgpack:"alert_id"`
 }
 
-// SilenceAlertReq is the body for TypeActionSilence.
+// SilenceAlertReq is the body for TypeActionSilence. At least one of
+// RuleName and InstanceKey must be set; the silence matches alerts that
+// agree on every field that is set. A zero Duration removes the silences
+// with exactly this rule name and instance key.
 type SilenceAlertReq struct {
-	RuleName string `msgpack:"rule_name"`
-	Duration int64  `msgpack:"duration"` // seconds
+	RuleName    string `msgpack:"rule_name"`
+	InstanceKey string `msgpack:"instance_key,omitempty"`
+	Duration    int64  `msgpack:"duration"` // seconds
+	Comment     string `msgpack:"comment,omitempty"`
+}
+
+// UnsilenceReq is the body for TypeActionUnsilence.
+type UnsilenceReq struct {
+	SilenceID int64 `msgpack:"silence_id"`
+}
+
+// SilenceMsg describes an active silence.
+type SilenceMsg struct {
+	ID          int64  `msgpack:"id"`
+	RuleName    string `msgpack:"rule_name,omitempty"`
+	InstanceKey string `msgpack:"instance_key,omitempty"`
+	CreatedAt   int64  `msgpack:"created_at"`
+	ExpiresAt   int64  `msgpack:"expires_at"`
+	CreatedBy   string `msgpack:"created_by,omitempty"` // agent-side user that created it
+	Comment     string `msgpack:"comment,omitempty"`
+}
+
+// QuerySilencesResp is the response for TypeQuerySilences.
+type QuerySilencesResp struct {
+	Silences []SilenceMsg `msgpack:"silences"`
 }
 
 // TestNotifyReq is the body for TypeActionTestNotify.
@@ -244,16 +297,140 @@ type AlertRuleInfo struct {
 	Cooldown       string   `msgpack:"cooldown,omitempty"`
 	NotifyCooldown string   `msgpack:"notify_cooldown,omitempty"`
 	Actions        []string `msgpack:"actions"`
+	Channels       []string `msgpack:"channels,omitempty"` // enabled notification channels the rule reaches
 	FiringCount    int      `msgpack:"firing_count"`
 	SilencedUntil  int64    `msgpack:"silenced_until,omitempty"` // unix timestamp, 0 = not silenced
 	Match          string   `msgpack:"match,omitempty"`
 	MatchRegex     bool     `msgpack:"match_regex,omitempty"`
 	Window         string   `msgpack:"window,omitempty"`
+	Selector       string   `msgpack:"selector,omitempty"`        // e.g. "project=shop name=db-*"
+	Aggregates     []string `msgpack:"aggregates,omitempty"`      // e.g. "avg of host.cpu_percent over 5m"
+	Maintenance    string   `msgpack:"maintenance,omitempty"`     // active maintenance window covering the rule
+	MaintenanceEnd int64    `msgpack:"maintenance_end,omitempty"` // unix timestamp, end of that window
+
+	Description     string `msgpack:"description,omitempty"`
+	RunbookURL      string `msgpack:"runbook_url,omitempty"`
+	MessageTemplate string `msgpack:"message_template,omitempty"` // Go text/template for the alert message
+
+	Managed  bool   `msgpack:"managed,omitempty"`  // created over the socket rather than in the config file
+	Disabled bool   `msgpack:"disabled,omitempty"` // kept but not evaluated
+	Config   string `msgpack:"config,omitempty"`   // TOML body of a managed rule
+}
+
+// MaintenanceInfo describes a configured maintenance window.
+type MaintenanceInfo struct {
+	Name     string `msgpack:"name"`
+	Schedule string `msgpack:"schedule"` // e.g. "sun 02:00-04:00 UTC"
+	Active   bool   `msgpack:"active"`
+	Until    int64  `msgpack:"until,omitempty"` // unix timestamp, end of the active occurrence
 }
 
 // QueryAlertRulesResp is the response for TypeQueryAlertRules.
 type QueryAlertRulesResp struct {
-	Rules []AlertRuleInfo `msgpack:"rules"`
+	Rules       []AlertRuleInfo   `msgpack:"rules"`
+	Maintenance []MaintenanceInfo `msgpack:"maintenance,omitempty"`
+}
+
+// SaveAlertRuleReq is the body for TypeActionCreateAlertRule and
+// TypeActionUpdateAlertRule. Config is the TOML body of the rule, as it
+// would appear under [alerts.<name>] in the config file. An update replaces
+// the whole rule.
+type SaveAlertRuleReq struct {
+	Name   string `msgpack:"name"`
+	Config string `msgpack:"config"`
+}
+
+// DisableAlertRuleReq is the body for TypeActionDisableAlertRule. Rules from
+// the config file can be disabled too; Disabled false enables the rule again.
+type DisableAlertRuleReq struct {
+	Name     string `msgpack:"name"`
+	Disabled bool   `msgpack:"disabled"`
+}
+
+// DeleteAlertRuleReq is the body for TypeActionDeleteAlertRule. Only rules
+// created over the socket can be deleted.
+type DeleteAlertRuleReq struct {
+	Name string `msgpack:"name"`
+}
+
+// QueryBacktestReq is the body for TypeQueryBacktest.
+type QueryBacktestReq struct {
+	RuleName string `msgpack:"rule_name"`
+	Start    int64  `msgpack:"start"`
+	End      int64  `msgpack:"end"`
+}
+
+// QueryBacktestResp is the response for TypeQueryBacktest.
+type QueryBacktestResp struct {
+	RuleName  string             `msgpack:"rule_name"`
+	Condition string             `msgpack:"condition"`
+	Start     int64              `msgpack:"start"`
+	End       int64              `msgpack:"end"`
+	Samples   int                `msgpack:"samples"`
+	Intervals []BacktestInterval `msgpack:"intervals"`
+}
+
+// BacktestInterval is one fire/resolve cycle a backtested rule would have produced.
+type BacktestInterval struct {
+	InstanceKey string `msgpack:"instance_key"`
+	Label       string `msgpack:"label,omitempty"`
+	FiredAt     int64  `msgpack:"fired_at"`
+	ResolvedAt  int64  `msgpack:"resolved_at,omitempty"` // 0 = still firing at end of range
+}
+
+// QueryAlertStatsReq is the body for TypeQueryAlertStats. Alerts that fired
+// within [Start, End] are counted.
+type QueryAlertStatsReq struct {
+	Start int64 `msgpack:"start"`
+	End   int64 `msgpack:"end"`
+}
+
+// QueryAlertStatsResp is the response for TypeQueryAlertStats. Rules and
+// Containers are ordered noisiest first: by alerts fired, then firing time.
+type QueryAlertStatsResp struct {
+	Start      int64        `msgpack:"start"`
+	End        int64        `msgpack:"end"`
+	Total      AlertStats   `msgpack:"total"`
+	Rules      []AlertStats `msgpack:"rules"`
+	Containers []AlertStats `msgpack:"containers"` // container and log rules only
+}
+
+// AlertStats summarizes the alerts of one rule, one container, or all of them.
+type AlertStats struct {
+	Name         string `msgpack:"name"` // rule name or container ID; "" for the total
+	Fired        int    `msgpack:"fired"`
+	Acknowledged int    `msgpack:"acknowledged"`
+	Resolved     int    `msgpack:"resolved"`
+	FiringSecs   int64  `msgpack:"firing_secs"`         // time spent firing, up to End
+	MTTASecs     int64  `msgpack:"mtta_secs,omitempty"` // mean time to acknowledge; 0 = none acknowledged
+	MTTRSecs     int64  `msgpack:"mttr_secs,omitempty"` // mean time to resolve; 0 = none resolved
+}
+
+// QueryNotificationsReq is the body for TypeQueryNotifications.
+type QueryNotificationsReq struct {
+	Status string `msgpack:"status,omitempty"` // a NotificationMsg status; "" = any
+	Limit  int    `msgpack:"limit,omitempty"`  // 0 = default
+}
+
+// QueryNotificationsResp is the response for TypeQueryNotifications.
+// Notifications are newest first; Queued and Failed count all deliveries.
+type QueryNotificationsResp struct {
+	Notifications []NotificationMsg `msgpack:"notifications"`
+	Queued        int               `msgpack:"queued"` // waiting for delivery, a retry or quiet hours to end, or being sent
+	Failed        int               `msgpack:"failed"`
+}
+
+// NotificationMsg is the delivery of one notification to one channel.
+type NotificationMsg struct {
+	ID          int64  `msgpack:"id"`
+	Channel     string `msgpack:"channel"`
+	Subject     string `msgpack:"subject"`
+	Status      string `msgpack:"status"` // "queued", "sending", "sent", "failed", "deferred" (quiet hours) or "summarized" (merged into a summary)
+	Attempts    int    `msgpack:"attempts"`
+	CreatedAt   int64  `msgpack:"created_at"`
+	NextAttempt int64  `msgpack:"next_attempt,omitempty"` // while queued or deferred
+	SentAt      int64  `msgpack:"sent_at,omitempty"`
+	Error       string `msgpack:"error,omitempty"` // last send error
 }
 
 // Result is the generic success response.
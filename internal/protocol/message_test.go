@@ -2,6 +2,7 @@ package protocol
 
 import (
 	"bytes"
+	"reflect"
 	"testing"
 
 	"github.com/vmihailenco/msgpack/v5"
@@ -101,7 +102,12 @@ func TestAlertEventRoundtrip(t *testing.T) {
 	orig := AlertEvent{
 		ID: 42, RuleName: "high_cpu", Severity: "critical",
 		Condition: "host.cpu_percent > 90", InstanceKey: "high_cpu",
-		FiredAt: 1700000000, Message: "CPU high", State: "firing",
+		FiredAt: 1700000000, Message: "CPU high", State: "firing", InhibitedBy: "host_mem", Flapping: true,
+		Description: "CPU saturated", RunbookURL: "https://wiki.example.com/cpu",
+		Remediations: []RemediationMsg{
+			{Action: "restart", Attempt: 1, Time: 1700000060},
+			{Action: "restart", Attempt: 2, Time: 1700000180, Error: "container not found"},
+		},
 	}
 
 	env, err := NewEnvelope(TypeAlertEvent, 0, &orig)
@@ -123,7 +129,7 @@ func TestAlertEventRoundtrip(t *testing.T) {
 	if err := DecodeBody(got.Body, &decoded); err != nil {
 		t.Fatal(err)
 	}
-	if decoded != orig {
+	if !reflect.DeepEqual(decoded, orig) {
 		t.Errorf("got %+v, want %+v", decoded, orig)
 	}
 }
@@ -139,7 +145,14 @@ func TestQueryMessagesRoundtrip(t *testing.T) {
 		{"QueryAlertsReq", TypeQueryAlerts, &QueryAlertsReq{Start: 1000, End: 2000}},
 		{"AckAlertReq", TypeActionAckAlert, &AckAlertReq{AlertID: 42}},
 		{"SilenceAlertReq", TypeActionSilence, &SilenceAlertReq{RuleName: "high_cpu", Duration: 3600}},
+		{"SilenceAlertReqInstance", TypeActionSilence, &SilenceAlertReq{InstanceKey: "exited:abc", Duration: 600, Comment: "deploy"}},
+		{"UnsilenceReq", TypeActionUnsilence, &UnsilenceReq{SilenceID: 7}},
 		{"TestNotifyReq", TypeActionTestNotify, &TestNotifyReq{RuleName: "high_cpu"}},
+		{"SaveAlertRuleReq", TypeActionCreateAlertRule, &SaveAlertRuleReq{Name: "disk", Config: "condition = \"disk.percent > 90\"\nseverity = \"warning\"\n"}},
+		{"DisableAlertRuleReq", TypeActionDisableAlertRule, &DisableAlertRuleReq{Name: "disk", Disabled: true}},
+		{"DeleteAlertRuleReq", TypeActionDeleteAlertRule, &DeleteAlertRuleReq{Name: "disk"}},
+		{"QueryAlertStatsReq", TypeQueryAlertStats, &QueryAlertStatsReq{Start: 1000, End: 2000}},
+		{"QueryNotificationsReq", TypeQueryNotifications, &QueryNotificationsReq{Status: "failed", Limit: 50}},
 		{"SubscribeLogs", TypeSubscribeLogs, &SubscribeLogs{ContainerID: "abc", Project: "myapp", Search: "panic", Level: "ERR"}},
 		{"Unsubscribe", TypeUnsubscribe, &Unsubscribe{Topic: "metrics"}},
 	}
@@ -646,13 +659,18 @@ func TestAlertRuleInfoRoundtrip(t *testing.T) {
 			{
 				Name: "high_cpu", Condition: "host.cpu_percent > 90",
 				Severity: "critical", For: "30s",
-				Actions: []string{"notify"}, FiringCount: 2, SilencedUntil: 1700000000,
+				Actions: []string{"notify:oncall"}, Channels: []string{"oncall"}, FiringCount: 2, SilencedUntil: 1700000000,
 			},
 			{
 				Name: "exited", Condition: "container.state == 'exited'",
 				Severity: "warning", Actions: []string{"notify"}, FiringCount: 0,
+				Maintenance: "deploys", MaintenanceEnd: 1700003600,
 			},
 		},
+		Maintenance: []MaintenanceInfo{
+			{Name: "deploys", Schedule: "sat,sun 22:00-02:00", Active: true, Until: 1700003600},
+			{Name: "backups", Schedule: "0 3 * * * for 30m"},
+		},
 	}
 
 	env, err := NewEnvelope(TypeResult, 1, &orig)
@@ -690,10 +708,19 @@ func TestAlertRuleInfoRoundtrip(t *testing.T) {
 	if r.For != "30s" {
 		t.Errorf("for = %q, want 30s", r.For)
 	}
+	if len(r.Channels) != 1 || r.Channels[0] != "oncall" {
+		t.Errorf("channels = %v, want [oncall]", r.Channels)
+	}
 	r2 := decoded.Rules[1]
 	if r2.SilencedUntil != 0 {
 		t.Errorf("silenced_until = %d, want 0 (omitempty)", r2.SilencedUntil)
 	}
+	if r2.Maintenance != "deploys" || r2.MaintenanceEnd != 1700003600 {
+		t.Errorf("maintenance = %q until %d", r2.Maintenance, r2.MaintenanceEnd)
+	}
+	if len(decoded.Maintenance) != 2 || !decoded.Maintenance[0].Active || decoded.Maintenance[1].Until != 0 {
+		t.Errorf("maintenance windows = %+v", decoded.Maintenance)
+	}
 }
 
 func TestContainerEventServiceRoundtrip(t *testing.T) {
@@ -16,28 +16,57 @@ type alertsSection int
 const (
 	sectionAlerts alertsSection = iota
 	sectionRules
+	sectionSilences
 )
 
 // AlertsState holds the state for the alerts view.
 type AlertsState struct {
 	rules            []protocol.AlertRuleInfo
 	resolved         []protocol.AlertMsg
+	silences         []protocol.SilenceMsg
 	focus            alertsSection
 	alertCursor      int
 	ruleCursor       int
+	silenceCursor    int
 	showResolved     bool
 	alertDialog      bool // true when alert detail dialog is open
 	ruleDialog       bool // true when rule detail dialog is open
 	silenceModal     *silenceModalState
+	backtest         *backtestState // non-nil while the backtest dialog is open
 	loaded           bool
 	testNotifyStatus string // "sent", error message, or "" (cleared on navigation/close)
+
+	ruleEditor *ruleEditorState // non-nil while the rule editor is open
+	ruleStatus string           // result of the last disable/delete, shown in the rule dialog
+	stats      *alertStatsState // non-nil while the alert report is open
+
+	notifyLog *notifyLogState // non-nil while the notification log is open
 }
 
 type silenceModalState struct {
+	ruleName     string
+	instanceKey  string // set when opened on an alert; "" for rule silences
+	instanceOnly bool   // silence only instanceKey instead of the whole rule
+	focus        int    // 0=duration, 1=scope (only with instanceKey), 2=comment
+	cursor       int    // 0=15m, 1=1h, 2=6h, 3=24h
+	comment      string
+}
+
+// maxSilenceComment caps the comment typed into the silence dialog.
+const maxSilenceComment = 128
+
+// backtestState holds the backtest dialog for one rule.
+type backtestState struct {
 	ruleName string
-	cursor   int // 0=15m, 1=1h, 2=6h, 3=24h
+	loading  bool
+	err      string
+	resp     *protocol.QueryBacktestResp
+	scroll   int
 }
 
+// backtestRange is how far back the TUI replays a rule.
+const backtestRange = 7 * 86400
+
 var silenceDurations = []struct {
 	label   string
 	seconds int64
@@ -50,23 +79,30 @@ var silenceDurations = []struct {
 
 // alertItem is a unified alert for rendering both firing and resolved.
 type alertItem struct {
-	id          int64
-	firedAt     int64
-	resolvedAt  int64
-	ruleName    string
-	severity    string
-	condition   string
-	instanceKey string
-	message     string
-	acked       bool
-	resolved    bool
+	id           int64
+	firedAt      int64
+	resolvedAt   int64
+	ruleName     string
+	severity     string
+	condition    string
+	instanceKey  string
+	message      string
+	acked        bool
+	resolved     bool
+	inhibitedBy  string                    // source rule that suppressed notifications
+	remediations []protocol.RemediationMsg // docker:restart / docker:stop attempts, oldest first
+	flapping     bool                      // notifications suppressed until the instance is stable
+	description  string
+	runbookURL   string
 }
 
 // Message types.
 type alertsDataMsg struct {
-	server   string
-	rules    []protocol.AlertRuleInfo
-	resolved []protocol.AlertMsg
+	server      string
+	rules       []protocol.AlertRuleInfo
+	maintenance []protocol.MaintenanceInfo
+	resolved    []protocol.AlertMsg
+	silences    []protocol.SilenceMsg
 }
 type alertAckDoneMsg struct{ server string }
 type alertSilenceDoneMsg struct{ server string }
@@ -74,18 +110,30 @@ type testNotifyDoneMsg struct {
 	server string
 	status string // "sent" or error message
 }
+type backtestDoneMsg struct {
+	server string
+	rule   string
+	resp   *protocol.QueryBacktestResp
+	err    error
+}
 
-// queryAlertsData fetches alert rules and recent historical alerts.
+// queryAlertsData fetches alert rules, active silences and recent historical alerts.
 func queryAlertsData(c *Client, server string) tea.Cmd {
 	return func() tea.Msg {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 
 		var rules []protocol.AlertRuleInfo
+		var maintenance []protocol.MaintenanceInfo
 		var resolved []protocol.AlertMsg
+		var silences []protocol.SilenceMsg
 
 		if r, err := c.QueryAlertRules(ctx); err == nil {
-			rules = r
+			rules = r.Rules
+			maintenance = r.Maintenance
+		}
+		if sl, err := c.QuerySilences(ctx); err == nil {
+			silences = sl
 		}
 
 		now := time.Now().Unix()
@@ -98,7 +146,7 @@ func queryAlertsData(c *Client, server string) tea.Cmd {
 			}
 		}
 
-		return alertsDataMsg{server: server, rules: rules, resolved: resolved}
+		return alertsDataMsg{server: server, rules: rules, maintenance: maintenance, resolved: resolved, silences: silences}
 	}
 }
 
@@ -112,14 +160,19 @@ func buildAlertList(firing map[int64]*protocol.AlertEvent, resolved []protocol.A
 	// Firing alerts from the live stream.
 	for _, e := range firing {
 		items = append(items, alertItem{
-			id:          e.ID,
-			firedAt:     e.FiredAt,
-			ruleName:    e.RuleName,
-			severity:    e.Severity,
-			condition:   e.Condition,
-			instanceKey: e.InstanceKey,
-			message:     e.Message,
-			acked:       e.Acknowledged,
+			id:           e.ID,
+			firedAt:      e.FiredAt,
+			ruleName:     e.RuleName,
+			severity:     e.Severity,
+			condition:    e.Condition,
+			instanceKey:  e.InstanceKey,
+			message:      e.Message,
+			acked:        e.Acknowledged,
+			inhibitedBy:  e.InhibitedBy,
+			remediations: e.Remediations,
+			flapping:     e.Flapping,
+			description:  e.Description,
+			runbookURL:   e.RunbookURL,
 		})
 	}
 	sort.Slice(items, func(i, j int) bool {
@@ -140,16 +193,21 @@ func buildAlertList(firing map[int64]*protocol.AlertEvent, resolved []protocol.A
 			continue
 		}
 		resolvedItems = append(resolvedItems, alertItem{
-			id:          a.ID,
-			firedAt:     a.FiredAt,
-			resolvedAt:  a.ResolvedAt,
-			ruleName:    a.RuleName,
-			severity:    a.Severity,
-			condition:   a.Condition,
-			instanceKey: a.InstanceKey,
-			message:     a.Message,
-			acked:       a.Acknowledged,
-			resolved:    true,
+			id:           a.ID,
+			firedAt:      a.FiredAt,
+			resolvedAt:   a.ResolvedAt,
+			ruleName:     a.RuleName,
+			severity:     a.Severity,
+			condition:    a.Condition,
+			instanceKey:  a.InstanceKey,
+			message:      a.Message,
+			acked:        a.Acknowledged,
+			resolved:     true,
+			inhibitedBy:  a.InhibitedBy,
+			remediations: a.Remediations,
+			flapping:     a.Flapping,
+			description:  a.Description,
+			runbookURL:   a.RunbookURL,
 		})
 	}
 	sort.Slice(resolvedItems, func(i, j int) bool {
@@ -173,10 +231,16 @@ func (a *App) enterAlerts() tea.Cmd {
 	av := &s.AlertsView
 	av.alertCursor = 0
 	av.ruleCursor = 0
+	av.silenceCursor = 0
 	av.alertDialog = false
 	av.ruleDialog = false
 	av.silenceModal = nil
+	av.backtest = nil
 	av.testNotifyStatus = ""
+	av.ruleEditor = nil
+	av.ruleStatus = ""
+	av.stats = nil
+	av.notifyLog = nil
 	av.focus = sectionAlerts
 	return queryAlertsData(s.Client, s.Name)
 }
@@ -195,10 +259,22 @@ func (a *App) handleAlertsKey(msg tea.KeyMsg) (App, tea.Cmd) {
 	av := &s.AlertsView
 	key := msg.String()
 
-	// Silence dialog captures keys first.
+	// Silence, backtest, rule editor and report dialogs capture keys first.
+	if av.ruleEditor != nil {
+		return a.handleRuleEditorKey(key)
+	}
+	if av.stats != nil {
+		return a.handleAlertStatsKey(key)
+	}
+	if av.notifyLog != nil {
+		return a.handleNotifyLogKey(key)
+	}
 	if av.silenceModal != nil {
 		return a.handleSilenceDialogKey(key)
 	}
+	if av.backtest != nil {
+		return a.handleBacktestDialogKey(key)
+	}
 
 	// Alert/rule dialog captures keys.
 	if av.alertDialog {
@@ -212,10 +288,13 @@ func (a *App) handleAlertsKey(msg tea.KeyMsg) (App, tea.Cmd) {
 	if a.pendingKey == "g" {
 		a.pendingKey = ""
 		if key == "g" {
-			if av.focus == sectionAlerts {
+			switch av.focus {
+			case sectionAlerts:
 				av.alertCursor = 0
-			} else {
+			case sectionRules:
 				av.ruleCursor = 0
+			case sectionSilences:
+				av.silenceCursor = 0
 			}
 			return *a, nil
 		}
@@ -240,9 +319,12 @@ func (a *App) handleAlertsKey(msg tea.KeyMsg) (App, tea.Cmd) {
 		return *a, nil
 
 	case "tab":
-		if av.focus == sectionAlerts {
+		switch av.focus {
+		case sectionAlerts:
 			av.focus = sectionRules
-		} else {
+		case sectionRules:
+			av.focus = sectionSilences
+		default:
 			av.focus = sectionAlerts
 		}
 		return *a, nil
@@ -271,16 +353,23 @@ func (a *App) handleAlertsKey(msg tea.KeyMsg) (App, tea.Cmd) {
 		a.clampAlertsCursor()
 		return *a, nil
 
+	case "R":
+		return a.openAlertStats()
+
+	case "N":
+		return a.openNotifyLog()
+
 	case "enter":
 		if av.focus == sectionAlerts {
 			items := buildAlertList(s.Alerts, av.resolved, av.showResolved)
 			if av.alertCursor >= 0 && av.alertCursor < len(items) {
 				av.alertDialog = true
 			}
-		} else {
+		} else if av.focus == sectionRules {
 			if av.ruleCursor >= 0 && av.ruleCursor < len(av.rules) {
 				av.ruleDialog = true
 				av.testNotifyStatus = ""
+				av.ruleStatus = ""
 			}
 		}
 		return *a, nil
@@ -300,13 +389,43 @@ func (a *App) handleAlertsKey(msg tea.KeyMsg) (App, tea.Cmd) {
 		}
 		return *a, nil
 
+	case "b":
+		if av.focus == sectionRules {
+			return a.backtestRule()
+		}
+		return *a, nil
+
+	case "n":
+		if av.focus == sectionRules {
+			return a.openRuleEditor(true)
+		}
+		return *a, nil
+
+	case "e":
+		if av.focus == sectionRules {
+			return a.openRuleEditor(false)
+		}
+		return *a, nil
+
+	case "x":
+		if av.focus == sectionRules {
+			return a.toggleRuleDisabled()
+		}
+		return *a, nil
+
+	case "D":
+		if av.focus == sectionRules {
+			return a.deleteRule()
+		}
+		return *a, nil
+
 	case "y":
 		if av.focus == sectionAlerts {
 			items := buildAlertList(s.Alerts, av.resolved, av.showResolved)
 			if av.alertCursor >= 0 && av.alertCursor < len(items) {
 				yankToClipboard(items[av.alertCursor].message)
 			}
-		} else {
+		} else if av.focus == sectionRules {
 			if av.ruleCursor >= 0 && av.ruleCursor < len(av.rules) {
 				yankToClipboard(av.rules[av.ruleCursor].Condition)
 			}
@@ -323,10 +442,14 @@ func (a *App) handleAlertsKey(msg tea.KeyMsg) (App, tea.Cmd) {
 			if last := len(items) - 1; last >= 0 {
 				av.alertCursor = last
 			}
-		} else {
+		} else if av.focus == sectionRules {
 			if last := len(av.rules) - 1; last >= 0 {
 				av.ruleCursor = last
 			}
+		} else {
+			if last := len(av.silences) - 1; last >= 0 {
+				av.silenceCursor = last
+			}
 		}
 		return *a, nil
 	}
@@ -380,20 +503,36 @@ func (a *App) handleRuleDialogKey(key string) (App, tea.Cmd) {
 	}
 	av := &s.AlertsView
 
+	// Any other key cancels a pending delete.
+	if key != "D" && av.ruleStatus == ruleDeleteConfirm {
+		av.ruleStatus = ""
+	}
+
 	switch key {
 	case "esc", "enter":
 		av.ruleDialog = false
 		av.testNotifyStatus = ""
+		av.ruleStatus = ""
 	case "j", "down":
 		av.testNotifyStatus = ""
+		av.ruleStatus = ""
 		a.alertsNavigate(1)
 	case "k", "up":
 		av.testNotifyStatus = ""
+		av.ruleStatus = ""
 		a.alertsNavigate(-1)
 	case "s":
 		return a.handleAlertsSilence()
 	case "t":
 		return a.testNotifyRule()
+	case "b":
+		return a.backtestRule()
+	case "e":
+		return a.openRuleEditor(false)
+	case "x":
+		return a.toggleRuleDisabled()
+	case "D":
+		return a.deleteRule()
 	}
 	return *a, nil
 }
@@ -451,7 +590,8 @@ func (a *App) goToAlertContainer() (App, tea.Cmd) {
 	return a.enterDetailByContainerID(containerID)
 }
 
-// handleAlertsSilence handles the "s" key: toggle silence or open dialog.
+// handleAlertsSilence handles the "s" key: lift the silence that applies to
+// the selection, or open the silence dialog if there is none.
 func (a *App) handleAlertsSilence() (App, tea.Cmd) {
 	s := a.session()
 	if s == nil || s.Client == nil {
@@ -459,52 +599,70 @@ func (a *App) handleAlertsSilence() (App, tea.Cmd) {
 	}
 	av := &s.AlertsView
 
-	var ruleName string
-	var silencedUntil int64
-
-	if av.focus == sectionRules {
+	var ruleName, instanceKey string
+	switch av.focus {
+	case sectionSilences:
+		if av.silenceCursor < 0 || av.silenceCursor >= len(av.silences) {
+			return *a, nil
+		}
+		return *a, unsilenceCmd(s.Client, s.Name, av.silences[av.silenceCursor].ID)
+	case sectionRules:
 		if av.ruleCursor < 0 || av.ruleCursor >= len(av.rules) {
 			return *a, nil
 		}
-		rule := av.rules[av.ruleCursor]
-		ruleName = rule.Name
-		silencedUntil = rule.SilencedUntil
-	} else {
+		ruleName = av.rules[av.ruleCursor].Name
+	default:
 		items := buildAlertList(s.Alerts, av.resolved, av.showResolved)
 		if av.alertCursor < 0 || av.alertCursor >= len(items) {
 			return *a, nil
 		}
 		ruleName = items[av.alertCursor].ruleName
-		// Check if this rule is already silenced.
-		for _, r := range av.rules {
-			if r.Name == ruleName {
-				silencedUntil = r.SilencedUntil
-				break
-			}
-		}
+		instanceKey = items[av.alertCursor].instanceKey
 	}
 
-	// If already silenced, unsilence immediately (duration=0).
-	if silencedUntil > 0 && time.Unix(silencedUntil, 0).After(time.Now()) {
-		client := s.Client
-		server := s.Name
-		name := ruleName
-		return *a, func() tea.Msg {
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-			defer cancel()
-			client.SilenceAlert(ctx, name, 0)
-			return alertSilenceDoneMsg{server: server}
-		}
+	// If a silence already covers the selection, lift it.
+	if sl := findSilence(av.silences, ruleName, instanceKey); sl != nil {
+		return *a, unsilenceCmd(s.Client, s.Name, sl.ID)
 	}
 
 	// Open silence dialog.
 	av.silenceModal = &silenceModalState{
-		ruleName: ruleName,
-		cursor:   0,
+		ruleName:    ruleName,
+		instanceKey: instanceKey,
 	}
 	return *a, nil
 }
 
+// findSilence returns the active silence that mutes ruleName, preferring a
+// rule-wide silence over one for instanceKey. Returns nil if none applies.
+func findSilence(silences []protocol.SilenceMsg, ruleName, instanceKey string) *protocol.SilenceMsg {
+	now := time.Now().Unix()
+	var inst *protocol.SilenceMsg
+	for i := range silences {
+		sl := &silences[i]
+		if sl.ExpiresAt <= now || (sl.RuleName != "" && sl.RuleName != ruleName) {
+			continue
+		}
+		if sl.InstanceKey == "" {
+			return sl
+		}
+		if instanceKey != "" && sl.InstanceKey == instanceKey && inst == nil {
+			inst = sl
+		}
+	}
+	return inst
+}
+
+// unsilenceCmd lifts a silence by ID and refreshes the alerts view.
+func unsilenceCmd(client *Client, server string, id int64) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		client.Unsilence(ctx, id)
+		return alertSilenceDoneMsg{server: server}
+	}
+}
+
 // testNotifyRule sends a test notification for the current rule.
 func (a *App) testNotifyRule() (App, tea.Cmd) {
 	s := a.session()
@@ -528,7 +686,59 @@ func (a *App) testNotifyRule() (App, tea.Cmd) {
 	}
 }
 
-// handleSilenceDialogKey handles keys within the silence duration dialog.
+// backtestRule replays the current rule over the last backtestRange seconds
+// and opens the backtest dialog.
+func (a *App) backtestRule() (App, tea.Cmd) {
+	s := a.session()
+	if s == nil || s.Client == nil {
+		return *a, nil
+	}
+	av := &s.AlertsView
+	if av.ruleCursor < 0 || av.ruleCursor >= len(av.rules) {
+		return *a, nil
+	}
+	ruleName := av.rules[av.ruleCursor].Name
+	av.backtest = &backtestState{ruleName: ruleName, loading: true}
+	rangeSecs := int64(backtestRange)
+	if rd := int64(s.RetentionDays); rd > 0 && rd*86400 < rangeSecs {
+		rangeSecs = rd * 86400
+	}
+	client := s.Client
+	server := s.Name
+	return *a, func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		defer cancel()
+		end := time.Now().Unix()
+		start := end - rangeSecs
+		resp, err := client.QueryBacktest(ctx, ruleName, start, end)
+		return backtestDoneMsg{server: server, rule: ruleName, resp: resp, err: err}
+	}
+}
+
+// handleBacktestDialogKey handles keys within the backtest result dialog.
+func (a *App) handleBacktestDialogKey(key string) (App, tea.Cmd) {
+	s := a.session()
+	if s == nil {
+		return *a, nil
+	}
+	bt := s.AlertsView.backtest
+
+	switch key {
+	case "j", "down":
+		if bt.resp != nil && bt.scroll < len(bt.resp.Intervals)-1 {
+			bt.scroll++
+		}
+	case "k", "up":
+		if bt.scroll > 0 {
+			bt.scroll--
+		}
+	case "esc", "enter", "b":
+		s.AlertsView.backtest = nil
+	}
+	return *a, nil
+}
+
+// handleSilenceDialogKey handles keys within the silence dialog.
 func (a *App) handleSilenceDialogKey(key string) (App, tea.Cmd) {
 	s := a.session()
 	if s == nil {
@@ -538,28 +748,59 @@ func (a *App) handleSilenceDialogKey(key string) (App, tea.Cmd) {
 	m := av.silenceModal
 
 	switch key {
-	case "h", "left":
-		if m.cursor > 0 {
-			m.cursor--
-		}
-	case "l", "right":
-		if m.cursor < len(silenceDurations)-1 {
-			m.cursor++
+	case "tab":
+		m.focus = (m.focus + 1) % 3
+		if m.focus == 1 && m.instanceKey == "" {
+			m.focus = 2
 		}
+		return *a, nil
 	case "enter":
-		dur := silenceDurations[m.cursor].seconds
+		req := &protocol.SilenceAlertReq{
+			RuleName: m.ruleName,
+			Duration: silenceDurations[m.cursor].seconds,
+			Comment:  strings.TrimSpace(m.comment),
+		}
+		if m.instanceOnly {
+			req.InstanceKey = m.instanceKey
+		}
 		client := s.Client
 		server := s.Name
-		name := m.ruleName
 		av.silenceModal = nil
 		return *a, func() tea.Msg {
 			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 			defer cancel()
-			client.SilenceAlert(ctx, name, dur)
+			client.SilenceAlert(ctx, req)
 			return alertSilenceDoneMsg{server: server}
 		}
 	case "esc":
 		av.silenceModal = nil
+		return *a, nil
+	}
+
+	if m.focus == 2 {
+		if key == "backspace" {
+			if len(m.comment) > 0 {
+				m.comment = m.comment[:len(m.comment)-1]
+			}
+		} else if len(key) == 1 && len(m.comment) < maxSilenceComment {
+			m.comment += key
+		}
+		return *a, nil
+	}
+
+	switch key {
+	case "h", "left":
+		if m.focus == 1 {
+			m.instanceOnly = false
+		} else if m.cursor > 0 {
+			m.cursor--
+		}
+	case "l", "right":
+		if m.focus == 1 {
+			m.instanceOnly = true
+		} else if m.cursor < len(silenceDurations)-1 {
+			m.cursor++
+		}
 	}
 	return *a, nil
 }
@@ -572,11 +813,14 @@ func (a *App) alertsNavigate(delta int) {
 	}
 	av := &s.AlertsView
 
-	if av.focus == sectionAlerts {
+	switch av.focus {
+	case sectionAlerts:
 		items := buildAlertList(s.Alerts, av.resolved, av.showResolved)
 		clampNav(&av.alertCursor, delta, len(items))
-	} else {
+	case sectionRules:
 		clampNav(&av.ruleCursor, delta, len(av.rules))
+	case sectionSilences:
+		clampNav(&av.silenceCursor, delta, len(av.silences))
 	}
 }
 
@@ -0,0 +1,361 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Rule editor fields, in tab order. The name can only be typed when creating.
+const (
+	editName = iota
+	editCondition
+	editFor
+	editSeverity
+	editActions
+	editFieldCount
+)
+
+// maxRuleEditorInput caps each text field of the rule editor.
+const maxRuleEditorInput = 512
+
+var ruleSeverities = []string{"warning", "critical"}
+
+// ruleEditorState holds the rule editor dialog. Only rules created over the
+// socket can be edited; rules in the agent's config file are read-only.
+type ruleEditorState struct {
+	create    bool
+	base      string // TOML body of the edited rule; keys the editor doesn't show are kept
+	name      string
+	condition string
+	forDur    string
+	severity  int // index into ruleSeverities
+	actions   string
+	focus     int
+	saving    bool
+	err       string
+}
+
+// ruleSavedMsg reports the result of a rule editor save.
+type ruleSavedMsg struct {
+	server string
+	err    error
+}
+
+// ruleChangedMsg reports the result of disabling, enabling or deleting a rule.
+type ruleChangedMsg struct {
+	server string
+	status string // "disabled", "enabled", "deleted" or an error message
+}
+
+// openRuleEditor opens the editor for the current rule, or for a new rule
+// when create is set.
+func (a *App) openRuleEditor(create bool) (App, tea.Cmd) {
+	s := a.session()
+	if s == nil || s.Client == nil {
+		return *a, nil
+	}
+	av := &s.AlertsView
+	if create {
+		av.ruleEditor = &ruleEditorState{create: true, actions: "notify"}
+		return *a, nil
+	}
+	if av.ruleCursor < 0 || av.ruleCursor >= len(av.rules) {
+		return *a, nil
+	}
+	rule := av.rules[av.ruleCursor]
+	if !rule.Managed {
+		av.ruleDialog = true
+		av.ruleStatus = "defined in the config file, edit it there"
+		return *a, nil
+	}
+	ed := &ruleEditorState{
+		base:      rule.Config,
+		name:      rule.Name,
+		condition: rule.Condition,
+		actions:   strings.Join(rule.Actions, ", "),
+		focus:     editCondition,
+	}
+	if rule.For != "0s" {
+		ed.forDur = rule.For
+	}
+	for i, sev := range ruleSeverities {
+		if sev == strings.ToLower(rule.Severity) {
+			ed.severity = i
+		}
+	}
+	av.ruleEditor = ed
+	return *a, nil
+}
+
+// handleRuleEditorKey handles keys within the rule editor dialog.
+func (a *App) handleRuleEditorKey(key string) (App, tea.Cmd) {
+	s := a.session()
+	if s == nil {
+		return *a, nil
+	}
+	av := &s.AlertsView
+	ed := av.ruleEditor
+	if ed.saving {
+		if key == "esc" {
+			av.ruleEditor = nil
+		}
+		return *a, nil
+	}
+
+	switch key {
+	case "esc":
+		av.ruleEditor = nil
+		return *a, nil
+	case "tab", "down":
+		ed.focus = ed.nextField(1)
+		return *a, nil
+	case "shift+tab", "up":
+		ed.focus = ed.nextField(-1)
+		return *a, nil
+	case "enter":
+		config, err := ruleEditorConfig(ed.base, ed.condition, ed.forDur, ruleSeverities[ed.severity], ed.actions)
+		if err != nil {
+			ed.err = err.Error()
+			return *a, nil
+		}
+		ed.saving = true
+		ed.err = ""
+		client := s.Client
+		server := s.Name
+		name := strings.TrimSpace(ed.name)
+		create := ed.create
+		return *a, func() tea.Msg {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			var err error
+			if create {
+				err = client.CreateAlertRule(ctx, name, config)
+			} else {
+				err = client.UpdateAlertRule(ctx, name, config)
+			}
+			return ruleSavedMsg{server: server, err: err}
+		}
+	}
+
+	if ed.focus == editSeverity {
+		switch key {
+		case "h", "left":
+			ed.severity = 0
+		case "l", "right":
+			ed.severity = len(ruleSeverities) - 1
+		}
+		return *a, nil
+	}
+
+	field := ed.field(ed.focus)
+	if key == "backspace" {
+		if len(*field) > 0 {
+			*field = (*field)[:len(*field)-1]
+		}
+	} else if len(key) == 1 && len(*field) < maxRuleEditorInput {
+		*field += key
+	}
+	return *a, nil
+}
+
+// nextField returns the field delta steps from the focused one, skipping the
+// name when editing an existing rule.
+func (ed *ruleEditorState) nextField(delta int) int {
+	f := ed.focus
+	for {
+		f = (f + delta + editFieldCount) % editFieldCount
+		if f != editName || ed.create {
+			return f
+		}
+	}
+}
+
+// field returns the text of a text field.
+func (ed *ruleEditorState) field(f int) *string {
+	switch f {
+	case editName:
+		return &ed.name
+	case editCondition:
+		return &ed.condition
+	case editFor:
+		return &ed.forDur
+	default:
+		return &ed.actions
+	}
+}
+
+// ruleEditorConfig builds the TOML body sent to the agent. The editor's
+// fields replace those keys in base; everything else in base is kept.
+func ruleEditorConfig(base, condition, forDur, severity, actions string) (string, error) {
+	m := map[string]any{}
+	if base != "" {
+		if _, err := toml.Decode(base, &m); err != nil {
+			return "", fmt.Errorf("parse rule: %w", err)
+		}
+	}
+	m["condition"] = strings.TrimSpace(condition)
+	if forDur = strings.TrimSpace(forDur); forDur != "" {
+		m["for"] = forDur
+	} else {
+		delete(m, "for")
+	}
+	m["severity"] = severity
+	list := []string{}
+	for _, act := range strings.Split(actions, ",") {
+		if act = strings.TrimSpace(act); act != "" {
+			list = append(list, act)
+		}
+	}
+	m["actions"] = list
+
+	var b strings.Builder
+	if err := toml.NewEncoder(&b).Encode(m); err != nil {
+		return "", fmt.Errorf("encode rule: %w", err)
+	}
+	return b.String(), nil
+}
+
+// toggleRuleDisabled disables the current rule, or enables it if disabled.
+func (a *App) toggleRuleDisabled() (App, tea.Cmd) {
+	s := a.session()
+	if s == nil || s.Client == nil {
+		return *a, nil
+	}
+	av := &s.AlertsView
+	if av.ruleCursor < 0 || av.ruleCursor >= len(av.rules) {
+		return *a, nil
+	}
+	name := av.rules[av.ruleCursor].Name
+	disable := !av.rules[av.ruleCursor].Disabled
+	av.ruleStatus = ""
+	client := s.Client
+	server := s.Name
+	return *a, func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := client.DisableAlertRule(ctx, name, disable); err != nil {
+			return ruleChangedMsg{server: server, status: err.Error()}
+		}
+		if disable {
+			return ruleChangedMsg{server: server, status: "disabled"}
+		}
+		return ruleChangedMsg{server: server, status: "enabled"}
+	}
+}
+
+// deleteRule asks for confirmation in the rule dialog and deletes the
+// current rule on the second press of D. Only rules created over the socket
+// can be deleted.
+func (a *App) deleteRule() (App, tea.Cmd) {
+	s := a.session()
+	if s == nil || s.Client == nil {
+		return *a, nil
+	}
+	av := &s.AlertsView
+	if av.ruleCursor < 0 || av.ruleCursor >= len(av.rules) {
+		return *a, nil
+	}
+	rule := av.rules[av.ruleCursor]
+	if !rule.Managed {
+		av.ruleDialog = true
+		av.ruleStatus = "defined in the config file, disable it instead"
+		return *a, nil
+	}
+	if !av.ruleDialog || av.ruleStatus != ruleDeleteConfirm {
+		av.ruleDialog = true
+		av.ruleStatus = ruleDeleteConfirm
+		return *a, nil
+	}
+	av.ruleStatus = ""
+	client := s.Client
+	server := s.Name
+	return *a, func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := client.DeleteAlertRule(ctx, rule.Name); err != nil {
+			return ruleChangedMsg{server: server, status: err.Error()}
+		}
+		return ruleChangedMsg{server: server, status: "deleted"}
+	}
+}
+
+// ruleDeleteConfirm is the rule dialog status asking to press D again.
+const ruleDeleteConfirm = "press D again to delete"
+
+// renderRuleEditor renders the rule editor dialog.
+func renderRuleEditor(ed *ruleEditorState, width, height int, theme *Theme) string {
+	accent := lipgloss.NewStyle().Foreground(theme.Accent)
+	muted := mutedStyle(theme)
+	fg := fgStyle(theme)
+	cursorStyle := lipgloss.NewStyle().Reverse(true)
+
+	modalW := width * 60 / 100
+	if modalW < 56 {
+		modalW = 56
+	}
+	if modalW > 80 {
+		modalW = 80
+	}
+	valueW := modalW - 2 - 4 - 11
+
+	label := func(name string, f int) string {
+		if ed.focus == f {
+			return accent.Render(fmt.Sprintf("%-11s", name))
+		}
+		return muted.Render(fmt.Sprintf("%-11s", name))
+	}
+	text := func(value, placeholder string, f int) string {
+		if ed.focus == f {
+			// Keep the end of long input in view while typing.
+			if len(value) > valueW-1 {
+				value = value[len(value)-(valueW-1):]
+			}
+			return fg.Render(value) + cursorStyle.Render(" ")
+		}
+		if value == "" {
+			return muted.Render(placeholder)
+		}
+		return fg.Render(Truncate(value, valueW))
+	}
+
+	lines := []string{""}
+	if ed.create {
+		lines = append(lines, label("name", editName)+text(ed.name, "letters, digits, - and _", editName))
+	}
+	lines = append(lines, label("condition", editCondition)+text(ed.condition, "e.g. host.cpu_percent > 90", editCondition))
+	lines = append(lines, label("for", editFor)+text(ed.forDur, "optional, e.g. 5m", editFor))
+	var sevParts []string
+	for i, sev := range ruleSeverities {
+		if i == ed.severity {
+			sevParts = append(sevParts, accent.Bold(true).Render(sev))
+		} else {
+			sevParts = append(sevParts, muted.Render(sev))
+		}
+	}
+	lines = append(lines, label("severity", editSeverity)+strings.Join(sevParts, "   "))
+	lines = append(lines, label("actions", editActions)+text(ed.actions, "comma separated, e.g. notify, notify:slack-1", editActions))
+
+	if ed.saving {
+		lines = append(lines, "", muted.Render("saving..."))
+	} else if ed.err != "" {
+		lines = append(lines, "")
+		lines = appendWrapped(lines, "", ed.err, modalW-6, lipgloss.NewStyle().Foreground(theme.Critical))
+	}
+
+	title := "New rule"
+	if !ed.create {
+		title = "Edit " + ed.name
+	}
+	return (dialogLayout{
+		title: title,
+		width: modalW,
+		lines: lines,
+		tips:  dialogTips(theme, "tab", "field", "h/l", "severity", "enter", "save", "esc", "cancel"),
+	}).render(width, height, theme)
+}
@@ -0,0 +1,70 @@
+package tui
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+)
+
+func TestRuleEditorConfig(t *testing.T) {
+	base := "condition = \"container.state == 'exited'\"\nseverity = \"warning\"\nfor = \"1m\"\nactions = [\"notify\"]\nproject = \"shop\"\n\n[remediate]\nmax_attempts = 2\n"
+
+	tests := []struct {
+		name    string
+		base    string
+		forDur  string
+		actions string
+		want    map[string]any
+	}{
+		{
+			name:    "new rule",
+			forDur:  " 5m ",
+			actions: "notify, exec:/usr/local/bin/fix,",
+			want: map[string]any{
+				"condition": "host.cpu_percent > 90",
+				"severity":  "critical",
+				"for":       "5m",
+				"actions":   []any{"notify", "exec:/usr/local/bin/fix"},
+			},
+		},
+		{
+			name:    "edit keeps other keys and clears for",
+			base:    base,
+			actions: "docker:restart",
+			want: map[string]any{
+				"condition": "host.cpu_percent > 90",
+				"severity":  "critical",
+				"actions":   []any{"docker:restart"},
+				"project":   "shop",
+				"remediate": map[string]any{"max_attempts": int64(2)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := ruleEditorConfig(tt.base, " host.cpu_percent > 90", tt.forDur, "critical", tt.actions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := map[string]any{}
+			if _, err := toml.Decode(body, &got); err != nil {
+				t.Fatalf("decode %q: %v", body, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleEditorNextFieldSkipsName(t *testing.T) {
+	ed := &ruleEditorState{focus: editCondition}
+	if f := ed.nextField(-1); f != editActions {
+		t.Errorf("editing: previous of condition = %d, want actions", f)
+	}
+	ed.create = true
+	if f := ed.nextField(-1); f != editName {
+		t.Errorf("creating: previous of condition = %d, want name", f)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/thobiasn/tori-cli/internal/protocol"
 )
 
 // renderAlertDialog renders a centered overlay with alert details.
@@ -46,19 +47,14 @@ func renderAlertDialog(a *App, s *Session, width, height int) string {
 	}
 	lines = append(lines, muted.Render("condition:  ")+fg.Render(item.condition))
 
-	if item.message != "" {
-		valueW := innerW - 4 - labelW
-		if valueW < 10 {
-			valueW = 10
-		}
-		wrapped := wrapText(item.message, valueW)
-		for i, wl := range wrapped {
-			if i == 0 {
-				lines = append(lines, muted.Render("message:    ")+fg.Render(wl))
-			} else {
-				lines = append(lines, strings.Repeat(" ", labelW)+fg.Render(wl))
-			}
-		}
+	valueW := innerW - 4 - labelW
+	if valueW < 10 {
+		valueW = 10
+	}
+	lines = appendWrapped(lines, muted.Render("message:    "), item.message, valueW, fg)
+	lines = appendWrapped(lines, muted.Render("about:      "), item.description, valueW, fg)
+	if item.runbookURL != "" {
+		lines = append(lines, muted.Render("runbook:    ")+fg.Render(Truncate(item.runbookURL, valueW)))
 	}
 
 	firedStr := time.Unix(item.firedAt, 0).Format(a.tsFormat())
@@ -74,6 +70,25 @@ func renderAlertDialog(a *App, s *Session, width, height int) string {
 		ackStr = "yes"
 	}
 	lines = append(lines, muted.Render("acked:      ")+fg.Render(ackStr))
+	if item.inhibitedBy != "" {
+		lines = append(lines, muted.Render("inhibited:  ")+fg.Render("by "+item.inhibitedBy+" (not notified)"))
+	}
+	if item.flapping {
+		lines = append(lines, muted.Render("flapping:   ")+lipgloss.NewStyle().Foreground(theme.Warning).Render("yes (notifications suppressed until stable)"))
+	}
+	for i, r := range item.remediations {
+		label := strings.Repeat(" ", labelW)
+		if i == 0 {
+			label = muted.Render("remediated: ")
+		}
+		line := fmt.Sprintf("%s #%d · %s · ", r.Action, r.Attempt, time.Unix(r.Time, 0).Format(a.tsFormat()))
+		if r.Error != "" {
+			line += lipgloss.NewStyle().Foreground(theme.Critical).Render(Truncate("failed: "+r.Error, valueW-lipgloss.Width(line)))
+		} else {
+			line += lipgloss.NewStyle().Foreground(theme.Healthy).Render("ok")
+		}
+		lines = append(lines, label+fg.Render(line))
+	}
 
 	// Build tips.
 	var tipBindings []string
@@ -94,6 +109,23 @@ func renderAlertDialog(a *App, s *Session, width, height int) string {
 	}).render(width, height, theme)
 }
 
+// appendWrapped appends text wrapped to valueW, with label on the first line
+// and matching indentation after. Empty text appends nothing.
+func appendWrapped(lines []string, label, text string, valueW int, style lipgloss.Style) []string {
+	if text == "" {
+		return lines
+	}
+	indent := strings.Repeat(" ", lipgloss.Width(label))
+	for i, wl := range wrapText(text, valueW) {
+		if i == 0 {
+			lines = append(lines, label+style.Render(wl))
+		} else {
+			lines = append(lines, indent+style.Render(wl))
+		}
+	}
+	return lines
+}
+
 // renderRuleDialog renders a centered overlay with rule details.
 func renderRuleDialog(a *App, s *Session, width, height int) string {
 	theme := &a.theme
@@ -126,6 +158,27 @@ func renderRuleDialog(a *App, s *Session, width, height int) string {
 	lines = append(lines, "")
 
 	lines = append(lines, muted.Render("condition:  ")+fg.Render(rule.Condition))
+	const labelW = 12
+	valueW := modalW - 2 - 4 - labelW
+	source := "config file"
+	if rule.Managed {
+		source = "managed (created at runtime)"
+	}
+	if rule.Disabled {
+		source += ", disabled"
+	}
+	lines = append(lines, muted.Render("source:     ")+fg.Render(source))
+	lines = appendWrapped(lines, muted.Render("about:      "), rule.Description, valueW, fg)
+	if rule.RunbookURL != "" {
+		lines = append(lines, muted.Render("runbook:    ")+fg.Render(Truncate(rule.RunbookURL, valueW)))
+	}
+	lines = appendWrapped(lines, muted.Render("message:    "), rule.MessageTemplate, valueW, fg)
+	if rule.Selector != "" {
+		lines = append(lines, muted.Render("selector:   ")+fg.Render(rule.Selector))
+	}
+	for _, agg := range rule.Aggregates {
+		lines = append(lines, muted.Render("aggregate:  ")+fg.Render(agg))
+	}
 
 	if rule.For != "" && rule.For != "0s" {
 		lines = append(lines, muted.Render("for:        ")+fg.Render(rule.For))
@@ -136,6 +189,13 @@ func renderRuleDialog(a *App, s *Session, width, height int) string {
 		actionsStr = strings.Join(rule.Actions, ", ")
 	}
 	lines = append(lines, muted.Render("actions:    ")+fg.Render(actionsStr))
+	if len(rule.Actions) > 0 {
+		targets := "no enabled channel"
+		if len(rule.Channels) > 0 {
+			targets = strings.Join(rule.Channels, ", ")
+		}
+		lines = append(lines, muted.Render("notify to:  ")+fg.Render(targets))
+	}
 
 	silencedStr := "no"
 	if rule.SilencedUntil > 0 && time.Unix(rule.SilencedUntil, 0).After(now) {
@@ -159,35 +219,169 @@ func renderRuleDialog(a *App, s *Session, width, height int) string {
 		}
 		lines = append(lines, muted.Render("test:       ")+statusStyle.Render(av.testNotifyStatus))
 	}
+	if av.ruleStatus != "" {
+		statusStyle := lipgloss.NewStyle().Foreground(theme.Critical)
+		switch av.ruleStatus {
+		case "disabled", "enabled", "deleted":
+			statusStyle = lipgloss.NewStyle().Foreground(theme.Healthy)
+		case ruleDeleteConfirm:
+			statusStyle = lipgloss.NewStyle().Foreground(theme.Warning)
+		}
+		lines = appendWrapped(lines, muted.Render("status:     "), av.ruleStatus, valueW, statusStyle)
+	}
+
+	toggle := "disable"
+	if rule.Disabled {
+		toggle = "enable"
+	}
+	tips := []string{"t", "test notify", "b", "backtest", "s", "silence"}
+	if rule.Managed {
+		tips = append(tips, "e", "edit", "D", "delete")
+	}
+	tips = append(tips, "x", toggle, "esc", "close")
 
 	return (dialogLayout{
 		title: "rule",
 		width: modalW,
 		lines: lines,
-		tips:  dialogTips(theme, "t", "test notify", "s", "silence", "j/k", "navigate", "esc", "close"),
+		tips:  dialogTips(theme, tips...),
 	}).render(width, height, theme)
 }
 
-// renderSilenceDialog renders the silence duration picker modal.
-func renderSilenceDialog(m *silenceModalState, width, height int, theme *Theme) string {
+// renderSilenceDialog renders the silence dialog: duration, scope (when
+// opened on an alert instance) and an optional comment.
+func renderSilenceDialog(m *silenceModalState, contInfo []protocol.ContainerInfo, width, height int, theme *Theme) string {
 	accent := lipgloss.NewStyle().Foreground(theme.Accent)
 	muted := mutedStyle(theme)
+	fg := fgStyle(theme)
+	cursorStyle := lipgloss.NewStyle().Reverse(true)
+
+	const modalW = 56
+	label := func(name string, focused bool) string {
+		if focused {
+			return accent.Render(fmt.Sprintf("%-9s", name))
+		}
+		return muted.Render(fmt.Sprintf("%-9s", name))
+	}
+	choice := func(text string, selected bool) string {
+		if selected {
+			return accent.Bold(true).Render(text)
+		}
+		return muted.Render(text)
+	}
 
 	var parts []string
 	for i, d := range silenceDurations {
-		if i == m.cursor {
-			parts = append(parts, accent.Bold(true).Render(d.label))
-		} else {
-			parts = append(parts, muted.Render(d.label))
-		}
+		parts = append(parts, choice(d.label, i == m.cursor))
 	}
+	lines := []string{"", label("for", m.focus == 0) + strings.Join(parts, "   ")}
 
-	lines := []string{"", strings.Join(parts, "   ")}
+	if m.instanceKey != "" {
+		inst := Truncate(instanceDisplayName(m.instanceKey, contInfo), 20)
+		lines = append(lines, label("scope", m.focus == 1)+
+			choice("rule", !m.instanceOnly)+"   "+choice(inst+" only", m.instanceOnly))
+	}
+
+	comment := Truncate(m.comment, modalW-14)
+	if m.focus == 2 {
+		comment = fg.Render(comment) + cursorStyle.Render(" ")
+	} else if comment == "" {
+		comment = muted.Render("optional")
+	} else {
+		comment = fg.Render(comment)
+	}
+	lines = append(lines, label("comment", m.focus == 2)+comment)
 
 	return (dialogLayout{
-		title: "Silence",
-		width: 43,
+		title: "Silence " + m.ruleName,
+		width: modalW,
 		lines: lines,
-		tips:  dialogTips(theme, "h/l", "navigate", "enter", "apply", "esc", "cancel"),
+		tips:  dialogTips(theme, "tab", "field", "h/l", "choose", "enter", "apply", "esc", "cancel"),
 	}).render(width, height, theme)
 }
+
+// backtestRows is the number of interval rows shown at once in the backtest dialog.
+const backtestRows = 10
+
+// renderBacktestDialog renders the result of replaying a rule against history.
+func renderBacktestDialog(a *App, bt *backtestState, width, height int) string {
+	theme := &a.theme
+	muted := mutedStyle(theme)
+	fg := fgStyle(theme)
+
+	modalW := width * 70 / 100
+	if modalW < 60 {
+		modalW = 60
+	}
+	if modalW > 96 {
+		modalW = 96
+	}
+
+	var lines []string
+	lines = append(lines, lipgloss.NewStyle().Bold(true).Foreground(theme.FgBright).Render(bt.ruleName))
+	lines = append(lines, "")
+
+	switch {
+	case bt.loading:
+		lines = append(lines, muted.Render("replaying stored history..."))
+	case bt.err != "":
+		lines = append(lines, lipgloss.NewStyle().Foreground(theme.Critical).Render(bt.err))
+	case bt.resp != nil:
+		r := bt.resp
+		lines = append(lines, muted.Render("condition:  ")+fg.Render(r.Condition))
+		rangeStr := fmt.Sprintf("%s – %s · %d samples",
+			time.Unix(r.Start, 0).Format(a.tsFormat()), time.Unix(r.End, 0).Format(a.tsFormat()), r.Samples)
+		lines = append(lines, muted.Render("range:      ")+fg.Render(rangeStr))
+
+		var total int64
+		for _, iv := range r.Intervals {
+			total += backtestIntervalEnd(iv, r.End) - iv.FiredAt
+		}
+		firedStr := "would not have fired"
+		if n := len(r.Intervals); n > 0 {
+			noun := "times"
+			if n == 1 {
+				noun = "time"
+			}
+			firedStr = fmt.Sprintf("%d %s · %s firing", n, noun, formatCompactDuration(time.Duration(total)*time.Second))
+		}
+		lines = append(lines, muted.Render("fired:      ")+fg.Render(firedStr))
+
+		if len(r.Intervals) > 0 {
+			lines = append(lines, "")
+			end := min(bt.scroll+backtestRows, len(r.Intervals))
+			for _, iv := range r.Intervals[bt.scroll:end] {
+				resolved := "firing"
+				if iv.ResolvedAt > 0 {
+					resolved = time.Unix(iv.ResolvedAt, 0).Format(a.tsFormat())
+				}
+				dur := formatCompactDuration(time.Duration(backtestIntervalEnd(iv, r.End)-iv.FiredAt) * time.Second)
+				instance := iv.Label
+				if instance == "" {
+					instance = iv.InstanceKey
+				}
+				row := fmt.Sprintf("%s → %s  %6s  %s", time.Unix(iv.FiredAt, 0).Format(a.tsFormat()), resolved, dur, instance)
+				lines = append(lines, fg.Render(Truncate(row, modalW-6)))
+			}
+			if len(r.Intervals) > backtestRows {
+				lines = append(lines, muted.Render(fmt.Sprintf("%d–%d of %d", bt.scroll+1, end, len(r.Intervals))))
+			}
+		}
+	}
+
+	return (dialogLayout{
+		title: "backtest",
+		width: modalW,
+		lines: lines,
+		tips:  dialogTips(theme, "j/k", "scroll", "esc", "close"),
+	}).render(width, height, theme)
+}
+
+// backtestIntervalEnd returns when an interval stopped firing, or rangeEnd if
+// it was still firing at the end of the replay.
+func backtestIntervalEnd(iv protocol.BacktestInterval, rangeEnd int64) int64 {
+	if iv.ResolvedAt > 0 {
+		return iv.ResolvedAt
+	}
+	return rangeEnd
+}
@@ -0,0 +1,220 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/thobiasn/tori-cli/internal/protocol"
+)
+
+// notifyFilters are the delivery statuses the notifications dialog can show;
+// "" shows all.
+var notifyFilters = []string{"", "queued", "deferred", "failed"}
+
+// notifyRows is the number of deliveries shown at once in the dialog.
+const notifyRows = 12
+
+// notifyLogState holds the notification deliveries dialog.
+type notifyLogState struct {
+	filter  int // index into notifyFilters
+	loading bool
+	err     string
+	resp    *protocol.QueryNotificationsResp
+	cursor  int
+	gen     uint64 // stale responses are discarded
+}
+
+type notifyLogDoneMsg struct {
+	server string
+	gen    uint64
+	resp   *protocol.QueryNotificationsResp
+	err    error
+}
+
+// openNotifyLog opens the notification deliveries dialog.
+func (a *App) openNotifyLog() (App, tea.Cmd) {
+	s := a.session()
+	if s == nil || s.Client == nil {
+		return *a, nil
+	}
+	st := &notifyLogState{}
+	s.AlertsView.notifyLog = st
+	return *a, queryNotifyLog(s, st)
+}
+
+// queryNotifyLog starts loading the deliveries for st's filter.
+func queryNotifyLog(s *Session, st *notifyLogState) tea.Cmd {
+	st.loading = true
+	st.err = ""
+	st.gen++
+	gen := st.gen
+	status := notifyFilters[st.filter]
+	client := s.Client
+	server := s.Name
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		resp, err := client.QueryNotifications(ctx, status, 200)
+		return notifyLogDoneMsg{server: server, gen: gen, resp: resp, err: err}
+	}
+}
+
+// handleNotifyLogKey handles keys within the notification deliveries dialog.
+func (a *App) handleNotifyLogKey(key string) (App, tea.Cmd) {
+	s := a.session()
+	if s == nil {
+		return *a, nil
+	}
+	st := s.AlertsView.notifyLog
+
+	switch key {
+	case "j", "down":
+		if st.resp != nil && st.cursor < len(st.resp.Notifications)-1 {
+			st.cursor++
+		}
+	case "k", "up":
+		if st.cursor > 0 {
+			st.cursor--
+		}
+	case "tab":
+		st.filter = (st.filter + 1) % len(notifyFilters)
+		st.cursor = 0
+		return *a, queryNotifyLog(s, st)
+	case "r":
+		return *a, queryNotifyLog(s, st)
+	case "esc", "N":
+		s.AlertsView.notifyLog = nil
+	}
+	return *a, nil
+}
+
+// notifyStatusStyle returns the style for a delivery status.
+func notifyStatusStyle(status string, theme *Theme) lipgloss.Style {
+	switch status {
+	case "sent":
+		return lipgloss.NewStyle().Foreground(theme.Healthy)
+	case "failed":
+		return lipgloss.NewStyle().Foreground(theme.Critical)
+	case "deferred", "summarized":
+		return mutedStyle(theme)
+	}
+	return lipgloss.NewStyle().Foreground(theme.Warning)
+}
+
+// notifyRetryIn describes when a queued delivery is retried.
+func notifyRetryIn(next int64, now time.Time) string {
+	d := time.Unix(next, 0).Sub(now)
+	if d <= 0 {
+		return "now"
+	}
+	return "in " + formatCompactDuration(d)
+}
+
+// renderNotifyLog renders the notification deliveries dialog.
+func renderNotifyLog(a *App, st *notifyLogState, width, height int) string {
+	theme := &a.theme
+	accent := lipgloss.NewStyle().Foreground(theme.Accent).Bold(true)
+	muted := mutedStyle(theme)
+	fg := fgStyle(theme)
+
+	modalW := width * 70 / 100
+	if modalW < 64 {
+		modalW = 64
+	}
+	if modalW > 100 {
+		modalW = 100
+	}
+	const timeW, channelW, statusW, triesW = 8, 14, 10, 5
+	subjectW := modalW - 6 - timeW - channelW - statusW - triesW - 4
+
+	var tabs string
+	for i, f := range notifyFilters {
+		label := f
+		if label == "" {
+			label = "all"
+		}
+		if i > 0 {
+			tabs += muted.Render(" · ")
+		}
+		if i == st.filter {
+			tabs += accent.Render(label)
+		} else {
+			tabs += muted.Render(label)
+		}
+	}
+
+	var lines []string
+	switch {
+	case st.loading && st.resp == nil:
+		lines = append(lines, muted.Render("loading..."))
+	case st.err != "":
+		lines = append(lines, lipgloss.NewStyle().Foreground(theme.Critical).Render(st.err))
+	case st.resp != nil:
+		r := st.resp
+		summary := fmt.Sprintf("%d queued · %d failed", r.Queued, r.Failed)
+		if st.loading {
+			summary += " · loading..."
+		}
+		lines = append(lines, muted.Render("queue:   ")+fg.Render(summary), "", tabs)
+		if len(r.Notifications) == 0 {
+			lines = append(lines, muted.Render("no notifications"))
+			break
+		}
+		header := fmt.Sprintf("%-*s %-*s %-*s %*s %s", timeW, "TIME", channelW, "CHANNEL", statusW, "STATUS", triesW, "TRIES", "SUBJECT")
+		lines = append(lines, muted.Render(header))
+
+		st.cursor = min(st.cursor, len(r.Notifications)-1)
+		start := max(0, min(st.cursor-notifyRows/2, len(r.Notifications)-notifyRows))
+		end := min(start+notifyRows, len(r.Notifications))
+		for i := start; i < end; i++ {
+			n := r.Notifications[i]
+			row := fg.Render(fmt.Sprintf("%-*s %-*s ", timeW, time.Unix(n.CreatedAt, 0).Format("15:04:05"),
+				channelW, Truncate(n.Channel, channelW))) +
+				notifyStatusStyle(n.Status, theme).Render(fmt.Sprintf("%-*s", statusW, n.Status)) +
+				fg.Render(fmt.Sprintf(" %*d %s", triesW, n.Attempts, Truncate(n.Subject, subjectW)))
+			if i == st.cursor {
+				row = cursorRow(row, modalW-6)
+			}
+			lines = append(lines, row)
+		}
+		if len(r.Notifications) > notifyRows {
+			lines = append(lines, muted.Render(fmt.Sprintf("%d–%d of %d", start+1, end, len(r.Notifications))))
+		}
+
+		// Details of the selected delivery.
+		n := r.Notifications[st.cursor]
+		lines = append(lines, "", muted.Render("queued:  ")+fg.Render(time.Unix(n.CreatedAt, 0).Format(a.tsFormat())))
+		switch {
+		case n.Status == "sent":
+			lines = append(lines, muted.Render("sent:    ")+fg.Render(time.Unix(n.SentAt, 0).Format(a.tsFormat())))
+		case n.Status == "summarized":
+			lines = append(lines, muted.Render("merged:  ")+fg.Render(time.Unix(n.SentAt, 0).Format(a.tsFormat())))
+		case n.Status == "deferred" && n.NextAttempt > 0:
+			lines = append(lines, muted.Render("until:   ")+fg.Render(time.Unix(n.NextAttempt, 0).Format(a.tsFormat())))
+		case n.Status == "queued" && n.NextAttempt > 0:
+			lines = append(lines, muted.Render("retry:   ")+fg.Render(notifyRetryIn(n.NextAttempt, time.Now())))
+		}
+		if n.Error != "" {
+			for i, l := range wrapText(n.Error, modalW-15) {
+				label := "         "
+				if i == 0 {
+					label = "error:   "
+				}
+				lines = append(lines, muted.Render(label)+lipgloss.NewStyle().Foreground(theme.Critical).Render(l))
+				if i == 2 {
+					break
+				}
+			}
+		}
+	}
+
+	return (dialogLayout{
+		title: "notifications",
+		width: modalW,
+		lines: lines,
+		tips:  dialogTips(theme, "tab", "filter", "j/k", "select", "r", "refresh", "esc", "close"),
+	}).render(width, height, theme)
+}
@@ -0,0 +1,69 @@
+package tui
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/thobiasn/tori-cli/internal/protocol"
+)
+
+func TestNotifyLogFilterAndStaleResponse(t *testing.T) {
+	s := NewSession("test", nil, nil)
+	st := &notifyLogState{}
+	s.AlertsView.notifyLog = st
+	app := App{
+		sessions:      map[string]*Session{"test": s},
+		activeSession: "test",
+	}
+
+	// Each tab switches the filter and starts a new query.
+	for _, want := range []string{"queued", "deferred", "failed", ""} {
+		if _, cmd := app.handleNotifyLogKey("tab"); cmd == nil {
+			t.Fatal("tab should start a query")
+		}
+		if got := notifyFilters[st.filter]; got != want {
+			t.Errorf("filter = %q, want %q", got, want)
+		}
+	}
+	if !st.loading || st.gen != 4 {
+		t.Fatalf("loading = %v, gen = %d, want true, 4", st.loading, st.gen)
+	}
+
+	// A response for an earlier filter is dropped.
+	result, _ := app.Update(notifyLogDoneMsg{server: "test", gen: 3, err: errors.New("stale")})
+	app = result.(App)
+	if !st.loading || st.err != "" {
+		t.Fatalf("stale response applied: loading = %v, err = %q", st.loading, st.err)
+	}
+
+	resp := &protocol.QueryNotificationsResp{Queued: 1}
+	result, _ = app.Update(notifyLogDoneMsg{server: "test", gen: 4, resp: resp})
+	app = result.(App)
+	if st.loading || st.resp != resp {
+		t.Fatalf("current response not applied: loading = %v, resp = %v", st.loading, st.resp)
+	}
+
+	app.handleNotifyLogKey("esc")
+	if s.AlertsView.notifyLog != nil {
+		t.Error("esc should close the notification log")
+	}
+}
+
+func TestNotifyRetryIn(t *testing.T) {
+	now := time.Unix(1000, 0)
+	tests := []struct {
+		next int64
+		want string
+	}{
+		{900, "now"},
+		{1000, "now"},
+		{1030, "in 30s"},
+		{1000 + 1800, "in 30m"},
+	}
+	for _, tt := range tests {
+		if got := notifyRetryIn(tt.next, now); got != tt.want {
+			t.Errorf("notifyRetryIn(%d) = %q, want %q", tt.next, got, tt.want)
+		}
+	}
+}
@@ -35,14 +35,22 @@ func renderAlerts(a *App, s *Session, width, height int) string {
 	// 4. Alerts section label.
 	sections = append(sections, sectionLabel("alerts", av.focus == sectionAlerts, theme))
 
-	// Calculate space: fixed overhead = bird(1) + blank(1) + header(1) + divider(2) + alerts label(1) + divider(1) + rules label(1) + divider(1) + help(1) = 10
-	fixedH := 10
+	// Calculate space: fixed overhead = bird(1) + blank(1) + header(1) + divider(2) + alerts label(1) + divider(1) + rules label(1) + divider(1) + silences label(1) + divider(1) + help(1) = 12
+	fixedH := 12
 	remaining := height - fixedH
-	if remaining < 4 {
-		remaining = 4
+	if remaining < 6 {
+		remaining = 6
 	}
-	alertsH := remaining / 2
-	rulesH := remaining - alertsH
+	// Silences get a small band sized to their count; alerts and rules split the rest.
+	silencesH := len(av.silences)
+	if silencesH < 1 {
+		silencesH = 1
+	}
+	if maxH := remaining / 4; silencesH > maxH {
+		silencesH = max(maxH, 1)
+	}
+	alertsH := (remaining - silencesH) / 2
+	rulesH := remaining - silencesH - alertsH
 
 	// 5. Alert rows.
 	sections = append(sections, renderAlertRows(s, contentW, alertsH, theme))
@@ -59,7 +67,14 @@ func renderAlerts(a *App, s *Session, width, height int) string {
 	// 9. Divider.
 	sections = append(sections, renderDivider(contentW, theme))
 
-	// 10. Help bar.
+	// 10. Silences section label and rows.
+	sections = append(sections, sectionLabel("silences", av.focus == sectionSilences, theme))
+	sections = append(sections, renderSilenceRows(s, contentW, silencesH, theme))
+
+	// 11. Divider.
+	sections = append(sections, renderDivider(contentW, theme))
+
+	// 12. Help bar.
 	sections = append(sections, renderAlertsHelp(contentW, theme))
 
 	result := pageFrame(strings.Join(sections, "\n"), contentW, width, height)
@@ -74,9 +89,25 @@ func renderAlerts(a *App, s *Session, width, height int) string {
 		result = Overlay(result, modal, width, height)
 	}
 
-	// Overlay silence dialog (layers on top of detail dialog).
+	// Overlay silence and backtest dialogs (layer on top of detail dialog).
 	if av.silenceModal != nil {
-		modal := renderSilenceDialog(av.silenceModal, width, height, theme)
+		modal := renderSilenceDialog(av.silenceModal, s.ContInfo, width, height, theme)
+		result = Overlay(result, modal, width, height)
+	}
+	if av.backtest != nil {
+		modal := renderBacktestDialog(a, av.backtest, width, height)
+		result = Overlay(result, modal, width, height)
+	}
+	if av.ruleEditor != nil {
+		modal := renderRuleEditor(av.ruleEditor, width, height, theme)
+		result = Overlay(result, modal, width, height)
+	}
+	if av.stats != nil {
+		modal := renderAlertStats(a, s, av.stats, width, height)
+		result = Overlay(result, modal, width, height)
+	}
+	if av.notifyLog != nil {
+		modal := renderNotifyLog(a, av.notifyLog, width, height)
 		result = Overlay(result, modal, width, height)
 	}
 
@@ -100,6 +131,9 @@ func renderAlertsHeader(s *Session, w int, theme *Theme) string {
 	}
 
 	line := nameBold + sep + statusStr
+	if m := activeMaintenance(s.Maintenance, time.Now()); m != nil {
+		line += sep + lipgloss.NewStyle().Foreground(theme.Warning).Render(maintenanceLabel(m, time.Now()))
+	}
 	return centerText(line, w)
 }
 
@@ -142,7 +176,8 @@ func renderAlertRows(s *Session, w, maxH int, theme *Theme) string {
 }
 
 // renderAlertRow renders a single alert row.
-// Firing rows are vivid; resolved rows are uniformly dimmed.
+// Firing rows are vivid; resolved rows are uniformly dimmed. Flapping
+// instances get a ≈ icon instead of ▲.
 func renderAlertRow(item alertItem, w int, now time.Time, contInfo []protocol.ContainerInfo, theme *Theme) string {
 	muted := mutedStyle(theme)
 	sevColor := severityColor(item.severity, theme)
@@ -153,9 +188,14 @@ func renderAlertRow(item alertItem, w int, now time.Time, contInfo []protocol.Co
 		instanceName = instanceDisplayName(item.instanceKey, contInfo)
 	}
 
+	glyph := "▲"
+	if item.flapping {
+		glyph = "≈"
+	}
+
 	if item.resolved {
 		// Entire row dimmed.
-		icon := muted.Render("▲")
+		icon := muted.Render(glyph)
 		label := muted.Render(fmt.Sprintf("%-4s", severityLabel(item.severity)))
 		name := muted.Render(item.ruleName)
 
@@ -163,6 +203,9 @@ func renderAlertRow(item alertItem, w int, now time.Time, contInfo []protocol.Co
 		if instanceName != "" {
 			left += " " + muted.Render("· "+instanceName)
 		}
+		if item.inhibitedBy != "" {
+			left += " " + muted.Render("· inhibited by "+item.inhibitedBy)
+		}
 
 		ago := formatCompactDuration(now.Sub(time.Unix(item.resolvedAt, 0)))
 		right := muted.Render(ago + " ago")
@@ -170,7 +213,7 @@ func renderAlertRow(item alertItem, w int, now time.Time, contInfo []protocol.Co
 	}
 
 	// Firing — vivid.
-	icon := lipgloss.NewStyle().Foreground(sevColor).Render("▲")
+	icon := lipgloss.NewStyle().Foreground(sevColor).Render(glyph)
 	label := lipgloss.NewStyle().Foreground(sevColor).Render(fmt.Sprintf("%-4s", severityLabel(item.severity)))
 	name := lipgloss.NewStyle().Foreground(theme.FgBright).Render(item.ruleName)
 
@@ -178,10 +221,16 @@ func renderAlertRow(item alertItem, w int, now time.Time, contInfo []protocol.Co
 	if instanceName != "" {
 		left += " " + muted.Render("·") + " " + lipgloss.NewStyle().Foreground(theme.Fg).Render(instanceName)
 	}
+	if item.inhibitedBy != "" {
+		left += " " + muted.Render("· inhibited by "+item.inhibitedBy)
+	}
 
 	dur := formatCompactDuration(now.Sub(time.Unix(item.firedAt, 0)))
 	sev := lipgloss.NewStyle().Foreground(sevColor)
 	statusLabel := "FIRING"
+	if item.flapping {
+		statusLabel = "FLAPPING"
+	}
 	if item.acked {
 		statusLabel = "ACK"
 	}
@@ -213,6 +262,34 @@ func renderRuleRows(av *AlertsState, w, maxH int, theme *Theme) string {
 	return scrollAndPad(lines, av.ruleCursor, maxH)
 }
 
+// actionKinds summarizes rule actions by kind, e.g. "notify+exec" or
+// "notify+restart".
+func actionKinds(actions []string) string {
+	var notify, exec bool
+	var docker string
+	for _, a := range actions {
+		switch {
+		case strings.HasPrefix(a, "exec:"):
+			exec = true
+		case strings.HasPrefix(a, "docker:"):
+			docker = strings.TrimPrefix(a, "docker:")
+		default:
+			notify = true
+		}
+	}
+	var kinds []string
+	if notify {
+		kinds = append(kinds, "notify")
+	}
+	if exec {
+		kinds = append(kinds, "exec")
+	}
+	if docker != "" {
+		kinds = append(kinds, docker)
+	}
+	return strings.Join(kinds, "+")
+}
+
 // renderRuleRow renders a single line for a rule with fixed-width columns.
 // Layout: "  ▲ WARN  " (10) + name (nameW) + "  " + condition (fill) + action (actionW) + status (statusW)
 func renderRuleRow(rule protocol.AlertRuleInfo, w int, now time.Time, theme *Theme) string {
@@ -221,7 +298,7 @@ func renderRuleRow(rule protocol.AlertRuleInfo, w int, now time.Time, theme *The
 
 	const prefixW = 10 // "  ▲ WARN  "
 	const nameW = 18
-	const actionW = 8
+	const actionW = 16
 	const statusW = 12
 
 	icon := lipgloss.NewStyle().Foreground(sevColor).Render("▲")
@@ -239,17 +316,18 @@ func renderRuleRow(rule protocol.AlertRuleInfo, w int, now time.Time, theme *The
 	if condW < 4 {
 		condW = 4
 	}
-	condStr := Truncate(rule.Condition, condW)
+	cond := rule.Condition
+	if rule.Selector != "" {
+		cond += " · " + rule.Selector
+	}
+	condStr := Truncate(cond, condW)
 	for len(condStr) < condW {
 		condStr += " "
 	}
 	condStyled := muted.Render(condStr)
 
 	// Action — right-aligned within actionW.
-	actionText := ""
-	if len(rule.Actions) > 0 {
-		actionText = "notify"
-	}
+	actionText := Truncate(actionKinds(rule.Actions), actionW-2)
 	actionPad := actionW - len(actionText)
 	if actionPad < 0 {
 		actionPad = 0
@@ -259,7 +337,10 @@ func renderRuleRow(rule protocol.AlertRuleInfo, w int, now time.Time, theme *The
 	// Status — right-aligned within statusW.
 	var statusText string
 	var statusStyle lipgloss.Style
-	if rule.SilencedUntil > 0 && time.Unix(rule.SilencedUntil, 0).After(now) {
+	if rule.Disabled {
+		statusText = "disabled"
+		statusStyle = muted
+	} else if rule.SilencedUntil > 0 && time.Unix(rule.SilencedUntil, 0).After(now) {
 		statusText = "silenced"
 		statusStyle = muted
 	} else if rule.FiringCount > 0 {
@@ -269,6 +350,9 @@ func renderRuleRow(rule protocol.AlertRuleInfo, w int, now time.Time, theme *The
 			statusText = fmt.Sprintf("firing(%d)", rule.FiringCount)
 		}
 		statusStyle = lipgloss.NewStyle().Foreground(sevColor)
+	} else if rule.Maintenance != "" && time.Unix(rule.MaintenanceEnd, 0).After(now) {
+		statusText = "maintenance"
+		statusStyle = lipgloss.NewStyle().Foreground(theme.Warning)
 	} else {
 		statusText = "ok"
 		statusStyle = lipgloss.NewStyle().Foreground(theme.Healthy)
@@ -282,6 +366,50 @@ func renderRuleRow(rule protocol.AlertRuleInfo, w int, now time.Time, theme *The
 	return "  " + icon + " " + label + "  " + nameStyled + "  " + condStyled + actionStyled + statusStyled
 }
 
+// renderSilenceRows renders the active silences section.
+// Layout: "  ◌ " + target + " · by creator — comment" ... "expires in 45m"
+func renderSilenceRows(s *Session, w, maxH int, theme *Theme) string {
+	av := &s.AlertsView
+	muted := mutedStyle(theme)
+	fg := fgStyle(theme)
+
+	if len(av.silences) == 0 {
+		return padLines(muted.Render("  no active silences"), maxH)
+	}
+
+	now := time.Now()
+	var lines []string
+	for idx, sl := range av.silences {
+		left := "  " + muted.Render("◌") + " " + fg.Render(silenceTarget(sl, s.ContInfo))
+		if sl.CreatedBy != "" {
+			left += muted.Render(" · by " + sl.CreatedBy)
+		}
+		if sl.Comment != "" {
+			left += muted.Render(" — " + sl.Comment)
+		}
+		right := muted.Render("expires in " + formatCompactDuration(time.Unix(sl.ExpiresAt, 0).Sub(now)))
+		row := padBetween(TruncateStyled(left, w-lipgloss.Width(right)-1), right, w)
+
+		if av.focus == sectionSilences && idx == av.silenceCursor {
+			row = cursorRow(row, w)
+		}
+		lines = append(lines, TruncateStyled(row, w))
+	}
+	return scrollAndPad(lines, av.silenceCursor, maxH)
+}
+
+// silenceTarget describes what a silence mutes: a rule, one of its
+// instances, or an instance of any rule.
+func silenceTarget(sl protocol.SilenceMsg, contInfo []protocol.ContainerInfo) string {
+	switch {
+	case sl.InstanceKey == "":
+		return sl.RuleName
+	case sl.RuleName == "":
+		return instanceDisplayName(sl.InstanceKey, contInfo) + " (all rules)"
+	}
+	return sl.RuleName + " · " + instanceDisplayName(sl.InstanceKey, contInfo)
+}
+
 // renderAlertsHelp renders the footer help bar for the alerts view.
 func renderAlertsHelp(w int, theme *Theme) string {
 	return renderHelpBar([]helpBinding{
@@ -3,6 +3,9 @@ package tui
 import (
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/thobiasn/tori-cli/internal/protocol"
 )
 
 func TestScrollAndPad(t *testing.T) {
@@ -67,3 +70,51 @@ func TestSectionLabel(t *testing.T) {
 		t.Errorf("inactive sectionLabel = %q, want Rules", stripped)
 	}
 }
+
+func TestSilenceTarget(t *testing.T) {
+	contInfo := []protocol.ContainerInfo{{ID: "aaa", Name: "web"}}
+	tests := []struct {
+		sl   protocol.SilenceMsg
+		want string
+	}{
+		{protocol.SilenceMsg{RuleName: "high_cpu"}, "high_cpu"},
+		{protocol.SilenceMsg{RuleName: "exited", InstanceKey: "exited:aaa"}, "exited · web"},
+		{protocol.SilenceMsg{InstanceKey: "exited:aaa"}, "web (all rules)"},
+	}
+	for _, tt := range tests {
+		if got := silenceTarget(tt.sl, contInfo); got != tt.want {
+			t.Errorf("silenceTarget(%+v) = %q, want %q", tt.sl, got, tt.want)
+		}
+	}
+}
+
+func TestFindSilence(t *testing.T) {
+	future := time.Now().Add(time.Hour).Unix()
+	past := time.Now().Add(-time.Hour).Unix()
+	silences := []protocol.SilenceMsg{
+		{ID: 1, RuleName: "exited", InstanceKey: "exited:aaa", ExpiresAt: future},
+		{ID: 2, RuleName: "high_cpu", ExpiresAt: past},
+		{ID: 3, RuleName: "disk", ExpiresAt: future},
+		{ID: 4, RuleName: "disk", InstanceKey: "disk:/", ExpiresAt: future},
+	}
+	tests := []struct {
+		rule, key string
+		want      int64 // 0 = none
+	}{
+		{"exited", "exited:aaa", 1},
+		{"exited", "exited:bbb", 0},
+		{"exited", "", 0},
+		{"high_cpu", "", 0},   // expired
+		{"disk", "disk:/", 3}, // rule-wide wins
+	}
+	for _, tt := range tests {
+		got := findSilence(silences, tt.rule, tt.key)
+		var id int64
+		if got != nil {
+			id = got.ID
+		}
+		if id != tt.want {
+			t.Errorf("findSilence(%q, %q) = %d, want %d", tt.rule, tt.key, id, tt.want)
+		}
+	}
+}
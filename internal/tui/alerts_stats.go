@@ -0,0 +1,214 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/thobiasn/tori-cli/internal/protocol"
+)
+
+// statsRanges are the time ranges the alert report can cover.
+var statsRanges = []timeWindow{
+	{"24h", 24 * 3600},
+	{"7d", 7 * 86400},
+	{"30d", 30 * 86400},
+}
+
+// statsRows is the number of table rows shown at once in the alert report.
+const statsRows = 10
+
+// alertStatsState holds the alert report dialog.
+type alertStatsState struct {
+	rangeIdx   int  // index into statsRanges
+	containers bool // show the per-container table instead of per-rule
+	loading    bool
+	err        string
+	resp       *protocol.QueryAlertStatsResp
+	scroll     int
+	gen        uint64 // stale responses are discarded
+}
+
+type alertStatsDoneMsg struct {
+	server string
+	gen    uint64
+	resp   *protocol.QueryAlertStatsResp
+	err    error
+}
+
+// openAlertStats opens the alert report over the last 7 days, or less if the
+// agent keeps less history.
+func (a *App) openAlertStats() (App, tea.Cmd) {
+	s := a.session()
+	if s == nil || s.Client == nil {
+		return *a, nil
+	}
+	st := &alertStatsState{rangeIdx: 1}
+	for st.rangeIdx > 0 && !statsRangeAllowed(s, st.rangeIdx) {
+		st.rangeIdx--
+	}
+	s.AlertsView.stats = st
+	return *a, queryAlertStats(s, st)
+}
+
+// statsRangeAllowed reports whether statsRanges[i] fits in the agent's retention.
+func statsRangeAllowed(s *Session, i int) bool {
+	return s.RetentionDays <= 0 || statsRanges[i].seconds <= int64(s.RetentionDays)*86400
+}
+
+// queryAlertStats starts loading the report for st's range.
+func queryAlertStats(s *Session, st *alertStatsState) tea.Cmd {
+	st.loading = true
+	st.err = ""
+	st.scroll = 0
+	st.gen++
+	gen := st.gen
+	rangeSecs := statsRanges[st.rangeIdx].seconds
+	client := s.Client
+	server := s.Name
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		end := time.Now().Unix()
+		resp, err := client.QueryAlertStats(ctx, end-rangeSecs, end)
+		return alertStatsDoneMsg{server: server, gen: gen, resp: resp, err: err}
+	}
+}
+
+// handleAlertStatsKey handles keys within the alert report dialog.
+func (a *App) handleAlertStatsKey(key string) (App, tea.Cmd) {
+	s := a.session()
+	if s == nil {
+		return *a, nil
+	}
+	st := s.AlertsView.stats
+
+	switch key {
+	case "j", "down":
+		if st.scroll < len(st.rows())-1 {
+			st.scroll++
+		}
+	case "k", "up":
+		if st.scroll > 0 {
+			st.scroll--
+		}
+	case "tab":
+		st.containers = !st.containers
+		st.scroll = 0
+	case "+", "=":
+		if st.rangeIdx+1 < len(statsRanges) && statsRangeAllowed(s, st.rangeIdx+1) {
+			st.rangeIdx++
+			return *a, queryAlertStats(s, st)
+		}
+	case "-":
+		if st.rangeIdx > 0 {
+			st.rangeIdx--
+			return *a, queryAlertStats(s, st)
+		}
+	case "esc", "R":
+		s.AlertsView.stats = nil
+	}
+	return *a, nil
+}
+
+// rows returns the table the dialog currently shows.
+func (st *alertStatsState) rows() []protocol.AlertStats {
+	if st.resp == nil {
+		return nil
+	}
+	if st.containers {
+		return st.resp.Containers
+	}
+	return st.resp.Rules
+}
+
+// formatStatsDuration formats a duration in seconds for the alert report,
+// with "–" for none.
+func formatStatsDuration(secs int64) string {
+	if secs <= 0 {
+		return "–"
+	}
+	return formatCompactDuration(time.Duration(secs) * time.Second)
+}
+
+// renderAlertStats renders the alert report dialog.
+func renderAlertStats(a *App, s *Session, st *alertStatsState, width, height int) string {
+	theme := &a.theme
+	accent := lipgloss.NewStyle().Foreground(theme.Accent).Bold(true)
+	muted := mutedStyle(theme)
+	fg := fgStyle(theme)
+
+	modalW := width * 70 / 100
+	if modalW < 64 {
+		modalW = 64
+	}
+	if modalW > 96 {
+		modalW = 96
+	}
+	nameW := modalW - 6 - 4*8
+
+	var lines []string
+	switch {
+	case st.loading && st.resp == nil:
+		lines = append(lines, muted.Render("loading..."))
+	case st.err != "":
+		lines = append(lines, lipgloss.NewStyle().Foreground(theme.Critical).Render(st.err))
+	case st.resp != nil:
+		r := st.resp
+		tot := r.Total
+		rangeStr := fmt.Sprintf("last %s · %s – %s", statsRanges[st.rangeIdx].label,
+			time.Unix(r.Start, 0).Format(a.tsFormat()), time.Unix(r.End, 0).Format(a.tsFormat()))
+		if st.loading {
+			rangeStr += " · loading..."
+		}
+		lines = append(lines, muted.Render("range:   ")+fg.Render(rangeStr))
+		if tot.Fired == 0 {
+			lines = append(lines, muted.Render("fired:   ")+fg.Render("no alerts"))
+			break
+		}
+		lines = append(lines, muted.Render("fired:   ")+fg.Render(fmt.Sprintf("%d · %d acknowledged · %d resolved",
+			tot.Fired, tot.Acknowledged, tot.Resolved)))
+		lines = append(lines, muted.Render("firing:  ")+fg.Render(formatStatsDuration(tot.FiringSecs)+" total"))
+		lines = append(lines, muted.Render("MTTA:    ")+fg.Render(fmt.Sprintf("%-8s", formatStatsDuration(tot.MTTASecs)))+
+			muted.Render("MTTR: ")+fg.Render(formatStatsDuration(tot.MTTRSecs)))
+
+		rulesTab, contTab := accent.Render("rules"), muted.Render("containers")
+		if st.containers {
+			rulesTab, contTab = muted.Render("rules"), accent.Render("containers")
+		}
+		lines = append(lines, "", rulesTab+muted.Render(" · ")+contTab+muted.Render("  (noisiest first)"))
+
+		rows := st.rows()
+		if len(rows) == 0 {
+			lines = append(lines, muted.Render("no container alerts"))
+			break
+		}
+		header := fmt.Sprintf("%-*s %7s %7s %7s %7s", nameW, "NAME", "FIRED", "FIRING", "MTTA", "MTTR")
+		lines = append(lines, muted.Render(header))
+		end := min(st.scroll+statsRows, len(rows))
+		for _, row := range rows[st.scroll:end] {
+			name := row.Name
+			if st.containers {
+				if svc := serviceNameByID(row.Name, s.ContInfo); svc != "" {
+					name = svc
+				} else if len(name) > 12 {
+					name = name[:12]
+				}
+			}
+			lines = append(lines, fg.Render(fmt.Sprintf("%-*s %7d %7s %7s %7s", nameW, Truncate(name, nameW), row.Fired,
+				formatStatsDuration(row.FiringSecs), formatStatsDuration(row.MTTASecs), formatStatsDuration(row.MTTRSecs))))
+		}
+		if len(rows) > statsRows {
+			lines = append(lines, muted.Render(fmt.Sprintf("%d–%d of %d", st.scroll+1, end, len(rows))))
+		}
+	}
+
+	return (dialogLayout{
+		title: "alert report",
+		width: modalW,
+		lines: lines,
+		tips:  dialogTips(theme, "+/-", "range", "tab", "rules/containers", "j/k", "scroll", "esc", "close"),
+	}).render(width, height, theme)
+}
@@ -0,0 +1,40 @@
+package tui
+
+import "testing"
+
+func TestStatsRangeAllowed(t *testing.T) {
+	tests := []struct {
+		retention int
+		want      []bool // per statsRanges entry
+	}{
+		{0, []bool{true, true, true}},
+		{1, []bool{true, false, false}},
+		{7, []bool{true, true, false}},
+		{30, []bool{true, true, true}},
+	}
+	for _, tt := range tests {
+		s := &Session{RetentionDays: tt.retention}
+		for i, want := range tt.want {
+			if got := statsRangeAllowed(s, i); got != want {
+				t.Errorf("retention %d, range %s: got %v, want %v", tt.retention, statsRanges[i].label, got, want)
+			}
+		}
+	}
+}
+
+func TestFormatStatsDuration(t *testing.T) {
+	tests := []struct {
+		secs int64
+		want string
+	}{
+		{0, "–"},
+		{45, "45s"},
+		{600, "10m"},
+		{3 * 86400, "3d"},
+	}
+	for _, tt := range tests {
+		if got := formatStatsDuration(tt.secs); got != tt.want {
+			t.Errorf("formatStatsDuration(%d) = %q, want %q", tt.secs, got, tt.want)
+		}
+	}
+}
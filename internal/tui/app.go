@@ -50,8 +50,9 @@ type trackingDoneMsg struct {
 	server string
 }
 type ruleCountMsg struct {
-	server string
-	count  int
+	server      string
+	count       int
+	maintenance []protocol.MaintenanceInfo
 }
 type helloMsg struct {
 	server       string
@@ -193,7 +194,7 @@ func (a *App) hasActiveSubModal() bool {
 	}
 	if a.view == viewAlerts {
 		av := &s.AlertsView
-		return av.silenceModal != nil || av.alertDialog || av.ruleDialog
+		return av.silenceModal != nil || av.backtest != nil || av.alertDialog || av.ruleDialog || av.ruleEditor != nil || av.stats != nil || av.notifyLog != nil
 	}
 	return a.switcher
 }
@@ -280,11 +281,11 @@ func queryRuleCount(c *Client) tea.Cmd {
 	return func() tea.Msg {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
-		rules, err := c.QueryAlertRules(ctx)
+		resp, err := c.QueryAlertRules(ctx)
 		if err != nil {
 			return nil
 		}
-		return ruleCountMsg{server: c.server, count: len(rules)}
+		return ruleCountMsg{server: c.server, count: len(resp.Rules), maintenance: resp.Maintenance}
 	}
 }
 
@@ -434,13 +435,20 @@ func (a App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 			}
 		}
+		var cmds []tea.Cmd
+		// Maintenance windows open and close on their own schedule;
+		// refresh rule status at most once a minute to track them.
+		if s := a.sessions[msg.Server]; s != nil && s.Client != nil && time.Since(s.RulesQueriedAt) >= time.Minute {
+			s.RulesQueriedAt = time.Now()
+			cmds = append(cmds, queryRuleCount(s.Client))
+		}
 		if !a.birdBlink {
 			a.birdBlink = true
-			return a, tea.Tick(150*time.Millisecond, func(time.Time) tea.Msg {
+			cmds = append(cmds, tea.Tick(150*time.Millisecond, func(time.Time) tea.Msg {
 				return birdBlinkResetMsg{}
-			})
+			}))
 		}
-		return a, nil
+		return a, tea.Batch(cmds...)
 
 	case LogMsg:
 		if s := a.sessions[msg.Server]; s != nil {
@@ -532,6 +540,7 @@ func (a App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case ruleCountMsg:
 		if s := a.sessions[msg.server]; s != nil {
 			s.RuleCount = msg.count
+			s.Maintenance = msg.maintenance
 		}
 		return a, nil
 
@@ -539,8 +548,15 @@ func (a App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if s := a.sessions[msg.server]; s != nil {
 			s.AlertsView.rules = msg.rules
 			s.AlertsView.resolved = msg.resolved
+			s.AlertsView.silences = msg.silences
+			clampNav(&s.AlertsView.silenceCursor, 0, len(msg.silences))
+			clampNav(&s.AlertsView.ruleCursor, 0, len(msg.rules))
+			if len(msg.rules) == 0 {
+				s.AlertsView.ruleDialog = false
+			}
 			s.AlertsView.loaded = true
 			s.RuleCount = len(msg.rules)
+			s.Maintenance = msg.maintenance
 		}
 		return a, nil
 
@@ -562,6 +578,76 @@ func (a App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return a, nil
 
+	case ruleSavedMsg:
+		if s := a.sessions[msg.server]; s != nil {
+			if ed := s.AlertsView.ruleEditor; ed != nil {
+				ed.saving = false
+				if msg.err != nil {
+					ed.err = msg.err.Error()
+					return a, nil
+				}
+				s.AlertsView.ruleEditor = nil
+			}
+			if s.Client != nil {
+				return a, queryAlertsData(s.Client, msg.server)
+			}
+		}
+		return a, nil
+
+	case ruleChangedMsg:
+		if s := a.sessions[msg.server]; s != nil {
+			s.AlertsView.ruleStatus = msg.status
+			switch msg.status {
+			case "deleted":
+				s.AlertsView.ruleDialog = false
+			case "disabled", "enabled":
+			default:
+				s.AlertsView.ruleDialog = true // show the error
+			}
+			if s.Client != nil {
+				return a, queryAlertsData(s.Client, msg.server)
+			}
+		}
+		return a, nil
+
+	case backtestDoneMsg:
+		if s := a.sessions[msg.server]; s != nil {
+			if bt := s.AlertsView.backtest; bt != nil && bt.ruleName == msg.rule {
+				bt.loading = false
+				bt.resp = msg.resp
+				if msg.err != nil {
+					bt.err = msg.err.Error()
+				}
+			}
+		}
+		return a, nil
+
+	case alertStatsDoneMsg:
+		if s := a.sessions[msg.server]; s != nil {
+			if st := s.AlertsView.stats; st != nil && st.gen == msg.gen {
+				st.loading = false
+				if msg.err != nil {
+					st.err = msg.err.Error()
+				} else {
+					st.resp = msg.resp
+				}
+			}
+		}
+		return a, nil
+
+	case notifyLogDoneMsg:
+		if s := a.sessions[msg.server]; s != nil {
+			if st := s.AlertsView.notifyLog; st != nil && st.gen == msg.gen {
+				st.loading = false
+				if msg.err != nil {
+					st.err = msg.err.Error()
+				} else {
+					st.resp = msg.resp
+				}
+			}
+		}
+		return a, nil
+
 	case spinnerTickMsg:
 		a.spinnerFrame++
 		return a, spinnerTick()
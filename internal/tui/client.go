@@ -281,7 +281,7 @@ func (c *Client) QueryAlerts(ctx context.Context, start, end int64) ([]protocol.
 }
 
 // QueryAlertRules returns the list of configured alert rules and their status.
-func (c *Client) QueryAlertRules(ctx context.Context) ([]protocol.AlertRuleInfo, error) {
+func (c *Client) QueryAlertRules(ctx context.Context) (*protocol.QueryAlertRulesResp, error) {
 	resp, err := c.Request(ctx, protocol.TypeQueryAlertRules, nil)
 	if err != nil {
 		return nil, err
@@ -290,7 +290,48 @@ func (c *Client) QueryAlertRules(ctx context.Context) ([]protocol.AlertRuleInfo,
 	if err := protocol.DecodeBody(resp.Body, &r); err != nil {
 		return nil, err
 	}
-	return r.Rules, nil
+	return &r, nil
+}
+
+// QueryBacktest replays a rule against the agent's stored history.
+func (c *Client) QueryBacktest(ctx context.Context, rule string, start, end int64) (*protocol.QueryBacktestResp, error) {
+	resp, err := c.Request(ctx, protocol.TypeQueryBacktest, &protocol.QueryBacktestReq{RuleName: rule, Start: start, End: end})
+	if err != nil {
+		return nil, err
+	}
+	var r protocol.QueryBacktestResp
+	if err := protocol.DecodeBody(resp.Body, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// QueryAlertStats fetches alert counts, firing time, MTTA and MTTR for the
+// alerts fired in a time range.
+func (c *Client) QueryAlertStats(ctx context.Context, start, end int64) (*protocol.QueryAlertStatsResp, error) {
+	resp, err := c.Request(ctx, protocol.TypeQueryAlertStats, &protocol.QueryAlertStatsReq{Start: start, End: end})
+	if err != nil {
+		return nil, err
+	}
+	var r protocol.QueryAlertStatsResp
+	if err := protocol.DecodeBody(resp.Body, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// QueryNotifications fetches the most recent notification deliveries,
+// optionally only those with the given status.
+func (c *Client) QueryNotifications(ctx context.Context, status string, limit int) (*protocol.QueryNotificationsResp, error) {
+	resp, err := c.Request(ctx, protocol.TypeQueryNotifications, &protocol.QueryNotificationsReq{Status: status, Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+	var r protocol.QueryNotificationsResp
+	if err := protocol.DecodeBody(resp.Body, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
 }
 
 // AckAlert acknowledges an alert by ID.
@@ -299,18 +340,62 @@ func (c *Client) AckAlert(ctx context.Context, alertID int64) error {
 	return err
 }
 
-// SilenceAlert silences a rule for the given duration (seconds).
-func (c *Client) SilenceAlert(ctx context.Context, rule string, dur int64) error {
-	_, err := c.Request(ctx, protocol.TypeActionSilence, &protocol.SilenceAlertReq{RuleName: rule, Duration: dur})
+// SilenceAlert creates a silence for a rule and/or instance key.
+func (c *Client) SilenceAlert(ctx context.Context, req *protocol.SilenceAlertReq) error {
+	_, err := c.Request(ctx, protocol.TypeActionSilence, req)
 	return err
 }
 
+// Unsilence lifts an active silence by ID.
+func (c *Client) Unsilence(ctx context.Context, id int64) error {
+	_, err := c.Request(ctx, protocol.TypeActionUnsilence, &protocol.UnsilenceReq{SilenceID: id})
+	return err
+}
+
+// QuerySilences fetches the agent's active silences.
+func (c *Client) QuerySilences(ctx context.Context) ([]protocol.SilenceMsg, error) {
+	resp, err := c.Request(ctx, protocol.TypeQuerySilences, nil)
+	if err != nil {
+		return nil, err
+	}
+	var r protocol.QuerySilencesResp
+	if err := protocol.DecodeBody(resp.Body, &r); err != nil {
+		return nil, err
+	}
+	return r.Silences, nil
+}
+
 // TestNotify sends a test notification for the given rule name.
 func (c *Client) TestNotify(ctx context.Context, ruleName string) error {
 	_, err := c.Request(ctx, protocol.TypeActionTestNotify, &protocol.TestNotifyReq{RuleName: ruleName})
 	return err
 }
 
+// CreateAlertRule adds an alert rule at runtime. config is the TOML body of
+// the rule, as under [alerts.<name>] in the agent config.
+func (c *Client) CreateAlertRule(ctx context.Context, name, config string) error {
+	_, err := c.Request(ctx, protocol.TypeActionCreateAlertRule, &protocol.SaveAlertRuleReq{Name: name, Config: config})
+	return err
+}
+
+// UpdateAlertRule replaces an alert rule created at runtime.
+func (c *Client) UpdateAlertRule(ctx context.Context, name, config string) error {
+	_, err := c.Request(ctx, protocol.TypeActionUpdateAlertRule, &protocol.SaveAlertRuleReq{Name: name, Config: config})
+	return err
+}
+
+// DisableAlertRule disables or re-enables an alert rule.
+func (c *Client) DisableAlertRule(ctx context.Context, name string, disabled bool) error {
+	_, err := c.Request(ctx, protocol.TypeActionDisableAlertRule, &protocol.DisableAlertRuleReq{Name: name, Disabled: disabled})
+	return err
+}
+
+// DeleteAlertRule removes an alert rule created at runtime.
+func (c *Client) DeleteAlertRule(ctx context.Context, name string) error {
+	_, err := c.Request(ctx, protocol.TypeActionDeleteAlertRule, &protocol.DeleteAlertRuleReq{Name: name})
+	return err
+}
+
 // SetTracking toggles tracking for a container name or compose project.
 func (c *Client) SetTracking(ctx context.Context, container, project string, tracked bool) error {
 	_, err := c.Request(ctx, protocol.TypeActionSetTracking, &protocol.SetTrackingReq{
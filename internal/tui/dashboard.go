@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/thobiasn/tori-cli/internal/protocol"
@@ -297,6 +298,10 @@ func renderStatusLine(s *Session, w int, theme *Theme) string {
 		parts = append(parts, muted.Render(FormatUptime(s.Host.Uptime)+" uptime"))
 	}
 
+	if m := activeMaintenance(s.Maintenance, time.Now()); m != nil {
+		parts = append(parts, lipgloss.NewStyle().Foreground(theme.Warning).Render(maintenanceLabel(m, time.Now())))
+	}
+
 	if s.VersionWarning != "" {
 		warn := lipgloss.NewStyle().Foreground(theme.Warning).Render(s.VersionWarning)
 		parts = append(parts, warn)
@@ -306,6 +311,29 @@ func renderStatusLine(s *Session, w int, theme *Theme) string {
 	return centerText(line, w)
 }
 
+// activeMaintenance returns the first maintenance window that is active at
+// now, or nil. Windows whose reported end has passed are ignored until the
+// next refresh.
+func activeMaintenance(windows []protocol.MaintenanceInfo, now time.Time) *protocol.MaintenanceInfo {
+	for i := range windows {
+		if windows[i].Active && windows[i].Until > now.Unix() {
+			return &windows[i]
+		}
+	}
+	return nil
+}
+
+// maintenanceLabel formats an active window as "maintenance: NAME until 04:00",
+// adding the weekday when the window ends on a later day.
+func maintenanceLabel(m *protocol.MaintenanceInfo, now time.Time) string {
+	until := time.Unix(m.Until, 0)
+	layout := "15:04"
+	if y1, m1, d1 := until.Date(); y1 != now.Year() || m1 != now.Month() || d1 != now.Day() {
+		layout = "Mon 15:04"
+	}
+	return fmt.Sprintf("maintenance: %s until %s", m.Name, until.Format(layout))
+}
+
 // countUntrackedStubs counts ContInfo entries not present in Containers.
 func countUntrackedStubs(s *Session) int {
 	seen := make(map[string]bool, len(s.Containers))
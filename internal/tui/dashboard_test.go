@@ -3,6 +3,7 @@ package tui
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/thobiasn/tori-cli/internal/protocol"
 )
@@ -366,3 +367,26 @@ func TestServiceNameByID(t *testing.T) {
 		})
 	}
 }
+
+func TestActiveMaintenance(t *testing.T) {
+	now := time.Date(2025, 1, 4, 23, 0, 0, 0, time.Local)
+	windows := []protocol.MaintenanceInfo{
+		{Name: "backups", Schedule: "0 3 * * * for 30m"},
+		{Name: "stale", Active: true, Until: now.Add(-time.Minute).Unix()},
+		{Name: "deploys", Active: true, Until: now.Add(3 * time.Hour).Unix()},
+	}
+	m := activeMaintenance(windows, now)
+	if m == nil || m.Name != "deploys" {
+		t.Fatalf("activeMaintenance = %+v, want deploys", m)
+	}
+	if got := maintenanceLabel(m, now); got != "maintenance: deploys until Sun 02:00" {
+		t.Errorf("label = %q", got)
+	}
+	m.Until = now.Add(30 * time.Minute).Unix()
+	if got := maintenanceLabel(m, now); got != "maintenance: deploys until 23:30" {
+		t.Errorf("label = %q", got)
+	}
+	if activeMaintenance(windows[:2], now) != nil {
+		t.Error("expected no active window")
+	}
+}
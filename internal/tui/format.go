@@ -320,9 +320,15 @@ func renderHelpModal(a *App, _ *Session, width, height int) string {
 			{"h/l", "navigate modal"},
 			{"enter", "expand details"},
 			{"a", "acknowledge alert"},
-			{"s", "silence rule/alert"},
+			{"s", "silence/unsilence"},
 			{"t", "test notification"},
+			{"b", "backtest rule"},
+			{"n/e", "new/edit rule"},
+			{"x", "disable/enable rule"},
+			{"D", "delete rule"},
 			{"r", "show/hide resolved"},
+			{"R", "alert report"},
+			{"N", "notification log"},
 			{"gd", "go to container"},
 		}
 	default: // dashboard
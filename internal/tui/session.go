@@ -2,6 +2,7 @@ package tui
 
 import (
 	"context"
+	"time"
 
 	"github.com/thobiasn/tori-cli/internal/protocol"
 )
@@ -39,7 +40,9 @@ type Session struct {
 	VersionWarning string
 
 	// Alert rules.
-	RuleCount int // number of configured alert rules
+	RuleCount      int                        // number of configured alert rules
+	Maintenance    []protocol.MaintenanceInfo // configured maintenance windows
+	RulesQueriedAt time.Time                  // last rule status refresh
 
 	// History for dashboard graphs.
 	HostCPUHist     *RingBuffer[float64]
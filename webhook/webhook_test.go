@@ -0,0 +1,61 @@
+package webhook
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	// Computed with: printf '1700000000.{"text":"hi"}' | openssl dgst -sha256 -hmac s3cret
+	got := Sign("s3cret", 1700000000, []byte(`{"text":"hi"}`))
+	want := "sha256=a4abab2c9ec335a751cf8c3848e413a84a4e0eef17a9660d993911fafacadb66"
+	if got != want {
+		t.Errorf("Sign = %q, want %q", got, want)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"text":"disk full"}`)
+	signed := func(secret string, at time.Time) http.Header {
+		h := http.Header{}
+		SignRequest(h, secret, body, at)
+		return h
+	}
+
+	tests := []struct {
+		name   string
+		header http.Header
+		body   []byte
+		want   error
+	}{
+		{"valid", signed("s3cret", now), body, nil},
+		{"within tolerance", signed("s3cret", now.Add(-4*time.Minute)), body, nil},
+		{"clock skew", signed("s3cret", now.Add(time.Minute)), body, nil},
+		{"expired", signed("s3cret", now.Add(-6*time.Minute)), body, ErrExpired},
+		{"future", signed("s3cret", now.Add(6*time.Minute)), body, ErrExpired},
+		{"wrong secret", signed("other", now), body, ErrInvalidSignature},
+		{"tampered body", signed("s3cret", now), []byte(`{"text":"all good"}`), ErrInvalidSignature},
+		{"unsigned", http.Header{}, body, ErrMissingSignature},
+		{"bad timestamp", http.Header{
+			TimestampHeader: {"yesterday"},
+			SignatureHeader: {Sign("s3cret", now.Unix(), body)},
+		}, body, ErrInvalidSignature},
+		{"replayed timestamp", func() http.Header {
+			// Signature from an old request with a fresh timestamp.
+			h := signed("s3cret", now.Add(-time.Hour))
+			h.Set(TimestampHeader, "1700000000")
+			return h
+		}(), body, ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verify("s3cret", tt.header, tt.body, DefaultTolerance, now)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("verify = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}